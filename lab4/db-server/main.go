@@ -13,33 +13,51 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"isbd4/openapi"
+	"isbd4/pkg/backup"
 	"isbd4/pkg/engine"
+	"isbd4/pkg/engine/compaction"
 	"isbd4/pkg/metadata"
 	"isbd4/pkg/service"
 )
 
 func main() {
-	log.Printf("Server started")
 	dbmsBaseDir := ".dbms_data"
 	chunkSize := uint64(1000)
 	maxRowsInFile := uint64(10000)
 	memoryLimitBytes := uint64(10 * 1024 * 1024) // 10MB default
+	compactionInterval := 30 * time.Second
+
+	// "server restore <backup directory>" rebuilds the data directory instead of starting the server
+	if len(os.Args) == 3 && os.Args[1] == "restore" {
+		if err := backup.Restore(os.Args[2], dbmsBaseDir); err != nil {
+			log.Fatalf("Restore failed: %v", err)
+		}
+		log.Printf("Restored %s from backup %s", dbmsBaseDir, os.Args[2])
+		return
+	}
+
+	log.Printf("Server started")
 
 	metastore := metadata.NewMetastore(dbmsBaseDir)
 
 	queryManager := engine.NewQueryManager(metastore, dbmsBaseDir, chunkSize, maxRowsInFile, memoryLimitBytes)
 
-	ExecutionAPIService := service.NewExecutionAPIService(queryManager)
+	compactor := compaction.NewCompactor(metastore, chunkSize, maxRowsInFile, compactionInterval)
+	compactor.Start()
+
+	SchemaAPIService := service.NewSchemaAPIService(metastore, compactor, dbmsBaseDir)
+	SchemaAPIController := openapi.NewSchemaAPIController(SchemaAPIService)
+
+	ExecutionAPIService := service.NewExecutionAPIService(queryManager, SchemaAPIService)
 	ExecutionAPIController := openapi.NewExecutionAPIController(ExecutionAPIService)
 
 	MetadataAPIService := service.NewMetadataAPIService()
 	MetadataAPIController := openapi.NewMetadataAPIController(MetadataAPIService)
 
-	SchemaAPIService := service.NewSchemaAPIService(metastore)
-	SchemaAPIController := openapi.NewSchemaAPIController(SchemaAPIService)
-
 	router := openapi.NewRouter(ExecutionAPIController, MetadataAPIController, SchemaAPIController)
 
 	// Required for Swagger UI
@@ -24,6 +24,7 @@ type ExecutionAPIRouter interface {
 	SubmitQuery(http.ResponseWriter, *http.Request)
 	GetQueryResult(http.ResponseWriter, *http.Request)
 	GetQueryError(http.ResponseWriter, *http.Request)
+	SubmitSql(http.ResponseWriter, *http.Request)
 }
 
 // MetadataAPIRouter defines the required methods for binding the api requests to a responses for the MetadataAPI
@@ -64,6 +65,18 @@ type SchemaAPIRouter interface {
 	GetTableById(http.ResponseWriter, *http.Request)
 	DeleteTable(http.ResponseWriter, *http.Request)
 	CreateTable(http.ResponseWriter, *http.Request)
+	AnalyzeTable(http.ResponseWriter, *http.Request)
+	GetTableVersions(http.ResponseWriter, *http.Request)
+	GetCompactionStatus(http.ResponseWriter, *http.Request)
+	CompactTable(http.ResponseWriter, *http.Request)
+	GetIndexes(http.ResponseWriter, *http.Request)
+	CreateIndex(http.ResponseWriter, *http.Request)
+	DropIndex(http.ResponseWriter, *http.Request)
+	CloneTable(http.ResponseWriter, *http.Request)
+	GetDatabases(http.ResponseWriter, *http.Request)
+	CreateDatabase(http.ResponseWriter, *http.Request)
+	DropDatabase(http.ResponseWriter, *http.Request)
+	Backup(http.ResponseWriter, *http.Request)
 }
 
 // ExecutionAPIServicer defines the api actions for the ExecutionAPI service
@@ -76,6 +89,7 @@ type ExecutionAPIServicer interface {
 	SubmitQuery(context.Context, ExecuteQueryRequest) (ImplResponse, error)
 	GetQueryResult(context.Context, string, GetQueryResultRequest) (ImplResponse, error)
 	GetQueryError(context.Context, string) (ImplResponse, error)
+	SubmitSql(context.Context, ExecuteSqlRequest) (ImplResponse, error)
 }
 
 // MetadataAPIServicer defines the api actions for the MetadataAPI service
@@ -120,4 +134,16 @@ type SchemaAPIServicer interface {
 	GetTableById(context.Context, string) (ImplResponse, error)
 	DeleteTable(context.Context, string) (ImplResponse, error)
 	CreateTable(context.Context, TableSchema) (ImplResponse, error)
+	AnalyzeTable(context.Context, string) (ImplResponse, error)
+	GetTableVersions(context.Context, string) (ImplResponse, error)
+	GetCompactionStatus(context.Context, string) (ImplResponse, error)
+	CompactTable(context.Context, string) (ImplResponse, error)
+	GetIndexes(context.Context, string) (ImplResponse, error)
+	CreateIndex(context.Context, string, string) (ImplResponse, error)
+	DropIndex(context.Context, string, string) (ImplResponse, error)
+	CloneTable(context.Context, string, CloneTableRequest) (ImplResponse, error)
+	GetDatabases(context.Context) (ImplResponse, error)
+	CreateDatabase(context.Context, Database) (ImplResponse, error)
+	DropDatabase(context.Context, string, bool) (ImplResponse, error)
+	Backup(context.Context, BackupRequest) (ImplResponse, error)
 }
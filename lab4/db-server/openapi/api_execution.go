@@ -83,6 +83,12 @@ func (c *ExecutionAPIController) Routes() Routes {
 			"/error/{queryId}",
 			c.GetQueryError,
 		},
+		"SubmitSql": Route{
+			"SubmitSql",
+			strings.ToUpper("Post"),
+			"/sql",
+			c.SubmitSql,
+		},
 	}
 }
 
@@ -119,6 +125,12 @@ func (c *ExecutionAPIController) OrderedRoutes() []Route {
 			"/error/{queryId}",
 			c.GetQueryError,
 		},
+		Route{
+			"SubmitSql",
+			strings.ToUpper("Post"),
+			"/sql",
+			c.SubmitSql,
+		},
 	}
 }
 
@@ -229,3 +241,30 @@ func (c *ExecutionAPIController) GetQueryError(w http.ResponseWriter, r *http.Re
 	// If no error, encode the body and the result code
 	_ = EncodeJSONResponse(result.Body, &result.Code, w)
 }
+
+// SubmitSql - Execute a SQL statement: SELECT and COPY are submitted like queries of /query, CREATE TABLE and DROP TABLE change the schema like /table
+func (c *ExecutionAPIController) SubmitSql(w http.ResponseWriter, r *http.Request) {
+	var executeSqlRequestParam ExecuteSqlRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&executeSqlRequestParam); err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	if err := AssertExecuteSqlRequestRequired(executeSqlRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := AssertExecuteSqlRequestConstraints(executeSqlRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.SubmitSql(r.Context(), executeSqlRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
@@ -75,6 +75,78 @@ func (c *SchemaAPIController) Routes() Routes {
 			"/table",
 			c.CreateTable,
 		},
+		"AnalyzeTable": Route{
+			"AnalyzeTable",
+			strings.ToUpper("Post"),
+			"/table/{tableId}/analyze",
+			c.AnalyzeTable,
+		},
+		"GetTableVersions": Route{
+			"GetTableVersions",
+			strings.ToUpper("Get"),
+			"/table/{tableId}/versions",
+			c.GetTableVersions,
+		},
+		"GetCompactionStatus": Route{
+			"GetCompactionStatus",
+			strings.ToUpper("Get"),
+			"/table/{tableId}/compaction",
+			c.GetCompactionStatus,
+		},
+		"CompactTable": Route{
+			"CompactTable",
+			strings.ToUpper("Post"),
+			"/table/{tableId}/compaction",
+			c.CompactTable,
+		},
+		"GetIndexes": Route{
+			"GetIndexes",
+			strings.ToUpper("Get"),
+			"/table/{tableId}/indexes",
+			c.GetIndexes,
+		},
+		"CreateIndex": Route{
+			"CreateIndex",
+			strings.ToUpper("Put"),
+			"/table/{tableId}/index/{columnName}",
+			c.CreateIndex,
+		},
+		"DropIndex": Route{
+			"DropIndex",
+			strings.ToUpper("Delete"),
+			"/table/{tableId}/index/{columnName}",
+			c.DropIndex,
+		},
+		"CloneTable": Route{
+			"CloneTable",
+			strings.ToUpper("Put"),
+			"/table/{tableId}/clone",
+			c.CloneTable,
+		},
+		"GetDatabases": Route{
+			"GetDatabases",
+			strings.ToUpper("Get"),
+			"/databases",
+			c.GetDatabases,
+		},
+		"CreateDatabase": Route{
+			"CreateDatabase",
+			strings.ToUpper("Put"),
+			"/database",
+			c.CreateDatabase,
+		},
+		"DropDatabase": Route{
+			"DropDatabase",
+			strings.ToUpper("Delete"),
+			"/database/{databaseName}",
+			c.DropDatabase,
+		},
+		"Backup": Route{
+			"Backup",
+			strings.ToUpper("Post"),
+			"/backup",
+			c.Backup,
+		},
 	}
 }
 
@@ -105,6 +177,78 @@ func (c *SchemaAPIController) OrderedRoutes() []Route {
 			"/table",
 			c.CreateTable,
 		},
+		Route{
+			"AnalyzeTable",
+			strings.ToUpper("Post"),
+			"/table/{tableId}/analyze",
+			c.AnalyzeTable,
+		},
+		Route{
+			"GetTableVersions",
+			strings.ToUpper("Get"),
+			"/table/{tableId}/versions",
+			c.GetTableVersions,
+		},
+		Route{
+			"GetCompactionStatus",
+			strings.ToUpper("Get"),
+			"/table/{tableId}/compaction",
+			c.GetCompactionStatus,
+		},
+		Route{
+			"CompactTable",
+			strings.ToUpper("Post"),
+			"/table/{tableId}/compaction",
+			c.CompactTable,
+		},
+		Route{
+			"GetIndexes",
+			strings.ToUpper("Get"),
+			"/table/{tableId}/indexes",
+			c.GetIndexes,
+		},
+		Route{
+			"CreateIndex",
+			strings.ToUpper("Put"),
+			"/table/{tableId}/index/{columnName}",
+			c.CreateIndex,
+		},
+		Route{
+			"DropIndex",
+			strings.ToUpper("Delete"),
+			"/table/{tableId}/index/{columnName}",
+			c.DropIndex,
+		},
+		Route{
+			"CloneTable",
+			strings.ToUpper("Put"),
+			"/table/{tableId}/clone",
+			c.CloneTable,
+		},
+		Route{
+			"GetDatabases",
+			strings.ToUpper("Get"),
+			"/databases",
+			c.GetDatabases,
+		},
+		Route{
+			"CreateDatabase",
+			strings.ToUpper("Put"),
+			"/database",
+			c.CreateDatabase,
+		},
+		Route{
+			"DropDatabase",
+			strings.ToUpper("Delete"),
+			"/database/{databaseName}",
+			c.DropDatabase,
+		},
+		Route{
+			"Backup",
+			strings.ToUpper("Post"),
+			"/backup",
+			c.Backup,
+		},
 	}
 }
 
@@ -182,3 +326,277 @@ func (c *SchemaAPIController) CreateTable(w http.ResponseWriter, r *http.Request
 	// If no error, encode the body and the result code
 	_ = EncodeJSONResponse(result.Body, &result.Code, w)
 }
+
+// AnalyzeTable - Recompute statistics of selected table
+func (c *SchemaAPIController) AnalyzeTable(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	tableIdParam := params["tableId"]
+	if tableIdParam == "" {
+		c.errorHandler(w, r, &RequiredError{"tableId"}, nil)
+		return
+	}
+	result, err := c.service.AnalyzeTable(r.Context(), tableIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetTableVersions - Get retained versions of selected table
+func (c *SchemaAPIController) GetTableVersions(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	tableIdParam := params["tableId"]
+	if tableIdParam == "" {
+		c.errorHandler(w, r, &RequiredError{"tableId"}, nil)
+		return
+	}
+	result, err := c.service.GetTableVersions(r.Context(), tableIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetCompactionStatus - Get status of compaction of selected table
+func (c *SchemaAPIController) GetCompactionStatus(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	tableIdParam := params["tableId"]
+	if tableIdParam == "" {
+		c.errorHandler(w, r, &RequiredError{"tableId"}, nil)
+		return
+	}
+	result, err := c.service.GetCompactionStatus(r.Context(), tableIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// CompactTable - Start compaction of small files of selected table
+func (c *SchemaAPIController) CompactTable(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	tableIdParam := params["tableId"]
+	if tableIdParam == "" {
+		c.errorHandler(w, r, &RequiredError{"tableId"}, nil)
+		return
+	}
+	result, err := c.service.CompactTable(r.Context(), tableIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetIndexes - Get secondary indexes of selected table
+func (c *SchemaAPIController) GetIndexes(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	tableIdParam := params["tableId"]
+	if tableIdParam == "" {
+		c.errorHandler(w, r, &RequiredError{"tableId"}, nil)
+		return
+	}
+	result, err := c.service.GetIndexes(r.Context(), tableIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// CreateIndex - Create secondary index of a column of selected table or index files not covered by it yet
+func (c *SchemaAPIController) CreateIndex(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	tableIdParam := params["tableId"]
+	if tableIdParam == "" {
+		c.errorHandler(w, r, &RequiredError{"tableId"}, nil)
+		return
+	}
+	columnNameParam := params["columnName"]
+	if columnNameParam == "" {
+		c.errorHandler(w, r, &RequiredError{"columnName"}, nil)
+		return
+	}
+	result, err := c.service.CreateIndex(r.Context(), tableIdParam, columnNameParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// DropIndex - Drop secondary index of a column of selected table
+func (c *SchemaAPIController) DropIndex(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	tableIdParam := params["tableId"]
+	if tableIdParam == "" {
+		c.errorHandler(w, r, &RequiredError{"tableId"}, nil)
+		return
+	}
+	columnNameParam := params["columnName"]
+	if columnNameParam == "" {
+		c.errorHandler(w, r, &RequiredError{"columnName"}, nil)
+		return
+	}
+	result, err := c.service.DropIndex(r.Context(), tableIdParam, columnNameParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// CloneTable - Create a table sharing files of the current version of selected table
+func (c *SchemaAPIController) CloneTable(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	tableIdParam := params["tableId"]
+	if tableIdParam == "" {
+		c.errorHandler(w, r, &RequiredError{"tableId"}, nil)
+		return
+	}
+	var cloneTableRequestParam CloneTableRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&cloneTableRequestParam); err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	if err := AssertCloneTableRequestRequired(cloneTableRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := AssertCloneTableRequestConstraints(cloneTableRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.CloneTable(r.Context(), tableIdParam, cloneTableRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetDatabases - Get list of databases
+func (c *SchemaAPIController) GetDatabases(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.GetDatabases(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// CreateDatabase - Create new database
+func (c *SchemaAPIController) CreateDatabase(w http.ResponseWriter, r *http.Request) {
+	var databaseParam Database
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&databaseParam); err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	if err := AssertDatabaseRequired(databaseParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := AssertDatabaseConstraints(databaseParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.CreateDatabase(r.Context(), databaseParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// DropDatabase - Drop selected database
+func (c *SchemaAPIController) DropDatabase(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	databaseNameParam := params["databaseName"]
+	if databaseNameParam == "" {
+		c.errorHandler(w, r, &RequiredError{"databaseName"}, nil)
+		return
+	}
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	var cascadeParam bool
+	if query.Has("cascade") {
+		param, err := parseBoolParameter(
+			query.Get("cascade"),
+			WithParse[bool](parseBool),
+		)
+		if err != nil {
+			c.errorHandler(w, r, &ParsingError{Param: "cascade", Err: err}, nil)
+			return
+		}
+
+		cascadeParam = param
+	} else {
+		var param bool = false
+		cascadeParam = param
+	}
+	result, err := c.service.DropDatabase(r.Context(), databaseNameParam, cascadeParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// Backup - Write a consistent backup of all databases to a directory
+func (c *SchemaAPIController) Backup(w http.ResponseWriter, r *http.Request) {
+	var backupRequestParam BackupRequest
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&backupRequestParam); err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	if err := AssertBackupRequestRequired(backupRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := AssertBackupRequestConstraints(backupRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.Backup(r.Context(), backupRequestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
@@ -25,6 +25,10 @@ func (l Literal) IsColumnExpression() bool                   { return true }
 func (f Function) IsColumnExpression() bool                  { return true }
 func (b ColumnarBinaryOperation) IsColumnExpression() bool   { return true }
 func (u ColumnarUnaryOperation) IsColumnExpression() bool    { return true }
+func (a AggregateFunction) IsColumnExpression() bool         { return true }
+func (i InSubquery) IsColumnExpression() bool                { return true }
+func (e ExistsSubquery) IsColumnExpression() bool            { return true }
+func (w WindowFunction) IsColumnExpression() bool            { return true }
 
 type ColumnExpression struct {
 	Expression ColumnExpressionImpl
@@ -46,6 +50,10 @@ func (c *ColumnExpression) UnmarshalJSON(data []byte) error {
 	_, hasOperator := raw["operator"]
 	_, hasLeft := raw["leftOperand"]
 	_, hasOperand := raw["operand"]
+	_, hasAggregate := raw["aggregateName"]
+	_, hasInSubquery := raw["inSubquery"]
+	_, hasExistsSubquery := raw["existsSubquery"]
+	_, hasWindow := raw["windowFunction"]
 
 	if hasValue {
 		var literal Literal
@@ -58,6 +66,50 @@ func (c *ColumnExpression) UnmarshalJSON(data []byte) error {
 		return nil
 	}
 
+	if hasInSubquery {
+		var in InSubquery
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&in); err != nil {
+			return fmt.Errorf("invalid InSubquery: %w", err)
+		}
+		c.Expression = in
+		return nil
+	}
+
+	if hasExistsSubquery {
+		var exists ExistsSubquery
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&exists); err != nil {
+			return fmt.Errorf("invalid ExistsSubquery: %w", err)
+		}
+		c.Expression = exists
+		return nil
+	}
+
+	if hasWindow {
+		var window WindowFunction
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&window); err != nil {
+			return fmt.Errorf("invalid WindowFunction: %w", err)
+		}
+		c.Expression = window
+		return nil
+	}
+
+	if hasAggregate {
+		var aggregate AggregateFunction
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&aggregate); err != nil {
+			return fmt.Errorf("invalid AggregateFunction: %w", err)
+		}
+		c.Expression = aggregate
+		return nil
+	}
+
 	if hasFunction {
 		var function Function
 		dec := json.NewDecoder(bytes.NewReader(data))
@@ -127,6 +179,14 @@ func AssertColumnExpressionRequired(obj ColumnExpression) error {
 		return AssertColumnarBinaryOperationRequired(e)
 	case ColumnarUnaryOperation:
 		return AssertColumnarUnaryOperationRequired(e)
+	case AggregateFunction:
+		return AssertAggregateFunctionRequired(e)
+	case InSubquery:
+		return AssertInSubqueryRequired(e)
+	case ExistsSubquery:
+		return AssertExistsSubqueryRequired(e)
+	case WindowFunction:
+		return AssertWindowFunctionRequired(e)
 	default:
 		return fmt.Errorf("unknown column expression type")
 	}
@@ -149,6 +209,14 @@ func AssertColumnExpressionConstraints(obj ColumnExpression) error {
 		return AssertColumnarBinaryOperationConstraints(e)
 	case ColumnarUnaryOperation:
 		return AssertColumnarUnaryOperationConstraints(e)
+	case AggregateFunction:
+		return AssertAggregateFunctionConstraints(e)
+	case InSubquery:
+		return AssertInSubqueryConstraints(e)
+	case ExistsSubquery:
+		return AssertExistsSubqueryConstraints(e)
+	case WindowFunction:
+		return AssertWindowFunctionConstraints(e)
 	}
 	return nil
 }
@@ -12,15 +12,32 @@ package openapi
 
 // LimitExpression - Description of LIMIT clause in SELECT query
 type LimitExpression struct {
-	Limit int32 `json:"limit,omitempty"`
+	// Maximal number of returned rows, all rows when omitted
+	Limit *int32 `json:"limit,omitempty"`
+
+	// Number of rows skipped before the returned ones
+	Offset int64 `json:"offset,omitempty"`
+
+	// Values of the order by expressions of the last row of the previous page, only rows ordered after them are returned
+	After []Literal `json:"after,omitempty"`
 }
 
 // AssertLimitExpressionRequired checks if the required fields are not zero-ed
 func AssertLimitExpressionRequired(obj LimitExpression) error {
+	for _, el := range obj.After {
+		if err := AssertLiteralRequired(el); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // AssertLimitExpressionConstraints checks if the values respects the defined constraints
 func AssertLimitExpressionConstraints(obj LimitExpression) error {
+	for _, el := range obj.After {
+		if err := AssertLiteralConstraints(el); err != nil {
+			return err
+		}
+	}
 	return nil
 }
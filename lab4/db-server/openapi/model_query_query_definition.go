@@ -10,8 +10,14 @@ type QueryDefinition interface {
 	IsQuery() bool
 }
 
-func (s SelectQuery) IsQuery() bool { return true }
-func (c CopyQuery) IsQuery() bool   { return true }
+func (s SelectQuery) IsQuery() bool              { return true }
+func (c CopyQuery) IsQuery() bool                { return true }
+func (d DeleteQuery) IsQuery() bool              { return true }
+func (u UpdateQuery) IsQuery() bool              { return true }
+func (i InsertQuery) IsQuery() bool              { return true }
+func (i InsertSelectQuery) IsQuery() bool        { return true }
+func (c CreateTableAsSelectQuery) IsQuery() bool { return true }
+func (s SetOperationQuery) IsQuery() bool        { return true }
 
 type QueryQueryDefinition struct {
 	Definition QueryDefinition
@@ -26,13 +32,102 @@ func (q *QueryQueryDefinition) UnmarshalJSON(data []byte) error {
 	_, hasSource := raw["sourceFilepath"]
 	_, hasDest := raw["destinationTableName"]
 	_, hasColumns := raw["columnClauses"]
+	_, hasTable := raw["tableName"]
+	_, hasSet := raw["setClauses"]
+	_, hasInto := raw["intoTableName"]
+	_, hasCreate := raw["createTableName"]
+	_, hasSelectQuery := raw["selectQuery"]
+	_, hasSetOperator := raw["setOperator"]
 
 	isCopy := hasSource || hasDest
 	isSelect := hasColumns
+	isSetOperation := hasSetOperator
+	isUpdate := hasTable && hasSet
+	isDelete := hasTable && !hasSet
 
 	if isCopy && isSelect {
 		return fmt.Errorf("ambiguous query definition: contains both COPY (sourceFilepath/destinationTableName) and SELECT (columnClauses) fields")
 	}
+	if hasTable && (isCopy || isSelect) {
+		return fmt.Errorf("ambiguous query definition: contains both DELETE or UPDATE (tableName) and COPY or SELECT fields")
+	}
+
+	if hasInto && (isCopy || isSelect || hasTable || hasCreate) {
+		return fmt.Errorf("ambiguous query definition: contains both INSERT (intoTableName) and other query fields")
+	}
+	if hasCreate && (isCopy || isSelect || hasTable) {
+		return fmt.Errorf("ambiguous query definition: contains both CREATE TABLE AS SELECT (createTableName) and other query fields")
+	}
+
+	if isSetOperation && (isCopy || isSelect || hasTable || hasInto || hasCreate) {
+		return fmt.Errorf("ambiguous query definition: contains both a set operation (setOperator) and other query fields")
+	}
+
+	if isSetOperation {
+		var setQ SetOperationQuery
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&setQ); err != nil {
+			return fmt.Errorf("invalid set operation query: %w", err)
+		}
+		q.Definition = setQ
+		return nil
+	}
+
+	if hasCreate {
+		var ctasQ CreateTableAsSelectQuery
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&ctasQ); err != nil {
+			return fmt.Errorf("invalid CREATE TABLE AS SELECT query: %w", err)
+		}
+		q.Definition = ctasQ
+		return nil
+	}
+
+	if hasInto && hasSelectQuery {
+		var insertSelectQ InsertSelectQuery
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&insertSelectQ); err != nil {
+			return fmt.Errorf("invalid INSERT SELECT query: %w", err)
+		}
+		q.Definition = insertSelectQ
+		return nil
+	}
+
+	if hasInto {
+		var insertQ InsertQuery
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&insertQ); err != nil {
+			return fmt.Errorf("invalid INSERT query: %w", err)
+		}
+		q.Definition = insertQ
+		return nil
+	}
+
+	if isUpdate {
+		var updateQ UpdateQuery
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&updateQ); err != nil {
+			return fmt.Errorf("invalid UPDATE query: %w", err)
+		}
+		q.Definition = updateQ
+		return nil
+	}
+
+	if isDelete {
+		var deleteQ DeleteQuery
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&deleteQ); err != nil {
+			return fmt.Errorf("invalid DELETE query: %w", err)
+		}
+		q.Definition = deleteQ
+		return nil
+	}
 
 	if isCopy {
 		var copyQ CopyQuery
@@ -56,7 +151,7 @@ func (q *QueryQueryDefinition) UnmarshalJSON(data []byte) error {
 		return nil
 	}
 
-	return fmt.Errorf("incompatible query definition: match neither COPY (sourceFilepath) nor SELECT (columnClauses)")
+	return fmt.Errorf("incompatible query definition: match neither COPY (sourceFilepath), SELECT (columnClauses), DELETE (tableName), UPDATE (tableName, setClauses), INSERT (intoTableName), CREATE TABLE AS SELECT (createTableName) nor a set operation (setOperator)")
 }
 
 func (q QueryQueryDefinition) MarshalJSON() ([]byte, error) {
@@ -74,6 +169,18 @@ func AssertQueryQueryDefinitionRequired(obj QueryQueryDefinition) error {
 		return AssertSelectQueryRequired(q)
 	case CopyQuery:
 		return AssertCopyQueryRequired(q)
+	case DeleteQuery:
+		return AssertDeleteQueryRequired(q)
+	case UpdateQuery:
+		return AssertUpdateQueryRequired(q)
+	case InsertQuery:
+		return AssertInsertQueryRequired(q)
+	case InsertSelectQuery:
+		return AssertInsertSelectQueryRequired(q)
+	case CreateTableAsSelectQuery:
+		return AssertCreateTableAsSelectQueryRequired(q)
+	case SetOperationQuery:
+		return AssertSetOperationQueryRequired(q)
 	default:
 		return fmt.Errorf("unknown query definition type")
 	}
@@ -90,6 +197,18 @@ func AssertQueryQueryDefinitionConstraints(obj QueryQueryDefinition) error {
 		return AssertSelectQueryConstraints(q)
 	case CopyQuery:
 		return AssertCopyQueryConstraints(q)
+	case DeleteQuery:
+		return AssertDeleteQueryConstraints(q)
+	case UpdateQuery:
+		return AssertUpdateQueryConstraints(q)
+	case InsertQuery:
+		return AssertInsertQueryConstraints(q)
+	case InsertSelectQuery:
+		return AssertInsertSelectQueryConstraints(q)
+	case CreateTableAsSelectQuery:
+		return AssertCreateTableAsSelectQueryConstraints(q)
+	case SetOperationQuery:
+		return AssertSetOperationQueryConstraints(q)
 	}
 
 	return nil
@@ -12,13 +12,30 @@ package openapi
 
 // SelectQuery - Description of a select query
 type SelectQuery struct {
+	// Common table expressions read like tables by the query
+	WithClause []CommonTableExpression `json:"withClause,omitempty"`
+
 	ColumnClauses []ColumnExpression `json:"columnClauses"`
 
+	// Removes duplicate rows of the result (SELECT DISTINCT), rows are compared by all column clauses
+	Distinct bool `json:"distinct,omitempty"`
+
+	// Tables joined by the query, without it the table is given by qualified column references
+	FromClause *FromClause `json:"fromClause,omitempty"`
+
 	WhereClause ColumnExpression `json:"whereClause,omitempty"`
 
+	// Expressions rows are grouped by, column clauses may then use only them and aggregate functions
+	GroupByClause []ColumnExpression `json:"groupByClause,omitempty"`
+
+	// Predicate on groups evaluated after aggregation, it may use grouped expressions and aggregate functions
+	HavingClause ColumnExpression `json:"havingClause,omitempty"`
+
 	OrderByClause []OrderByExpression `json:"orderByClause,omitempty"`
 
 	LimitClause *LimitExpression `json:"limitClause,omitempty"`
+
+	AsOf *AsOfExpression `json:"asOf,omitempty"`
 }
 
 // AssertSelectQueryRequired checks if the required fields are not zero-ed
@@ -32,16 +49,36 @@ func AssertSelectQueryRequired(obj SelectQuery) error {
 		}
 	}
 
+	for _, el := range obj.WithClause {
+		if err := AssertCommonTableExpressionRequired(el); err != nil {
+			return err
+		}
+	}
 	for _, el := range obj.ColumnClauses {
 		if err := AssertColumnExpressionRequired(el); err != nil {
 			return err
 		}
 	}
+	if obj.FromClause != nil {
+		if err := AssertFromClauseRequired(*obj.FromClause); err != nil {
+			return err
+		}
+	}
 	if obj.WhereClause.Expression != nil {
 		if err := AssertColumnExpressionRequired(obj.WhereClause); err != nil {
 			return err
 		}
 	}
+	for _, el := range obj.GroupByClause {
+		if err := AssertColumnExpressionRequired(el); err != nil {
+			return err
+		}
+	}
+	if obj.HavingClause.Expression != nil {
+		if err := AssertColumnExpressionRequired(obj.HavingClause); err != nil {
+			return err
+		}
+	}
 	for _, el := range obj.OrderByClause {
 		if err := AssertOrderByExpressionRequired(el); err != nil {
 			return err
@@ -52,21 +89,46 @@ func AssertSelectQueryRequired(obj SelectQuery) error {
 			return err
 		}
 	}
+	if obj.AsOf != nil {
+		if err := AssertAsOfExpressionRequired(*obj.AsOf); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // AssertSelectQueryConstraints checks if the values respects the defined constraints
 func AssertSelectQueryConstraints(obj SelectQuery) error {
+	for _, el := range obj.WithClause {
+		if err := AssertCommonTableExpressionConstraints(el); err != nil {
+			return err
+		}
+	}
 	for _, el := range obj.ColumnClauses {
 		if err := AssertColumnExpressionConstraints(el); err != nil {
 			return err
 		}
 	}
+	if obj.FromClause != nil {
+		if err := AssertFromClauseConstraints(*obj.FromClause); err != nil {
+			return err
+		}
+	}
 	if obj.WhereClause.Expression != nil {
 		if err := AssertColumnExpressionConstraints(obj.WhereClause); err != nil {
 			return err
 		}
 	}
+	for _, el := range obj.GroupByClause {
+		if err := AssertColumnExpressionConstraints(el); err != nil {
+			return err
+		}
+	}
+	if obj.HavingClause.Expression != nil {
+		if err := AssertColumnExpressionConstraints(obj.HavingClause); err != nil {
+			return err
+		}
+	}
 	for _, el := range obj.OrderByClause {
 		if err := AssertOrderByExpressionConstraints(el); err != nil {
 			return err
@@ -77,5 +139,10 @@ func AssertSelectQueryConstraints(obj SelectQuery) error {
 			return err
 		}
 	}
+	if obj.AsOf != nil {
+		if err := AssertAsOfExpressionConstraints(*obj.AsOf); err != nil {
+			return err
+		}
+	}
 	return nil
 }
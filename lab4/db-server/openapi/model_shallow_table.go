@@ -17,6 +17,8 @@ type ShallowTable struct {
 	TableId string `json:"tableId,omitempty"`
 
 	Name string `json:"name"`
+
+	Database string `json:"database,omitempty"`
 }
 
 // AssertShallowTableRequired checks if the required fields are not zero-ed
@@ -12,9 +12,18 @@ package openapi
 
 // TableSchema - Description of the table in the database
 type TableSchema struct {
+	// Name of the table, may be qualified with a database name (database.table)
 	Name string `json:"name"`
 
+	// Database of the table, "default" when not given
+	Database string `json:"database,omitempty"`
+
 	Columns []Column `json:"columns"`
+
+	// Cluster keys of the table, rows of every data file are sorted by them
+	SortKeys []SortKey `json:"sortKeys,omitempty"`
+
+	Statistics *TableStatistics `json:"statistics,omitempty"`
 }
 
 // AssertTableSchemaRequired checks if the required fields are not zero-ed
@@ -34,6 +43,16 @@ func AssertTableSchemaRequired(obj TableSchema) error {
 			return err
 		}
 	}
+	for _, el := range obj.SortKeys {
+		if err := AssertSortKeyRequired(el); err != nil {
+			return err
+		}
+	}
+	if obj.Statistics != nil {
+		if err := AssertTableStatisticsRequired(*obj.Statistics); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -44,5 +63,15 @@ func AssertTableSchemaConstraints(obj TableSchema) error {
 			return err
 		}
 	}
+	for _, el := range obj.SortKeys {
+		if err := AssertSortKeyConstraints(el); err != nil {
+			return err
+		}
+	}
+	if obj.Statistics != nil {
+		if err := AssertTableStatisticsConstraints(*obj.Statistics); err != nil {
+			return err
+		}
+	}
 	return nil
 }
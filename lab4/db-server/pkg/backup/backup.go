@@ -0,0 +1,258 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"isbd4/pkg/metadata"
+)
+
+const (
+	ManifestFileName  = "manifest.json"
+	metastoreFileName = "metastore.json"
+	filesDirName      = "files"
+)
+
+// Manifest describes a backup directory: the metastore and every file it references, with checksums.
+// Files of incremental backups which didn't change since the previous backup are stored in the directory
+// of the backup which copied them.
+type Manifest struct {
+	CreatedAt time.Time `json:"created_at"`
+	DataDir   string    `json:"data_dir"`           // data directory the backup was taken of
+	Previous  string    `json:"previous,omitempty"` // previous backup of incremental backups, relative to the backup
+	Metastore File      `json:"metastore"`
+	Files     []File    `json:"files"`
+}
+
+type File struct {
+	Path     string `json:"path"` // relative to the data directory
+	Size     int64  `json:"size"`
+	Checksum string `json:"sha256"`
+	Location string `json:"location,omitempty"` // backup storing the file relative to this one, empty if it is this one
+}
+
+// Summary reports what a backup copied
+type Summary struct {
+	Files       int   // files referenced by the backup
+	CopiedFiles int   // files copied by the backup, the rest is stored in previous backups
+	CopiedBytes int64 // bytes of copied files including the metastore
+}
+
+// Backup writes a consistent snapshot of the metastore and the files it references into the target directory,
+// which must not exist yet. Queries and COPYs may run meanwhile, files they commit later are not part of the backup.
+// If previous is not empty the backup is incremental: files already stored by the previous backup are not copied.
+func Backup(m *metadata.Metastore, dataDir string, target string, previous string) (Summary, error) {
+	var prev *Manifest
+	if previous != "" {
+		var err error
+		if prev, err = ReadManifest(previous); err != nil {
+			return Summary{}, fmt.Errorf("failed to read previous backup: %w", err)
+		}
+		if filepath.Clean(prev.DataDir) != filepath.Clean(dataDir) {
+			return Summary{}, fmt.Errorf("previous backup is of data directory %s, not %s", prev.DataDir, dataDir)
+		}
+	}
+
+	if _, err := os.Stat(target); err == nil {
+		return Summary{}, fmt.Errorf("backup directory %s already exists", target)
+	}
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return Summary{}, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	summary, err := backup(m, dataDir, target, previous, prev)
+	if err != nil {
+		os.RemoveAll(target)
+		return Summary{}, err
+	}
+	return summary, nil
+}
+
+func backup(m *metadata.Metastore, dataDir string, target string, previous string, prev *Manifest) (Summary, error) {
+	checkpoint, err := m.Checkpoint()
+	if err != nil {
+		return Summary{}, fmt.Errorf("failed to checkpoint metastore: %w", err)
+	}
+	defer checkpoint.Release()
+
+	manifest := Manifest{CreatedAt: time.Now(), DataDir: dataDir}
+	var summary Summary
+
+	if err := os.WriteFile(filepath.Join(target, metastoreFileName), checkpoint.Metadata, 0644); err != nil {
+		return Summary{}, fmt.Errorf("failed to write metastore: %w", err)
+	}
+	sum := sha256.Sum256(checkpoint.Metadata)
+	manifest.Metastore = File{Path: metastoreFileName, Size: int64(len(checkpoint.Metadata)), Checksum: hex.EncodeToString(sum[:])}
+	summary.CopiedBytes += manifest.Metastore.Size
+
+	stored := make(map[string]File)
+	if prev != nil {
+		if manifest.Previous, err = relativeLocation(target, previous); err != nil {
+			return Summary{}, err
+		}
+		for _, f := range prev.Files {
+			if f.Location, err = relativeLocation(target, filepath.Join(previous, f.Location)); err != nil {
+				return Summary{}, err
+			}
+			stored[f.Path] = f
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, entry := range checkpoint.Files {
+		rel, err := filepath.Rel(dataDir, entry.Path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return Summary{}, fmt.Errorf("file %s is outside of the data directory %s", entry.Path, dataDir)
+		}
+		if seen[rel] {
+			continue
+		}
+		seen[rel] = true
+
+		// table files are never modified, so a file with the same path is the same file
+		if f, ok := stored[rel]; ok {
+			manifest.Files = append(manifest.Files, f)
+			continue
+		}
+
+		f := File{Path: rel}
+		if f.Size, f.Checksum, err = copyFile(entry.Path, filepath.Join(target, filesDirName, rel)); err != nil {
+			return Summary{}, fmt.Errorf("failed to copy file %s: %w", entry.Path, err)
+		}
+		manifest.Files = append(manifest.Files, f)
+		summary.CopiedFiles++
+		summary.CopiedBytes += f.Size
+	}
+	summary.Files = len(manifest.Files)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Summary{}, err
+	}
+	// the manifest is written last, a directory without it is not a complete backup
+	if err := os.WriteFile(filepath.Join(target, ManifestFileName), data, 0644); err != nil {
+		return Summary{}, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return summary, nil
+}
+
+// Restore rebuilds the data directory from the backup, verifying checksums of every file. The data directory
+// must not exist, it is created only once all files are restored. The server must not run meanwhile.
+func Restore(backupDir string, dataDir string) error {
+	manifest, err := ReadManifest(backupDir)
+	if err != nil {
+		return err
+	}
+	// the metastore refers to files by paths including the data directory
+	if filepath.Clean(manifest.DataDir) != filepath.Clean(dataDir) {
+		return fmt.Errorf("backup of data directory %s cannot be restored into %s", manifest.DataDir, dataDir)
+	}
+	if _, err := os.Stat(dataDir); err == nil {
+		return fmt.Errorf("data directory %s already exists, move it away first", dataDir)
+	}
+
+	staging := fmt.Sprintf("%s.restore_%d", filepath.Clean(dataDir), time.Now().UnixNano())
+	if err := restore(manifest, backupDir, staging); err != nil {
+		os.RemoveAll(staging)
+		return err
+	}
+	if err := os.Rename(staging, dataDir); err != nil {
+		os.RemoveAll(staging)
+		return fmt.Errorf("failed to move restored data directory: %w", err)
+	}
+	return nil
+}
+
+func restore(manifest *Manifest, backupDir string, staging string) error {
+	metastorePath := filepath.Join(staging, "ms_data", metastoreFileName)
+	if err := restoreFile(filepath.Join(backupDir, manifest.Metastore.Path), metastorePath, manifest.Metastore); err != nil {
+		return err
+	}
+	for _, f := range manifest.Files {
+		source := filepath.Join(backupDir, f.Location, filesDirName, f.Path)
+		if err := restoreFile(source, filepath.Join(staging, f.Path), f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func restoreFile(source string, target string, f File) error {
+	size, checksum, err := copyFile(source, target)
+	if err != nil {
+		return fmt.Errorf("failed to restore file %s: %w", f.Path, err)
+	}
+	if size != f.Size || checksum != f.Checksum {
+		return fmt.Errorf("checksum mismatch of file %s stored in %s", f.Path, source)
+	}
+	return nil
+}
+
+// ReadManifest reads the manifest of the backup directory
+func ReadManifest(backupDir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(backupDir, ManifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("backup %s is incomplete or does not exist: %w", backupDir, err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("malformed manifest of backup %s: %w", backupDir, err)
+	}
+	return &manifest, nil
+}
+
+// relativeLocation returns the backup directory relative to the target backup
+func relativeLocation(target string, backupDir string) (string, error) {
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	absBackup, err := filepath.Abs(backupDir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absTarget, absBackup)
+	if err != nil || rel == "." {
+		return "", err
+	}
+	return rel, nil
+}
+
+// copyFile copies the file creating missing directories, returns its size and SHA-256 checksum
+func copyFile(source string, target string) (int64, string, error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return 0, "", err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return 0, "", err
+	}
+	out, err := os.Create(target)
+	if err != nil {
+		return 0, "", err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, hash), in)
+	if err != nil {
+		out.Close()
+		return 0, "", err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return 0, "", err
+	}
+	if err := out.Close(); err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,109 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"isbd4/pkg/metadata"
+)
+
+func addFile(t *testing.T, m *metadata.Metastore, table string, name string) string {
+	t.Helper()
+	path := filepath.Join(m.DatabaseDir(metadata.DefaultDatabase), name)
+	if err := os.WriteFile(path, []byte("data of "+name), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := m.AddFile(table, path, nil); err != nil {
+		t.Fatalf("AddFile failed: %v", err)
+	}
+	return path
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "data")
+	m := metadata.NewMetastore(dataDir)
+	tableId, err := m.CreateTable("t1", []metadata.ColumnDef{{Name: "a", Type: metadata.Int64Type}}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	addFile(t, m, "t1", "f1.tomy")
+
+	full := filepath.Join(tmpDir, "full")
+	summary, err := Backup(m, dataDir, full, "")
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if summary.Files != 1 || summary.CopiedFiles != 1 {
+		t.Errorf("Expected one copied file, got %+v", summary)
+	}
+	if _, err := Backup(m, dataDir, full, ""); err == nil {
+		t.Errorf("Expected error when backup directory exists")
+	}
+
+	addFile(t, m, "t1", "f2.tomy")
+	incremental := filepath.Join(tmpDir, "incremental")
+	summary, err = Backup(m, dataDir, incremental, full)
+	if err != nil {
+		t.Fatalf("Incremental backup failed: %v", err)
+	}
+	if summary.Files != 2 || summary.CopiedFiles != 1 {
+		t.Errorf("Expected only the new file to be copied, got %+v", summary)
+	}
+
+	// changes after the backup are not restored
+	addFile(t, m, "t1", "f3.tomy")
+	if err := Restore(incremental, dataDir); err == nil {
+		t.Errorf("Expected error when restoring into existing data directory")
+	}
+	if err := os.Rename(dataDir, filepath.Join(tmpDir, "old")); err != nil {
+		t.Fatalf("Failed to move data directory: %v", err)
+	}
+	if err := Restore(incremental, filepath.Join(tmpDir, "other")); err == nil {
+		t.Errorf("Expected error when restoring into other data directory")
+	}
+	if err := Restore(incremental, dataDir); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	restored := metadata.NewMetastore(dataDir)
+	table, exists := restored.GetTableById(tableId)
+	if !exists || len(table.Files) != 2 {
+		t.Fatalf("Expected restored table with 2 files, got %+v", table)
+	}
+	for _, f := range table.Files {
+		if data, err := os.ReadFile(f.Path); err != nil || string(data) != "data of "+filepath.Base(f.Path) {
+			t.Errorf("Expected file %s to be restored, got %q, %v", f.Path, data, err)
+		}
+	}
+}
+
+func TestRestore_ChecksumMismatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "data")
+	m := metadata.NewMetastore(dataDir)
+	if _, err := m.CreateTable("t1", []metadata.ColumnDef{{Name: "a", Type: metadata.Int64Type}}, nil); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	path := addFile(t, m, "t1", "f1.tomy")
+
+	backupDir := filepath.Join(tmpDir, "backup")
+	if _, err := Backup(m, dataDir, backupDir, ""); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	rel, _ := filepath.Rel(dataDir, path)
+	if err := os.WriteFile(filepath.Join(backupDir, filesDirName, rel), []byte("corrupted"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt file: %v", err)
+	}
+
+	if err := os.RemoveAll(dataDir); err != nil {
+		t.Fatalf("Failed to remove data directory: %v", err)
+	}
+	if err := Restore(backupDir, dataDir); err == nil {
+		t.Fatalf("Expected checksum mismatch error")
+	}
+	if _, err := os.Stat(dataDir); !os.IsNotExist(err) {
+		t.Errorf("Data directory should not be created by failed restore, got err: %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+package compaction
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"isbd4/pkg/metadata"
+)
+
+type State string
+
+const (
+	StateIdle    State = "IDLE"
+	StateRunning State = "RUNNING"
+	StateFailed  State = "FAILED"
+)
+
+// minSmallFiles is the number of small files from which a table is compacted in the background
+const minSmallFiles = 2
+
+// TableStatus describes the last compaction of a table
+type TableStatus struct {
+	State          State
+	LastStartedAt  *time.Time
+	LastFinishedAt *time.Time
+	LastError      string
+	FilesCompacted int // files replaced by the last successful run
+	FilesWritten   int // files written by the last successful run
+}
+
+// Compactor merges files smaller than maxRowsInFile rows into files of up to maxRowsInFile rows
+// and rewrites files with deleted rows without them.
+// Merged files are swapped in as a new table version, running snapshots keep reading the old files.
+type Compactor struct {
+	metastore     *metadata.Metastore
+	chunkSize     uint64
+	maxRowsInFile uint64
+	interval      time.Duration
+
+	statuses map[string]*TableStatus // table id -> status
+	mu       sync.Mutex
+}
+
+func NewCompactor(m *metadata.Metastore, chunkSize uint64, maxRowsInFile uint64, interval time.Duration) *Compactor {
+	return &Compactor{
+		metastore:     m,
+		chunkSize:     chunkSize,
+		maxRowsInFile: maxRowsInFile,
+		interval:      interval,
+		statuses:      make(map[string]*TableStatus),
+	}
+}
+
+// Start runs background compaction of all tables every interval
+func (c *Compactor) Start() {
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			c.compactAll()
+		}
+	}()
+}
+
+func (c *Compactor) compactAll() {
+	_, ids, _ := c.metastore.GetTables()
+	c.forgetDroppedTables(ids)
+	for _, id := range ids {
+		smallFiles, _, ok := c.SmallFiles(id)
+		deletedRows, _ := c.metastore.DeletedRows(id)
+		if !ok || (smallFiles < minSmallFiles && len(deletedRows) == 0) || c.Status(id).State == StateRunning {
+			continue
+		}
+		if err := c.Compact(id); err != nil {
+			log.Printf("Compaction of table %s failed: %v", id, err)
+		}
+	}
+}
+
+// forgetDroppedTables removes statuses of tables which no longer exist, running compactions keep theirs until they finish
+func (c *Compactor) forgetDroppedTables(ids []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	existing := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		existing[id] = true
+	}
+	for id, status := range c.statuses {
+		if !existing[id] && status.State != StateRunning {
+			delete(c.statuses, id)
+		}
+	}
+}
+
+// Trigger starts compaction of the table in the background
+func (c *Compactor) Trigger(tableId string) error {
+	if _, exists := c.metastore.GetTableById(tableId); !exists {
+		return fmt.Errorf("table %s does not exist", tableId)
+	}
+	if !c.begin(tableId) {
+		return fmt.Errorf("compaction of table %s is already running", tableId)
+	}
+
+	go func() {
+		if err := c.run(tableId); err != nil {
+			log.Printf("Compaction of table %s failed: %v", tableId, err)
+		}
+	}()
+	return nil
+}
+
+// Compact compacts the table synchronously
+func (c *Compactor) Compact(tableId string) error {
+	if !c.begin(tableId) {
+		return fmt.Errorf("compaction of table %s is already running", tableId)
+	}
+	return c.run(tableId)
+}
+
+func (c *Compactor) Status(tableId string) TableStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if status, ok := c.statuses[tableId]; ok {
+		return *status
+	}
+	return TableStatus{State: StateIdle}
+}
+
+// SmallFiles returns the number of files below maxRowsInFile rows and the number of all files of the table
+func (c *Compactor) SmallFiles(tableId string) (small int, total int, ok bool) {
+	files, exists := c.metastore.GetTableFiles(tableId)
+	if !exists {
+		return 0, 0, false
+	}
+	for _, f := range files {
+		if f.Stats == nil || f.Stats.NumRows < c.maxRowsInFile {
+			small++
+		}
+	}
+	return small, len(files), true
+}
+
+func (c *Compactor) begin(tableId string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status, ok := c.statuses[tableId]
+	if !ok {
+		status = &TableStatus{}
+		c.statuses[tableId] = status
+	}
+	if status.State == StateRunning {
+		return false
+	}
+
+	now := time.Now()
+	status.State = StateRunning
+	status.LastStartedAt = &now
+	return true
+}
+
+func (c *Compactor) run(tableId string) error {
+	compacted, written, err := c.compactTable(tableId)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.metastore.GetTableById(tableId); !exists {
+		delete(c.statuses, tableId)
+		return err
+	}
+
+	now := time.Now()
+	status := c.statuses[tableId]
+	status.LastFinishedAt = &now
+	if err != nil {
+		status.State = StateFailed
+		status.LastError = err.Error()
+		return err
+	}
+	status.State = StateIdle
+	status.LastError = ""
+	status.FilesCompacted = compacted
+	status.FilesWritten = written
+	return nil
+}
@@ -0,0 +1,266 @@
+package compaction
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+func addFile(t *testing.T, m *metadata.Metastore, tableName string, ids []int64) {
+	t.Helper()
+	addSortedFile(t, m, tableName, ids, nil)
+}
+
+// addSortedFile adds a file with the given sort order recorded in its footer
+func addSortedFile(t *testing.T, m *metadata.Metastore, tableName string, ids []int64, sortOrder []tomy_file.SortColumn) {
+	t.Helper()
+	names := &tomy_file.VarcharColumn{Name: "name"}
+	for _, id := range ids {
+		names.Offsets = append(names.Offsets, uint64(len(names.Data)))
+		names.Data = append(names.Data, fmt.Sprintf("row%d", id)...)
+	}
+	table := tomy_file.ColumnarTable{
+		NumRows:   uint64(len(ids)),
+		Columns:   []tomy_file.AnyColumn{&tomy_file.Int64Column{Name: "id", Values: ids}, names},
+		SortOrder: sortOrder,
+	}
+
+	path := filepath.Join(m.DatabaseDir(metadata.DefaultDatabase), fmt.Sprintf("f_%d.tomy", time.Now().UnixNano()))
+	if err := table.Serialize(path); err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	stats, err := metadata.FileStatsFromFooter(path)
+	if err != nil {
+		t.Fatalf("FileStatsFromFooter failed: %v", err)
+	}
+	if err := m.AddFile(tableName, path, stats); err != nil {
+		t.Fatalf("AddFile failed: %v", err)
+	}
+}
+
+func readIds(t *testing.T, files []*metadata.FileEntry) []int64 {
+	t.Helper()
+	var ids []int64
+	for _, f := range files {
+		table, err := tomy_file.Deserialize(f.Path)
+		if err != nil {
+			t.Fatalf("Deserialize failed: %v", err)
+		}
+		ids = append(ids, table.Columns[0].(*tomy_file.Int64Column).Values...)
+		names := table.Columns[1].(*tomy_file.VarcharColumn)
+		for i, id := range table.Columns[0].(*tomy_file.Int64Column).Values {
+			end := uint64(len(names.Data))
+			if i+1 < len(names.Offsets) {
+				end = names.Offsets[i+1]
+			}
+			if name := string(names.Data[names.Offsets[i]:end]); name != fmt.Sprintf("row%d", id) {
+				t.Errorf("Expected name row%d, got %s", id, name)
+			}
+		}
+	}
+	return ids
+}
+
+func TestCompactor_MergesSmallFiles(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	tableId, err := m.CreateTable("t1", []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "name", Type: metadata.VarcharType},
+	}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	addFile(t, m, "t1", []int64{1, 2, 3, 4}) // already full, left untouched
+	addFile(t, m, "t1", []int64{5, 6, 7})
+	addFile(t, m, "t1", []int64{8})
+	addFile(t, m, "t1", []int64{9, 10})
+
+	before, err := m.GetTableSnapshot("t1", nil)
+	if err != nil {
+		t.Fatalf("GetTableSnapshot failed: %v", err)
+	}
+	defer before.Release()
+
+	c := NewCompactor(m, 3, 4, time.Hour)
+	if err := c.Compact(tableId); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	status := c.Status(tableId)
+	if status.State != StateIdle || status.FilesCompacted != 3 || status.FilesWritten != 2 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	after, err := m.GetTableSnapshot("t1", nil)
+	if err != nil {
+		t.Fatalf("GetTableSnapshot failed: %v", err)
+	}
+	defer after.Release()
+
+	if len(after.Files) != 3 {
+		t.Fatalf("Expected 3 files after compaction, got %d", len(after.Files))
+	}
+	expected := []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	if ids := readIds(t, after.Files); !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected ids %v after compaction, got %v", expected, ids)
+	}
+
+	// the snapshot taken before compaction still reads the old files
+	if ids := readIds(t, before.Files); !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected ids %v in old snapshot, got %v", expected, ids)
+	}
+	if small, _, _ := c.SmallFiles(tableId); small != 1 {
+		t.Errorf("Expected one small file left, got %d", small)
+	}
+
+	stats, _ := m.GetTableStatistics(tableId)
+	if stats.RowCount != 10 {
+		t.Errorf("Expected 10 rows in statistics, got %d", stats.RowCount)
+	}
+}
+
+func TestCompactor_KeepsClusteredTableSorted(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	tableId, err := m.CreateTable("t1", []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "name", Type: metadata.VarcharType},
+	}, []metadata.SortKey{{Column: "id", Ascending: false}})
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	byIdDesc := []tomy_file.SortColumn{{ColumnIdx: 0, Ascending: false}}
+	addSortedFile(t, m, "t1", []int64{9, 5, 1}, byIdDesc)
+	addSortedFile(t, m, "t1", []int64{8, 7, 2}, byIdDesc)
+	addSortedFile(t, m, "t1", []int64{10, 6, 4, 3}, byIdDesc)
+
+	c := NewCompactor(m, 2, 5, time.Hour)
+	if err := c.Compact(tableId); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	snapshot, err := m.GetTableSnapshot("t1", nil)
+	if err != nil {
+		t.Fatalf("GetTableSnapshot failed: %v", err)
+	}
+	defer snapshot.Release()
+
+	if len(snapshot.Files) != 2 {
+		t.Fatalf("Expected 2 files after compaction, got %d", len(snapshot.Files))
+	}
+	expected := []int64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+	if ids := readIds(t, snapshot.Files); !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected ids %v after compaction, got %v", expected, ids)
+	}
+	for _, f := range snapshot.Files {
+		if !f.Stats.IsSortedBy([]metadata.SortKey{{Column: "id", Ascending: false}}) {
+			t.Errorf("Expected file %s to record the sort order, got %+v", f.Path, f.Stats.SortOrder)
+		}
+	}
+}
+
+func TestCompactor_PurgesDeletedRows(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	tableId, err := m.CreateTable("t1", []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "name", Type: metadata.VarcharType},
+	}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	addFile(t, m, "t1", []int64{1, 2, 3, 4})
+	addFile(t, m, "t1", []int64{5, 6})
+
+	files, _ := m.GetTableFiles(tableId)
+	full, small := &metadata.DeletionVector{}, &metadata.DeletionVector{}
+	full.Add(1)
+	small.Add(0)
+	small.Add(1)
+	if _, err := m.DeleteRows(tableId, map[string]*metadata.DeletionVector{files[0].Path: full, files[1].Path: small}); err != nil {
+		t.Fatalf("DeleteRows failed: %v", err)
+	}
+
+	c := NewCompactor(m, 3, 4, time.Hour)
+	if err := c.Compact(tableId); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	snapshot, err := m.GetTableSnapshot("t1", nil)
+	if err != nil {
+		t.Fatalf("GetTableSnapshot failed: %v", err)
+	}
+	defer snapshot.Release()
+
+	expected := []int64{1, 3, 4}
+	if ids := readIds(t, snapshot.Files); !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected ids %v after compaction, got %v", expected, ids)
+	}
+	if deleted, _ := m.DeletedRows(tableId); len(deleted) != 0 {
+		t.Errorf("Expected no deleted rows after compaction, got %v", deleted)
+	}
+}
+
+func TestCompactor_ConcurrentRewriteFails(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	tableId, err := m.CreateTable("t1", []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "name", Type: metadata.VarcharType},
+	}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	addFile(t, m, "t1", []int64{1})
+	addFile(t, m, "t1", []int64{2})
+
+	files, _ := m.GetTableFiles(tableId)
+	versions, _ := m.GetTableVersions(tableId)
+	base := versions[len(versions)-1].Version
+	if err := m.ReplaceFiles(tableId, base, []string{files[0].Path}, nil); err != nil {
+		t.Fatalf("ReplaceFiles failed: %v", err)
+	}
+	if err := m.ReplaceFiles(tableId, base, []string{files[0].Path}, nil); err == nil {
+		t.Errorf("Expected error when replacing file which is no longer present")
+	}
+
+	entries, _ := os.ReadDir(m.DatabaseDir(metadata.DefaultDatabase))
+	if len(entries) != 2 {
+		t.Errorf("Expected replaced file to be kept for time travel, got %d files", len(entries))
+	}
+}
+
+func TestCompactor_ForgetsDroppedTables(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	cols := []metadata.ColumnDef{{Name: "id", Type: metadata.Int64Type}, {Name: "name", Type: metadata.VarcharType}}
+	tableId, err := m.CreateTable("t1", cols, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	addFile(t, m, "t1", []int64{1})
+	addFile(t, m, "t1", []int64{2})
+
+	c := NewCompactor(m, 3, 4, time.Hour)
+	if err := c.Compact(tableId); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if err := m.DeleteTable(tableId); err != nil {
+		t.Fatalf("DeleteTable failed: %v", err)
+	}
+	c.compactAll()
+	if _, ok := c.statuses[tableId]; ok {
+		t.Errorf("Expected status of the dropped table to be removed")
+	}
+
+	if err := c.Compact(tableId); err == nil {
+		t.Errorf("Expected compaction of the dropped table to fail")
+	}
+	if len(c.statuses) != 0 {
+		t.Errorf("Expected no status kept for the dropped table, got %v", c.statuses)
+	}
+}
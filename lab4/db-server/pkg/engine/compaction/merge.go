@@ -0,0 +1,207 @@
+package compaction
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"isbd4/pkg/engine/executor/operators"
+	operators_sort "isbd4/pkg/engine/executor/operators/sort"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+// compactTable rewrites small files and files with deleted rows of the current table version and commits the result.
+// Returns the number of replaced and written files.
+func (c *Compactor) compactTable(tableId string) (int, int, error) {
+	tableDef, exists := c.metastore.GetTableById(tableId)
+	if !exists {
+		return 0, 0, fmt.Errorf("table %s does not exist", tableId)
+	}
+
+	snapshot, err := c.metastore.GetTableSnapshotById(tableId)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer snapshot.Release()
+
+	toRewrite, hasDeletions, err := c.selectFiles(snapshot)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(toRewrite) < 2 && !hasDeletions {
+		return 0, 0, nil
+	}
+
+	outDir := c.metastore.DatabaseDir(tableDef.Database)
+	written, err := c.mergeFiles(toRewrite, snapshot, outDir, tableDef.Name)
+	if err != nil {
+		removeFiles(written)
+		return 0, 0, err
+	}
+
+	if err := c.metastore.ReplaceFiles(tableId, snapshot.Version, toRewrite, written); err != nil {
+		if !errors.Is(err, metadata.ErrNotPersisted) {
+			removeFiles(written)
+		}
+		return 0, 0, err
+	}
+	return len(toRewrite), len(written), nil
+}
+
+// selectFiles returns small files and files with deleted rows, which are purged by the rewrite
+func (c *Compactor) selectFiles(snapshot *metadata.MetastoreSnapshot) ([]string, bool, error) {
+	var selected []string
+	hasDeletions := false
+	for _, f := range snapshot.Files {
+		if snapshot.Deletions[f.Path].Count() > 0 {
+			selected = append(selected, f.Path)
+			hasDeletions = true
+			continue
+		}
+
+		numRows := uint64(0)
+		if f.Stats != nil {
+			numRows = f.Stats.NumRows
+		} else {
+			meta, err := tomy_file.ReadFileMetadata(f.Path)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to read footer of %s: %w", f.Path, err)
+			}
+			numRows = meta.NumRows
+		}
+
+		if numRows < c.maxRowsInFile {
+			selected = append(selected, f.Path)
+		}
+	}
+	return selected, hasDeletions, nil
+}
+
+// mergeFiles concatenates rows of the files into new files of up to maxRowsInFile rows, deleted rows are left out.
+// Files of clustered tables are merge-sorted, so the written files stay sorted by the cluster keys.
+func (c *Compactor) mergeFiles(paths []string, snapshot *metadata.MetastoreSnapshot, outDir string, tableName string) ([]metadata.NewFile, error) {
+	colNames := make([]string, len(snapshot.Columns))
+	for i, col := range snapshot.Columns {
+		colNames[i] = col.Name
+	}
+
+	var input operators.Operator
+	var sortOrder []tomy_file.SortColumn
+	if len(snapshot.SortKeys) > 0 {
+		sortFields, _ := operators_sort.SortFieldsForKeys(snapshot.Columns, colNames, snapshot.SortKeys)
+		readers := make([]operators.Operator, len(paths))
+		for i, path := range paths {
+			readers[i] = operators.NewFileReaderOperator([]string{path}, colNames, snapshot.Deletions, c.chunkSize)
+		}
+		input = operators_sort.NewSortedMergeOperator(readers, sortFields, c.chunkSize)
+		sortOrder = operators_sort.TomySortOrder(sortFields)
+	} else {
+		input = operators.NewFileReaderOperator(paths, colNames, snapshot.Deletions, c.chunkSize)
+	}
+	defer input.Close()
+
+	var written []metadata.NewFile
+	buffer := newBuffer(snapshot.Columns, sortOrder)
+	for {
+		batch, err := input.NextBatch()
+		if err != nil {
+			return written, err
+		}
+		if batch == nil {
+			break
+		}
+
+		for offset := uint64(0); offset < batch.RowCount; {
+			count := min(batch.RowCount-offset, c.maxRowsInFile-buffer.NumRows)
+			if err := appendRows(buffer, batch, offset, count); err != nil {
+				return written, err
+			}
+			offset += count
+
+			if buffer.NumRows < c.maxRowsInFile {
+				continue
+			}
+			file, err := writeFile(buffer, outDir, tableName)
+			if err != nil {
+				return written, err
+			}
+			written = append(written, file)
+			buffer = newBuffer(snapshot.Columns, sortOrder)
+		}
+	}
+
+	if buffer.NumRows > 0 {
+		file, err := writeFile(buffer, outDir, tableName)
+		if err != nil {
+			return written, err
+		}
+		written = append(written, file)
+	}
+	return written, nil
+}
+
+func writeFile(table *tomy_file.ColumnarTable, outDir string, tableName string) (metadata.NewFile, error) {
+	outPath := filepath.Join(outDir, fmt.Sprintf("%s_%d.tomy", tableName, time.Now().UnixNano()))
+	if err := table.Serialize(outPath); err != nil {
+		return metadata.NewFile{}, fmt.Errorf("failed to serialize compacted file: %w", err)
+	}
+
+	stats, err := metadata.FileStatsFromFooter(outPath)
+	if err != nil {
+		os.Remove(outPath)
+		return metadata.NewFile{}, fmt.Errorf("failed to collect file statistics: %w", err)
+	}
+	return metadata.NewFile{Path: outPath, Stats: stats}, nil
+}
+
+func removeFiles(files []metadata.NewFile) {
+	for _, f := range files {
+		os.Remove(f.Path)
+	}
+}
+
+func newBuffer(columns []metadata.ColumnDef, sortOrder []tomy_file.SortColumn) *tomy_file.ColumnarTable {
+	table := &tomy_file.ColumnarTable{
+		Columns:   make([]tomy_file.AnyColumn, len(columns)),
+		SortOrder: sortOrder,
+	}
+	for i, col := range columns {
+		if col.Type == metadata.Int64Type {
+			table.Columns[i] = &tomy_file.Int64Column{Name: col.Name}
+		} else {
+			table.Columns[i] = &tomy_file.VarcharColumn{Name: col.Name}
+		}
+	}
+	return table
+}
+
+// appendRows appends count rows of the batch starting at offset to the buffered file
+func appendRows(dst *tomy_file.ColumnarTable, batch *types.ChunkResult, offset uint64, count uint64) error {
+	cols, err := operators.SliceColumns(batch.Columns, offset, count)
+	if err != nil {
+		return err
+	}
+
+	for i, col := range cols {
+		switch src := col.(type) {
+		case *types.Int64ChunkColumn:
+			d := dst.Columns[i].(*tomy_file.Int64Column)
+			d.Values = append(d.Values, src.Values...)
+		case *types.VarcharChunkColumn:
+			d := dst.Columns[i].(*tomy_file.VarcharColumn)
+			base := uint64(len(d.Data))
+			for _, off := range src.Offsets {
+				d.Offsets = append(d.Offsets, base+off)
+			}
+			d.Data = append(d.Data, src.Data...)
+		default:
+			return fmt.Errorf("unsupported column type: %T", col)
+		}
+	}
+	dst.NumRows += count
+	return nil
+}
@@ -2,13 +2,18 @@ package executor
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"strconv"
 	"time"
 
+	operators_sort "isbd4/pkg/engine/executor/operators/sort"
+	"isbd4/pkg/engine/index"
 	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
 	"isbd4/pkg/metadata"
 	"isbd4/pkg/tomy_file"
 )
@@ -66,7 +71,11 @@ func createCsvToTableMap(columnsMapping []string, schemaColumns []metadata.Colum
 }
 
 func (e *Executor) executeCopy(p *planner.CopyPlan) error {
-	tableDef, exists := p.Metastore.GetTableByName(p.TableName)
+	tableId, exists := p.Metastore.GetTableId(p.TableName)
+	if !exists {
+		return fmt.Errorf("table %s does not exist", p.TableName)
+	}
+	tableDef, exists := p.Metastore.GetTableById(tableId)
 	if !exists {
 		return fmt.Errorf("table %s does not exist", p.TableName)
 	}
@@ -144,18 +153,101 @@ func (e *Executor) executeCopy(p *planner.CopyPlan) error {
 		}
 	}
 
-	fileName := fmt.Sprintf("%s_%d.tomy", p.TableName, time.Now().UnixNano())
-	outPath := filepath.Join(e.tablesDir, fileName)
+	newFile, err := writeTableFile(p.Metastore, tableId, tableDef, &columnarTable)
+	if err != nil {
+		return err
+	}
+
+	// the file is already committed, rows of files not covered by an index are found by scanning them
+	if err := index.Update(p.Metastore, tableId, []string{newFile.Path}); err != nil {
+		log.Printf("Indexing file %s of table %s written by COPY failed: %v", newFile.Path, tableId, err)
+	}
+	return nil
+}
+
+// writeTableFile writes rows of the table into a new file and commits it to the table by its id,
+// rows of clustered tables are sorted by the cluster keys first
+func writeTableFile(m *metadata.Metastore, tableId string, tableDef *metadata.TableDef, columnarTable *tomy_file.ColumnarTable) (metadata.NewFile, error) {
+	newFile, err := serializeTableFile(m, tableDef, columnarTable)
+	if err != nil {
+		return metadata.NewFile{}, err
+	}
+
+	if err := commitTableFile(m, tableId, tableDef, newFile, "import"); err != nil {
+		log.Printf("Committing file %s of table %s written by COPY failed: %v", newFile.Path, tableId, err)
+		return metadata.NewFile{}, err
+	}
+	return newFile, nil
+}
+
+// commitTableFile commits the new file to the table by its id. The file is removed unless it was committed,
+// e.g. when only saving the metastore failed.
+func commitTableFile(m *metadata.Metastore, tableId string, tableDef *metadata.TableDef, newFile metadata.NewFile, statement string) error {
+	err := m.AddFiles(tableId, []metadata.NewFile{newFile})
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, metadata.ErrNotPersisted) {
+		os.Remove(newFile.Path)
+		if _, exists := m.GetTableById(tableId); !exists {
+			return fmt.Errorf("table %s was removed during %s", tableDef.QualifiedName(), statement)
+		}
+	}
+	return fmt.Errorf("%s into table %s failed: %w", statement, tableDef.QualifiedName(), err)
+}
+
+// serializeTableFile writes rows into a new file of the table without committing it, rows of clustered tables are sorted first
+func serializeTableFile(m *metadata.Metastore, tableDef *metadata.TableDef, columnarTable *tomy_file.ColumnarTable) (metadata.NewFile, error) {
+	if len(tableDef.SortKeys) > 0 && columnarTable.NumRows > 0 {
+		if err := sortByKeys(columnarTable, tableDef.Columns, tableDef.SortKeys); err != nil {
+			return metadata.NewFile{}, fmt.Errorf("failed to sort data by cluster keys: %w", err)
+		}
+	}
+
+	fileName := fmt.Sprintf("%s_%d.tomy", tableDef.Name, time.Now().UnixNano())
+	outPath := filepath.Join(m.DatabaseDir(tableDef.Database), fileName)
 
 	if err := columnarTable.Serialize(outPath); err != nil {
-		return fmt.Errorf("failed to serialize data: %w", err)
+		return metadata.NewFile{}, fmt.Errorf("failed to serialize data: %w", err)
 	}
 
-	if err := p.Metastore.AddFile(p.TableName, outPath); err != nil {
+	stats, err := metadata.FileStatsFromFooter(outPath)
+	if err != nil {
 		os.Remove(outPath)
-		fmt.Println("Warning: copy finished, but could not add file to metastore. Error: ", err)
-		return fmt.Errorf("table %s was removed during import", p.TableName)
+		return metadata.NewFile{}, fmt.Errorf("failed to collect file statistics: %w", err)
 	}
+	return metadata.NewFile{Path: outPath, Stats: stats}, nil
+}
+
+// sortByKeys sorts rows of a file of a clustered table and records the order in its footer
+func sortByKeys(table *tomy_file.ColumnarTable, columns []metadata.ColumnDef, keys []metadata.SortKey) error {
+	sortFields, _ := operators_sort.SortFieldsForKeys(columns, nil, keys)
 
+	batch := &types.ChunkResult{
+		RowCount:  table.NumRows,
+		Columns:   make([]types.ChunkColumn, len(table.Columns)),
+		FilterIdx: -1,
+	}
+	for i, col := range table.Columns {
+		chunkCol, err := types.ChunkColumnFromTomy(col)
+		if err != nil {
+			return err
+		}
+		batch.Columns[i] = chunkCol
+	}
+
+	sorted, err := operators_sort.SortBatch(batch, sortFields)
+	if err != nil {
+		return err
+	}
+
+	for i, col := range sorted.Columns {
+		tomyCol, err := types.TomyColumnFromChunk(col)
+		if err != nil {
+			return err
+		}
+		table.Columns[i] = tomyCol
+	}
+	table.SortOrder = operators_sort.TomySortOrder(sortFields)
 	return nil
 }
@@ -0,0 +1,80 @@
+package executor
+
+import (
+	"fmt"
+
+	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+// executeDelete marks rows matching the where expression as deleted, the result holds the number of deleted rows
+func (e *Executor) executeDelete(p *planner.DeletePlan) (*types.ColumnarResult, error) {
+	defer p.Snapshot.Release()
+
+	deleted := make(map[string]*metadata.DeletionVector)
+	for _, f := range p.Snapshot.Files {
+		dv, err := e.matchingRows(f, p)
+		if err != nil {
+			return nil, err
+		}
+		if dv.Count() > 0 {
+			deleted[f.Path] = dv
+		}
+	}
+
+	count, err := p.Metastore.DeleteRows(p.TableId, deleted)
+	if err != nil {
+		return nil, err
+	}
+	return &types.ColumnarResult{
+		RowCount: 1,
+		Columns:  []any{[]int64{int64(count)}},
+	}, nil
+}
+
+// matchingRows returns positions of rows of the file which match the where expression and are not deleted yet
+func (e *Executor) matchingRows(f *metadata.FileEntry, p *planner.DeletePlan) (*metadata.DeletionVector, error) {
+	alreadyDeleted := p.Snapshot.Deletions[f.Path]
+	dv := &metadata.DeletionVector{}
+
+	if p.WhereExpr == nil {
+		meta, err := tomy_file.ReadFileMetadata(f.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read footer of %s: %w", f.Path, err)
+		}
+		for row := uint64(0); row < meta.NumRows; row++ {
+			if !alreadyDeleted.Contains(row) {
+				dv.Add(row)
+			}
+		}
+		return dv, nil
+	}
+
+	// deleted rows are read too, so that positions in batches match positions in the file
+	reader := operators.NewFileReaderOperator([]string{f.Path}, p.WhereExpr.GetUsedColumns(), nil, e.chunkSize)
+	op := operators.NewFilterTransformationOperator(reader, p.WhereExpr)
+	defer op.Close()
+
+	var position uint64
+	for {
+		batch, err := op.NextBatch()
+		if err != nil {
+			return nil, err
+		}
+		if batch == nil {
+			return dv, nil
+		}
+
+		predCol := batch.Columns[batch.FilterIdx].(*types.BooleanChunkColumn)
+		for i, matches := range predCol.Values {
+			row := position + uint64(i)
+			if matches && !alreadyDeleted.Contains(row) {
+				dv.Add(row)
+			}
+		}
+		position += batch.RowCount
+	}
+}
@@ -0,0 +1,33 @@
+package executor
+
+import (
+	"testing"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/metadata"
+)
+
+func TestExecuteDelete_RecreatedTable(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	tableId := createIdTable(t, m, nil, []int64{1, 2})
+	e := NewExecutor(t.TempDir(), 10, 10, 0)
+
+	plan, err := planner.NewPlanner(m).PlanDelete(openapi.DeleteQuery{TableName: "t1"})
+	if err != nil {
+		t.Fatalf("PlanDelete failed: %v", err)
+	}
+
+	// a table dropped and created again under the same name is a different table
+	if err := m.DeleteTable(tableId); err != nil {
+		t.Fatalf("DeleteTable failed: %v", err)
+	}
+	newId := createIdTable(t, m, nil, []int64{3})
+
+	if _, err := e.Execute(plan); err == nil {
+		t.Errorf("Expected the delete from the dropped table to fail")
+	}
+	if versions, _ := m.GetTableVersions(newId); len(versions) != 2 {
+		t.Errorf("Expected no version committed to the recreated table, got %d versions", len(versions))
+	}
+}
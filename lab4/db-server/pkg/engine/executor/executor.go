@@ -15,6 +15,7 @@ type Executor struct {
 	chunkSize        uint64
 	maxRowsInFile    uint64
 	memoryLimitBytes uint64
+	insertBuffer     *insertBuffer
 }
 
 func NewExecutor(baseDir string, chunkSize uint64, maxRowsInFile uint64, memoryLimitBytes uint64) *Executor {
@@ -28,6 +29,7 @@ func NewExecutor(baseDir string, chunkSize uint64, maxRowsInFile uint64, memoryL
 		chunkSize:        chunkSize,
 		maxRowsInFile:    maxRowsInFile,
 		memoryLimitBytes: memoryLimitBytes,
+		insertBuffer:     newInsertBuffer(maxRowsInFile, insertFlushDelay),
 	}
 }
 
@@ -37,6 +39,16 @@ func (e *Executor) Execute(plan planner.QueryPlan) (*types.ColumnarResult, error
 		return nil, e.executeCopy(p)
 	case *planner.SelectPlan:
 		return e.executeSelect(p)
+	case *planner.DeletePlan:
+		return e.executeDelete(p)
+	case *planner.UpdatePlan:
+		return e.executeUpdate(p)
+	case *planner.InsertPlan:
+		return e.executeInsert(p)
+	case *planner.SelectIntoPlan:
+		return e.executeSelectInto(p)
+	case *planner.SetOperationPlan:
+		return e.executeSetOperation(p)
 
 	default:
 		return nil, fmt.Errorf("unknown plan type")
@@ -0,0 +1,150 @@
+package executor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+// insertFlushDelay is the longest time rows of a buffered insert wait for rows of other inserts
+const insertFlushDelay = 200 * time.Millisecond
+
+// executeInsert writes the inserted rows into a new file, buffered rows are written together with rows
+// of other buffered inserts into the table. Returns once the rows are committed, the result holds their number.
+func (e *Executor) executeInsert(p *planner.InsertPlan) (*types.ColumnarResult, error) {
+	var err error
+	if p.Buffered {
+		err = <-e.insertBuffer.add(p)
+	} else {
+		err = writeInsertedRows(p.Metastore, p.TableId, p.Table)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ColumnarResult{
+		RowCount: 1,
+		Columns:  []any{[]int64{int64(p.Table.NumRows)}},
+	}, nil
+}
+
+// writeInsertedRows writes the rows into a new file committed to the table by its id, so rows are not added
+// to another table created under the same name meanwhile
+func writeInsertedRows(m *metadata.Metastore, tableId string, table *tomy_file.ColumnarTable) error {
+	tableDef, exists := m.GetTableById(tableId)
+	if !exists {
+		return fmt.Errorf("table %s was removed during insert", tableId)
+	}
+	newFile, err := serializeTableFile(m, tableDef, table)
+	if err != nil {
+		return err
+	}
+
+	return commitTableFile(m, tableId, tableDef, newFile, "insert")
+}
+
+// insertBuffer coalesces rows of buffered inserts into the same table into a single file. Rows are written
+// once insertFlushDelay passes since the first buffered insert or there are at least maxRowsInFile of them.
+type insertBuffer struct {
+	maxRowsInFile uint64
+	flushDelay    time.Duration
+
+	pending map[string]*pendingInsert // table id -> buffered rows
+	mu      sync.Mutex
+}
+
+type pendingInsert struct {
+	metastore *metadata.Metastore
+	tableId   string
+	table     *tomy_file.ColumnarTable
+	waiters   []chan error
+	timer     *time.Timer
+}
+
+func newInsertBuffer(maxRowsInFile uint64, flushDelay time.Duration) *insertBuffer {
+	return &insertBuffer{
+		maxRowsInFile: maxRowsInFile,
+		flushDelay:    flushDelay,
+		pending:       make(map[string]*pendingInsert),
+	}
+}
+
+// add buffers rows of the insert, the returned channel receives the result of writing them
+func (b *insertBuffer) add(p *planner.InsertPlan) <-chan error {
+	done := make(chan error, 1)
+
+	b.mu.Lock()
+	pi, ok := b.pending[p.TableId]
+	if !ok {
+		pi = &pendingInsert{
+			metastore: p.Metastore,
+			tableId:   p.TableId,
+			table:     emptyTableLike(p.Table),
+		}
+		pi.timer = time.AfterFunc(b.flushDelay, func() { b.flush(pi) })
+		b.pending[p.TableId] = pi
+	}
+	appendTable(pi.table, p.Table)
+	pi.waiters = append(pi.waiters, done)
+	full := pi.table.NumRows >= b.maxRowsInFile
+	b.mu.Unlock()
+
+	if full {
+		b.flush(pi)
+	}
+	return done
+}
+
+// flush writes the buffered rows unless they were already flushed
+func (b *insertBuffer) flush(pi *pendingInsert) {
+	b.mu.Lock()
+	if b.pending[pi.tableId] != pi {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, pi.tableId)
+	pi.timer.Stop()
+	b.mu.Unlock()
+
+	err := writeInsertedRows(pi.metastore, pi.tableId, pi.table)
+	for _, w := range pi.waiters {
+		w <- err
+	}
+}
+
+func emptyTableLike(table *tomy_file.ColumnarTable) *tomy_file.ColumnarTable {
+	empty := &tomy_file.ColumnarTable{Columns: make([]tomy_file.AnyColumn, len(table.Columns))}
+	for i, col := range table.Columns {
+		switch c := col.(type) {
+		case *tomy_file.Int64Column:
+			empty.Columns[i] = &tomy_file.Int64Column{Name: c.Name}
+		case *tomy_file.VarcharColumn:
+			empty.Columns[i] = &tomy_file.VarcharColumn{Name: c.Name}
+		}
+	}
+	return empty
+}
+
+// appendTable appends rows of src to dst, both have the same columns
+func appendTable(dst *tomy_file.ColumnarTable, src *tomy_file.ColumnarTable) {
+	for i, col := range src.Columns {
+		switch c := col.(type) {
+		case *tomy_file.Int64Column:
+			d := dst.Columns[i].(*tomy_file.Int64Column)
+			d.Values = append(d.Values, c.Values...)
+		case *tomy_file.VarcharColumn:
+			d := dst.Columns[i].(*tomy_file.VarcharColumn)
+			base := uint64(len(d.Data))
+			for _, off := range c.Offsets {
+				d.Offsets = append(d.Offsets, base+off)
+			}
+			d.Data = append(d.Data, c.Data...)
+		}
+	}
+	dst.NumRows += src.NumRows
+}
@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+func insertPlan(m *metadata.Metastore, tableId string, ids ...int64) *planner.InsertPlan {
+	return &planner.InsertPlan{
+		TableId: tableId,
+		Table: &tomy_file.ColumnarTable{
+			NumRows: uint64(len(ids)),
+			Columns: []tomy_file.AnyColumn{&tomy_file.Int64Column{Name: "id", Values: ids}},
+		},
+		Buffered:  true,
+		Metastore: m,
+	}
+}
+
+func TestInsertBuffer_CoalescesInserts(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	tableId, err := m.CreateTable("t1", []metadata.ColumnDef{{Name: "id", Type: metadata.Int64Type}}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	b := newInsertBuffer(5, time.Hour)
+	first := b.add(insertPlan(m, tableId, 1, 2))
+	second := b.add(insertPlan(m, tableId, 3))
+	if files, _ := m.GetTableFiles(tableId); len(files) != 0 {
+		t.Fatalf("Expected rows to be buffered, got %d files", len(files))
+	}
+
+	// reaching maxRowsInFile flushes all buffered rows at once
+	third := b.add(insertPlan(m, tableId, 4, 5))
+	for i, done := range []<-chan error{first, second, third} {
+		if err := <-done; err != nil {
+			t.Errorf("Insert %d failed: %v", i, err)
+		}
+	}
+
+	files, _ := m.GetTableFiles(tableId)
+	if len(files) != 1 || files[0].Stats.NumRows != 5 {
+		t.Fatalf("Expected a single file with 5 rows, got %d files", len(files))
+	}
+
+	// a smaller batch is written after the flush delay
+	b.flushDelay = time.Millisecond
+	if err := <-b.add(insertPlan(m, tableId, 6)); err != nil {
+		t.Errorf("Insert failed: %v", err)
+	}
+	if files, _ := m.GetTableFiles(tableId); len(files) != 2 {
+		t.Errorf("Expected 2 files, got %d", len(files))
+	}
+}
+
+func TestWriteInsertedRows_RecreatedTable(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	tableId, err := m.CreateTable("t1", []metadata.ColumnDef{{Name: "id", Type: metadata.Int64Type}}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	table := insertPlan(m, tableId, 1).Table
+
+	// a table dropped and created again under the same name is a different table
+	if err := m.DeleteTable(tableId); err != nil {
+		t.Fatalf("DeleteTable failed: %v", err)
+	}
+	newId, err := m.CreateTable("t1", []metadata.ColumnDef{{Name: "id", Type: metadata.Int64Type}}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	if err := writeInsertedRows(m, tableId, table); err == nil {
+		t.Errorf("Expected the insert into the dropped table to fail")
+	}
+	if files, _ := m.GetTableFiles(newId); len(files) != 0 {
+		t.Errorf("Expected no rows in the recreated table, got %d files", len(files))
+	}
+}
+
+func TestWriteInsertedRows_NotPersisted(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	tableId, err := m.CreateTable("t1", []metadata.ColumnDef{{Name: "id", Type: metadata.Int64Type}}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	// saving fails, as the directory of the metastore file does not exist
+	m.FilePath = filepath.Join(t.TempDir(), "missing", "metastore.json")
+	err = writeInsertedRows(m, tableId, insertPlan(m, tableId, 1).Table)
+	if !errors.Is(err, metadata.ErrNotPersisted) || strings.Contains(err.Error(), "removed") {
+		t.Fatalf("Expected the save error, got %v", err)
+	}
+
+	files, _ := m.GetTableFiles(tableId)
+	if len(files) != 1 {
+		t.Fatalf("Expected the file to stay committed, got %d files", len(files))
+	}
+	if _, err := os.Stat(files[0].Path); err != nil {
+		t.Errorf("Expected the committed file to be kept: %v", err)
+	}
+}
@@ -0,0 +1,241 @@
+package aggregate
+
+import (
+	"os"
+	"path/filepath"
+
+	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+// DistinctOperator removes duplicate rows of the selected columns of the child, returning batches of only these
+// columns. Rows are returned as soon as they are seen first, their keys are kept in a hash table. When the table
+// exceeds the memory limit, keys of returned rows and later rows are spilled into partition files by hash of the
+// row, then rows of every partition which were not returned yet are deduplicated and returned.
+type DistinctOperator struct {
+	Child            operators.Operator
+	ChunkSize        uint64
+	MemoryLimitBytes uint64
+
+	baseDir  string
+	spillDir string
+
+	// aggregation groups rows by all selected columns, without aggregates
+	aggregation *planner.Aggregation
+	table       *groupTable
+	returned    *partitionSet // keys of returned rows
+	pending     *partitionSet // rows seen after spilling started
+	consumed    bool
+	partition   int
+	result      *types.ChunkResult
+	offset      uint64
+	keyBuffer   []byte
+}
+
+func NewDistinctOperator(child operators.Operator, chunkSize uint64, memoryLimitBytes uint64, baseDir string) *DistinctOperator {
+	return &DistinctOperator{
+		Child:            child,
+		ChunkSize:        chunkSize,
+		MemoryLimitBytes: memoryLimitBytes,
+		baseDir:          baseDir,
+	}
+}
+
+func (op *DistinctOperator) Close() {
+	if op.Child != nil {
+		op.Child.Close()
+		op.Child = nil
+	}
+	if op.returned != nil {
+		op.returned.finish()
+		op.pending.finish()
+	}
+	op.table = nil
+	op.result = nil
+	if op.spillDir != "" {
+		os.RemoveAll(op.spillDir)
+		op.spillDir = ""
+	}
+}
+
+func (op *DistinctOperator) NextBatch() (*types.ChunkResult, error) {
+	for !op.consumed {
+		batch, err := op.Child.NextBatch()
+		if err != nil {
+			return nil, err
+		}
+		if batch == nil {
+			op.consumed = true
+			if err := op.finishSpilling(); err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		distinct, err := op.addBatch(batch)
+		if err != nil {
+			return nil, err
+		}
+		if distinct != nil {
+			return distinct, nil
+		}
+	}
+
+	for op.result == nil || op.offset >= op.result.RowCount {
+		if op.returned == nil || op.partition >= partitionCount {
+			return nil, nil
+		}
+		if err := op.deduplicatePartition(); err != nil {
+			return nil, err
+		}
+	}
+
+	count := min(op.ChunkSize, op.result.RowCount-op.offset)
+	columns, err := operators.SliceColumns(op.result.Columns, op.offset, count)
+	if err != nil {
+		return nil, err
+	}
+	op.offset += count
+	return &types.ChunkResult{
+		RowCount:  count,
+		Columns:   columns,
+		SelectIdx: op.result.SelectIdx,
+		FilterIdx: -1,
+	}, nil
+}
+
+// addBatch returns rows of the batch seen for the first time, or spills them once spilling started
+func (op *DistinctOperator) addBatch(batch *types.ChunkResult) (*types.ChunkResult, error) {
+	columns := batch.Columns
+	if batch.SelectIdx != nil {
+		columns = make([]types.ChunkColumn, len(batch.SelectIdx))
+		for i, idx := range batch.SelectIdx {
+			columns[i] = batch.Columns[idx]
+		}
+	}
+	if op.aggregation == nil {
+		op.aggregation = &planner.Aggregation{}
+		for _, col := range columns {
+			op.aggregation.GroupBy = append(op.aggregation.GroupBy, &expr.ColumnRefExpr{ColName: col.GetName(), ColType: col.GetType()})
+			op.aggregation.GroupNames = append(op.aggregation.GroupNames, col.GetName())
+		}
+		op.table = newGroupTable(op.aggregation)
+	}
+
+	var keep []int
+	for row := 0; row < int(batch.RowCount); row++ {
+		op.keyBuffer = op.keyBuffer[:0]
+		for _, col := range columns {
+			op.keyBuffer = operators.AppendKeyValue(op.keyBuffer, col.GetValueAny(row))
+		}
+		if _, ok := op.table.index[string(op.keyBuffer)]; ok {
+			continue
+		}
+		op.table.get(string(op.keyBuffer), func() []any {
+			values := make([]any, len(columns))
+			for i, col := range columns {
+				values[i] = col.GetValueAny(row)
+			}
+			return values
+		})
+
+		if op.pending != nil {
+			// the row may have been returned before, which is checked once its partition is deduplicated
+			if op.MemoryLimitBytes > 0 && op.table.sizeBytes > op.MemoryLimitBytes {
+				if err := op.pending.spill(op.table); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		keep = append(keep, row)
+		if op.MemoryLimitBytes > 0 && op.table.sizeBytes > op.MemoryLimitBytes {
+			if err := op.startSpilling(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(keep) == 0 {
+		return nil, nil
+	}
+	kept, err := operators.FilterBatchColumns(columns, keep)
+	if err != nil {
+		return nil, err
+	}
+	selectIdx := make([]int, len(kept))
+	for i := range selectIdx {
+		selectIdx[i] = i
+	}
+	return &types.ChunkResult{
+		RowCount:  uint64(len(keep)),
+		Columns:   kept,
+		SelectIdx: selectIdx,
+		FilterIdx: -1,
+	}, nil
+}
+
+// startSpilling spills keys of the returned rows, later rows are collected in the table and spilled to pending partitions
+func (op *DistinctOperator) startSpilling() error {
+	parent := filepath.Join(filepath.Dir(op.baseDir), ".agg_spill")
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp(parent, "distinct_")
+	if err != nil {
+		return err
+	}
+	op.spillDir = dir
+	op.returned = newPartitionSet(dir, 0, 1)
+	op.pending = newPartitionSet(dir, 0, 2)
+	return op.returned.spill(op.table)
+}
+
+func (op *DistinctOperator) finishSpilling() error {
+	if op.pending == nil {
+		return nil
+	}
+	if err := op.pending.spill(op.table); err != nil {
+		return err
+	}
+	if _, err := op.returned.finish(); err != nil {
+		return err
+	}
+	_, err := op.pending.finish()
+	return err
+}
+
+// deduplicatePartition collects rows of the next pending partition whose keys were not returned
+func (op *DistinctOperator) deduplicatePartition() error {
+	i := op.partition
+	op.partition++
+
+	op.table.reset()
+	err := readPartition(op.returned.files[i], func(g *groupState) error {
+		op.keyBuffer = encodeKey(op.keyBuffer[:0], g.Keys)
+		op.table.get(string(op.keyBuffer), func() []any { return nil })
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	rows := newGroupTable(op.aggregation)
+	err = readPartition(op.pending.files[i], func(g *groupState) error {
+		op.keyBuffer = encodeKey(op.keyBuffer[:0], g.Keys)
+		if _, ok := op.table.index[string(op.keyBuffer)]; !ok {
+			rows.get(string(op.keyBuffer), func() []any { return g.Keys })
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	op.table.reset()
+
+	op.result = rows.toChunk()
+	op.offset = 0
+	return nil
+}
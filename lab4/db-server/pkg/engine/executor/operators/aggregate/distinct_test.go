@@ -0,0 +1,90 @@
+package aggregate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"isbd4/pkg/engine/executor/operators/operatortest"
+	"isbd4/pkg/engine/types"
+)
+
+func TestDistinctOperator(t *testing.T) {
+	const rows, distinct, batchSize = 3000, 400, 128
+
+	// every row is repeated; the second column is selected only to check that it is dropped
+	var batches []*types.ChunkResult
+	for start := 0; start < rows; start += batchSize {
+		count := min(batchSize, rows-start)
+		ids := make([]int64, count)
+		names := make([]string, count)
+		positions := make([]int64, count)
+		for i := range ids {
+			ids[i] = int64((start + i) * 7 % distinct)
+			names[i] = fmt.Sprintf("n%d", ids[i]%3)
+			positions[i] = int64(start + i)
+		}
+		batches = append(batches, &types.ChunkResult{
+			RowCount: uint64(count),
+			Columns: []types.ChunkColumn{
+				types.NewInt64Column("position", positions),
+				types.NewInt64Column("id", ids),
+				types.VarcharChunkColumnFromStrings("name", names),
+			},
+			SelectIdx: []int{1, 2},
+			FilterIdx: -1,
+		})
+	}
+
+	for _, memoryLimit := range []uint64{1 << 20, 4096} {
+		t.Run(fmt.Sprintf("limit %d", memoryLimit), func(t *testing.T) {
+			baseDir := filepath.Join(t.TempDir(), "tables")
+			child := operatortest.NewBatchesOperator(batches...)
+			op := NewDistinctOperator(child, 100, memoryLimit, baseDir)
+
+			var got []int64
+			for {
+				batch, err := op.NextBatch()
+				if err != nil {
+					t.Fatalf("Distinct failed: %v", err)
+				}
+				if batch == nil {
+					break
+				}
+				if len(batch.SelectIdx) != 2 || batch.RowCount > 128 {
+					t.Fatalf("Expected batches of 2 selected columns and at most 128 rows, got %d and %d", len(batch.SelectIdx), batch.RowCount)
+				}
+				for i := 0; i < int(batch.RowCount); i++ {
+					id := batch.Columns[batch.SelectIdx[0]].GetValueAny(i).(int64)
+					if name := batch.Columns[batch.SelectIdx[1]].GetValueAny(i); name != fmt.Sprintf("n%d", id%3) {
+						t.Fatalf("Row %d: name %v doesn't match id %d", i, name, id)
+					}
+					got = append(got, id)
+				}
+			}
+			spilled := op.spillDir != ""
+			op.Close()
+			if spilled != (memoryLimit < 1<<20) {
+				t.Errorf("Expected spilling only with the low memory limit, spilled: %v", spilled)
+			}
+			if entries, _ := os.ReadDir(filepath.Join(filepath.Dir(baseDir), ".agg_spill")); len(entries) != 0 {
+				t.Errorf("Expected spill files to be removed, got %d entries", len(entries))
+			}
+
+			if len(got) != distinct {
+				t.Fatalf("Expected %d distinct rows, got %d", distinct, len(got))
+			}
+			seen := make(map[int64]bool)
+			for i, id := range got {
+				if seen[id] {
+					t.Fatalf("Row %d returned twice", id)
+				}
+				seen[id] = true
+				if !spilled && id != int64(i*7%distinct) {
+					t.Fatalf("Expected rows in order of their first occurrence, got %d at %d", id, i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package aggregate
+
+import (
+	"fmt"
+	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+// groupState holds partial results of all aggregates of one group, states of the same group are merged
+// when a group was spilled to disk more than once
+type groupState struct {
+	Keys   []any
+	Counts []int64 // COUNT and AVG
+	Sums   []int64 // SUM and AVG
+	Values []any   // MIN and MAX, int64(0) placeholder for other aggregates
+	// Distinct holds values of COUNT DISTINCT encoded by AppendKeyValue, nil for other aggregates
+	Distinct []map[string]bool
+}
+
+const (
+	groupOverheadBytes     = 96
+	aggregateOverheadBytes = 40
+	distinctOverheadBytes  = 48
+)
+
+// groupTable is an in-memory hash table of groups, groups are kept in order of their first row
+type groupTable struct {
+	aggregation *planner.Aggregation
+	index       map[string]int
+	groups      []*groupState
+	sizeBytes   uint64
+}
+
+func newGroupTable(aggregation *planner.Aggregation) *groupTable {
+	return &groupTable{
+		aggregation: aggregation,
+		index:       make(map[string]int),
+	}
+}
+
+func (t *groupTable) reset() {
+	t.index = make(map[string]int)
+	t.groups = nil
+	t.sizeBytes = 0
+}
+
+// get returns the state of the group with the given encoded keys, creating an empty one if needed
+func (t *groupTable) get(encodedKey string, keys func() []any) *groupState {
+	if idx, ok := t.index[encodedKey]; ok {
+		return t.groups[idx]
+	}
+
+	n := len(t.aggregation.Aggregates)
+	g := &groupState{
+		Keys:   keys(),
+		Counts: make([]int64, n),
+		Sums:   make([]int64, n),
+		Values: make([]any, n),
+	}
+	for i, call := range t.aggregation.Aggregates {
+		if call.Function != expr.Min && call.Function != expr.Max {
+			g.Values[i] = int64(0)
+		}
+		if call.Distinct {
+			if g.Distinct == nil {
+				g.Distinct = make([]map[string]bool, n)
+			}
+			g.Distinct[i] = make(map[string]bool)
+		}
+	}
+	t.index[encodedKey] = len(t.groups)
+	t.groups = append(t.groups, g)
+	t.sizeBytes += uint64(2*len(encodedKey)) + groupOverheadBytes + uint64(n)*aggregateOverheadBytes
+	return g
+}
+
+// update adds the value of the i-th aggregate of a row to the group, value is nil for COUNT of all rows.
+// It returns the number of bytes the group grew by.
+func (g *groupState) update(i int, call planner.AggregateCall, value any) uint64 {
+	if call.Distinct {
+		return g.addDistinct(i, string(operators.AppendKeyValue(nil, value)))
+	}
+
+	switch call.Function {
+	case expr.Count:
+		g.Counts[i]++
+	case expr.Sum:
+		g.Sums[i] += value.(int64)
+	case expr.Avg:
+		g.Counts[i]++
+		g.Sums[i] += value.(int64)
+	case expr.Min:
+		if g.Values[i] == nil || less(value, g.Values[i]) {
+			g.Values[i] = value
+		}
+	case expr.Max:
+		if g.Values[i] == nil || less(g.Values[i], value) {
+			g.Values[i] = value
+		}
+	}
+	return 0
+}
+
+func (g *groupState) addDistinct(i int, encodedValue string) uint64 {
+	if g.Distinct[i] == nil {
+		g.Distinct[i] = make(map[string]bool)
+	}
+	if g.Distinct[i][encodedValue] {
+		return 0
+	}
+	g.Distinct[i][encodedValue] = true
+	return uint64(len(encodedValue)) + distinctOverheadBytes
+}
+
+// merge adds the partial results of other state of the same group, returns the number of bytes the group grew by
+func (g *groupState) merge(other *groupState, aggregates []planner.AggregateCall) uint64 {
+	var added uint64
+	for i, call := range aggregates {
+		g.Counts[i] += other.Counts[i]
+		g.Sums[i] += other.Sums[i]
+		if call.Distinct {
+			for value := range other.Distinct[i] {
+				added += g.addDistinct(i, value)
+			}
+		} else if call.Function == expr.Min || call.Function == expr.Max {
+			g.update(i, call, other.Values[i])
+		}
+	}
+	return added
+}
+
+// result returns the value of the i-th aggregate, aggregates of a group without rows return the zero value
+func (g *groupState) result(i int, call planner.AggregateCall) any {
+	switch call.Function {
+	case expr.Count:
+		if call.Distinct {
+			return int64(len(g.Distinct[i]))
+		}
+		return g.Counts[i]
+	case expr.Sum:
+		return g.Sums[i]
+	case expr.Avg:
+		if g.Counts[i] == 0 {
+			return int64(0)
+		}
+		return g.Sums[i] / g.Counts[i]
+	default:
+		if g.Values[i] == nil {
+			return zeroValue(call.ResultType)
+		}
+		return g.Values[i]
+	}
+}
+
+func zeroValue(colType types.ChunkColumnType) any {
+	switch colType {
+	case types.ChunkColumnTypeVarchar:
+		return ""
+	case types.ChunkColumnTypeBoolean:
+		return false
+	default:
+		return int64(0)
+	}
+}
+
+func less(a, b any) bool {
+	switch av := a.(type) {
+	case int64:
+		return av < b.(int64)
+	case string:
+		return av < b.(string)
+	default:
+		panic(fmt.Sprintf("unsupported aggregated value: %T", a))
+	}
+}
+
+// encodeKey encodes values of the group keys, different keys are encoded differently
+func encodeKey(buf []byte, keys []any) []byte {
+	for _, k := range keys {
+		buf = operators.AppendKeyValue(buf, k)
+	}
+	return buf
+}
+
+// toChunk returns the groups of the table as a chunk with group keys followed by results of aggregates
+func (t *groupTable) toChunk() *types.ChunkResult {
+	groupCount := len(t.aggregation.GroupBy)
+	columns := make([]types.ChunkColumn, 0, groupCount+len(t.aggregation.Aggregates))
+	for k, groupExpr := range t.aggregation.GroupBy {
+		columns = append(columns, buildColumn(t.aggregation.GroupNames[k], groupExpr.ResultType(), len(t.groups), func(i int) any {
+			return t.groups[i].Keys[k]
+		}))
+	}
+	for a, call := range t.aggregation.Aggregates {
+		columns = append(columns, buildColumn(call.Name, call.ResultType, len(t.groups), func(i int) any {
+			return t.groups[i].result(a, call)
+		}))
+	}
+
+	selectIdx := make([]int, len(columns))
+	for i := range selectIdx {
+		selectIdx[i] = i
+	}
+	return &types.ChunkResult{
+		RowCount:  uint64(len(t.groups)),
+		Columns:   columns,
+		SelectIdx: selectIdx,
+		FilterIdx: -1,
+	}
+}
+
+func buildColumn(name string, colType types.ChunkColumnType, rowCount int, value func(i int) any) types.ChunkColumn {
+	switch colType {
+	case types.ChunkColumnTypeInt64:
+		values := make([]int64, rowCount)
+		for i := range values {
+			values[i] = value(i).(int64)
+		}
+		return types.NewInt64Column(name, values)
+	case types.ChunkColumnTypeVarchar:
+		values := make([]string, rowCount)
+		for i := range values {
+			values[i] = value(i).(string)
+		}
+		return types.VarcharChunkColumnFromStrings(name, values)
+	default:
+		values := make([]bool, rowCount)
+		for i := range values {
+			values[i] = value(i).(bool)
+		}
+		return types.NewBooleanColumn(name, values)
+	}
+}
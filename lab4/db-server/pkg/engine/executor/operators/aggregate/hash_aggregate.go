@@ -0,0 +1,251 @@
+package aggregate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+// partitions of groups which still do not fit in memory at this level are aggregated regardless of the limit
+const maxSpillLevel = 4
+
+// HashAggregateOperator groups rows of the child by the group keys and computes aggregates of every group.
+// Child batches contain the group keys followed by arguments of aggregates, as returned by Aggregation.InputExprs.
+// If the groups do not fit in the memory limit they are spilled to partition files, which are aggregated one by one.
+type HashAggregateOperator struct {
+	Child            operators.Operator
+	Aggregation      *planner.Aggregation
+	ChunkSize        uint64
+	MemoryLimitBytes uint64
+
+	baseDir  string
+	spillDir string
+	setCount int
+
+	table     *groupTable
+	pending   []partition
+	consumed  bool
+	result    *types.ChunkResult
+	offset    uint64
+	argIdx    []int // index of the input column with the argument of every aggregate, -1 for COUNT of all rows
+	keyBuffer []byte
+}
+
+func NewHashAggregateOperator(child operators.Operator, aggregation *planner.Aggregation, chunkSize uint64, memoryLimitBytes uint64, baseDir string) *HashAggregateOperator {
+	argIdx := make([]int, len(aggregation.Aggregates))
+	next := len(aggregation.GroupBy)
+	for i, call := range aggregation.Aggregates {
+		argIdx[i] = -1
+		if call.Argument != nil {
+			argIdx[i] = next
+			next++
+		}
+	}
+
+	return &HashAggregateOperator{
+		Child:            child,
+		Aggregation:      aggregation,
+		ChunkSize:        chunkSize,
+		MemoryLimitBytes: memoryLimitBytes,
+		baseDir:          baseDir,
+		table:            newGroupTable(aggregation),
+		argIdx:           argIdx,
+	}
+}
+
+func (op *HashAggregateOperator) Close() {
+	if op.Child != nil {
+		op.Child.Close()
+		op.Child = nil
+	}
+	op.table = nil
+	op.result = nil
+	if op.spillDir != "" {
+		os.RemoveAll(op.spillDir)
+		op.spillDir = ""
+	}
+}
+
+func (op *HashAggregateOperator) NextBatch() (*types.ChunkResult, error) {
+	if !op.consumed {
+		partitions, err := op.consumeChild()
+		if err != nil {
+			return nil, err
+		}
+		op.pending = partitions
+		op.consumed = true
+		if partitions == nil {
+			if len(op.Aggregation.GroupBy) == 0 && len(op.table.groups) == 0 {
+				// aggregates of all rows are returned also when there are no rows
+				op.table.get("", func() []any { return nil })
+			}
+			op.result = op.table.toChunk()
+			op.table.reset()
+		}
+	}
+
+	for op.result == nil || op.offset >= op.result.RowCount {
+		if len(op.pending) == 0 {
+			return nil, nil
+		}
+		if err := op.aggregateNextPartition(); err != nil {
+			return nil, err
+		}
+	}
+
+	count := min(op.ChunkSize, op.result.RowCount-op.offset)
+	columns, err := operators.SliceColumns(op.result.Columns, op.offset, count)
+	if err != nil {
+		return nil, err
+	}
+	op.offset += count
+	return &types.ChunkResult{
+		RowCount:  count,
+		Columns:   columns,
+		SelectIdx: op.result.SelectIdx,
+		FilterIdx: -1,
+	}, nil
+}
+
+// consumeChild aggregates all rows of the child, returns partitions to aggregate if any groups were spilled
+func (op *HashAggregateOperator) consumeChild() ([]partition, error) {
+	var spilled *partitionSet
+	for {
+		batch, err := op.Child.NextBatch()
+		if err != nil {
+			return nil, err
+		}
+		if batch == nil {
+			break
+		}
+		if err := op.addBatch(batch); err != nil {
+			return nil, err
+		}
+
+		if op.MemoryLimitBytes > 0 && op.table.sizeBytes > op.MemoryLimitBytes {
+			if spilled == nil {
+				if spilled, err = op.newPartitionSet(0); err != nil {
+					return nil, err
+				}
+			}
+			if err := spilled.spill(op.table); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if spilled == nil {
+		return nil, nil
+	}
+	if err := spilled.spill(op.table); err != nil {
+		return nil, err
+	}
+	return spilled.finish()
+}
+
+func (op *HashAggregateOperator) addBatch(batch *types.ChunkResult) error {
+	keyCount := len(op.Aggregation.GroupBy)
+	if len(batch.Columns) < op.inputWidth() {
+		return fmt.Errorf("aggregation expects %d input columns, got %d", op.inputWidth(), len(batch.Columns))
+	}
+
+	for row := 0; row < int(batch.RowCount); row++ {
+		keys := func() []any {
+			values := make([]any, keyCount)
+			for k := range values {
+				values[k] = batch.Columns[k].GetValueAny(row)
+			}
+			return values
+		}
+
+		op.keyBuffer = op.keyBuffer[:0]
+		for k := 0; k < keyCount; k++ {
+			op.keyBuffer = operators.AppendKeyValue(op.keyBuffer, batch.Columns[k].GetValueAny(row))
+		}
+		g := op.table.get(string(op.keyBuffer), keys)
+
+		for i, call := range op.Aggregation.Aggregates {
+			var value any
+			if op.argIdx[i] >= 0 {
+				value = batch.Columns[op.argIdx[i]].GetValueAny(row)
+			}
+			op.table.sizeBytes += g.update(i, call, value)
+		}
+	}
+	return nil
+}
+
+func (op *HashAggregateOperator) inputWidth() int {
+	width := len(op.Aggregation.GroupBy)
+	for _, idx := range op.argIdx {
+		if idx >= 0 {
+			width++
+		}
+	}
+	return width
+}
+
+// aggregateNextPartition merges spilled states of groups of the next partition. If they do not fit in memory
+// the partition is split into partitions of the next level instead.
+func (op *HashAggregateOperator) aggregateNextPartition() error {
+	p := op.pending[0]
+	op.pending = op.pending[1:]
+
+	var split *partitionSet
+	err := readPartition(p.path, func(g *groupState) error {
+		op.keyBuffer = encodeKey(op.keyBuffer[:0], g.Keys)
+		merged := op.table.get(string(op.keyBuffer), func() []any { return g.Keys })
+		op.table.sizeBytes += merged.merge(g, op.Aggregation.Aggregates)
+
+		if op.MemoryLimitBytes > 0 && op.table.sizeBytes > op.MemoryLimitBytes && p.level+1 < maxSpillLevel {
+			if split == nil {
+				var err error
+				if split, err = op.newPartitionSet(p.level + 1); err != nil {
+					return err
+				}
+			}
+			return split.spill(op.table)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if split != nil {
+		if err := split.spill(op.table); err != nil {
+			return err
+		}
+		partitions, err := split.finish()
+		if err != nil {
+			return err
+		}
+		op.pending = append(partitions, op.pending...)
+		return nil
+	}
+
+	op.result = op.table.toChunk()
+	op.offset = 0
+	op.table.reset()
+	return nil
+}
+
+func (op *HashAggregateOperator) newPartitionSet(level int) (*partitionSet, error) {
+	if op.spillDir == "" {
+		parent := filepath.Join(filepath.Dir(op.baseDir), ".agg_spill")
+		if err := os.MkdirAll(parent, 0755); err != nil {
+			return nil, err
+		}
+		dir, err := os.MkdirTemp(parent, "agg_")
+		if err != nil {
+			return nil, err
+		}
+		op.spillDir = dir
+	}
+	op.setCount++
+	return newPartitionSet(op.spillDir, level, op.setCount), nil
+}
@@ -0,0 +1,197 @@
+package aggregate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/executor/operators/operatortest"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+func TestHashAggregateOperator(t *testing.T) {
+	const groups, rows, batchSize = 300, 3000, 250
+
+	var batches []*types.ChunkResult
+	for start := 0; start < rows; start += batchSize {
+		names := make([]string, batchSize)
+		values := make([]int64, batchSize)
+		for i := range names {
+			names[i] = fmt.Sprintf("g%d", (start+i)%groups)
+			values[i] = int64(start + i)
+		}
+		batches = append(batches, &types.ChunkResult{
+			RowCount:  batchSize,
+			Columns:   []types.ChunkColumn{types.VarcharChunkColumnFromStrings("name", names), types.NewInt64Column("v", values)},
+			SelectIdx: []int{0, 1},
+			FilterIdx: -1,
+		})
+	}
+
+	v := &expr.ColumnRefExpr{ColName: "v", ColType: types.ChunkColumnTypeInt64}
+	aggregation := &planner.Aggregation{
+		GroupBy:    []expr.Expression{&expr.ColumnRefExpr{ColName: "name", ColType: types.ChunkColumnTypeVarchar}},
+		GroupNames: []string{"name"},
+		Aggregates: []planner.AggregateCall{
+			{Function: expr.Count, Name: "count_0", ResultType: types.ChunkColumnTypeInt64},
+			{Function: expr.Sum, Argument: v, Name: "sum_1", ResultType: types.ChunkColumnTypeInt64},
+			{Function: expr.Min, Argument: v, Name: "min_2", ResultType: types.ChunkColumnTypeInt64},
+			{Function: expr.Max, Argument: v, Name: "max_3", ResultType: types.ChunkColumnTypeInt64},
+			{Function: expr.Avg, Argument: v, Name: "avg_4", ResultType: types.ChunkColumnTypeInt64},
+		},
+	}
+	// the child returns the group key and the argument of every aggregate
+	for _, b := range batches {
+		b.Columns = append(b.Columns, b.Columns[1], b.Columns[1], b.Columns[1])
+	}
+
+	for _, memoryLimit := range []uint64{1 << 20, 2048} {
+		t.Run(fmt.Sprintf("limit %d", memoryLimit), func(t *testing.T) {
+			baseDir := filepath.Join(t.TempDir(), "tables")
+			child := operatortest.NewBatchesOperator(batches...)
+			op := NewHashAggregateOperator(child, aggregation, 64, memoryLimit, baseDir)
+
+			result, err := operators.CollectAllBatches(op)
+			if err != nil {
+				t.Fatalf("Aggregation failed: %v", err)
+			}
+			spilled := op.spillDir != ""
+			op.Close()
+			if spilled != (memoryLimit < 1<<20) {
+				t.Errorf("Expected spilling only with the low memory limit, spilled: %v", spilled)
+			}
+			if entries, _ := os.ReadDir(filepath.Join(filepath.Dir(baseDir), ".agg_spill")); len(entries) != 0 {
+				t.Errorf("Expected spill files to be removed, got %d entries", len(entries))
+			}
+
+			if result.RowCount != groups {
+				t.Fatalf("Expected %d groups, got %d", groups, result.RowCount)
+			}
+			names := result.Columns[0].([]string)
+			seen := make(map[string]bool)
+			for i, name := range names {
+				var g int64
+				fmt.Sscanf(name, "g%d", &g)
+				count := int64(rows / groups)
+				sum := count*g + groups*count*(count-1)/2
+				want := []int64{count, sum, g, g + groups*(count-1), sum / count}
+				for a, w := range want {
+					if got := result.Columns[a+1].([]int64)[i]; got != w {
+						t.Errorf("Group %s: expected %s = %d, got %d", name, aggregation.Aggregates[a].Name, w, got)
+					}
+				}
+				if seen[name] {
+					t.Errorf("Group %s returned more than once", name)
+				}
+				seen[name] = true
+			}
+		})
+	}
+}
+
+func TestHashAggregateOperator_NoGroupBy(t *testing.T) {
+	v := &expr.ColumnRefExpr{ColName: "v", ColType: types.ChunkColumnTypeVarchar}
+	aggregation := &planner.Aggregation{
+		Aggregates: []planner.AggregateCall{
+			{Function: expr.Count, Name: "count_0", ResultType: types.ChunkColumnTypeInt64},
+			{Function: expr.Max, Argument: v, Name: "max_1", ResultType: types.ChunkColumnTypeVarchar},
+		},
+	}
+	rows := &types.ChunkResult{
+		RowCount:  3,
+		Columns:   []types.ChunkColumn{types.VarcharChunkColumnFromStrings("v", []string{"b", "c", "a"})},
+		SelectIdx: []int{0},
+		FilterIdx: -1,
+	}
+
+	tests := []struct {
+		name    string
+		batches []*types.ChunkResult
+		count   int64
+		max     string
+	}{
+		{"rows", []*types.ChunkResult{rows}, 3, "c"},
+		{"no rows", nil, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := NewHashAggregateOperator(operatortest.NewBatchesOperator(tt.batches...), aggregation, 64, 1<<20, t.TempDir())
+			defer op.Close()
+			batch, err := op.NextBatch()
+			if err != nil {
+				t.Fatalf("Aggregation failed: %v", err)
+			}
+			if batch == nil || batch.RowCount != 1 {
+				t.Fatalf("Expected a single row, got %+v", batch)
+			}
+			if got := batch.Columns[0].GetValueAny(0); got != tt.count {
+				t.Errorf("Expected count %d, got %v", tt.count, got)
+			}
+			if got := batch.Columns[1].GetValueAny(0); got != tt.max {
+				t.Errorf("Expected max %q, got %v", tt.max, got)
+			}
+			if batch, _ := op.NextBatch(); batch != nil {
+				t.Errorf("Expected no more batches, got %+v", batch)
+			}
+		})
+	}
+}
+
+func TestHashAggregateOperator_CountDistinct(t *testing.T) {
+	const groups, rows, batchSize = 50, 3000, 250
+
+	// group g has rows g, g+50, ..., valued by their position in the group modulo 20
+	var batches []*types.ChunkResult
+	for start := 0; start < rows; start += batchSize {
+		names := make([]string, batchSize)
+		values := make([]int64, batchSize)
+		for i := range names {
+			names[i] = fmt.Sprintf("g%d", (start+i)%groups)
+			values[i] = int64((start+i)/groups) % 20
+		}
+		batches = append(batches, &types.ChunkResult{
+			RowCount:  batchSize,
+			Columns:   []types.ChunkColumn{types.VarcharChunkColumnFromStrings("name", names), types.NewInt64Column("v", values), types.NewInt64Column("v", values)},
+			SelectIdx: []int{0, 1, 2},
+			FilterIdx: -1,
+		})
+	}
+
+	v := &expr.ColumnRefExpr{ColName: "v", ColType: types.ChunkColumnTypeInt64}
+	aggregation := &planner.Aggregation{
+		GroupBy:    []expr.Expression{&expr.ColumnRefExpr{ColName: "name", ColType: types.ChunkColumnTypeVarchar}},
+		GroupNames: []string{"name"},
+		Aggregates: []planner.AggregateCall{
+			{Function: expr.Count, Argument: v, Distinct: true, Name: "count_distinct_0", ResultType: types.ChunkColumnTypeInt64},
+			{Function: expr.Count, Argument: v, Name: "count_1", ResultType: types.ChunkColumnTypeInt64},
+		},
+	}
+
+	for _, memoryLimit := range []uint64{1 << 20, 2048} {
+		t.Run(fmt.Sprintf("limit %d", memoryLimit), func(t *testing.T) {
+			child := operatortest.NewBatchesOperator(batches...)
+			op := NewHashAggregateOperator(child, aggregation, 64, memoryLimit, filepath.Join(t.TempDir(), "tables"))
+			defer op.Close()
+
+			result, err := operators.CollectAllBatches(op)
+			if err != nil {
+				t.Fatalf("Aggregation failed: %v", err)
+			}
+			if result.RowCount != groups {
+				t.Fatalf("Expected %d groups, got %d", groups, result.RowCount)
+			}
+			for i := 0; i < groups; i++ {
+				if got := result.Columns[1].([]int64)[i]; got != 20 {
+					t.Errorf("Group %v: expected 20 distinct values, got %d", result.Columns[0].([]string)[i], got)
+				}
+				if got := result.Columns[2].([]int64)[i]; got != rows/groups {
+					t.Errorf("Group %v: expected %d values, got %d", result.Columns[0].([]string)[i], rows/groups, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package aggregate
+
+import (
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+func init() {
+	gob.Register(int64(0))
+	gob.Register("")
+	gob.Register(bool(false))
+}
+
+const partitionCount = 16
+
+// partitionSet spreads spilled groups into partition files by hash of their keys, groups of one partition
+// are aggregated together later. The hash depends on the level, so groups of a partition which does not fit
+// in memory are spread again when the partition is split.
+type partitionSet struct {
+	dir     string
+	level   int
+	files   []string
+	writers []*partitionWriter
+}
+
+type partitionWriter struct {
+	file    *os.File
+	encoder *gob.Encoder
+}
+
+// partition is a spilled file to aggregate, level is the level of the set which wrote it
+type partition struct {
+	path  string
+	level int
+}
+
+func newPartitionSet(dir string, level int, id int) *partitionSet {
+	set := &partitionSet{
+		dir:     dir,
+		level:   level,
+		files:   make([]string, partitionCount),
+		writers: make([]*partitionWriter, partitionCount),
+	}
+	for i := range set.files {
+		set.files[i] = filepath.Join(dir, fmt.Sprintf("part_%d_%d_%d.gob", level, id, i))
+	}
+	return set
+}
+
+func (s *partitionSet) partitionOf(encodedKey string) int {
+	h := fnv.New64a()
+	h.Write([]byte{byte(s.level)})
+	h.Write([]byte(encodedKey))
+	return int(h.Sum64() % partitionCount)
+}
+
+// spill writes all groups of the table into partitions and clears the table
+func (s *partitionSet) spill(table *groupTable) error {
+	for encodedKey, idx := range table.index {
+		p := s.partitionOf(encodedKey)
+		if s.writers[p] == nil {
+			file, err := os.Create(s.files[p])
+			if err != nil {
+				return err
+			}
+			s.writers[p] = &partitionWriter{file: file, encoder: gob.NewEncoder(file)}
+		}
+		if err := s.writers[p].encoder.Encode(table.groups[idx]); err != nil {
+			return err
+		}
+	}
+	table.reset()
+	return nil
+}
+
+func (s *partitionSet) used() bool {
+	for _, w := range s.writers {
+		if w != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// finish closes partition files and returns the written ones
+func (s *partitionSet) finish() ([]partition, error) {
+	var partitions []partition
+	var firstErr error
+	for i, w := range s.writers {
+		if w == nil {
+			continue
+		}
+		if err := w.file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		s.writers[i] = nil
+		partitions = append(partitions, partition{path: s.files[i], level: s.level})
+	}
+	return partitions, firstErr
+}
+
+// readPartition passes groups stored in the partition file to the callback and removes the file,
+// a missing file has no groups
+func readPartition(path string, callback func(g *groupState) error) error {
+	defer os.Remove(path)
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := gob.NewDecoder(file)
+	for {
+		var g groupState
+		if err := decoder.Decode(&g); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to read spilled groups: %w", err)
+		}
+		if err := callback(&g); err != nil {
+			return err
+		}
+	}
+}
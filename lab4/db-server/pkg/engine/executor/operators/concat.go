@@ -0,0 +1,62 @@
+package operators
+
+import (
+	"isbd4/pkg/engine/types"
+)
+
+// ConcatOperator returns the selected columns of batches of the children one child after another (UNION ALL).
+// Columns are matched by position and renamed to the given names, so rows of all children share one schema,
+// e.g. a single child is read as a derived table.
+type ConcatOperator struct {
+	Children []Operator
+	Names    []string
+}
+
+func NewConcatOperator(children []Operator, names []string) *ConcatOperator {
+	return &ConcatOperator{
+		Children: children,
+		Names:    names,
+	}
+}
+
+func (op *ConcatOperator) Close() {
+	for _, child := range op.Children {
+		child.Close()
+	}
+	op.Children = nil
+}
+
+func (op *ConcatOperator) NextBatch() (*types.ChunkResult, error) {
+	for len(op.Children) > 0 {
+		batch, err := op.Children[0].NextBatch()
+		if err != nil {
+			return nil, err
+		}
+		if batch == nil {
+			op.Children[0].Close()
+			op.Children = op.Children[1:]
+			continue
+		}
+		if batch.RowCount == 0 {
+			continue
+		}
+
+		columns := make([]types.ChunkColumn, len(op.Names))
+		selectIdx := make([]int, len(op.Names))
+		for i, name := range op.Names {
+			idx := i
+			if batch.SelectIdx != nil {
+				idx = batch.SelectIdx[i]
+			}
+			columns[i] = RenameColumn(batch.Columns[idx], name)
+			selectIdx[i] = i
+		}
+		return &types.ChunkResult{
+			RowCount:  batch.RowCount,
+			Columns:   columns,
+			SelectIdx: selectIdx,
+			FilterIdx: -1,
+		}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,60 @@
+package operators
+
+import (
+	"slices"
+	"testing"
+
+	"isbd4/pkg/engine/executor/operators/operatortest"
+	"isbd4/pkg/engine/types"
+)
+
+func TestConcatOperator(t *testing.T) {
+	first := operatortest.NewBatchesOperator(&types.ChunkResult{
+		RowCount:  2,
+		Columns:   []types.ChunkColumn{types.NewInt64Column("id", []int64{1, 2}), types.NewInt64Column("price", []int64{10, 20})},
+		SelectIdx: []int{1, 0},
+		FilterIdx: -1,
+	})
+	second := operatortest.NewBatchesOperator(
+		&types.ChunkResult{
+			RowCount:  0,
+			Columns:   []types.ChunkColumn{types.NewInt64Column("cost", nil), types.NewInt64Column("no", nil)},
+			SelectIdx: []int{0, 1},
+			FilterIdx: -1,
+		},
+		&types.ChunkResult{
+			RowCount:  1,
+			Columns:   []types.ChunkColumn{types.NewInt64Column("cost", []int64{30}), types.NewInt64Column("no", []int64{3})},
+			SelectIdx: []int{0, 1},
+			FilterIdx: -1,
+		},
+	)
+
+	op := NewConcatOperator([]Operator{first, operatortest.NewBatchesOperator(), second}, []string{"col_0", "col_1"})
+	defer op.Close()
+
+	var prices, ids []int64
+	for {
+		batch, err := op.NextBatch()
+		if err != nil {
+			t.Fatalf("Concat failed: %v", err)
+		}
+		if batch == nil {
+			break
+		}
+		if batch.RowCount == 0 {
+			t.Errorf("Expected empty batches to be skipped")
+		}
+		for i, name := range []string{"col_0", "col_1"} {
+			if got := batch.Columns[batch.SelectIdx[i]].GetName(); got != name {
+				t.Errorf("Expected column %s, got %s", name, got)
+			}
+		}
+		prices = append(prices, batch.Columns[batch.SelectIdx[0]].(*types.Int64ChunkColumn).Values...)
+		ids = append(ids, batch.Columns[batch.SelectIdx[1]].(*types.Int64ChunkColumn).Values...)
+	}
+
+	if !slices.Equal(prices, []int64{10, 20, 30}) || !slices.Equal(ids, []int64{1, 2, 3}) {
+		t.Errorf("Expected selected columns of all children in order, got %v and %v", prices, ids)
+	}
+}
@@ -0,0 +1,155 @@
+package operators
+
+import (
+	"fmt"
+
+	"isbd4/pkg/engine/index"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+// IndexScanOperator reads only rows with keys in the range of the lookup from files covered by the index,
+// files not covered by it are read whole. Rows still have to be filtered by the where clause.
+type IndexScanOperator struct {
+	Snapshot      *metadata.MetastoreSnapshot
+	Lookup        *planner.IndexLookup
+	ColumnsToRead []string
+	ChunkSize     uint64
+
+	reader *RowsReaderOperator
+}
+
+func NewIndexScanOperator(snapshot *metadata.MetastoreSnapshot, queryDef *planner.SelectQueryDefinition, lookup *planner.IndexLookup, chunkSize uint64) *IndexScanOperator {
+	return &IndexScanOperator{
+		Snapshot:      snapshot,
+		Lookup:        lookup,
+		ColumnsToRead: ExtractUsedColumns(queryDef),
+		ChunkSize:     chunkSize,
+	}
+}
+
+func (o *IndexScanOperator) Close() {}
+
+func (o *IndexScanOperator) NextBatch() (*types.ChunkResult, error) {
+	if o.reader == nil {
+		if err := o.init(); err != nil {
+			return nil, err
+		}
+	}
+	return o.reader.NextBatch()
+}
+
+func (o *IndexScanOperator) init() error {
+	indexFiles := o.Snapshot.Indexes[o.Lookup.Column]
+	covered := make(map[string]bool)
+	for _, f := range o.Snapshot.Files {
+		for _, indexFile := range indexFiles {
+			if indexFile.Covers(f.Path) {
+				covered[f.Path] = true
+				break
+			}
+		}
+	}
+
+	rows, err := index.Lookup(indexFiles, o.Lookup.Keys, covered)
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	for _, f := range o.Snapshot.Files {
+		if _, matched := rows[f.Path]; matched || !covered[f.Path] {
+			files = append(files, f.Path)
+		}
+	}
+	o.reader = NewRowsReaderOperator(files, rows, o.ColumnsToRead, o.Snapshot.Deletions, o.ChunkSize)
+	return nil
+}
+
+// RowsReaderOperator reads the given rows of the files one after another, files without listed rows are read whole.
+// Deleted rows are skipped.
+type RowsReaderOperator struct {
+	Files         []string
+	Rows          map[string][]uint64 // sorted positions of rows to read, by file path
+	ColumnsToRead []string
+	Deletions     map[string]*metadata.DeletionVector
+	ChunkSize     uint64
+
+	fileIdx   int
+	columns   []types.ChunkColumn // columns of the current file
+	positions []int               // positions of rows of the current file left to return
+}
+
+func NewRowsReaderOperator(files []string, rows map[string][]uint64, colNames []string, deletions map[string]*metadata.DeletionVector, chunkSize uint64) *RowsReaderOperator {
+	return &RowsReaderOperator{
+		Files:         files,
+		Rows:          rows,
+		ColumnsToRead: colNames,
+		Deletions:     deletions,
+		ChunkSize:     chunkSize,
+	}
+}
+
+func (r *RowsReaderOperator) Close() {
+	r.columns = nil
+}
+
+func (r *RowsReaderOperator) NextBatch() (*types.ChunkResult, error) {
+	for len(r.positions) == 0 {
+		if r.fileIdx >= len(r.Files) {
+			return nil, nil
+		}
+		if err := r.loadNextFile(); err != nil {
+			return nil, err
+		}
+	}
+
+	count := min(uint64(len(r.positions)), r.ChunkSize)
+	columns, err := FilterBatchColumns(r.columns, r.positions[:count])
+	if err != nil {
+		return nil, err
+	}
+	r.positions = r.positions[count:]
+
+	return &types.ChunkResult{
+		RowCount:  count,
+		Columns:   columns,
+		SelectIdx: nil,
+		FilterIdx: -1,
+	}, nil
+}
+
+func (r *RowsReaderOperator) loadNextFile() error {
+	path := r.Files[r.fileIdx]
+	r.fileIdx++
+
+	table, err := tomy_file.DeserializeColumns(path, r.ColumnsToRead)
+	if err != nil {
+		return fmt.Errorf("failed to load file %s: %w", path, err)
+	}
+	r.columns = make([]types.ChunkColumn, len(table.Columns))
+	for i, col := range table.Columns {
+		if r.columns[i], err = types.ChunkColumnFromTomy(col); err != nil {
+			return fmt.Errorf("failed to convert column %s: %w", col.GetName(), err)
+		}
+	}
+
+	dv := r.Deletions[path]
+	r.positions = r.positions[:0]
+	if rows, ok := r.Rows[path]; ok {
+		for _, row := range rows {
+			if row < table.NumRows && !dv.Contains(row) {
+				r.positions = append(r.positions, int(row))
+			}
+		}
+		return nil
+	}
+	for row := uint64(0); row < table.NumRows; row++ {
+		if !dv.Contains(row) {
+			r.positions = append(r.positions, int(row))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+package join
+
+import (
+	"hash/fnv"
+	"os"
+	"path/filepath"
+
+	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+// partitions of both sides when the build side does not fit in memory
+const partitionCount = 32
+
+// HashJoinOperator joins rows of the left child (probe side) with rows of the right child (build side).
+// Rows of the right child are collected in a hash table by their keys and every left row is matched with rows
+// of equal keys satisfying the condition. If the right rows exceed the memory limit, rows of both sides are
+// spilled into partition files by hash of their keys and partitions are joined one by one.
+// Joined batches hold the left columns followed by the right columns.
+type HashJoinOperator struct {
+	Left             operators.Operator
+	Right            operators.Operator
+	Join             planner.Join
+	LeftColumns      []planner.JoinedColumn
+	RightColumns     []planner.JoinedColumn
+	ChunkSize        uint64
+	MemoryLimitBytes uint64
+
+	baseDir  string
+	spillDir string
+
+	built      bool
+	build      *buildSide
+	probe      operators.Operator // left child or reader of the current left partition
+	pending    []int              // partitions to join
+	leftParts  *rowPartitions
+	rightParts *rowPartitions
+	output     outputQueue
+	done       bool
+}
+
+func NewHashJoinOperator(left, right operators.Operator, join planner.Join, leftColumns, rightColumns []planner.JoinedColumn,
+	chunkSize uint64, memoryLimitBytes uint64, baseDir string) *HashJoinOperator {
+	return &HashJoinOperator{
+		Left:             left,
+		Right:            right,
+		Join:             join,
+		LeftColumns:      leftColumns,
+		RightColumns:     rightColumns,
+		ChunkSize:        chunkSize,
+		MemoryLimitBytes: memoryLimitBytes,
+		baseDir:          baseDir,
+	}
+}
+
+func (op *HashJoinOperator) Close() {
+	if op.Left != nil {
+		op.Left.Close()
+		op.Left = nil
+	}
+	if op.Right != nil {
+		op.Right.Close()
+		op.Right = nil
+	}
+	if r, ok := op.probe.(*rowReader); ok {
+		r.Close()
+	}
+	op.probe = nil
+	if op.leftParts != nil {
+		op.leftParts.close()
+		op.rightParts.close()
+	}
+	op.build = nil
+	op.output = outputQueue{}
+	if op.spillDir != "" {
+		os.RemoveAll(op.spillDir)
+		op.spillDir = ""
+	}
+}
+
+func (op *HashJoinOperator) NextBatch() (*types.ChunkResult, error) {
+	for op.output.empty() {
+		if op.done {
+			return nil, nil
+		}
+		if err := op.advance(); err != nil {
+			return nil, err
+		}
+	}
+	return op.output.pop(op.ChunkSize)
+}
+
+// advance joins the next probe batch, or finishes the current partition and moves to the next one
+func (op *HashJoinOperator) advance() error {
+	if !op.built {
+		op.built = true
+		return op.buildRight()
+	}
+
+	batch, err := op.probe.NextBatch()
+	if err != nil {
+		return err
+	}
+	if batch != nil {
+		return op.probeBatch(batch)
+	}
+
+	if keepsUnmatchedRight(op.Join.Type) {
+		unmatched, err := withZeroLeft(op.LeftColumns, op.build.columns, unmatchedRows(op.build.matched))
+		if err != nil {
+			return err
+		}
+		op.output.push(unmatched)
+	}
+	if len(op.pending) == 0 {
+		op.done = true
+		return nil
+	}
+	return op.loadPartition()
+}
+
+// buildRight reads the right child into the hash table, or into partitions if it does not fit in memory
+func (op *HashJoinOperator) buildRight() error {
+	var batches []*types.ChunkResult
+	var size uint64
+	for {
+		batch, err := op.Right.NextBatch()
+		if err != nil {
+			return err
+		}
+		if batch == nil {
+			break
+		}
+		batches = append(batches, batch)
+		size += batch.SizeInBytes()
+		if op.MemoryLimitBytes > 0 && size > op.MemoryLimitBytes {
+			return op.spill(batches)
+		}
+	}
+
+	build, err := op.newBuildSide(batches)
+	if err != nil {
+		return err
+	}
+	op.build = build
+	op.probe = op.Left
+	return nil
+}
+
+// spill partitions the collected and remaining right rows, then all left rows
+func (op *HashJoinOperator) spill(rightBatches []*types.ChunkResult) error {
+	parent := filepath.Join(filepath.Dir(op.baseDir), ".join_spill")
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp(parent, "join_")
+	if err != nil {
+		return err
+	}
+	op.spillDir = dir
+	op.leftParts = newRowPartitions(dir, "left", partitionCount)
+	op.rightParts = newRowPartitions(dir, "right", partitionCount)
+
+	for _, batch := range rightBatches {
+		if err := op.spillBatch(op.rightParts, batch, op.Join.RightKeys, op.RightColumns); err != nil {
+			return err
+		}
+	}
+	if err := op.spillChild(op.Right, op.rightParts, op.Join.RightKeys, op.RightColumns); err != nil {
+		return err
+	}
+	if err := op.spillChild(op.Left, op.leftParts, op.Join.LeftKeys, op.LeftColumns); err != nil {
+		return err
+	}
+	if err := op.rightParts.close(); err != nil {
+		return err
+	}
+	if err := op.leftParts.close(); err != nil {
+		return err
+	}
+
+	for i := 0; i < partitionCount; i++ {
+		op.pending = append(op.pending, i)
+	}
+	return op.loadPartition()
+}
+
+func (op *HashJoinOperator) spillChild(child operators.Operator, parts *rowPartitions, keys []expr.Expression, columns []planner.JoinedColumn) error {
+	for {
+		batch, err := child.NextBatch()
+		if err != nil {
+			return err
+		}
+		if batch == nil {
+			return nil
+		}
+		if err := op.spillBatch(parts, batch, keys, columns); err != nil {
+			return err
+		}
+	}
+}
+
+func (op *HashJoinOperator) spillBatch(parts *rowPartitions, batch *types.ChunkResult, keys []expr.Expression, columns []planner.JoinedColumn) error {
+	keyCols, err := evaluateKeys(keys, batch, columns)
+	if err != nil {
+		return err
+	}
+	partitionOf := make([]int, batch.RowCount)
+	var buf []byte
+	for row := range partitionOf {
+		buf = encodeKey(buf[:0], keyCols, row)
+		h := fnv.New64a()
+		h.Write(buf)
+		partitionOf[row] = int(h.Sum64() % partitionCount)
+	}
+	return parts.write(batch, partitionOf)
+}
+
+// loadPartition builds the hash table of the right rows of the next partition and probes it with its left rows
+func (op *HashJoinOperator) loadPartition() error {
+	part := op.pending[0]
+	op.pending = op.pending[1:]
+
+	right, err := openRowReader(op.rightParts.files[part], op.RightColumns, op.ChunkSize)
+	if err != nil {
+		return err
+	}
+	defer right.Close()
+	var batches []*types.ChunkResult
+	for {
+		batch, err := right.NextBatch()
+		if err != nil {
+			return err
+		}
+		if batch == nil {
+			break
+		}
+		batches = append(batches, batch)
+	}
+	os.Remove(op.rightParts.files[part])
+
+	if op.build, err = op.newBuildSide(batches); err != nil {
+		return err
+	}
+	if r, ok := op.probe.(*rowReader); ok {
+		r.Close()
+	}
+	op.probe, err = openRowReader(op.leftParts.files[part], op.LeftColumns, op.ChunkSize)
+	return err
+}
+
+// buildSide holds right rows in memory with row numbers of every key
+type buildSide struct {
+	columns  []types.ChunkColumn
+	rowCount int
+	rows     map[string][]int
+	matched  []bool
+}
+
+func (op *HashJoinOperator) newBuildSide(batches []*types.ChunkResult) (*buildSide, error) {
+	build := &buildSide{rows: make(map[string][]int)}
+	if len(batches) == 0 {
+		build.columns = zeroColumns(op.RightColumns, 0)
+		return build, nil
+	}
+
+	merged, err := operators.MergeChunkResultsWithinOneSchema(batches)
+	if err != nil {
+		return nil, err
+	}
+	build.columns = merged.Columns
+	build.rowCount = int(merged.RowCount)
+	build.matched = make([]bool, build.rowCount)
+
+	keyCols, err := evaluateKeys(op.Join.RightKeys, merged, op.RightColumns)
+	if err != nil {
+		return nil, err
+	}
+	var buf []byte
+	for row := 0; row < build.rowCount; row++ {
+		buf = encodeKey(buf[:0], keyCols, row)
+		build.rows[string(buf)] = append(build.rows[string(buf)], row)
+	}
+	return build, nil
+}
+
+// probeBatch joins left rows of the batch with matching right rows
+func (op *HashJoinOperator) probeBatch(batch *types.ChunkResult) error {
+	keyCols, err := evaluateKeys(op.Join.LeftKeys, batch, op.LeftColumns)
+	if err != nil {
+		return err
+	}
+
+	var leftIdx, rightIdx []int
+	var buf []byte
+	for row := 0; row < int(batch.RowCount); row++ {
+		buf = encodeKey(buf[:0], keyCols, row)
+		for _, r := range op.build.rows[string(buf)] {
+			leftIdx = append(leftIdx, row)
+			rightIdx = append(rightIdx, r)
+		}
+	}
+
+	var joined *types.ChunkResult
+	if len(leftIdx) > 0 {
+		joined, leftIdx, rightIdx, err = joinPairs(batch.Columns, op.build.columns, leftIdx, rightIdx, op.Join.Condition)
+		if err != nil {
+			return err
+		}
+	}
+
+	matchedLeft := make([]bool, batch.RowCount)
+	for i := range leftIdx {
+		matchedLeft[leftIdx[i]] = true
+		op.build.matched[rightIdx[i]] = true
+	}
+	op.output.push(joined)
+
+	if keepsUnmatchedLeft(op.Join.Type) {
+		unmatched, err := withZeroRight(batch.Columns, unmatchedRows(matchedLeft), op.RightColumns)
+		if err != nil {
+			return err
+		}
+		op.output.push(unmatched)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package join
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/executor/operators/operatortest"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+// idBatches returns batches of rows with ids from the given range, named after the id
+func idBatches(alias string, from, to, batchSize int) []*types.ChunkResult {
+	var batches []*types.ChunkResult
+	for start := from; start < to; start += batchSize {
+		var ids []int64
+		var names []string
+		for id := start; id < to && id < start+batchSize; id++ {
+			ids = append(ids, int64(id))
+			names = append(names, fmt.Sprintf("%s%d", alias, id))
+		}
+		batches = append(batches, newBatch([]types.ChunkColumn{
+			types.NewInt64Column(alias+".id", ids),
+			types.VarcharChunkColumnFromStrings(alias+".name", names),
+		}, uint64(len(ids))))
+	}
+	return batches
+}
+
+func TestHashJoinOperator(t *testing.T) {
+	// left ids 0..599, right ids 400..999 and every right id divisible by 3 twice
+	leftBatches := idBatches("l", 0, 600, 128)
+	rightBatches := append(idBatches("r", 400, 1000, 128), idBatches("r", 0, 1000, 128)...)
+	for _, b := range rightBatches[5:] {
+		var keep []int
+		for i, id := range b.Columns[0].(*types.Int64ChunkColumn).Values {
+			if id%3 == 0 {
+				keep = append(keep, i)
+			}
+		}
+		b.Columns, _ = operators.FilterBatchColumns(b.Columns, keep)
+		b.RowCount = uint64(len(keep))
+	}
+
+	columns := func(alias string) []planner.JoinedColumn {
+		return []planner.JoinedColumn{
+			{Name: alias + ".id", TableColumn: "id", Type: types.ChunkColumnTypeInt64},
+			{Name: alias + ".name", TableColumn: "name", Type: types.ChunkColumnTypeVarchar},
+		}
+	}
+	lID := &expr.ColumnRefExpr{ColName: "l.id", ColType: types.ChunkColumnTypeInt64}
+	rID := &expr.ColumnRefExpr{ColName: "r.id", ColType: types.ChunkColumnTypeInt64}
+	// residual condition excluding ids ending with 7: l.id - l.id / 10 * 10 != 7
+	lit := func(v int64) expr.Expression { return &expr.LiteralExpr{Value: v, Type: types.ChunkColumnTypeInt64} }
+	quotient, _ := expr.NewBinaryOp(lID, lit(10), expr.Divide)
+	tens, _ := expr.NewBinaryOp(quotient, lit(10), expr.Multiply)
+	lastDigit, _ := expr.NewBinaryOp(lID, tens, expr.Subtract)
+	condition, _ := expr.NewBinaryOp(lastDigit, lit(7), expr.NotEqual)
+
+	// expected pairs of ids, -1 for a missing row
+	expected := func(joinType planner.JoinType) []string {
+		var res []string
+		rightMatched := make(map[int]bool)
+		for l := 0; l < 600; l++ {
+			matches := 0
+			if l%10 != 7 {
+				if l >= 400 {
+					matches++
+				}
+				if l%3 == 0 {
+					matches++
+				}
+			}
+			for i := 0; i < matches; i++ {
+				res = append(res, fmt.Sprintf("%d-%d", l, l))
+			}
+			if matches > 0 {
+				rightMatched[l] = true
+			} else if joinType == planner.LeftJoin || joinType == planner.FullJoin {
+				res = append(res, fmt.Sprintf("%d-%d", l, -1))
+			}
+		}
+		if joinType == planner.RightJoin || joinType == planner.FullJoin {
+			for r := 0; r < 1000; r++ {
+				copies := 0
+				if r >= 400 {
+					copies++
+				}
+				if r%3 == 0 {
+					copies++
+				}
+				if !rightMatched[r] || r >= 600 {
+					for i := 0; i < copies; i++ {
+						res = append(res, fmt.Sprintf("%d-%d", -1, r))
+					}
+				}
+			}
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	for _, joinType := range []planner.JoinType{planner.InnerJoin, planner.LeftJoin, planner.RightJoin, planner.FullJoin} {
+		for _, memoryLimit := range []uint64{0, 1 << 20, 4096} { // 0 is no limit
+			t.Run(fmt.Sprintf("%s limit %d", joinType, memoryLimit), func(t *testing.T) {
+				baseDir := filepath.Join(t.TempDir(), "tables")
+				join := planner.Join{
+					Type:      joinType,
+					LeftKeys:  []expr.Expression{lID},
+					RightKeys: []expr.Expression{rID},
+					Condition: condition,
+				}
+				op := NewHashJoinOperator(
+					operatortest.NewBatchesOperator(leftBatches...),
+					operatortest.NewBatchesOperator(rightBatches...),
+					join, columns("l"), columns("r"), 100, memoryLimit, baseDir)
+
+				var got []string
+				for {
+					batch, err := op.NextBatch()
+					if err != nil {
+						t.Fatalf("Join failed: %v", err)
+					}
+					if batch == nil {
+						break
+					}
+					if batch.RowCount > 100 {
+						t.Errorf("Expected batches of at most 100 rows, got %d", batch.RowCount)
+					}
+					for i := 0; i < int(batch.RowCount); i++ {
+						l, r := batch.Columns[0].GetValueAny(i).(int64), batch.Columns[2].GetValueAny(i).(int64)
+						lName, rName := batch.Columns[1].GetValueAny(i).(string), batch.Columns[3].GetValueAny(i).(string)
+						if lName == "" {
+							l = -1
+						} else if lName != fmt.Sprintf("l%d", l) {
+							t.Fatalf("Row %d: left name %s doesn't match id %d", i, lName, l)
+						}
+						if rName == "" {
+							r = -1
+						} else if rName != fmt.Sprintf("r%d", r) {
+							t.Fatalf("Row %d: right name %s doesn't match id %d", i, rName, r)
+						}
+						got = append(got, fmt.Sprintf("%d-%d", l, r))
+					}
+				}
+				spilled := op.spillDir != ""
+				op.Close()
+				if spilled != (memoryLimit > 0 && memoryLimit < 1<<20) {
+					t.Errorf("Expected spilling only with the low memory limit, spilled: %v", spilled)
+				}
+				if entries, _ := os.ReadDir(filepath.Join(filepath.Dir(baseDir), ".join_spill")); len(entries) != 0 {
+					t.Errorf("Expected spill files to be removed, got %d entries", len(entries))
+				}
+
+				sort.Strings(got)
+				want := expected(joinType)
+				if len(got) != len(want) {
+					t.Fatalf("Expected %d rows, got %d", len(want), len(got))
+				}
+				for i := range want {
+					if got[i] != want[i] {
+						t.Fatalf("Expected row %s, got %s", want[i], got[i])
+					}
+				}
+			})
+		}
+	}
+}
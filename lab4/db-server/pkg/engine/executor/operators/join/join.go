@@ -0,0 +1,160 @@
+package join
+
+import (
+	"fmt"
+
+	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+// outputQueue holds joined batches until they are returned in batches of at most chunkSize rows
+type outputQueue struct {
+	batches []*types.ChunkResult
+}
+
+func (q *outputQueue) push(batch *types.ChunkResult) {
+	if batch != nil && batch.RowCount > 0 {
+		q.batches = append(q.batches, batch)
+	}
+}
+
+func (q *outputQueue) empty() bool {
+	return len(q.batches) == 0
+}
+
+func (q *outputQueue) pop(chunkSize uint64) (*types.ChunkResult, error) {
+	batch := q.batches[0]
+	if batch.RowCount <= chunkSize {
+		q.batches = q.batches[1:]
+		return batch, nil
+	}
+	head, err := operators.SliceColumns(batch.Columns, 0, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	tail, err := operators.SliceColumns(batch.Columns, chunkSize, batch.RowCount-chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	q.batches[0] = newBatch(tail, batch.RowCount-chunkSize)
+	return newBatch(head, chunkSize), nil
+}
+
+func keepsUnmatchedLeft(joinType planner.JoinType) bool {
+	return joinType == planner.LeftJoin || joinType == planner.FullJoin
+}
+
+func keepsUnmatchedRight(joinType planner.JoinType) bool {
+	return joinType == planner.RightJoin || joinType == planner.FullJoin
+}
+
+// joinPairs returns pairs of left and right rows given by indices which satisfy the condition (if any),
+// together with indices of the kept pairs
+func joinPairs(left, right []types.ChunkColumn, leftIdx, rightIdx []int, condition expr.Expression) (*types.ChunkResult, []int, []int, error) {
+	leftCols, err := operators.FilterBatchColumns(left, leftIdx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	rightCols, err := operators.FilterBatchColumns(right, rightIdx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	joined := newBatch(append(leftCols, rightCols...), uint64(len(leftIdx)))
+	if condition == nil {
+		return joined, leftIdx, rightIdx, nil
+	}
+
+	colMapping := make(map[string]int, len(joined.Columns))
+	for i, col := range joined.Columns {
+		colMapping[col.GetName()] = i
+	}
+	result, err := condition.Evaluate(joined, colMapping)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var keep []int
+	for i, v := range result.(*types.BooleanChunkColumn).Values {
+		if v {
+			keep = append(keep, i)
+		}
+	}
+	if len(keep) == len(leftIdx) {
+		return joined, leftIdx, rightIdx, nil
+	}
+
+	columns, err := operators.FilterBatchColumns(joined.Columns, keep)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	keptLeft := make([]int, len(keep))
+	keptRight := make([]int, len(keep))
+	for i, idx := range keep {
+		keptLeft[i] = leftIdx[idx]
+		keptRight[i] = rightIdx[idx]
+	}
+	return newBatch(columns, uint64(len(keep))), keptLeft, keptRight, nil
+}
+
+func unmatchedRows(matched []bool) []int {
+	var rows []int
+	for row, m := range matched {
+		if !m {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// withZeroRight returns the given left rows with zeroed right columns, nil if there are no rows
+func withZeroRight(left []types.ChunkColumn, rows []int, rightColumns []planner.JoinedColumn) (*types.ChunkResult, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	leftCols, err := operators.FilterBatchColumns(left, rows)
+	if err != nil {
+		return nil, err
+	}
+	return newBatch(append(leftCols, zeroColumns(rightColumns, len(rows))...), uint64(len(rows))), nil
+}
+
+// withZeroLeft returns the given right rows with zeroed left columns, nil if there are no rows
+func withZeroLeft(leftColumns []planner.JoinedColumn, right []types.ChunkColumn, rows []int) (*types.ChunkResult, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	rightCols, err := operators.FilterBatchColumns(right, rows)
+	if err != nil {
+		return nil, err
+	}
+	return newBatch(append(zeroColumns(leftColumns, len(rows)), rightCols...), uint64(len(rows))), nil
+}
+
+// evaluateKeys evaluates key expressions on a batch with the given columns
+func evaluateKeys(keys []expr.Expression, batch *types.ChunkResult, columns []planner.JoinedColumn) ([]types.ChunkColumn, error) {
+	if len(batch.Columns) != len(columns) {
+		return nil, fmt.Errorf("join expects %d input columns, got %d", len(columns), len(batch.Columns))
+	}
+	colMapping := make(map[string]int, len(columns))
+	for i, col := range columns {
+		colMapping[col.Name] = i
+	}
+	keyCols := make([]types.ChunkColumn, len(keys))
+	for i, key := range keys {
+		col, err := key.Evaluate(batch, colMapping)
+		if err != nil {
+			return nil, err
+		}
+		keyCols[i] = col
+	}
+	return keyCols, nil
+}
+
+func encodeKey(buf []byte, keyCols []types.ChunkColumn, row int) []byte {
+	for _, col := range keyCols {
+		buf = operators.AppendKeyValue(buf, col.GetValueAny(row))
+	}
+	return buf
+}
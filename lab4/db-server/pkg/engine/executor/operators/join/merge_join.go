@@ -0,0 +1,308 @@
+package join
+
+import (
+	"strings"
+
+	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+// MergeJoinOperator joins children returning rows in ascending order of their keys. Both children are read
+// group by group of rows with equal keys: every pair of groups with equal keys is joined (rows satisfying
+// the condition), groups without a counterpart are unmatched. Only the current group of each side is kept
+// in memory. Joined batches hold the left columns followed by the right columns.
+type MergeJoinOperator struct {
+	Left         operators.Operator
+	Right        operators.Operator
+	Join         planner.Join
+	LeftColumns  []planner.JoinedColumn
+	RightColumns []planner.JoinedColumn
+	ChunkSize    uint64
+
+	started    bool
+	left       *keyGroups
+	right      *keyGroups
+	leftGroup  *keyGroup
+	rightGroup *keyGroup
+	output     outputQueue
+	done       bool
+}
+
+func NewMergeJoinOperator(left, right operators.Operator, join planner.Join, leftColumns, rightColumns []planner.JoinedColumn,
+	chunkSize uint64) *MergeJoinOperator {
+	return &MergeJoinOperator{
+		Left:         left,
+		Right:        right,
+		Join:         join,
+		LeftColumns:  leftColumns,
+		RightColumns: rightColumns,
+		ChunkSize:    chunkSize,
+		left:         &keyGroups{child: left, keys: join.LeftKeys, columns: leftColumns},
+		right:        &keyGroups{child: right, keys: join.RightKeys, columns: rightColumns},
+	}
+}
+
+func (op *MergeJoinOperator) Close() {
+	if op.Left != nil {
+		op.Left.Close()
+		op.Left = nil
+	}
+	if op.Right != nil {
+		op.Right.Close()
+		op.Right = nil
+	}
+	op.leftGroup, op.rightGroup = nil, nil
+	op.output = outputQueue{}
+}
+
+func (op *MergeJoinOperator) NextBatch() (*types.ChunkResult, error) {
+	for op.output.empty() {
+		if op.done {
+			return nil, nil
+		}
+		if err := op.advance(); err != nil {
+			return nil, err
+		}
+	}
+	return op.output.pop(op.ChunkSize)
+}
+
+// advance consumes the group with the lower key, or joins groups with equal keys
+func (op *MergeJoinOperator) advance() error {
+	var err error
+	if !op.started {
+		op.started = true
+		if op.leftGroup, err = op.left.next(); err != nil {
+			return err
+		}
+		if op.rightGroup, err = op.right.next(); err != nil {
+			return err
+		}
+	}
+
+	l, r := op.leftGroup, op.rightGroup
+	keepLeft, keepRight := keepsUnmatchedLeft(op.Join.Type), keepsUnmatchedRight(op.Join.Type)
+	if (l == nil && r == nil) || (l == nil && !keepRight) || (r == nil && !keepLeft) {
+		op.done = true
+		return nil
+	}
+
+	cmp := 0
+	switch {
+	case r == nil:
+		cmp = -1
+	case l == nil:
+		cmp = 1
+	default:
+		cmp = compareKeys(l.key, r.key)
+	}
+
+	if cmp < 0 {
+		if keepLeft {
+			unmatched, err := withZeroRight(l.rows.Columns, allRows(l.rows), op.RightColumns)
+			if err != nil {
+				return err
+			}
+			op.output.push(unmatched)
+		}
+		op.leftGroup, err = op.left.next()
+		return err
+	}
+	if cmp > 0 {
+		if keepRight {
+			unmatched, err := withZeroLeft(op.LeftColumns, r.rows.Columns, allRows(r.rows))
+			if err != nil {
+				return err
+			}
+			op.output.push(unmatched)
+		}
+		op.rightGroup, err = op.right.next()
+		return err
+	}
+
+	if err := op.joinGroups(l, r); err != nil {
+		return err
+	}
+	if op.leftGroup, err = op.left.next(); err != nil {
+		return err
+	}
+	op.rightGroup, err = op.right.next()
+	return err
+}
+
+// joinGroups joins every pair of rows of groups with equal keys
+func (op *MergeJoinOperator) joinGroups(l, r *keyGroup) error {
+	leftCount, rightCount := int(l.rows.RowCount), int(r.rows.RowCount)
+	leftIdx := make([]int, 0, leftCount*rightCount)
+	rightIdx := make([]int, 0, leftCount*rightCount)
+	for i := 0; i < leftCount; i++ {
+		for j := 0; j < rightCount; j++ {
+			leftIdx = append(leftIdx, i)
+			rightIdx = append(rightIdx, j)
+		}
+	}
+
+	joined, leftIdx, rightIdx, err := joinPairs(l.rows.Columns, r.rows.Columns, leftIdx, rightIdx, op.Join.Condition)
+	if err != nil {
+		return err
+	}
+	op.output.push(joined)
+	if op.Join.Condition == nil {
+		return nil
+	}
+
+	matchedLeft := make([]bool, leftCount)
+	matchedRight := make([]bool, rightCount)
+	for i := range leftIdx {
+		matchedLeft[leftIdx[i]] = true
+		matchedRight[rightIdx[i]] = true
+	}
+	if keepsUnmatchedLeft(op.Join.Type) {
+		unmatched, err := withZeroRight(l.rows.Columns, unmatchedRows(matchedLeft), op.RightColumns)
+		if err != nil {
+			return err
+		}
+		op.output.push(unmatched)
+	}
+	if keepsUnmatchedRight(op.Join.Type) {
+		unmatched, err := withZeroLeft(op.LeftColumns, r.rows.Columns, unmatchedRows(matchedRight))
+		if err != nil {
+			return err
+		}
+		op.output.push(unmatched)
+	}
+	return nil
+}
+
+func allRows(batch *types.ChunkResult) []int {
+	rows := make([]int, batch.RowCount)
+	for i := range rows {
+		rows[i] = i
+	}
+	return rows
+}
+
+// keyGroups splits rows of a child ordered by keys into groups of rows with equal keys
+type keyGroups struct {
+	child   operators.Operator
+	keys    []expr.Expression
+	columns []planner.JoinedColumn
+
+	batch   *types.ChunkResult
+	keyCols []types.ChunkColumn
+	pos     int
+	done    bool
+}
+
+type keyGroup struct {
+	key  []any
+	rows *types.ChunkResult
+}
+
+// next returns the next group, which may span several batches of the child, nil when there are no more rows
+func (g *keyGroups) next() (*keyGroup, error) {
+	var key []any
+	var parts []*types.ChunkResult
+	for {
+		if g.batch == nil || g.pos >= int(g.batch.RowCount) {
+			if g.done {
+				break
+			}
+			batch, err := g.child.NextBatch()
+			if err != nil {
+				return nil, err
+			}
+			if batch == nil {
+				g.done = true
+				break
+			}
+			if g.keyCols, err = evaluateKeys(g.keys, batch, g.columns); err != nil {
+				return nil, err
+			}
+			g.batch, g.pos = batch, 0
+			continue
+		}
+
+		if key == nil {
+			key = make([]any, len(g.keyCols))
+			for i, col := range g.keyCols {
+				key[i] = col.GetValueAny(g.pos)
+			}
+		}
+		end := g.pos
+		for end < int(g.batch.RowCount) && g.compareRow(end, key) == 0 {
+			end++
+		}
+		if end > g.pos {
+			cols, err := operators.SliceColumns(g.batch.Columns, uint64(g.pos), uint64(end-g.pos))
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, newBatch(cols, uint64(end-g.pos)))
+			g.pos = end
+		}
+		if g.pos < int(g.batch.RowCount) {
+			// the next key starts within the batch
+			break
+		}
+	}
+
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	if len(parts) == 1 {
+		return &keyGroup{key: key, rows: parts[0]}, nil
+	}
+	rows, err := operators.MergeChunkResultsWithinOneSchema(parts)
+	if err != nil {
+		return nil, err
+	}
+	return &keyGroup{key: key, rows: rows}, nil
+}
+
+func (g *keyGroups) compareRow(row int, key []any) int {
+	for i, col := range g.keyCols {
+		if c := compareValues(col.GetValueAny(row), key[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareKeys(a, b []any) int {
+	for i := range a {
+		if c := compareValues(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValues orders values the way sorted files and the external sort do
+func compareValues(a, b any) int {
+	switch v := a.(type) {
+	case int64:
+		w := b.(int64)
+		if v < w {
+			return -1
+		}
+		if v > w {
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(v, b.(string))
+	case bool:
+		w := b.(bool)
+		if v == w {
+			return 0
+		}
+		if !v {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
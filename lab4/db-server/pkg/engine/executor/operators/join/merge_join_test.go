@@ -0,0 +1,101 @@
+package join
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"isbd4/pkg/engine/executor/operators/operatortest"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+// keyBatches returns rows with the given ordered keys in batches of batchSize rows, named by alias and position
+func keyBatches(alias string, keys []int64, batchSize int) []*types.ChunkResult {
+	var batches []*types.ChunkResult
+	for start := 0; start < len(keys); start += batchSize {
+		end := min(start+batchSize, len(keys))
+		names := make([]string, end-start)
+		for i := range names {
+			names[i] = fmt.Sprintf("%s%d", alias, start+i)
+		}
+		batches = append(batches, newBatch([]types.ChunkColumn{
+			types.NewInt64Column(alias+".id", keys[start:end]),
+			types.VarcharChunkColumnFromStrings(alias+".name", names),
+		}, uint64(end-start)))
+	}
+	return batches
+}
+
+func joinedRows(t *testing.T, op interface {
+	NextBatch() (*types.ChunkResult, error)
+}) []string {
+	var rows []string
+	for {
+		batch, err := op.NextBatch()
+		if err != nil {
+			t.Fatalf("Join failed: %v", err)
+		}
+		if batch == nil {
+			break
+		}
+		for i := 0; i < int(batch.RowCount); i++ {
+			rows = append(rows, fmt.Sprintf("%v %v %v %v", batch.Columns[0].GetValueAny(i), batch.Columns[1].GetValueAny(i),
+				batch.Columns[2].GetValueAny(i), batch.Columns[3].GetValueAny(i)))
+		}
+	}
+	sort.Strings(rows)
+	return rows
+}
+
+func TestMergeJoinOperator(t *testing.T) {
+	// duplicate keys on both sides, groups spanning batches and keys missing on either side
+	leftKeys := []int64{1, 2, 2, 2, 2, 4, 5, 5, 7, 9, 9, 9}
+	rightKeys := []int64{0, 2, 2, 2, 3, 5, 5, 5, 5, 5, 8, 9}
+
+	columns := func(alias string) []planner.JoinedColumn {
+		return []planner.JoinedColumn{
+			{Name: alias + ".id", TableColumn: "id", Type: types.ChunkColumnTypeInt64},
+			{Name: alias + ".name", TableColumn: "name", Type: types.ChunkColumnTypeVarchar},
+		}
+	}
+	lID := &expr.ColumnRefExpr{ColName: "l.id", ColType: types.ChunkColumnTypeInt64}
+	rID := &expr.ColumnRefExpr{ColName: "r.id", ColType: types.ChunkColumnTypeInt64}
+	lName := &expr.ColumnRefExpr{ColName: "l.name", ColType: types.ChunkColumnTypeVarchar}
+	notFirst, _ := expr.NewBinaryOp(lName, &expr.LiteralExpr{Value: "l1", Type: types.ChunkColumnTypeVarchar}, expr.NotEqual)
+
+	for _, condition := range []expr.Expression{nil, notFirst} {
+		for _, joinType := range []planner.JoinType{planner.InnerJoin, planner.LeftJoin, planner.RightJoin, planner.FullJoin} {
+			t.Run(fmt.Sprintf("%s condition %v", joinType, condition != nil), func(t *testing.T) {
+				join := planner.Join{
+					Type:      joinType,
+					LeftKeys:  []expr.Expression{lID},
+					RightKeys: []expr.Expression{rID},
+					Condition: condition,
+					Strategy:  planner.MergeJoin,
+				}
+				merge := NewMergeJoinOperator(
+					operatortest.NewBatchesOperator(keyBatches("l", leftKeys, 3)...),
+					operatortest.NewBatchesOperator(keyBatches("r", rightKeys, 2)...),
+					join, columns("l"), columns("r"), 4)
+				defer merge.Close()
+				hash := NewHashJoinOperator(
+					operatortest.NewBatchesOperator(keyBatches("l", leftKeys, 3)...),
+					operatortest.NewBatchesOperator(keyBatches("r", rightKeys, 2)...),
+					join, columns("l"), columns("r"), 4, 1<<20, t.TempDir())
+				defer hash.Close()
+
+				got, want := joinedRows(t, merge), joinedRows(t, hash)
+				if len(got) != len(want) {
+					t.Fatalf("Expected %d rows, got %d: %v", len(want), len(got), got)
+				}
+				for i := range want {
+					if got[i] != want[i] {
+						t.Fatalf("Expected row %q, got %q", want[i], got[i])
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package join
+
+import (
+	"hash/fnv"
+	"os"
+	"path/filepath"
+
+	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+// per key of the hash set, besides the key itself
+const keyOverheadBytes = 48
+
+// SemiJoinOperator returns rows of the child with keys equal to keys of some row of the subquery, or for anti joins
+// rows without such a row. Keys of the subquery are collected in a hash set; if it exceeds the memory limit, keys
+// and rows of the child are spilled into partition files by hash of their keys and partitions are filtered one by one,
+// which doesn't keep the order of rows.
+type SemiJoinOperator struct {
+	Child            operators.Operator
+	Subquery         operators.Operator
+	SemiJoin         planner.SemiJoin
+	ChunkSize        uint64
+	MemoryLimitBytes uint64
+
+	baseDir  string
+	spillDir string
+
+	built    bool
+	keys     map[string]struct{}
+	keyBytes uint64
+	input    operators.Operator // child or reader of the current partition
+	pending  []int              // partitions to filter
+	keyParts *rowPartitions
+	rowParts *rowPartitions
+	columns  []planner.JoinedColumn // columns of spilled rows
+}
+
+func NewSemiJoinOperator(child, subquery operators.Operator, semiJoin planner.SemiJoin, chunkSize uint64,
+	memoryLimitBytes uint64, baseDir string) *SemiJoinOperator {
+	return &SemiJoinOperator{
+		Child:            child,
+		Subquery:         subquery,
+		SemiJoin:         semiJoin,
+		ChunkSize:        chunkSize,
+		MemoryLimitBytes: memoryLimitBytes,
+		baseDir:          baseDir,
+		keys:             make(map[string]struct{}),
+	}
+}
+
+func (op *SemiJoinOperator) Close() {
+	if op.Child != nil {
+		op.Child.Close()
+		op.Child = nil
+	}
+	if op.Subquery != nil {
+		op.Subquery.Close()
+		op.Subquery = nil
+	}
+	if r, ok := op.input.(*rowReader); ok {
+		r.Close()
+	}
+	op.input = nil
+	if op.keyParts != nil {
+		op.keyParts.close()
+		op.rowParts.close()
+	}
+	op.keys = nil
+	if op.spillDir != "" {
+		os.RemoveAll(op.spillDir)
+		op.spillDir = ""
+	}
+}
+
+func (op *SemiJoinOperator) NextBatch() (*types.ChunkResult, error) {
+	if !op.built {
+		op.built = true
+		if err := op.buildKeys(); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		batch, err := op.input.NextBatch()
+		if err != nil {
+			return nil, err
+		}
+		if batch == nil {
+			if len(op.pending) == 0 {
+				return nil, nil
+			}
+			if err := op.loadPartition(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		filtered, err := op.filter(batch)
+		if err != nil {
+			return nil, err
+		}
+		if filtered.RowCount > 0 {
+			return filtered, nil
+		}
+	}
+}
+
+// buildKeys collects keys of the subquery rows, spilling them and the child rows if they don't fit in memory
+func (op *SemiJoinOperator) buildKeys() error {
+	for {
+		batch, err := op.Subquery.NextBatch()
+		if err != nil {
+			return err
+		}
+		if batch == nil {
+			break
+		}
+		keys := op.subqueryKeys(batch)
+		if op.keyParts != nil {
+			if err := op.spillKeys(keys); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, key := range keys {
+			if _, ok := op.keys[key]; !ok {
+				op.keys[key] = struct{}{}
+				op.keyBytes += uint64(len(key)) + keyOverheadBytes
+			}
+		}
+		if len(op.SemiJoin.Keys) == 0 && len(op.keys) > 0 {
+			// without keys a single row of the subquery decides
+			break
+		}
+		if op.MemoryLimitBytes > 0 && op.keyBytes > op.MemoryLimitBytes {
+			if err := op.startSpilling(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if op.keyParts == nil {
+		op.input = op.Child
+		return nil
+	}
+	return op.spillRows()
+}
+
+// subqueryKeys returns encoded keys of the subquery rows
+func (op *SemiJoinOperator) subqueryKeys(batch *types.ChunkResult) []string {
+	keyCols := make([]types.ChunkColumn, len(op.SemiJoin.SubqueryKeys))
+	for i, idx := range op.SemiJoin.SubqueryKeys {
+		if batch.SelectIdx != nil {
+			idx = batch.SelectIdx[idx]
+		}
+		keyCols[i] = batch.Columns[idx]
+	}
+	keys := make([]string, batch.RowCount)
+	var buf []byte
+	for row := range keys {
+		buf = encodeKey(buf[:0], keyCols, row)
+		keys[row] = string(buf)
+	}
+	return keys
+}
+
+func (op *SemiJoinOperator) startSpilling() error {
+	parent := filepath.Join(filepath.Dir(op.baseDir), ".join_spill")
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp(parent, "semi_join_")
+	if err != nil {
+		return err
+	}
+	op.spillDir = dir
+	op.keyParts = newRowPartitions(dir, "keys", partitionCount)
+	op.rowParts = newRowPartitions(dir, "rows", partitionCount)
+
+	keys := make([]string, 0, len(op.keys))
+	for key := range op.keys {
+		keys = append(keys, key)
+	}
+	op.keys = make(map[string]struct{})
+	return op.spillKeys(keys)
+}
+
+func (op *SemiJoinOperator) spillKeys(keys []string) error {
+	partitionOf := make([]int, len(keys))
+	for i, key := range keys {
+		partitionOf[i] = partitionOfKey(key)
+	}
+	batch := newBatch([]types.ChunkColumn{types.VarcharChunkColumnFromStrings("key", keys)}, uint64(len(keys)))
+	return op.keyParts.write(batch, partitionOf)
+}
+
+// spillRows partitions all rows of the child by their keys
+func (op *SemiJoinOperator) spillRows() error {
+	for {
+		batch, err := op.Child.NextBatch()
+		if err != nil {
+			return err
+		}
+		if batch == nil {
+			break
+		}
+		if op.columns == nil {
+			for _, col := range batch.Columns {
+				op.columns = append(op.columns, planner.JoinedColumn{Name: col.GetName(), Type: col.GetType()})
+			}
+		}
+		keys, err := op.childKeys(batch)
+		if err != nil {
+			return err
+		}
+		partitionOf := make([]int, len(keys))
+		for i, key := range keys {
+			partitionOf[i] = partitionOfKey(key)
+		}
+		if err := op.rowParts.write(batch, partitionOf); err != nil {
+			return err
+		}
+	}
+	if err := op.keyParts.close(); err != nil {
+		return err
+	}
+	if err := op.rowParts.close(); err != nil {
+		return err
+	}
+
+	for i := 0; i < partitionCount; i++ {
+		op.pending = append(op.pending, i)
+	}
+	return op.loadPartition()
+}
+
+// loadPartition collects keys of the next partition and starts reading its rows
+func (op *SemiJoinOperator) loadPartition() error {
+	part := op.pending[0]
+	op.pending = op.pending[1:]
+
+	keyColumns := []planner.JoinedColumn{{Name: "key", Type: types.ChunkColumnTypeVarchar}}
+	reader, err := openRowReader(op.keyParts.files[part], keyColumns, op.ChunkSize)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	op.keys = make(map[string]struct{})
+	for {
+		batch, err := reader.NextBatch()
+		if err != nil {
+			return err
+		}
+		if batch == nil {
+			break
+		}
+		for row := 0; row < int(batch.RowCount); row++ {
+			op.keys[batch.Columns[0].GetValueAny(row).(string)] = struct{}{}
+		}
+	}
+	os.Remove(op.keyParts.files[part])
+
+	if r, ok := op.input.(*rowReader); ok {
+		r.Close()
+	}
+	op.input, err = openRowReader(op.rowParts.files[part], op.columns, op.ChunkSize)
+	return err
+}
+
+// childKeys returns encoded keys of the child rows
+func (op *SemiJoinOperator) childKeys(batch *types.ChunkResult) ([]string, error) {
+	colMapping := make(map[string]int, len(batch.Columns))
+	for i, col := range batch.Columns {
+		colMapping[col.GetName()] = i
+	}
+	keyCols := make([]types.ChunkColumn, len(op.SemiJoin.Keys))
+	for i, key := range op.SemiJoin.Keys {
+		col, err := key.Evaluate(batch, colMapping)
+		if err != nil {
+			return nil, err
+		}
+		keyCols[i] = col
+	}
+
+	keys := make([]string, batch.RowCount)
+	var buf []byte
+	for row := range keys {
+		buf = encodeKey(buf[:0], keyCols, row)
+		keys[row] = string(buf)
+	}
+	return keys, nil
+}
+
+// filter keeps rows of the batch with (or for anti joins without) a matching key
+func (op *SemiJoinOperator) filter(batch *types.ChunkResult) (*types.ChunkResult, error) {
+	keys, err := op.childKeys(batch)
+	if err != nil {
+		return nil, err
+	}
+	var keep []int
+	for row, key := range keys {
+		if _, found := op.keys[key]; found != op.SemiJoin.Anti {
+			keep = append(keep, row)
+		}
+	}
+	if len(keep) == len(keys) {
+		return batch, nil
+	}
+
+	columns, err := operators.FilterBatchColumns(batch.Columns, keep)
+	if err != nil {
+		return nil, err
+	}
+	return newBatch(columns, uint64(len(keep))), nil
+}
+
+func partitionOfKey(key string) int {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int(h.Sum64() % partitionCount)
+}
@@ -0,0 +1,117 @@
+package join
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"isbd4/pkg/engine/executor/operators/operatortest"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+// subqueryBatches returns batches of a single selected column with multiples of 3 below 1000, each twice
+func subqueryBatches() []*types.ChunkResult {
+	var batches []*types.ChunkResult
+	for i := 0; i < 2; i++ {
+		var values []int64
+		for v := int64(0); v < 1000; v += 3 {
+			values = append(values, v)
+			if len(values) == 100 {
+				batches = append(batches, newBatch([]types.ChunkColumn{types.NewInt64Column("col_0", values)}, 100))
+				values = nil
+			}
+		}
+		batches = append(batches, newBatch([]types.ChunkColumn{types.NewInt64Column("col_0", values)}, uint64(len(values))))
+	}
+	return batches
+}
+
+func semiJoinedIDs(t *testing.T, op *SemiJoinOperator) map[int64]bool {
+	ids := make(map[int64]bool)
+	for {
+		batch, err := op.NextBatch()
+		if err != nil {
+			t.Fatalf("Semi join failed: %v", err)
+		}
+		if batch == nil {
+			return ids
+		}
+		for i := 0; i < int(batch.RowCount); i++ {
+			id := batch.Columns[0].GetValueAny(i).(int64)
+			if name := batch.Columns[1].GetValueAny(i).(string); name != fmt.Sprintf("l%d", id) {
+				t.Fatalf("Name %s doesn't match id %d", name, id)
+			}
+			if ids[id] {
+				t.Fatalf("Row with id %d returned twice", id)
+			}
+			ids[id] = true
+		}
+	}
+}
+
+func TestSemiJoinOperator(t *testing.T) {
+	lID := &expr.ColumnRefExpr{ColName: "l.id", ColType: types.ChunkColumnTypeInt64}
+
+	for _, anti := range []bool{false, true} {
+		for _, memoryLimit := range []uint64{1 << 20, 1024} {
+			t.Run(fmt.Sprintf("anti %v limit %d", anti, memoryLimit), func(t *testing.T) {
+				baseDir := filepath.Join(t.TempDir(), "tables")
+				semiJoin := planner.SemiJoin{Anti: anti, Keys: []expr.Expression{lID}, SubqueryKeys: []int{0}}
+				op := NewSemiJoinOperator(
+					operatortest.NewBatchesOperator(idBatches("l", 0, 600, 128)...),
+					operatortest.NewBatchesOperator(subqueryBatches()...),
+					semiJoin, 100, memoryLimit, baseDir)
+
+				ids := semiJoinedIDs(t, op)
+				spilled := op.spillDir != ""
+				op.Close()
+				if spilled != (memoryLimit < 1<<20) {
+					t.Errorf("Expected spilling only with the low memory limit, spilled: %v", spilled)
+				}
+				if entries, _ := os.ReadDir(filepath.Join(filepath.Dir(baseDir), ".join_spill")); len(entries) != 0 {
+					t.Errorf("Expected spill files to be removed, got %d entries", len(entries))
+				}
+
+				expected := 0
+				for id := int64(0); id < 600; id++ {
+					if (id%3 == 0) != anti {
+						expected++
+						if !ids[id] {
+							t.Fatalf("Expected row with id %d", id)
+						}
+					}
+				}
+				if len(ids) != expected {
+					t.Errorf("Expected %d rows, got %d", expected, len(ids))
+				}
+			})
+		}
+	}
+}
+
+func TestSemiJoinOperator_WithoutKeys(t *testing.T) {
+	for _, anti := range []bool{false, true} {
+		for _, empty := range []bool{false, true} {
+			t.Run(fmt.Sprintf("anti %v empty %v", anti, empty), func(t *testing.T) {
+				subquery := operatortest.NewBatchesOperator()
+				if !empty {
+					subquery = operatortest.NewBatchesOperator(subqueryBatches()...)
+				}
+				op := NewSemiJoinOperator(operatortest.NewBatchesOperator(idBatches("l", 0, 300, 128)...), subquery,
+					planner.SemiJoin{Anti: anti}, 100, 1<<20, t.TempDir())
+				defer op.Close()
+
+				got, want := len(semiJoinedIDs(t, op)), 0
+				if empty == anti {
+					want = 300
+				}
+				if got != want {
+					t.Errorf("Expected %d rows, got %d", want, got)
+				}
+			})
+		}
+	}
+}
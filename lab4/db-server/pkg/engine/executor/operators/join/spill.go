@@ -0,0 +1,186 @@
+package join
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+func init() {
+	gob.Register(int64(0))
+	gob.Register("")
+	gob.Register(bool(false))
+}
+
+type spilledRow struct {
+	Values []any
+}
+
+// rowPartitions spreads spilled rows of one side of the join into partition files
+type rowPartitions struct {
+	files   []string
+	writers []*os.File
+	encoder []*gob.Encoder
+}
+
+func newRowPartitions(dir string, side string, count int) *rowPartitions {
+	p := &rowPartitions{
+		files:   make([]string, count),
+		writers: make([]*os.File, count),
+		encoder: make([]*gob.Encoder, count),
+	}
+	for i := range p.files {
+		p.files[i] = filepath.Join(dir, fmt.Sprintf("%s_%d.gob", side, i))
+	}
+	return p
+}
+
+// write appends rows of the batch to partitions given for every row
+func (p *rowPartitions) write(batch *types.ChunkResult, partitionOf []int) error {
+	for row, part := range partitionOf {
+		if p.writers[part] == nil {
+			file, err := os.Create(p.files[part])
+			if err != nil {
+				return err
+			}
+			p.writers[part] = file
+			p.encoder[part] = gob.NewEncoder(file)
+		}
+		values := make([]any, len(batch.Columns))
+		for i, col := range batch.Columns {
+			values[i] = col.GetValueAny(row)
+		}
+		if err := p.encoder[part].Encode(&spilledRow{Values: values}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *rowPartitions) close() error {
+	var firstErr error
+	for i, w := range p.writers {
+		if w == nil {
+			continue
+		}
+		if err := w.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		p.writers[i] = nil
+	}
+	return firstErr
+}
+
+// rowReader returns rows of a partition file in batches with the given columns, a missing file has no rows
+type rowReader struct {
+	file      *os.File
+	decoder   *gob.Decoder
+	columns   []planner.JoinedColumn
+	chunkSize uint64
+}
+
+func openRowReader(path string, columns []planner.JoinedColumn, chunkSize uint64) (*rowReader, error) {
+	r := &rowReader{columns: columns, chunkSize: chunkSize}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.file = file
+	r.decoder = gob.NewDecoder(file)
+	return r, nil
+}
+
+func (r *rowReader) Close() {
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+}
+
+func (r *rowReader) NextBatch() (*types.ChunkResult, error) {
+	if r.file == nil {
+		return nil, nil
+	}
+
+	var rows [][]any
+	for uint64(len(rows)) < r.chunkSize {
+		var row spilledRow
+		if err := r.decoder.Decode(&row); err != nil {
+			if err == io.EOF {
+				r.Close()
+				break
+			}
+			return nil, fmt.Errorf("failed to read spilled rows: %w", err)
+		}
+		rows = append(rows, row.Values)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns := make([]types.ChunkColumn, len(r.columns))
+	for i, col := range r.columns {
+		columns[i] = buildColumn(col, len(rows), func(row int) any { return rows[row][i] })
+	}
+	return newBatch(columns, uint64(len(rows))), nil
+}
+
+func buildColumn(col planner.JoinedColumn, rowCount int, value func(row int) any) types.ChunkColumn {
+	switch col.Type {
+	case types.ChunkColumnTypeInt64:
+		values := make([]int64, rowCount)
+		for i := range values {
+			values[i] = value(i).(int64)
+		}
+		return types.NewInt64Column(col.Name, values)
+	case types.ChunkColumnTypeVarchar:
+		values := make([]string, rowCount)
+		for i := range values {
+			values[i] = value(i).(string)
+		}
+		return types.VarcharChunkColumnFromStrings(col.Name, values)
+	default:
+		values := make([]bool, rowCount)
+		for i := range values {
+			values[i] = value(i).(bool)
+		}
+		return types.NewBooleanColumn(col.Name, values)
+	}
+}
+
+// zeroColumns returns columns of the given number of zero values, used for rows without a match in outer joins
+func zeroColumns(columns []planner.JoinedColumn, rowCount int) []types.ChunkColumn {
+	res := make([]types.ChunkColumn, len(columns))
+	for i, col := range columns {
+		switch col.Type {
+		case types.ChunkColumnTypeInt64:
+			res[i] = types.NewInt64Column(col.Name, make([]int64, rowCount))
+		case types.ChunkColumnTypeVarchar:
+			res[i] = types.VarcharChunkColumnFromStrings(col.Name, make([]string, rowCount))
+		default:
+			res[i] = types.NewBooleanColumn(col.Name, make([]bool, rowCount))
+		}
+	}
+	return res
+}
+
+func newBatch(columns []types.ChunkColumn, rowCount uint64) *types.ChunkResult {
+	selectIdx := make([]int, len(columns))
+	for i := range selectIdx {
+		selectIdx[i] = i
+	}
+	return &types.ChunkResult{
+		RowCount:  rowCount,
+		Columns:   columns,
+		SelectIdx: selectIdx,
+		FilterIdx: -1,
+	}
+}
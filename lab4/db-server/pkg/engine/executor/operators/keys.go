@@ -0,0 +1,24 @@
+package operators
+
+import "encoding/binary"
+
+// AppendKeyValue appends an encoding of the value used as a hash key, different values (also of different types)
+// are encoded differently, so encodings of several values appended one after another are unique as well
+func AppendKeyValue(buf []byte, value any) []byte {
+	switch v := value.(type) {
+	case int64:
+		buf = append(buf, 'i')
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(v))
+	case string:
+		buf = append(buf, 's')
+		buf = binary.AppendUvarint(buf, uint64(len(v)))
+		buf = append(buf, v...)
+	case bool:
+		if v {
+			buf = append(buf, 't')
+		} else {
+			buf = append(buf, 'f')
+		}
+	}
+	return buf
+}
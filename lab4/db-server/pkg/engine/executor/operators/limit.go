@@ -4,18 +4,22 @@ import (
 	"isbd4/pkg/engine/types"
 )
 
+// LimitOperator skips the first Offset rows of the child and returns at most Limit of the following ones.
+// Batches lying entirely within the offset are dropped without copying their rows.
 type LimitOperator struct {
-	Child Operator
-	Limit uint64
+	Child  Operator
+	Offset uint64
+	Limit  uint64
 
-	count uint64
+	skipped uint64
+	count   uint64
 }
 
-func NewLimitOperator(child Operator, limit uint64) *LimitOperator {
+func NewLimitOperator(child Operator, offset uint64, limit uint64) *LimitOperator {
 	return &LimitOperator{
-		Child: child,
-		Limit: limit,
-		count: 0,
+		Child:  child,
+		Offset: offset,
+		Limit:  limit,
 	}
 }
 
@@ -26,36 +30,46 @@ func (op *LimitOperator) Close() {
 }
 
 func (op *LimitOperator) NextBatch() (*types.ChunkResult, error) {
-	if op.count >= op.Limit {
-		op.Close()
-		return nil, nil
-	}
+	for {
+		if op.count >= op.Limit {
+			op.Close()
+			return nil, nil
+		}
 
-	batch, err := op.Child.NextBatch()
-	if err != nil {
-		return nil, err
-	}
-	if batch == nil {
-		return nil, nil
-	}
+		batch, err := op.Child.NextBatch()
+		if err != nil {
+			return nil, err
+		}
+		if batch == nil {
+			return nil, nil
+		}
 
-	remaining := op.Limit - op.count
-	if batch.RowCount <= remaining {
-		op.count += batch.RowCount
-		return batch, nil
-	}
+		start := uint64(0)
+		if op.skipped < op.Offset {
+			toSkip := op.Offset - op.skipped
+			if batch.RowCount <= toSkip {
+				op.skipped += batch.RowCount
+				continue
+			}
+			op.skipped = op.Offset
+			start = toSkip
+		}
 
-	slicedCols, err := SliceColumns(batch.Columns, 0, remaining)
-	if err != nil {
-		return nil, err
-	}
-
-	op.count += remaining
+		count := min(batch.RowCount-start, op.Limit-op.count)
+		op.count += count
+		if start == 0 && count == batch.RowCount {
+			return batch, nil
+		}
 
-	return &types.ChunkResult{
-		RowCount:  remaining,
-		Columns:   slicedCols,
-		SelectIdx: batch.SelectIdx,
-		FilterIdx: batch.FilterIdx,
-	}, nil
+		slicedCols, err := SliceColumns(batch.Columns, start, count)
+		if err != nil {
+			return nil, err
+		}
+		return &types.ChunkResult{
+			RowCount:  count,
+			Columns:   slicedCols,
+			SelectIdx: batch.SelectIdx,
+			FilterIdx: batch.FilterIdx,
+		}, nil
+	}
 }
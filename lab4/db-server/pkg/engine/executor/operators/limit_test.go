@@ -0,0 +1,64 @@
+package operators
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"isbd4/pkg/engine/executor/operators/operatortest"
+	"isbd4/pkg/engine/types"
+)
+
+func TestLimitOperator(t *testing.T) {
+	// 3 batches of 10 rows
+	var batches []*types.ChunkResult
+	for start := 0; start < 30; start += 10 {
+		ids := make([]int64, 10)
+		for i := range ids {
+			ids[i] = int64(start + i)
+		}
+		batches = append(batches, &types.ChunkResult{
+			RowCount:  10,
+			Columns:   []types.ChunkColumn{types.NewInt64Column("id", ids)},
+			SelectIdx: []int{0},
+			FilterIdx: -1,
+		})
+	}
+
+	tests := []struct {
+		offset, limit uint64
+		from, to      int64
+	}{
+		{0, 5, 0, 5},
+		{0, 100, 0, 30},
+		{10, 10, 10, 20},
+		{15, 10, 15, 25},
+		{25, 100, 25, 30},
+		{30, 5, 0, 0},
+		{5, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("offset %d limit %d", tt.offset, tt.limit), func(t *testing.T) {
+			op := NewLimitOperator(operatortest.NewBatchesOperator(batches...), tt.offset, tt.limit)
+			var got []int64
+			for {
+				batch, err := op.NextBatch()
+				if err != nil {
+					t.Fatalf("Limit failed: %v", err)
+				}
+				if batch == nil {
+					break
+				}
+				got = append(got, batch.Columns[0].(*types.Int64ChunkColumn).Values...)
+			}
+
+			var want []int64
+			for id := tt.from; id < tt.to; id++ {
+				want = append(want, id)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("Expected rows %v, got %v", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package operators
+
+import (
+	"fmt"
+
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/tomy_file"
+)
+
+// MemoryReaderOperator returns the given columns of a table held in memory in chunks, e.g. of a system table
+type MemoryReaderOperator struct {
+	Table         *tomy_file.ColumnarTable
+	ColumnsToRead []string
+	ChunkSize     uint64
+
+	columns []types.ChunkColumn
+	offset  uint64
+}
+
+func NewMemoryReaderOperator(table *tomy_file.ColumnarTable, colNames []string, chunkSize uint64) *MemoryReaderOperator {
+	return &MemoryReaderOperator{
+		Table:         table,
+		ColumnsToRead: colNames,
+		ChunkSize:     chunkSize,
+	}
+}
+
+func (r *MemoryReaderOperator) Close() {
+	r.columns = nil
+}
+
+func (r *MemoryReaderOperator) NextBatch() (*types.ChunkResult, error) {
+	if r.columns == nil {
+		if err := r.init(); err != nil {
+			return nil, err
+		}
+	}
+	if r.offset >= r.Table.NumRows {
+		return nil, nil
+	}
+
+	count := min(r.Table.NumRows-r.offset, r.ChunkSize)
+	positions := make([]int, count)
+	for i := range positions {
+		positions[i] = int(r.offset) + i
+	}
+	columns, err := FilterBatchColumns(r.columns, positions)
+	if err != nil {
+		return nil, err
+	}
+	r.offset += count
+
+	return &types.ChunkResult{
+		RowCount:  count,
+		Columns:   columns,
+		SelectIdx: nil,
+		FilterIdx: -1,
+	}, nil
+}
+
+func (r *MemoryReaderOperator) init() error {
+	r.columns = make([]types.ChunkColumn, 0, len(r.ColumnsToRead))
+	for _, name := range r.ColumnsToRead {
+		var col tomy_file.AnyColumn
+		for _, c := range r.Table.Columns {
+			if c.GetName() == name {
+				col = c
+			}
+		}
+		if col == nil {
+			return fmt.Errorf("column %s not found", name)
+		}
+		chunkCol, err := types.ChunkColumnFromTomy(col)
+		if err != nil {
+			return fmt.Errorf("failed to convert column %s: %w", name, err)
+		}
+		r.columns = append(r.columns, chunkCol)
+	}
+	return nil
+}
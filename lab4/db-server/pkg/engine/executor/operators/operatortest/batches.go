@@ -0,0 +1,28 @@
+// Package operatortest provides helpers for tests of operators
+package operatortest
+
+import (
+	"isbd4/pkg/engine/types"
+)
+
+// BatchesOperator returns the given batches one after another, e.g. as the child of the tested operator
+type BatchesOperator struct {
+	batches []*types.ChunkResult
+}
+
+func NewBatchesOperator(batches ...*types.ChunkResult) *BatchesOperator {
+	return &BatchesOperator{batches: batches}
+}
+
+func (op *BatchesOperator) Close() {
+	op.batches = nil
+}
+
+func (op *BatchesOperator) NextBatch() (*types.ChunkResult, error) {
+	if len(op.batches) == 0 {
+		return nil, nil
+	}
+	batch := op.batches[0]
+	op.batches = op.batches[1:]
+	return batch, nil
+}
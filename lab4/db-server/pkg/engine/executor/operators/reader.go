@@ -14,26 +14,39 @@ type ReaderOperator struct {
 	TableReader   *tomy_file.BatchReader
 	ChunkSize     uint64
 	ColumnsToRead []string
+	Deletions     map[string]*metadata.DeletionVector // rows to skip, by file path
 }
 
 func NewReaderOperator(snapshot *metadata.MetastoreSnapshot, queryDef *planner.SelectQueryDefinition, chunkSize uint64) *ReaderOperator {
-	colNames := extractUsedColumns(queryDef)
-
-	filePaths := metadata.FileNames(snapshot.Files)
-	reader := tomy_file.NewBatchReader(filePaths, colNames)
+	return NewFileReaderOperator(metadata.FileNames(snapshot.Files), ExtractUsedColumns(queryDef), snapshot.Deletions, chunkSize)
+}
 
+// NewFileReaderOperator reads the given columns of the files one after another, skipping deleted rows
+func NewFileReaderOperator(filePaths []string, colNames []string, deletions map[string]*metadata.DeletionVector, chunkSize uint64) *ReaderOperator {
 	return &ReaderOperator{
-		TableReader:   reader,
+		TableReader:   tomy_file.NewBatchReader(filePaths, colNames),
 		ChunkSize:     chunkSize,
 		ColumnsToRead: colNames,
+		Deletions:     deletions,
 	}
 }
 
-func extractUsedColumns(queryDef *planner.SelectQueryDefinition) []string {
+// ExtractUsedColumns returns names of table columns read by the query, in the order in which the reader returns them
+func ExtractUsedColumns(queryDef *planner.SelectQueryDefinition) []string {
 	allExprs := queryDef.SelectExpr
+	if queryDef.Aggregation != nil {
+		// select expressions refer to output columns of the aggregation
+		allExprs = queryDef.Aggregation.InputExprs()
+	} else if len(queryDef.Windows) > 0 {
+		// and to output columns of the windows, the first one reads the passed columns
+		allExprs = queryDef.Windows[0].InputExprs()
+	}
 	if queryDef.WhereExpr != nil {
 		allExprs = append(allExprs, queryDef.WhereExpr)
 	}
+	for _, semiJoin := range queryDef.SemiJoins {
+		allExprs = append(allExprs, semiJoin.Keys...)
+	}
 	return expr.GetUsedColumnsFromExpressions(allExprs)
 }
 
@@ -68,8 +81,28 @@ func (r *ReaderOperator) NextBatch() (*types.ChunkResult, error) {
 		chunkColumns[i] = chunkCol
 	}
 
+	rowCount := batch.NumRows
+	file, start := r.TableReader.BatchPosition()
+	if dv := r.Deletions[file]; dv != nil {
+		passIndices := make([]int, 0, batch.NumRows)
+		for i := uint64(0); i < batch.NumRows; i++ {
+			if !dv.Contains(start + i) {
+				passIndices = append(passIndices, int(i))
+			}
+		}
+		if len(passIndices) == 0 {
+			return r.NextBatch()
+		}
+		if len(passIndices) < int(batch.NumRows) {
+			if chunkColumns, err = FilterBatchColumns(chunkColumns, passIndices); err != nil {
+				return nil, err
+			}
+			rowCount = uint64(len(passIndices))
+		}
+	}
+
 	return &types.ChunkResult{
-		RowCount:  batch.NumRows,
+		RowCount:  rowCount,
 		Columns:   chunkColumns,
 		SelectIdx: nil,
 		FilterIdx: -1,
@@ -0,0 +1,74 @@
+package operators
+
+import (
+	"fmt"
+
+	"isbd4/pkg/engine/types"
+)
+
+// RenameOperator returns the given columns of the child batches in the given order under new names,
+// e.g. columns of a joined table qualified by its alias
+type RenameOperator struct {
+	Child Operator
+	From  []string
+	To    []string
+}
+
+func NewRenameOperator(child Operator, from []string, to []string) *RenameOperator {
+	return &RenameOperator{
+		Child: child,
+		From:  from,
+		To:    to,
+	}
+}
+
+func (op *RenameOperator) Close() {
+	if op.Child != nil {
+		op.Child.Close()
+		op.Child = nil
+	}
+}
+
+func (op *RenameOperator) NextBatch() (*types.ChunkResult, error) {
+	batch, err := op.Child.NextBatch()
+	if err != nil || batch == nil {
+		return nil, err
+	}
+
+	colMapping := make(map[string]int, len(batch.Columns))
+	for i, c := range batch.Columns {
+		colMapping[c.GetName()] = i
+	}
+
+	columns := make([]types.ChunkColumn, len(op.From))
+	selectIdx := make([]int, len(op.From))
+	for i, name := range op.From {
+		idx, ok := colMapping[name]
+		if !ok {
+			return nil, fmt.Errorf("column %s not found in batch", name)
+		}
+		columns[i] = RenameColumn(batch.Columns[idx], op.To[i])
+		selectIdx[i] = i
+	}
+
+	return &types.ChunkResult{
+		RowCount:  batch.RowCount,
+		Columns:   columns,
+		SelectIdx: selectIdx,
+		FilterIdx: -1,
+	}, nil
+}
+
+// RenameColumn returns the column under a new name, sharing its values
+func RenameColumn(col types.ChunkColumn, name string) types.ChunkColumn {
+	switch c := col.(type) {
+	case *types.Int64ChunkColumn:
+		return &types.Int64ChunkColumn{Name: name, Values: c.Values}
+	case *types.BooleanChunkColumn:
+		return &types.BooleanChunkColumn{Name: name, Values: c.Values}
+	case *types.VarcharChunkColumn:
+		return &types.VarcharChunkColumn{Name: name, Offsets: c.Offsets, Data: c.Data}
+	default:
+		panic(fmt.Sprintf("unsupported column type: %T", col))
+	}
+}
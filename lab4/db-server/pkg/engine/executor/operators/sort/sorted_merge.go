@@ -0,0 +1,200 @@
+package sort
+
+import (
+	"container/heap"
+	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+// SortBatch sorts rows of a single in-memory batch
+func SortBatch(batch *types.ChunkResult, sortFields []planner.OrderByColumnReference) (*types.ChunkResult, error) {
+	return newBatchSorter(batch, sortFields).sort()
+}
+
+// SortedMergeOperator merges children which already return rows sorted by SortFields,
+// e.g. readers of files of a clustered table. Only one batch per child is kept in memory.
+type SortedMergeOperator struct {
+	Children   []operators.Operator
+	SortFields []planner.OrderByColumnReference
+	ChunkSize  uint64
+
+	initialized bool
+	inputs      *inputHeap
+}
+
+func NewSortedMergeOperator(children []operators.Operator, sortFields []planner.OrderByColumnReference, chunkSize uint64) *SortedMergeOperator {
+	return &SortedMergeOperator{
+		Children:   children,
+		SortFields: sortFields,
+		ChunkSize:  chunkSize,
+	}
+}
+
+func (op *SortedMergeOperator) Close() {
+	for _, child := range op.Children {
+		child.Close()
+	}
+	op.Children = nil
+	op.inputs = nil
+}
+
+func (op *SortedMergeOperator) NextBatch() (*types.ChunkResult, error) {
+	if !op.initialized {
+		if err := op.init(); err != nil {
+			return nil, err
+		}
+		op.initialized = true
+	}
+	if op.inputs.Len() == 0 {
+		return nil, nil
+	}
+
+	first := op.inputs.inputs[0].batch
+	outputCols := make([]types.ChunkColumn, len(first.Columns))
+	for i, col := range first.Columns {
+		outputCols[i] = types.CloneEmpty(col, int(op.ChunkSize), 0)
+	}
+	selectIdx, filterIdx := first.SelectIdx, first.FilterIdx
+
+	var rowCount uint64
+	for rowCount < op.ChunkSize && op.inputs.Len() > 0 {
+		in := op.inputs.inputs[0]
+		for i, col := range in.batch.Columns {
+			appendAnyToColumn(outputCols[i], col.GetValueAny(in.row))
+		}
+		rowCount++
+
+		in.row++
+		if in.row < int(in.batch.RowCount) {
+			heap.Fix(op.inputs, 0)
+			continue
+		}
+
+		hasNext, err := in.next()
+		if err != nil {
+			return nil, err
+		}
+		if hasNext {
+			heap.Fix(op.inputs, 0)
+		} else {
+			heap.Pop(op.inputs)
+		}
+	}
+
+	return &types.ChunkResult{
+		RowCount:  rowCount,
+		Columns:   outputCols,
+		SelectIdx: selectIdx,
+		FilterIdx: filterIdx,
+	}, nil
+}
+
+func (op *SortedMergeOperator) init() error {
+	op.inputs = &inputHeap{sortFields: op.SortFields}
+	for _, child := range op.Children {
+		in := &mergeInput{child: child}
+		hasNext, err := in.next()
+		if err != nil {
+			return err
+		}
+		if hasNext {
+			op.inputs.inputs = append(op.inputs.inputs, in)
+		}
+	}
+	heap.Init(op.inputs)
+	return nil
+}
+
+type mergeInput struct {
+	child operators.Operator
+	batch *types.ChunkResult
+	row   int
+}
+
+// next loads the next non-empty batch of the child, returns false when the child is exhausted
+func (in *mergeInput) next() (bool, error) {
+	for {
+		batch, err := in.child.NextBatch()
+		if err != nil {
+			return false, err
+		}
+		if batch == nil {
+			return false, nil
+		}
+		if batch.RowCount > 0 {
+			in.batch = batch
+			in.row = 0
+			return true, nil
+		}
+	}
+}
+
+type inputHeap struct {
+	inputs     []*mergeInput
+	sortFields []planner.OrderByColumnReference
+}
+
+func (h *inputHeap) Len() int      { return len(h.inputs) }
+func (h *inputHeap) Swap(i, j int) { h.inputs[i], h.inputs[j] = h.inputs[j], h.inputs[i] }
+func (h *inputHeap) Push(x any)    { h.inputs = append(h.inputs, x.(*mergeInput)) }
+
+func (h *inputHeap) Pop() any {
+	old := h.inputs
+	n := len(old)
+	in := old[n-1]
+	h.inputs = old[:n-1]
+	return in
+}
+
+func (h *inputHeap) Less(i, j int) bool {
+	a, b := h.inputs[i], h.inputs[j]
+	for _, sf := range h.sortFields {
+		res := compareAny(a.batch.Columns[sf.Index].GetValueAny(a.row), b.batch.Columns[sf.Index].GetValueAny(b.row))
+		if res == 0 {
+			continue
+		}
+		if sf.Ascending {
+			return res < 0
+		}
+		return res > 0
+	}
+	return false
+}
+
+// SortFieldsForKeys maps sort keys of a table to indices of the columns returned by a reader of its files.
+// Readers return the read columns in the order of the table definition, empty readColumns means all of them.
+// Returns false when some key column is not read.
+func SortFieldsForKeys(tableColumns []metadata.ColumnDef, readColumns []string, keys []metadata.SortKey) ([]planner.OrderByColumnReference, bool) {
+	isRead := make(map[string]bool, len(readColumns))
+	for _, name := range readColumns {
+		isRead[name] = true
+	}
+	readIdx := make(map[string]int)
+	for _, col := range tableColumns {
+		if isRead[col.Name] || len(readColumns) == 0 {
+			readIdx[col.Name] = len(readIdx)
+		}
+	}
+
+	fields := make([]planner.OrderByColumnReference, len(keys))
+	for i, key := range keys {
+		idx, ok := readIdx[key.Column]
+		if !ok {
+			return nil, false
+		}
+		fields[i] = planner.OrderByColumnReference{Index: idx, Ascending: key.Ascending}
+	}
+	return fields, true
+}
+
+// TomySortOrder converts sort fields of columns of a tomy file to the order recorded in its footer
+func TomySortOrder(fields []planner.OrderByColumnReference) []tomy_file.SortColumn {
+	order := make([]tomy_file.SortColumn, len(fields))
+	for i, f := range fields {
+		order[i] = tomy_file.SortColumn{ColumnIdx: f.Index, Ascending: f.Ascending}
+	}
+	return order
+}
@@ -0,0 +1,173 @@
+package sort
+
+import (
+	"container/heap"
+	"sort"
+
+	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+// TopNOperator returns the first Limit rows of the child in the order of the sort fields. It keeps only the best
+// Limit rows seen so far in a heap whose root is the worst of them, so nothing is spilled and rows which can't
+// get into the result are not copied.
+type TopNOperator struct {
+	Child      operators.Operator
+	SortFields []planner.OrderByColumnReference
+	Limit      uint64
+	ChunkSize  uint64
+
+	rows      *rowHeap
+	columns   []types.ChunkColumn // empty columns with names and types of the output
+	selectIdx []int
+	consumed  bool
+	offset    int
+}
+
+func NewTopNOperator(child operators.Operator, sortFields []planner.OrderByColumnReference, limit uint64, chunkSize uint64) *TopNOperator {
+	return &TopNOperator{
+		Child:      child,
+		SortFields: sortFields,
+		Limit:      limit,
+		ChunkSize:  chunkSize,
+		rows:       &rowHeap{sortFields: sortFields},
+	}
+}
+
+func (op *TopNOperator) Close() {
+	if op.Child != nil {
+		op.Child.Close()
+		op.Child = nil
+	}
+	op.rows = nil
+}
+
+func (op *TopNOperator) NextBatch() (*types.ChunkResult, error) {
+	if !op.consumed {
+		if err := op.collect(); err != nil {
+			return nil, err
+		}
+	}
+	if op.offset >= len(op.rows.rows) {
+		return nil, nil
+	}
+
+	end := min(op.offset+int(op.ChunkSize), len(op.rows.rows))
+	columns := make([]types.ChunkColumn, len(op.columns))
+	for i, col := range op.columns {
+		columns[i] = types.CloneEmpty(col, end-op.offset, 0)
+	}
+	for _, row := range op.rows.rows[op.offset:end] {
+		for i, value := range row {
+			appendAnyToColumn(columns[i], value)
+		}
+	}
+	count := end - op.offset
+	op.offset = end
+	return &types.ChunkResult{
+		RowCount:  uint64(count),
+		Columns:   columns,
+		SelectIdx: op.selectIdx,
+		FilterIdx: -1,
+	}, nil
+}
+
+// collect reads all rows of the child keeping the best ones, then sorts them
+func (op *TopNOperator) collect() error {
+	for op.Limit > 0 {
+		batch, err := op.Child.NextBatch()
+		if err != nil {
+			return err
+		}
+		if batch == nil {
+			break
+		}
+		if op.columns == nil {
+			op.columns = make([]types.ChunkColumn, len(batch.Columns))
+			for i, col := range batch.Columns {
+				op.columns[i] = types.CloneEmpty(col, 0, 0)
+			}
+			op.selectIdx = batch.SelectIdx
+		}
+
+		for row := 0; row < int(batch.RowCount); row++ {
+			if uint64(op.rows.Len()) < op.Limit {
+				heap.Push(op.rows, rowValues(batch.Columns, row))
+			} else if op.compareWithWorst(batch.Columns, row) < 0 {
+				op.rows.rows[0] = rowValues(batch.Columns, row)
+				heap.Fix(op.rows, 0)
+			}
+		}
+	}
+	op.consumed = true
+	op.Child.Close()
+	op.Child = nil
+
+	sort.SliceStable(op.rows.rows, func(i, j int) bool {
+		return compareRows(op.rows.rows[i], op.rows.rows[j], op.SortFields) < 0
+	})
+	return nil
+}
+
+// compareWithWorst compares the row of the batch with the worst row kept, only by values of the sort fields
+func (op *TopNOperator) compareWithWorst(columns []types.ChunkColumn, row int) int {
+	worst := op.rows.rows[0]
+	for _, sf := range op.SortFields {
+		res := compareAny(columns[sf.Index].GetValueAny(row), worst[sf.Index])
+		if res == 0 {
+			continue
+		}
+		if sf.Ascending {
+			return res
+		}
+		return -res
+	}
+	return 0
+}
+
+func rowValues(columns []types.ChunkColumn, row int) []any {
+	values := make([]any, len(columns))
+	for i, col := range columns {
+		values[i] = col.GetValueAny(row)
+	}
+	return values
+}
+
+// compareRows returns a negative number if row a is ordered before row b
+func compareRows(a, b []any, sortFields []planner.OrderByColumnReference) int {
+	for _, sf := range sortFields {
+		res := compareAny(a[sf.Index], b[sf.Index])
+		if res == 0 {
+			continue
+		}
+		if sf.Ascending {
+			return res
+		}
+		return -res
+	}
+	return 0
+}
+
+// rowHeap is a heap of rows with the row ordered last at the root
+type rowHeap struct {
+	rows       [][]any
+	sortFields []planner.OrderByColumnReference
+}
+
+func (h *rowHeap) Len() int { return len(h.rows) }
+
+func (h *rowHeap) Less(i, j int) bool {
+	return compareRows(h.rows[i], h.rows[j], h.sortFields) > 0
+}
+
+func (h *rowHeap) Swap(i, j int) { h.rows[i], h.rows[j] = h.rows[j], h.rows[i] }
+
+func (h *rowHeap) Push(x any) { h.rows = append(h.rows, x.([]any)) }
+
+func (h *rowHeap) Pop() any {
+	old := h.rows
+	row := old[len(old)-1]
+	h.rows = old[:len(old)-1]
+	return row
+}
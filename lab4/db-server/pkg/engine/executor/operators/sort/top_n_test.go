@@ -0,0 +1,71 @@
+package sort
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"isbd4/pkg/engine/executor/operators/operatortest"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+func TestTopNOperator(t *testing.T) {
+	const rows, batchSize = 2000, 128
+
+	// groups of 10 rows share a score, ids are shuffled
+	var batches []*types.ChunkResult
+	for start := 0; start < rows; start += batchSize {
+		count := min(batchSize, rows-start)
+		ids := make([]int64, count)
+		scores := make([]int64, count)
+		names := make([]string, count)
+		for i := range ids {
+			ids[i] = int64((start + i) * 7919 % rows)
+			scores[i] = ids[i] / 10
+			names[i] = fmt.Sprintf("n%04d", ids[i])
+		}
+		batches = append(batches, &types.ChunkResult{
+			RowCount:  uint64(count),
+			Columns:   []types.ChunkColumn{types.NewInt64Column("score", scores), types.VarcharChunkColumnFromStrings("name", names)},
+			SelectIdx: []int{1, 0},
+			FilterIdx: -1,
+		})
+	}
+
+	// by score descending, then by name ascending
+	sortFields := []planner.OrderByColumnReference{{Index: 0, Ascending: false}, {Index: 1, Ascending: true}}
+	for _, limit := range []uint64{0, 1, 25, 150, rows + 10} {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			op := NewTopNOperator(operatortest.NewBatchesOperator(batches...), sortFields, limit, 100)
+			defer op.Close()
+
+			var got []string
+			for {
+				batch, err := op.NextBatch()
+				if err != nil {
+					t.Fatalf("TopN failed: %v", err)
+				}
+				if batch == nil {
+					break
+				}
+				if batch.RowCount > 100 || !slices.Equal(batch.SelectIdx, []int{1, 0}) || batch.Columns[1].GetName() != "name" {
+					t.Fatalf("Expected batches of at most 100 rows with columns of the child, got %d rows, %v", batch.RowCount, batch.SelectIdx)
+				}
+				for i := 0; i < int(batch.RowCount); i++ {
+					got = append(got, batch.Columns[1].GetValueAny(i).(string))
+				}
+			}
+
+			var want []string
+			for score := int64(rows/10 - 1); score >= 0 && len(want) < int(limit); score-- {
+				for id := score * 10; id < score*10+10 && len(want) < int(limit); id++ {
+					want = append(want, fmt.Sprintf("n%04d", id))
+				}
+			}
+			if !slices.Equal(got, want) {
+				t.Fatalf("Expected %d rows %v, got %d rows %v", len(want), want, len(got), got)
+			}
+		})
+	}
+}
@@ -0,0 +1,410 @@
+package window
+
+import (
+	"fmt"
+
+	"isbd4/pkg/engine/executor/operators"
+	operators_sort "isbd4/pkg/engine/executor/operators/sort"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+// WindowOperator computes window functions of a window for every row of the child. Rows are sorted by partition
+// keys and order keys by the external sort, then every partition is collected in memory and its functions are
+// computed. It returns batches of the passed columns followed by results of the functions.
+type WindowOperator struct {
+	Child     operators.Operator
+	ChunkSize uint64
+
+	window *planner.Window
+	// positions of the keys and arguments in rows of the child, column names may be lost by the sort
+	partitionIdx []int
+	orderIdx     []int
+	argumentIdx  []int // -1 for functions without arguments
+
+	partition    []*types.ChunkResult // rows of the current partition
+	partitionKey []byte
+	keyBuffer    []byte
+	started      bool
+	consumed     bool
+
+	finished     []*types.ChunkResult // computed partitions waiting for output
+	finishedRows uint64
+	result       *types.ChunkResult
+	offset       uint64
+}
+
+func NewWindowOperator(child operators.Operator, window *planner.Window, chunkSize uint64, memoryLimitBytes uint64, baseDir string) *WindowOperator {
+	var lastOp operators.Operator = operators.NewTransformationOperator(child, window.InputExprs())
+
+	op := &WindowOperator{ChunkSize: chunkSize, window: window}
+	idx := len(window.Passed)
+	var sortFields []planner.OrderByColumnReference
+	for range window.PartitionBy {
+		op.partitionIdx = append(op.partitionIdx, idx)
+		sortFields = append(sortFields, planner.OrderByColumnReference{Index: idx, Ascending: true})
+		idx++
+	}
+	for _, order := range window.OrderBy {
+		op.orderIdx = append(op.orderIdx, idx)
+		sortFields = append(sortFields, planner.OrderByColumnReference{Index: idx, Ascending: order.Ascending})
+		idx++
+	}
+	for _, call := range window.Functions {
+		if call.Argument == nil {
+			op.argumentIdx = append(op.argumentIdx, -1)
+			continue
+		}
+		op.argumentIdx = append(op.argumentIdx, idx)
+		idx++
+	}
+
+	if len(sortFields) > 0 {
+		lastOp = operators_sort.NewExternalMergeSortOperator(lastOp, sortFields, chunkSize, memoryLimitBytes, baseDir)
+	}
+	op.Child = lastOp
+	return op
+}
+
+func (op *WindowOperator) Close() {
+	if op.Child != nil {
+		op.Child.Close()
+		op.Child = nil
+	}
+	op.partition = nil
+	op.finished = nil
+	op.result = nil
+}
+
+func (op *WindowOperator) NextBatch() (*types.ChunkResult, error) {
+	for op.result == nil || op.offset >= op.result.RowCount {
+		if err := op.fillResult(); err != nil {
+			return nil, err
+		}
+		if op.result == nil {
+			return nil, nil
+		}
+	}
+
+	count := min(op.ChunkSize, op.result.RowCount-op.offset)
+	columns, err := operators.SliceColumns(op.result.Columns, op.offset, count)
+	if err != nil {
+		return nil, err
+	}
+	op.offset += count
+	return &types.ChunkResult{
+		RowCount:  count,
+		Columns:   columns,
+		SelectIdx: op.result.SelectIdx,
+		FilterIdx: -1,
+	}, nil
+}
+
+// fillResult reads partitions until at least ChunkSize rows are computed or the child is consumed,
+// the result is nil when no rows are left
+func (op *WindowOperator) fillResult() error {
+	op.result = nil
+	op.offset = 0
+	for !op.consumed && op.finishedRows < op.ChunkSize {
+		batch, err := op.Child.NextBatch()
+		if err != nil {
+			return err
+		}
+		if batch == nil {
+			op.consumed = true
+			if err := op.finishPartition(); err != nil {
+				return err
+			}
+			break
+		}
+		if err := op.addBatch(batch); err != nil {
+			return err
+		}
+	}
+
+	if len(op.finished) == 0 {
+		return nil
+	}
+	result, err := operators.MergeChunkResultsWithinOneSchema(op.finished)
+	if err != nil {
+		return err
+	}
+	op.result = result
+	op.finished = nil
+	op.finishedRows = 0
+	return nil
+}
+
+// addBatch appends rows of the batch to the current partition, finishing it at every change of partition keys
+func (op *WindowOperator) addBatch(batch *types.ChunkResult) error {
+	columns := batch.Columns
+	if batch.SelectIdx != nil {
+		columns = make([]types.ChunkColumn, len(batch.SelectIdx))
+		for i, idx := range batch.SelectIdx {
+			columns[i] = batch.Columns[idx]
+		}
+	}
+
+	start := 0
+	for row := 0; row < int(batch.RowCount); row++ {
+		op.keyBuffer = op.keyBuffer[:0]
+		for _, idx := range op.partitionIdx {
+			op.keyBuffer = operators.AppendKeyValue(op.keyBuffer, columns[idx].GetValueAny(row))
+		}
+		if !op.started {
+			op.started = true
+			op.partitionKey = append(op.partitionKey[:0], op.keyBuffer...)
+			continue
+		}
+		if string(op.keyBuffer) == string(op.partitionKey) {
+			continue
+		}
+
+		if err := op.appendRows(columns, start, row); err != nil {
+			return err
+		}
+		if err := op.finishPartition(); err != nil {
+			return err
+		}
+		start = row
+		op.partitionKey = append(op.partitionKey[:0], op.keyBuffer...)
+	}
+	return op.appendRows(columns, start, int(batch.RowCount))
+}
+
+func (op *WindowOperator) appendRows(columns []types.ChunkColumn, start, end int) error {
+	if start == end {
+		return nil
+	}
+	sliced, err := operators.SliceColumns(columns, uint64(start), uint64(end-start))
+	if err != nil {
+		return err
+	}
+	op.partition = append(op.partition, &types.ChunkResult{RowCount: uint64(end - start), Columns: sliced})
+	return nil
+}
+
+// finishPartition computes the functions of the current partition and adds it to the finished ones
+func (op *WindowOperator) finishPartition() error {
+	if len(op.partition) == 0 {
+		return nil
+	}
+	rows, err := operators.MergeChunkResultsWithinOneSchema(op.partition)
+	if err != nil {
+		return err
+	}
+	op.partition = nil
+
+	n := int(rows.RowCount)
+	columns := make([]types.ChunkColumn, 0, len(op.window.Passed)+len(op.window.Functions))
+	for i, col := range op.window.Passed {
+		columns = append(columns, operators.RenameColumn(rows.Columns[i], col.ColName))
+	}
+	peers := peerGroups(rows.Columns, op.orderIdx, n)
+	for i, call := range op.window.Functions {
+		var arg types.ChunkColumn
+		if op.argumentIdx[i] >= 0 {
+			arg = rows.Columns[op.argumentIdx[i]]
+		}
+		values, err := computeFunction(call, arg, peers, len(op.orderIdx) > 0, n)
+		if err != nil {
+			return err
+		}
+		columns = append(columns, buildColumn(call.Name, call.ResultType, values))
+	}
+
+	selectIdx := make([]int, len(columns))
+	for i := range selectIdx {
+		selectIdx[i] = i
+	}
+	op.finished = append(op.finished, &types.ChunkResult{
+		RowCount:  uint64(n),
+		Columns:   columns,
+		SelectIdx: selectIdx,
+		FilterIdx: -1,
+	})
+	op.finishedRows += uint64(n)
+	return nil
+}
+
+// peerGroups returns the index of the first row of the group of rows with equal order keys for every row
+func peerGroups(columns []types.ChunkColumn, orderIdx []int, n int) []int {
+	peers := make([]int, n)
+	var prev, key []byte
+	for row := 0; row < n; row++ {
+		key = key[:0]
+		for _, idx := range orderIdx {
+			key = operators.AppendKeyValue(key, columns[idx].GetValueAny(row))
+		}
+		if row > 0 && string(key) == string(prev) {
+			peers[row] = peers[row-1]
+		} else {
+			peers[row] = row
+		}
+		prev, key = key, prev
+	}
+	return peers
+}
+
+// computeFunction returns values of the function for all n rows of a partition
+func computeFunction(call planner.WindowCall, arg types.ChunkColumn, peers []int, ordered bool, n int) ([]any, error) {
+	values := make([]any, n)
+	switch call.Function {
+	case expr.RowNumber:
+		for i := range values {
+			values[i] = int64(i + 1)
+		}
+	case expr.Rank:
+		for i := range values {
+			values[i] = int64(peers[i] + 1)
+		}
+	case expr.DenseRank:
+		rank := int64(0)
+		for i := range values {
+			if peers[i] == i {
+				rank++
+			}
+			values[i] = rank
+		}
+	case expr.Lag, expr.Lead:
+		offset := int(call.Offset)
+		if call.Function == expr.Lag {
+			offset = -offset
+		}
+		for i := range values {
+			if j := i + offset; j >= 0 && j < n {
+				values[i] = arg.GetValueAny(j)
+			} else {
+				values[i] = zeroValue(call.ResultType)
+			}
+		}
+	default:
+		return computeAggregate(call, arg, frameBounds(call.Frame, peers, ordered, n))
+	}
+	return values, nil
+}
+
+// frameBounds returns the first and the last row of the frame of every row, clamped to the partition.
+// Both bounds never decrease, the frame is empty when the first row is after the last one.
+func frameBounds(frame *planner.WindowFrame, peers []int, ordered bool, n int) [][2]int {
+	bounds := make([][2]int, n)
+	for i := range bounds {
+		first, last := 0, n-1
+		switch {
+		case frame != nil:
+			if frame.Start != nil {
+				first = max(0, i+int(*frame.Start))
+			}
+			if frame.End != nil {
+				last = min(n-1, i+int(*frame.End))
+			}
+		case ordered:
+			// the default frame ends with the last peer of the row
+			last = i
+			for last+1 < n && peers[last+1] == peers[i] {
+				last++
+			}
+		}
+		bounds[i] = [2]int{first, last}
+	}
+	return bounds
+}
+
+func computeAggregate(call planner.WindowCall, arg types.ChunkColumn, bounds [][2]int) ([]any, error) {
+	values := make([]any, len(bounds))
+	fn := expr.AggregateFunction(call.Function)
+	switch fn {
+	case expr.Count, expr.Sum, expr.Avg:
+		// prefix sums of the argument, prefix[i] is the sum of rows before row i
+		prefix := make([]int64, len(bounds)+1)
+		if fn != expr.Count {
+			for i := range bounds {
+				prefix[i+1] = prefix[i] + arg.GetValueAny(i).(int64)
+			}
+		}
+		for i, b := range bounds {
+			count := int64(max(0, b[1]-b[0]+1))
+			switch {
+			case fn == expr.Count:
+				values[i] = count
+			case count == 0:
+				values[i] = int64(0)
+			case fn == expr.Sum:
+				values[i] = prefix[b[1]+1] - prefix[b[0]]
+			default:
+				values[i] = (prefix[b[1]+1] - prefix[b[0]]) / count
+			}
+		}
+	case expr.Min, expr.Max:
+		// indices of rows of the frame which may still become the extreme, their values are strictly monotonic
+		var deque []int
+		better := func(a, b any) bool { return less(a, b) == (fn == expr.Min) && a != b }
+		next := 0
+		for i, b := range bounds {
+			for ; next <= b[1]; next++ {
+				value := arg.GetValueAny(next)
+				for len(deque) > 0 && !better(arg.GetValueAny(deque[len(deque)-1]), value) {
+					deque = deque[:len(deque)-1]
+				}
+				deque = append(deque, next)
+			}
+			for len(deque) > 0 && deque[0] < b[0] {
+				deque = deque[1:]
+			}
+			if len(deque) == 0 || b[0] > b[1] {
+				values[i] = zeroValue(call.ResultType)
+			} else {
+				values[i] = arg.GetValueAny(deque[0])
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported window function: %s", call.Function)
+	}
+	return values, nil
+}
+
+func less(a, b any) bool {
+	switch av := a.(type) {
+	case int64:
+		return av < b.(int64)
+	case string:
+		return av < b.(string)
+	default:
+		panic(fmt.Sprintf("unsupported aggregated value: %T", a))
+	}
+}
+
+func zeroValue(colType types.ChunkColumnType) any {
+	switch colType {
+	case types.ChunkColumnTypeInt64:
+		return int64(0)
+	case types.ChunkColumnTypeVarchar:
+		return ""
+	default:
+		return false
+	}
+}
+
+func buildColumn(name string, colType types.ChunkColumnType, values []any) types.ChunkColumn {
+	switch colType {
+	case types.ChunkColumnTypeInt64:
+		typed := make([]int64, len(values))
+		for i, v := range values {
+			typed[i] = v.(int64)
+		}
+		return types.NewInt64Column(name, typed)
+	case types.ChunkColumnTypeVarchar:
+		typed := make([]string, len(values))
+		for i, v := range values {
+			typed[i] = v.(string)
+		}
+		return types.VarcharChunkColumnFromStrings(name, typed)
+	default:
+		typed := make([]bool, len(values))
+		for i, v := range values {
+			typed[i] = v.(bool)
+		}
+		return types.NewBooleanColumn(name, typed)
+	}
+}
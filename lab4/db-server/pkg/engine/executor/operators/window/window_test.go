@@ -0,0 +1,166 @@
+package window
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"isbd4/pkg/engine/executor/operators/operatortest"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+const rows, groups = 1000, 4
+
+var (
+	idRef  = &expr.ColumnRefExpr{ColName: "id", ColType: types.ChunkColumnTypeInt64}
+	grpRef = &expr.ColumnRefExpr{ColName: "grp", ColType: types.ChunkColumnTypeInt64}
+	keyRef = &expr.ColumnRefExpr{ColName: "key", ColType: types.ChunkColumnTypeInt64}
+)
+
+// shuffledBatches returns rows with unique ids in a shuffled order, ids of a group are equal modulo groups
+// and every key is shared by two ids of a group
+func shuffledBatches(batchSize int) []*types.ChunkResult {
+	var batches []*types.ChunkResult
+	for start := 0; start < rows; start += batchSize {
+		count := min(batchSize, rows-start)
+		ids := make([]int64, count)
+		grps := make([]int64, count)
+		keys := make([]int64, count)
+		for i := range ids {
+			ids[i] = int64((start + i) * 7919 % rows)
+			grps[i] = ids[i] % groups
+			keys[i] = ids[i] / (2 * groups)
+		}
+		batches = append(batches, &types.ChunkResult{
+			RowCount:  uint64(count),
+			Columns:   []types.ChunkColumn{types.NewInt64Column("id", ids), types.NewInt64Column("grp", grps), types.NewInt64Column("key", keys)},
+			SelectIdx: []int{0, 1, 2},
+			FilterIdx: -1,
+		})
+	}
+	return batches
+}
+
+func bound(offset int64) *int64 {
+	return &offset
+}
+
+// runWindow returns the output rows of the window by values of the first passed column
+func runWindow(t *testing.T, w *planner.Window, memoryLimit uint64) map[int64][]int64 {
+	t.Helper()
+	child := operatortest.NewBatchesOperator(shuffledBatches(128)...)
+	op := NewWindowOperator(child, w, 100, memoryLimit, filepath.Join(t.TempDir(), "tables"))
+	defer op.Close()
+
+	got := make(map[int64][]int64)
+	for {
+		batch, err := op.NextBatch()
+		if err != nil {
+			t.Fatalf("Window failed: %v", err)
+		}
+		if batch == nil {
+			break
+		}
+		if batch.RowCount > 100 {
+			t.Fatalf("Expected batches of at most 100 rows, got %d", batch.RowCount)
+		}
+		for i, col := range w.Passed {
+			if name := batch.Columns[batch.SelectIdx[i]].GetName(); name != col.ColName {
+				t.Fatalf("Expected passed column %s, got %s", col.ColName, name)
+			}
+		}
+		for row := 0; row < int(batch.RowCount); row++ {
+			values := make([]int64, len(batch.SelectIdx))
+			for i, idx := range batch.SelectIdx {
+				values[i] = batch.Columns[idx].GetValueAny(row).(int64)
+			}
+			got[values[0]] = values
+		}
+	}
+	if len(got) != rows {
+		t.Fatalf("Expected %d rows, got %d", rows, len(got))
+	}
+	return got
+}
+
+func TestWindowOperator_PartitionedFunctions(t *testing.T) {
+	w := &planner.Window{
+		Passed:      []*expr.ColumnRefExpr{idRef},
+		PartitionBy: []expr.Expression{grpRef},
+		OrderBy:     []planner.WindowOrder{{Expr: idRef, Ascending: true}},
+		Functions: []planner.WindowCall{
+			{Function: expr.RowNumber, Name: "rn", ResultType: types.ChunkColumnTypeInt64},
+			{Function: expr.Lag, Argument: idRef, Offset: 1, Name: "lag", ResultType: types.ChunkColumnTypeInt64},
+			{Function: expr.Lead, Argument: idRef, Offset: 2, Name: "lead", ResultType: types.ChunkColumnTypeInt64},
+			{Function: "SUM", Argument: idRef, Name: "running_sum", ResultType: types.ChunkColumnTypeInt64},
+			{Function: "MIN", Argument: idRef, Frame: &planner.WindowFrame{Start: bound(-1), End: bound(1)}, Name: "min", ResultType: types.ChunkColumnTypeInt64},
+			{Function: "MAX", Argument: idRef, Frame: &planner.WindowFrame{Start: bound(-2), End: bound(-1)}, Name: "max", ResultType: types.ChunkColumnTypeInt64},
+			{Function: "AVG", Argument: idRef, Frame: &planner.WindowFrame{Start: bound(0)}, Name: "avg", ResultType: types.ChunkColumnTypeInt64},
+			{Function: "COUNT", Frame: &planner.WindowFrame{}, Name: "count", ResultType: types.ChunkColumnTypeInt64},
+		},
+	}
+
+	// rows of a partition ordered by id are g, g+groups, g+2*groups, ...
+	expected := func(id int64) []int64 {
+		g, pos := id%groups, id/groups
+		size := int64((rows - int(g) + groups - 1) / groups)
+		at := func(p int64) int64 { return g + p*groups }
+
+		values := []int64{id, pos + 1, 0, 0, 0, id, 0, 0, size}
+		if pos >= 1 {
+			values[2] = at(pos - 1)
+			values[5] = at(pos - 1)
+			values[6] = at(pos - 1)
+		}
+		if pos+2 < size {
+			values[3] = at(pos + 2)
+		}
+		for p := int64(0); p <= pos; p++ {
+			values[4] += at(p)
+		}
+		values[7] = (at(pos) + at(size-1)) / 2
+		return values
+	}
+
+	for _, memoryLimit := range []uint64{1 << 20, 1024} {
+		t.Run(fmt.Sprintf("limit %d", memoryLimit), func(t *testing.T) {
+			got := runWindow(t, w, memoryLimit)
+			for id := int64(0); id < rows; id++ {
+				if want := expected(id); !slices.Equal(got[id], want) {
+					t.Fatalf("Row %d: expected %v, got %v", id, want, got[id])
+				}
+			}
+		})
+	}
+}
+
+func TestWindowOperator_RanksOfPeers(t *testing.T) {
+	w := &planner.Window{
+		Passed:  []*expr.ColumnRefExpr{idRef, keyRef},
+		OrderBy: []planner.WindowOrder{{Expr: keyRef, Ascending: false}},
+		Functions: []planner.WindowCall{
+			{Function: expr.Rank, Name: "rank", ResultType: types.ChunkColumnTypeInt64},
+			{Function: expr.DenseRank, Name: "dense_rank", ResultType: types.ChunkColumnTypeInt64},
+			{Function: "SUM", Argument: idRef, Name: "sum", ResultType: types.ChunkColumnTypeInt64},
+		},
+	}
+
+	got := runWindow(t, w, 1024)
+	const perKey = 2 * groups
+	const maxKey = (rows - 1) / perKey
+	for id := int64(0); id < rows; id++ {
+		key := id / perKey
+		// all ids of greater keys are ordered before the row, its peers are the ids with the same key
+		sum := int64(0)
+		for other := key * perKey; other < rows; other++ {
+			sum += other
+		}
+		want := []int64{id, key, (maxKey-key)*perKey + 1, maxKey - key + 1, sum}
+		if !slices.Equal(got[id], want) {
+			t.Fatalf("Row %d: expected %v, got %v", id, want, got[id])
+		}
+	}
+}
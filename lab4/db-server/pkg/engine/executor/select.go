@@ -1,29 +1,80 @@
 package executor
 
 import (
+	"math"
+
 	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/executor/operators/aggregate"
+	"isbd4/pkg/engine/executor/operators/join"
 	operators_sort "isbd4/pkg/engine/executor/operators/sort"
+	"isbd4/pkg/engine/executor/operators/window"
+	"isbd4/pkg/engine/expr"
 	"isbd4/pkg/engine/planner"
 	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
 )
 
 func (e *Executor) executeSelect(p *planner.SelectPlan) (*types.ColumnarResult, error) {
+	defer p.Release()
+
+	lastOp := e.buildSelect(p)
+	defer lastOp.Close()
+	return operators.CollectAllBatches(lastOp)
+}
+
+// buildSelect returns the operator producing batches of the select, selected columns are given by SelectIdx.
+// The plan has to be released by the caller once the operator is closed.
+func (e *Executor) buildSelect(p *planner.SelectPlan) operators.Operator {
 	var lastOp operators.Operator
 
-	if p.Snapshot == nil {
+	sortedScan := false
+	if p.Derived != nil {
+		source := e.buildSelect(p.Derived.Select)
+		lastOp = operators.NewConcatOperator([]operators.Operator{source}, p.Derived.Columns)
+	} else if len(p.Tables) > 0 {
+		lastOp = e.newJoinReader(p)
+	} else if p.Snapshot == nil {
 		lastOp = &operators.DummyReaderOperator{}
 	} else {
-		lastOp = operators.NewReaderOperator(p.Snapshot, p.QueryDef, e.chunkSize)
+		lastOp, sortedScan = e.newTableReader(p)
 	}
 
 	if p.QueryDef.WhereExpr != nil {
 		lastOp = operators.NewFilterTransformationOperator(lastOp, p.QueryDef.WhereExpr)
 		lastOp = operators.NewFilterOperator(lastOp)
 	}
+	for _, semiJoin := range p.QueryDef.SemiJoins {
+		lastOp = join.NewSemiJoinOperator(lastOp, e.buildSelect(semiJoin.Subquery), semiJoin,
+			e.chunkSize, e.memoryLimitBytes, e.tablesDir)
+	}
+
+	if agg := p.QueryDef.Aggregation; agg != nil {
+		lastOp = operators.NewTransformationOperator(lastOp, agg.InputExprs())
+		lastOp = aggregate.NewHashAggregateOperator(lastOp, agg, e.chunkSize, e.memoryLimitBytes, e.tablesDir)
+	}
+	for _, w := range p.QueryDef.Windows {
+		lastOp = window.NewWindowOperator(lastOp, w, e.chunkSize, e.memoryLimitBytes, e.tablesDir)
+	}
+	if p.QueryDef.HavingExpr != nil {
+		lastOp = operators.NewFilterTransformationOperator(lastOp, p.QueryDef.HavingExpr)
+		lastOp = operators.NewFilterOperator(lastOp)
+	}
 
 	lastOp = operators.NewTransformationOperator(lastOp, p.QueryDef.SelectExpr)
+	if p.QueryDef.Distinct {
+		lastOp = aggregate.NewDistinctOperator(lastOp, e.chunkSize, e.memoryLimitBytes, e.tablesDir)
+	}
+
+	offset := uint64(p.QueryDef.Offset)
+	limit := uint64(math.MaxUint64)
+	if p.QueryDef.Limit >= 0 {
+		limit = uint64(p.QueryDef.Limit)
+	}
 
-	if len(p.QueryDef.OrderByClause) > 0 {
+	if p.TopN {
+		// the heap keeps the skipped rows too
+		lastOp = operators_sort.NewTopNOperator(lastOp, p.QueryDef.OrderByClause, offset+limit, e.chunkSize)
+	} else if len(p.QueryDef.OrderByClause) > 0 && !sortedScan {
 		lastOp = operators_sort.NewExternalMergeSortOperator(
 			lastOp,
 			p.QueryDef.OrderByClause,
@@ -33,10 +84,107 @@ func (e *Executor) executeSelect(p *planner.SelectPlan) (*types.ColumnarResult,
 		)
 	}
 
-	if p.QueryDef.Limit >= 0 {
-		lastOp = operators.NewLimitOperator(lastOp, uint64(p.QueryDef.Limit))
+	if p.QueryDef.Limit >= 0 || offset > 0 {
+		lastOp = operators.NewLimitOperator(lastOp, offset, limit)
 	}
+	return lastOp
+}
 
-	defer lastOp.Close()
-	return operators.CollectAllBatches(lastOp)
+// newTableReader merges files in the order of the sort keys for sorted scans, returns false if the rows still need sorting.
+// Plans with an index lookup read only matching rows of files covered by the index.
+func (e *Executor) newTableReader(p *planner.SelectPlan) (operators.Operator, bool) {
+	if p.SystemTable != nil {
+		return operators.NewMemoryReaderOperator(p.SystemTable, operators.ExtractUsedColumns(p.QueryDef), e.chunkSize), false
+	}
+	if p.IndexLookup != nil {
+		return operators.NewIndexScanOperator(p.Snapshot, p.QueryDef, p.IndexLookup, e.chunkSize), false
+	}
+	if len(p.SortedScan) == 0 {
+		return operators.NewReaderOperator(p.Snapshot, p.QueryDef, e.chunkSize), false
+	}
+
+	readColumns := operators.ExtractUsedColumns(p.QueryDef)
+	sortFields, ok := operators_sort.SortFieldsForKeys(p.Snapshot.Columns, readColumns, p.SortedScan)
+	if !ok {
+		return operators.NewReaderOperator(p.Snapshot, p.QueryDef, e.chunkSize), false
+	}
+
+	readers := make([]operators.Operator, len(p.Snapshot.Files))
+	for i, f := range p.Snapshot.Files {
+		readers[i] = operators.NewFileReaderOperator([]string{f.Path}, readColumns, p.Snapshot.Deletions, e.chunkSize)
+	}
+	return operators_sort.NewSortedMergeOperator(readers, sortFields, e.chunkSize), true
+}
+
+// newJoinReader joins the tables of the plan from left to right, returning their columns qualified by table aliases
+func (e *Executor) newJoinReader(p *planner.SelectPlan) operators.Operator {
+	var lastOp operators.Operator
+	var leftColumns []planner.JoinedColumn
+	for i, table := range p.Tables {
+		// number of sort keys the table is read in order of
+		sortedKeys := 0
+		if i == 0 && len(p.Joins) > 0 && p.Joins[0].LeftSorted {
+			sortedKeys = len(p.Joins[0].LeftKeys)
+		} else if i > 0 && p.Joins[i-1].RightSorted {
+			sortedKeys = len(p.Joins[i-1].RightKeys)
+		}
+		reader := e.newJoinedTableReader(table, sortedKeys)
+
+		if i == 0 {
+			lastOp = reader
+		} else if j := p.Joins[i-1]; j.Strategy == planner.MergeJoin {
+			if !j.LeftSorted {
+				lastOp = e.sortByKeys(lastOp, j.LeftKeys, leftColumns)
+			}
+			if !j.RightSorted {
+				reader = e.sortByKeys(reader, j.RightKeys, table.Columns)
+			}
+			lastOp = join.NewMergeJoinOperator(lastOp, reader, j, leftColumns, table.Columns, e.chunkSize)
+		} else {
+			lastOp = join.NewHashJoinOperator(lastOp, reader, j, leftColumns, table.Columns,
+				e.chunkSize, e.memoryLimitBytes, e.tablesDir)
+		}
+		leftColumns = append(leftColumns[:len(leftColumns):len(leftColumns)], table.Columns...)
+	}
+	return lastOp
+}
+
+// newJoinedTableReader reads columns of a joined table renamed to their qualified names. With sortedKeys > 0
+// the table is read in order of the first sortedKeys of its sort keys by merging its sorted files.
+func (e *Executor) newJoinedTableReader(table *planner.JoinedTable, sortedKeys int) operators.Operator {
+	from := make([]string, len(table.Columns))
+	to := make([]string, len(table.Columns))
+	for j, col := range table.Columns {
+		from[j] = col.TableColumn
+		to[j] = col.Name
+	}
+
+	var reader operators.Operator
+	if table.SystemTable != nil {
+		reader = operators.NewMemoryReaderOperator(table.SystemTable, from, e.chunkSize)
+	} else if sortedKeys > 0 {
+		sortFields, _ := operators_sort.SortFieldsForKeys(table.Snapshot.Columns, from, table.Snapshot.SortKeys[:sortedKeys])
+		readers := make([]operators.Operator, len(table.Snapshot.Files))
+		for i, f := range table.Snapshot.Files {
+			readers[i] = operators.NewFileReaderOperator([]string{f.Path}, from, table.Snapshot.Deletions, e.chunkSize)
+		}
+		reader = operators_sort.NewSortedMergeOperator(readers, sortFields, e.chunkSize)
+	} else {
+		reader = operators.NewFileReaderOperator(metadata.FileNames(table.Snapshot.Files), from, table.Snapshot.Deletions, e.chunkSize)
+	}
+	return operators.NewRenameOperator(reader, from, to)
+}
+
+// sortByKeys sorts rows with the given columns in ascending order of key columns
+func (e *Executor) sortByKeys(child operators.Operator, keys []expr.Expression, columns []planner.JoinedColumn) operators.Operator {
+	fields := make([]planner.OrderByColumnReference, len(keys))
+	for i, key := range keys {
+		name := key.(*expr.ColumnRefExpr).ColName
+		for j, col := range columns {
+			if col.Name == name {
+				fields[i] = planner.OrderByColumnReference{Index: j, Ascending: true}
+			}
+		}
+	}
+	return operators_sort.NewExternalMergeSortOperator(child, fields, e.chunkSize, e.memoryLimitBytes, e.tablesDir)
 }
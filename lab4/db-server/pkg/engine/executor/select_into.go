@@ -0,0 +1,134 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+
+	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+// executeSelectInto streams the select result into new files of at most maxRowsInFile rows and commits all of them
+// in a single version, the created table is committed together with its files. Written files are removed when
+// anything fails before they are committed, so either all rows are stored or none. The result holds the number of stored rows.
+func (e *Executor) executeSelectInto(p *planner.SelectIntoPlan) (*types.ColumnarResult, error) {
+	defer p.Select.Release()
+
+	tableDef := &metadata.TableDef{Columns: p.Columns, SortKeys: p.SortKeys}
+	if p.TableName != "" {
+		tableDef.Database, tableDef.Name = metadata.SplitQualifiedName(p.TableName)
+	} else {
+		existing, exists := p.Metastore.GetTableById(p.TableId)
+		if !exists {
+			return nil, fmt.Errorf("table %s does not exist", p.TableId)
+		}
+		tableDef.Database, tableDef.Name = existing.Database, existing.Name
+	}
+
+	written, rows, err := e.writeSelectResult(p.Metastore, p.Select, tableDef)
+	if err != nil {
+		removeWrittenFiles(written)
+		return nil, err
+	}
+
+	if p.TableName != "" {
+		_, err = p.Metastore.CreateTableWithFiles(p.TableName, p.Columns, p.SortKeys, written)
+	} else if len(written) > 0 {
+		err = p.Metastore.AddFiles(p.TableId, written)
+	}
+	if err != nil {
+		if !errors.Is(err, metadata.ErrNotPersisted) {
+			removeWrittenFiles(written)
+		}
+		return nil, err
+	}
+
+	return &types.ColumnarResult{
+		RowCount: 1,
+		Columns:  []any{[]int64{int64(rows)}},
+	}, nil
+}
+
+// writeSelectResult writes selected columns of every batch into files of the table, returns files written so far on error
+func (e *Executor) writeSelectResult(m *metadata.Metastore, p *planner.SelectPlan, tableDef *metadata.TableDef) ([]metadata.NewFile, uint64, error) {
+	op := e.buildSelect(p)
+	defer op.Close()
+
+	var written []metadata.NewFile
+	var rows uint64
+	buffer := emptyTable(tableDef.Columns)
+	for {
+		batch, err := op.NextBatch()
+		if err != nil {
+			return written, 0, err
+		}
+		if batch == nil {
+			break
+		}
+
+		selected := make([]types.ChunkColumn, len(batch.SelectIdx))
+		for i, idx := range batch.SelectIdx {
+			selected[i] = batch.Columns[idx]
+		}
+
+		for start := uint64(0); start < batch.RowCount; {
+			count := min(batch.RowCount-start, e.maxRowsInFile-buffer.NumRows)
+			part, err := tableFromColumns(selected, start, count)
+			if err != nil {
+				return written, 0, err
+			}
+			appendTable(buffer, part)
+			start += count
+			rows += count
+
+			if buffer.NumRows >= e.maxRowsInFile {
+				newFile, err := serializeTableFile(m, tableDef, buffer)
+				if err != nil {
+					return written, 0, err
+				}
+				written = append(written, newFile)
+				buffer = emptyTable(tableDef.Columns)
+			}
+		}
+	}
+
+	if buffer.NumRows > 0 {
+		newFile, err := serializeTableFile(m, tableDef, buffer)
+		if err != nil {
+			return written, 0, err
+		}
+		written = append(written, newFile)
+	}
+	return written, rows, nil
+}
+
+func emptyTable(columns []metadata.ColumnDef) *tomy_file.ColumnarTable {
+	table := &tomy_file.ColumnarTable{Columns: make([]tomy_file.AnyColumn, len(columns))}
+	for i, col := range columns {
+		switch col.Type {
+		case metadata.Int64Type:
+			table.Columns[i] = &tomy_file.Int64Column{Name: col.Name}
+		case metadata.VarcharType:
+			table.Columns[i] = &tomy_file.VarcharColumn{Name: col.Name}
+		}
+	}
+	return table
+}
+
+// tableFromColumns converts count rows of the columns starting at start, column names are left as in the batch
+func tableFromColumns(columns []types.ChunkColumn, start, count uint64) (*tomy_file.ColumnarTable, error) {
+	sliced, err := operators.SliceColumns(columns, start, count)
+	if err != nil {
+		return nil, err
+	}
+	table := &tomy_file.ColumnarTable{NumRows: count, Columns: make([]tomy_file.AnyColumn, len(sliced))}
+	for i, col := range sliced {
+		if table.Columns[i], err = types.TomyColumnFromChunk(col); err != nil {
+			return nil, err
+		}
+	}
+	return table, nil
+}
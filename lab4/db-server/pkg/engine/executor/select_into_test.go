@@ -0,0 +1,103 @@
+package executor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/metadata"
+)
+
+func selectFromT1(columns ...openapi.ColumnExpression) openapi.SelectQuery {
+	return openapi.SelectQuery{ColumnClauses: columns, FromClause: &openapi.FromClause{TableName: "t1"}}
+}
+
+func TestExecuteSelectInto_CommitsRows(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	createIdTable(t, m, nil, []int64{1, 2, 3})
+	e := NewExecutor(t.TempDir(), 2, 2, 0)
+	p := planner.NewPlanner(m)
+
+	ctas, err := p.PlanCreateTableAsSelect(openapi.CreateTableAsSelectQuery{
+		CreateTableName: "t2",
+		SelectQuery:     selectFromT1(idColumn()),
+	})
+	if err != nil {
+		t.Fatalf("PlanCreateTableAsSelect failed: %v", err)
+	}
+	result, err := e.Execute(ctas)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if rows := result.Columns[0].([]int64)[0]; rows != 3 {
+		t.Errorf("Expected 3 stored rows, got %d", rows)
+	}
+	newId, exists := m.GetTableId("t2")
+	if !exists {
+		t.Fatalf("Expected table t2 to be created")
+	}
+	versions, _ := m.GetTableVersions(newId)
+	if len(versions) != 2 {
+		t.Errorf("Expected the table created with its files in a single version, got %d versions", len(versions))
+	}
+	if ids := readTableIds(t, m, newId); !reflect.DeepEqual(ids, [][]int64{{1, 2}, {3}}) {
+		t.Errorf("Expected rows split into files of maxRowsInFile rows, got %v", ids)
+	}
+
+	insert, err := p.PlanInsertSelect(openapi.InsertSelectQuery{
+		IntoTableName: "t2",
+		SelectQuery:   selectFromT1(binaryOp("MULTIPLY", idColumn(), int64Literal(10))),
+	})
+	if err != nil {
+		t.Fatalf("PlanInsertSelect failed: %v", err)
+	}
+	if _, err := e.Execute(insert); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	versions, _ = m.GetTableVersions(newId)
+	if len(versions) != 3 {
+		t.Errorf("Expected inserted files committed in a single version, got %d versions", len(versions))
+	}
+	if ids := readTableIds(t, m, newId); !reflect.DeepEqual(ids, [][]int64{{1, 2}, {3}, {10, 20}, {30}}) {
+		t.Errorf("Unexpected ids after INSERT SELECT: %v", ids)
+	}
+}
+
+func TestExecuteSelectInto_FailedSelectCommitsNothing(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	tableId := createIdTable(t, m, nil, []int64{1, 2, 3})
+	e := NewExecutor(t.TempDir(), 1, 1, 0)
+	p := planner.NewPlanner(m)
+	entries, _ := os.ReadDir(m.DatabaseDir(metadata.DefaultDatabase))
+
+	// 6 / (id - 3) fails on the last row, after files of the first rows are written
+	failing := selectFromT1(binaryOp("DIVIDE", int64Literal(6), binaryOp("SUBTRACT", idColumn(), int64Literal(3))))
+
+	ctas, err := p.PlanCreateTableAsSelect(openapi.CreateTableAsSelectQuery{CreateTableName: "t2", SelectQuery: failing})
+	if err != nil {
+		t.Fatalf("PlanCreateTableAsSelect failed: %v", err)
+	}
+	if _, err := e.Execute(ctas); err == nil {
+		t.Errorf("Expected CREATE TABLE AS SELECT to fail")
+	}
+	if _, exists := m.GetTableId("t2"); exists {
+		t.Errorf("Expected table t2 not to be created")
+	}
+
+	insert, err := p.PlanInsertSelect(openapi.InsertSelectQuery{IntoTableName: "t1", SelectQuery: failing})
+	if err != nil {
+		t.Fatalf("PlanInsertSelect failed: %v", err)
+	}
+	if _, err := e.Execute(insert); err == nil {
+		t.Errorf("Expected INSERT SELECT to fail")
+	}
+	if ids := readTableIds(t, m, tableId); !reflect.DeepEqual(ids, [][]int64{{1, 2, 3}}) {
+		t.Errorf("Expected no rows inserted, got %v", ids)
+	}
+
+	if after, _ := os.ReadDir(m.DatabaseDir(metadata.DefaultDatabase)); len(after) != len(entries) {
+		t.Errorf("Expected files written by the failed queries to be removed, got %d files instead of %d", len(after), len(entries))
+	}
+}
@@ -0,0 +1,70 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+
+	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/executor/operators/aggregate"
+	"isbd4/pkg/engine/executor/operators/join"
+	operators_sort "isbd4/pkg/engine/executor/operators/sort"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+)
+
+func (e *Executor) executeSetOperation(p *planner.SetOperationPlan) (*types.ColumnarResult, error) {
+	defer p.Release()
+
+	lastOp := e.buildSetOperation(p)
+	defer lastOp.Close()
+	return operators.CollectAllBatches(lastOp)
+}
+
+// buildSetOperation returns the operator producing the combined rows. Columns of the selects are renamed to col_<index>.
+// UNION ALL concatenates the selects and UNION deduplicates the concatenation by the hash-based distinct operator.
+// INTERSECT and EXCEPT deduplicate rows of the first select, then keep rows with (or without) an equal row
+// in every following select by hash semi joins, which spill into partition files like for IN subqueries.
+func (e *Executor) buildSetOperation(p *planner.SetOperationPlan) operators.Operator {
+	selectExprs := p.Selects[0].QueryDef.SelectExpr
+	names := make([]string, len(selectExprs))
+	keys := make([]expr.Expression, len(selectExprs))
+	subqueryKeys := make([]int, len(selectExprs))
+	for i, selectExpr := range selectExprs {
+		names[i] = fmt.Sprintf("col_%d", i)
+		keys[i] = &expr.ColumnRefExpr{ColName: names[i], ColType: selectExpr.ResultType()}
+		subqueryKeys[i] = i
+	}
+
+	var lastOp operators.Operator
+	switch p.Operator {
+	case planner.UnionAll, planner.Union:
+		children := make([]operators.Operator, len(p.Selects))
+		for i, s := range p.Selects {
+			children[i] = e.buildSelect(s)
+		}
+		lastOp = operators.NewConcatOperator(children, names)
+		if p.Operator == planner.Union {
+			lastOp = aggregate.NewDistinctOperator(lastOp, e.chunkSize, e.memoryLimitBytes, e.tablesDir)
+		}
+	default:
+		lastOp = operators.NewConcatOperator([]operators.Operator{e.buildSelect(p.Selects[0])}, names)
+		lastOp = aggregate.NewDistinctOperator(lastOp, e.chunkSize, e.memoryLimitBytes, e.tablesDir)
+		for _, s := range p.Selects[1:] {
+			semiJoin := planner.SemiJoin{Anti: p.Operator == planner.Except, Keys: keys, SubqueryKeys: subqueryKeys}
+			lastOp = join.NewSemiJoinOperator(lastOp, e.buildSelect(s), semiJoin, e.chunkSize, e.memoryLimitBytes, e.tablesDir)
+		}
+	}
+
+	if len(p.OrderByClause) > 0 {
+		lastOp = operators_sort.NewExternalMergeSortOperator(lastOp, p.OrderByClause, e.chunkSize, e.memoryLimitBytes, e.tablesDir)
+	}
+	if p.Limit >= 0 || p.Offset > 0 {
+		limit := uint64(math.MaxUint64)
+		if p.Limit >= 0 {
+			limit = uint64(p.Limit)
+		}
+		lastOp = operators.NewLimitOperator(lastOp, uint64(p.Offset), limit)
+	}
+	return lastOp
+}
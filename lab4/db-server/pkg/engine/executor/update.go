@@ -0,0 +1,188 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"isbd4/pkg/engine/executor/operators"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+// executeUpdate rewrites every file with a matching row and swaps the files in a single version,
+// the result holds the number of updated rows
+func (e *Executor) executeUpdate(p *planner.UpdatePlan) (*types.ColumnarResult, error) {
+	defer p.Snapshot.Release()
+
+	tableDef, exists := p.Metastore.GetTableById(p.TableId)
+	if !exists {
+		return nil, fmt.Errorf("table %s does not exist", p.TableId)
+	}
+	outDir := p.Metastore.DatabaseDir(tableDef.Database)
+
+	var removed []string
+	var added []metadata.NewFile
+	var updated uint64
+	for _, f := range p.Snapshot.Files {
+		newFile, count, err := e.rewriteFile(f, p, outDir, tableDef.Name)
+		if err != nil {
+			removeWrittenFiles(added)
+			return nil, err
+		}
+		if count == 0 {
+			continue
+		}
+		removed = append(removed, f.Path)
+		added = append(added, newFile)
+		updated += count
+	}
+
+	if len(removed) > 0 {
+		if err := p.Metastore.ReplaceFiles(p.TableId, p.Snapshot.Version, removed, added); err != nil {
+			if !errors.Is(err, metadata.ErrNotPersisted) {
+				removeWrittenFiles(added)
+			}
+			return nil, err
+		}
+	}
+
+	return &types.ColumnarResult{
+		RowCount: 1,
+		Columns:  []any{[]int64{int64(updated)}},
+	}, nil
+}
+
+// rewriteFile writes a copy of the file with assignments applied to matching rows, deleted rows are left out.
+// Nothing is written when no row matches.
+func (e *Executor) rewriteFile(f *metadata.FileEntry, p *planner.UpdatePlan, outDir string, tableName string) (metadata.NewFile, uint64, error) {
+	var op operators.Operator = operators.NewFileReaderOperator([]string{f.Path}, nil, p.Snapshot.Deletions, e.chunkSize)
+	if p.WhereExpr != nil {
+		op = operators.NewFilterTransformationOperator(op, p.WhereExpr)
+	}
+	defer op.Close()
+
+	var chunks []*types.ChunkResult
+	var updated uint64
+	for {
+		batch, err := op.NextBatch()
+		if err != nil {
+			return metadata.NewFile{}, 0, err
+		}
+		if batch == nil {
+			break
+		}
+
+		matches := make([]bool, batch.RowCount)
+		for i := range matches {
+			matches[i] = p.WhereExpr == nil || batch.Columns[batch.FilterIdx].(*types.BooleanChunkColumn).Values[i]
+			if matches[i] {
+				updated++
+			}
+		}
+
+		chunk, err := applyAssignments(batch, p.Snapshot.Columns, p.Assignments, matches)
+		if err != nil {
+			return metadata.NewFile{}, 0, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	if updated == 0 {
+		return metadata.NewFile{}, 0, nil
+	}
+
+	merged, err := operators.MergeChunkResultsWithinOneSchema(chunks)
+	if err != nil {
+		return metadata.NewFile{}, 0, err
+	}
+	table := tomy_file.ColumnarTable{
+		NumRows: merged.RowCount,
+		Columns: make([]tomy_file.AnyColumn, len(merged.Columns)),
+	}
+	for i, col := range merged.Columns {
+		if table.Columns[i], err = types.TomyColumnFromChunk(col); err != nil {
+			return metadata.NewFile{}, 0, err
+		}
+	}
+	if len(p.Snapshot.SortKeys) > 0 {
+		if err := sortByKeys(&table, p.Snapshot.Columns, p.Snapshot.SortKeys); err != nil {
+			return metadata.NewFile{}, 0, fmt.Errorf("failed to sort data by cluster keys: %w", err)
+		}
+	}
+
+	outPath := filepath.Join(outDir, fmt.Sprintf("%s_%d.tomy", tableName, time.Now().UnixNano()))
+	if err := table.Serialize(outPath); err != nil {
+		return metadata.NewFile{}, 0, fmt.Errorf("failed to serialize data: %w", err)
+	}
+	stats, err := metadata.FileStatsFromFooter(outPath)
+	if err != nil {
+		os.Remove(outPath)
+		return metadata.NewFile{}, 0, fmt.Errorf("failed to collect file statistics: %w", err)
+	}
+	return metadata.NewFile{Path: outPath, Stats: stats}, updated, nil
+}
+
+// applyAssignments returns table columns of the batch with assigned columns replaced in matching rows.
+// Expressions are evaluated only on matching rows, e.g. a division guarded by the where clause can't fail.
+func applyAssignments(batch *types.ChunkResult, columns []metadata.ColumnDef, assignments []planner.ColumnAssignment, matches []bool) (*types.ChunkResult, error) {
+	result := &types.ChunkResult{
+		RowCount:  batch.RowCount,
+		Columns:   append([]types.ChunkColumn(nil), batch.Columns[:len(columns)]...),
+		FilterIdx: -1,
+	}
+
+	passIndices := make([]int, 0, batch.RowCount)
+	for i, m := range matches {
+		if m {
+			passIndices = append(passIndices, i)
+		}
+	}
+	if len(passIndices) == 0 {
+		return result, nil
+	}
+	matchedCols, err := operators.FilterBatchColumns(batch.Columns, passIndices)
+	if err != nil {
+		return nil, err
+	}
+	matched := &types.ChunkResult{RowCount: uint64(len(passIndices)), Columns: matchedCols, FilterIdx: -1}
+
+	colMapping := make(map[string]int, len(matchedCols))
+	for i, col := range matchedCols {
+		colMapping[col.GetName()] = i
+	}
+
+	for _, a := range assignments {
+		newCol, err := a.Expr.Evaluate(matched, colMapping)
+		if err != nil {
+			return nil, err
+		}
+
+		switch old := batch.Columns[a.ColumnIdx].(type) {
+		case *types.Int64ChunkColumn:
+			values := append([]int64(nil), old.Values...)
+			for j, i := range passIndices {
+				values[i] = newCol.GetValueAny(j).(int64)
+			}
+			result.Columns[a.ColumnIdx] = types.NewInt64Column(old.Name, values)
+		case *types.VarcharChunkColumn:
+			values := old.GetValuesAsString()
+			for j, i := range passIndices {
+				values[i] = newCol.GetValueAny(j).(string)
+			}
+			result.Columns[a.ColumnIdx] = types.VarcharChunkColumnFromStrings(old.Name, values)
+		default:
+			return nil, fmt.Errorf("unsupported column type: %T", old)
+		}
+	}
+	return result, nil
+}
+
+func removeWrittenFiles(files []metadata.NewFile) {
+	for _, f := range files {
+		os.Remove(f.Path)
+	}
+}
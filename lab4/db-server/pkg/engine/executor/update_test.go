@@ -0,0 +1,168 @@
+package executor
+
+import (
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+func idColumn() openapi.ColumnExpression {
+	return openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{ColumnName: "id"}}
+}
+
+func int64Literal(value int64) openapi.ColumnExpression {
+	return openapi.ColumnExpression{Expression: openapi.Literal{Value: openapi.LiteralValue{Data: value}}}
+}
+
+func binaryOp(operator string, left, right openapi.ColumnExpression) openapi.ColumnExpression {
+	return openapi.ColumnExpression{Expression: openapi.ColumnarBinaryOperation{Operator: operator, LeftOperand: left, RightOperand: right}}
+}
+
+// createIdTable creates table t1 with a single id column and commits a file for every list of ids
+func createIdTable(t *testing.T, m *metadata.Metastore, sortKeys []metadata.SortKey, files ...[]int64) string {
+	t.Helper()
+	tableId, err := m.CreateTable("t1", []metadata.ColumnDef{{Name: "id", Type: metadata.Int64Type}}, sortKeys)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	for _, ids := range files {
+		if err := writeInsertedRows(m, tableId, insertPlan(m, tableId, ids...).Table); err != nil {
+			t.Fatalf("writeInsertedRows failed: %v", err)
+		}
+	}
+	return tableId
+}
+
+// readTableIds returns ids stored in current files of the table, file by file
+func readTableIds(t *testing.T, m *metadata.Metastore, tableId string) [][]int64 {
+	t.Helper()
+	files, _ := m.GetTableFiles(tableId)
+	var ids [][]int64
+	for _, f := range files {
+		table, err := tomy_file.Deserialize(f.Path)
+		if err != nil {
+			t.Fatalf("Deserialize failed: %v", err)
+		}
+		ids = append(ids, table.Columns[0].(*tomy_file.Int64Column).Values)
+	}
+	return ids
+}
+
+func runUpdate(t *testing.T, e *Executor, p *planner.Planner, query openapi.UpdateQuery) int64 {
+	t.Helper()
+	plan, err := p.PlanUpdate(query)
+	if err != nil {
+		t.Fatalf("PlanUpdate failed: %v", err)
+	}
+	result, err := e.Execute(plan)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	return result.Columns[0].([]int64)[0]
+}
+
+func TestExecuteUpdate_AssignsValues(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	tableId := createIdTable(t, m, nil, []int64{1, 2, 3}, []int64{4, 5})
+	e := NewExecutor(t.TempDir(), 2, 10, 0)
+
+	// id = id * 10 WHERE id > 3, the first file has no matching row and is kept
+	updated := runUpdate(t, e, planner.NewPlanner(m), openapi.UpdateQuery{
+		TableName:   "t1",
+		SetClauses:  []openapi.ColumnAssignment{{ColumnName: "id", Expression: binaryOp("MULTIPLY", idColumn(), int64Literal(10))}},
+		WhereClause: binaryOp("GREATER_THAN", idColumn(), int64Literal(3)),
+	})
+	if updated != 2 {
+		t.Errorf("Expected 2 updated rows, got %d", updated)
+	}
+	if ids := readTableIds(t, m, tableId); !reflect.DeepEqual(ids, [][]int64{{1, 2, 3}, {40, 50}}) {
+		t.Errorf("Unexpected ids after update: %v", ids)
+	}
+}
+
+func TestExecuteUpdate_PurgesDeletedRows(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	tableId := createIdTable(t, m, nil, []int64{1, 2, 3, 4})
+	e := NewExecutor(t.TempDir(), 10, 10, 0)
+	p := planner.NewPlanner(m)
+
+	deletePlan, err := p.PlanDelete(openapi.DeleteQuery{TableName: "t1", WhereClause: binaryOp("EQUAL", idColumn(), int64Literal(2))})
+	if err != nil {
+		t.Fatalf("PlanDelete failed: %v", err)
+	}
+	if _, err := e.Execute(deletePlan); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	updated := runUpdate(t, e, p, openapi.UpdateQuery{
+		TableName:   "t1",
+		SetClauses:  []openapi.ColumnAssignment{{ColumnName: "id", Expression: int64Literal(0)}},
+		WhereClause: binaryOp("EQUAL", idColumn(), int64Literal(4)),
+	})
+	if updated != 1 {
+		t.Errorf("Expected 1 updated row, got %d", updated)
+	}
+	if ids := readTableIds(t, m, tableId); !reflect.DeepEqual(ids, [][]int64{{1, 3, 0}}) {
+		t.Errorf("Expected the deleted row to be left out of the rewritten file, got %v", ids)
+	}
+	if deleted, _ := m.DeletedRows(tableId); len(deleted) != 0 {
+		t.Errorf("Expected no deleted rows after the rewrite, got %v", deleted)
+	}
+}
+
+func TestExecuteUpdate_KeepsClusteredTableSorted(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	tableId := createIdTable(t, m, []metadata.SortKey{{Column: "id", Ascending: true}}, []int64{1, 2, 3, 4})
+	e := NewExecutor(t.TempDir(), 10, 10, 0)
+
+	// id = 10 - id reverses the order of all rows
+	runUpdate(t, e, planner.NewPlanner(m), openapi.UpdateQuery{
+		TableName:  "t1",
+		SetClauses: []openapi.ColumnAssignment{{ColumnName: "id", Expression: binaryOp("SUBTRACT", int64Literal(10), idColumn())}},
+	})
+	ids := readTableIds(t, m, tableId)
+	if len(ids) != 1 || !slices.IsSorted(ids[0]) || !reflect.DeepEqual(ids[0], []int64{6, 7, 8, 9}) {
+		t.Errorf("Expected the rewritten file to be sorted, got %v", ids)
+	}
+}
+
+func TestExecuteUpdate_ConcurrentModificationFails(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	tableId := createIdTable(t, m, nil, []int64{1, 2})
+	e := NewExecutor(t.TempDir(), 10, 10, 0)
+	p := planner.NewPlanner(m)
+
+	query := openapi.UpdateQuery{
+		TableName:  "t1",
+		SetClauses: []openapi.ColumnAssignment{{ColumnName: "id", Expression: binaryOp("ADD", idColumn(), int64Literal(1))}},
+	}
+	first, err := p.PlanUpdate(query)
+	if err != nil {
+		t.Fatalf("PlanUpdate failed: %v", err)
+	}
+	second, err := p.PlanUpdate(query)
+	if err != nil {
+		t.Fatalf("PlanUpdate failed: %v", err)
+	}
+
+	if _, err := e.Execute(first); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	entries, _ := os.ReadDir(m.DatabaseDir(metadata.DefaultDatabase))
+	if _, err := e.Execute(second); err == nil {
+		t.Fatalf("Expected the update of a replaced file to fail")
+	}
+
+	if ids := readTableIds(t, m, tableId); !reflect.DeepEqual(ids, [][]int64{{2, 3}}) {
+		t.Errorf("Expected only the first update to be applied, got %v", ids)
+	}
+	if after, _ := os.ReadDir(m.DatabaseDir(metadata.DefaultDatabase)); len(after) != len(entries) {
+		t.Errorf("Expected files written by the failed update to be removed, got %d files instead of %d", len(after), len(entries))
+	}
+}
@@ -0,0 +1,55 @@
+package expr
+
+import (
+	"fmt"
+	"isbd4/pkg/engine/types"
+)
+
+// AggregateFunction computes a single value of a group of rows, it is evaluated by the aggregation operator
+type AggregateFunction string
+
+const (
+	Count AggregateFunction = "COUNT"
+	Sum   AggregateFunction = "SUM"
+	Min   AggregateFunction = "MIN"
+	Max   AggregateFunction = "MAX"
+	Avg   AggregateFunction = "AVG" // mean truncated toward zero, like DIVIDE of INT64 values
+)
+
+func AggregateFunctionFromString(name string) (AggregateFunction, error) {
+	switch fn := AggregateFunction(name); fn {
+	case Count, Sum, Min, Max, Avg:
+		return fn, nil
+	default:
+		return "", fmt.Errorf("unknown aggregate function: %s", name)
+	}
+}
+
+// AggregateResultType checks the argument of the aggregate and returns the type of its result,
+// the argument is nil for COUNT of all rows
+func AggregateResultType(fn AggregateFunction, arg Expression) (types.ChunkColumnType, error) {
+	if arg == nil {
+		if fn != Count {
+			return 0, fmt.Errorf("%s expects 1 argument", fn)
+		}
+		return types.ChunkColumnTypeInt64, nil
+	}
+
+	argType := arg.ResultType()
+	switch fn {
+	case Count:
+		return types.ChunkColumnTypeInt64, nil
+	case Sum, Avg:
+		if argType != types.ChunkColumnTypeInt64 {
+			return 0, fmt.Errorf("%s argument must be INT64", fn)
+		}
+		return types.ChunkColumnTypeInt64, nil
+	case Min, Max:
+		if argType == types.ChunkColumnTypeBoolean {
+			return 0, fmt.Errorf("%s argument must be INT64 or VARCHAR", fn)
+		}
+		return argType, nil
+	default:
+		return 0, fmt.Errorf("unsupported aggregate function: %s", fn)
+	}
+}
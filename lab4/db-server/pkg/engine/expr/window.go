@@ -0,0 +1,58 @@
+package expr
+
+import (
+	"fmt"
+	"isbd4/pkg/engine/types"
+)
+
+// WindowFunction computes a value of every row from rows of its partition, it is evaluated by the window operator.
+// Aggregate functions are window functions too, computed over the frame of the row.
+type WindowFunction string
+
+const (
+	RowNumber WindowFunction = "ROW_NUMBER"
+	Rank      WindowFunction = "RANK"       // number of rows ordered before the row plus 1, peers get the same rank
+	DenseRank WindowFunction = "DENSE_RANK" // number of distinct orderings before the row plus 1
+	Lag       WindowFunction = "LAG"
+	Lead      WindowFunction = "LEAD"
+)
+
+func WindowFunctionFromString(name string) (WindowFunction, error) {
+	switch fn := WindowFunction(name); fn {
+	case RowNumber, Rank, DenseRank, Lag, Lead:
+		return fn, nil
+	default:
+		if _, err := AggregateFunctionFromString(name); err != nil {
+			return "", fmt.Errorf("unknown window function: %s", name)
+		}
+		return fn, nil
+	}
+}
+
+// IsAggregate reports whether the function aggregates the frame of the row
+func (fn WindowFunction) IsAggregate() bool {
+	switch fn {
+	case RowNumber, Rank, DenseRank, Lag, Lead:
+		return false
+	default:
+		return true
+	}
+}
+
+// WindowResultType checks the argument of the window function and returns the type of its result
+func WindowResultType(fn WindowFunction, arg Expression) (types.ChunkColumnType, error) {
+	switch fn {
+	case RowNumber, Rank, DenseRank:
+		if arg != nil {
+			return 0, fmt.Errorf("%s expects no arguments", fn)
+		}
+		return types.ChunkColumnTypeInt64, nil
+	case Lag, Lead:
+		if arg == nil {
+			return 0, fmt.Errorf("%s expects 1 argument", fn)
+		}
+		return arg.ResultType(), nil
+	default:
+		return AggregateResultType(AggregateFunction(fn), arg)
+	}
+}
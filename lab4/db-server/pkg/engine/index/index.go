@@ -0,0 +1,302 @@
+package index
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+// Columns of index files, rows are sorted by key, then by file and row
+const (
+	keyColumn  = "key"
+	fileColumn = "file"
+	rowColumn  = "row"
+)
+
+// KeyRange bounds keys looked up in the index, nil bounds are unbounded
+type KeyRange struct {
+	Lower          *metadata.ColumnBound
+	Upper          *metadata.ColumnBound
+	LowerInclusive bool
+	UpperInclusive bool
+}
+
+// RestrictLower narrows the range to keys above the bound
+func (r *KeyRange) RestrictLower(bound *metadata.ColumnBound, inclusive bool) {
+	if r.Lower == nil {
+		r.Lower, r.LowerInclusive = bound, inclusive
+		return
+	}
+	if c := bound.Compare(r.Lower); c > 0 || (c == 0 && !inclusive) {
+		r.Lower, r.LowerInclusive = bound, inclusive
+	}
+}
+
+// RestrictUpper narrows the range to keys below the bound
+func (r *KeyRange) RestrictUpper(bound *metadata.ColumnBound, inclusive bool) {
+	if r.Upper == nil {
+		r.Upper, r.UpperInclusive = bound, inclusive
+		return
+	}
+	if c := bound.Compare(r.Upper); c < 0 || (c == 0 && !inclusive) {
+		r.Upper, r.UpperInclusive = bound, inclusive
+	}
+}
+
+// IsPoint reports whether the range holds a single key
+func (r *KeyRange) IsPoint() bool {
+	return r.Lower != nil && r.Upper != nil && r.LowerInclusive && r.UpperInclusive && r.Lower.Compare(r.Upper) == 0
+}
+
+// overlaps returns false only if no key between min and max is in the range
+func (r *KeyRange) overlaps(min, max *metadata.ColumnBound) bool {
+	if min == nil || max == nil {
+		return true
+	}
+	if r.Lower != nil {
+		if c := max.Compare(r.Lower); c < 0 || (c == 0 && !r.LowerInclusive) {
+			return false
+		}
+	}
+	if r.Upper != nil {
+		if c := min.Compare(r.Upper); c > 0 || (c == 0 && !r.UpperInclusive) {
+			return false
+		}
+	}
+	return true
+}
+
+// Build indexes files of the current version of the table which are not yet covered by the index on the column,
+// the index is created first if it doesn't exist. Returns the number of newly indexed files.
+func Build(m *metadata.Metastore, tableId string, column string) (int, error) {
+	if err := m.CreateIndex(tableId, column); err != nil {
+		return 0, err
+	}
+	return indexFiles(m, tableId, column, nil)
+}
+
+// Update indexes the data files by every index of the table, e.g. files just written by COPY
+func Update(m *metadata.Metastore, tableId string, dataFiles []string) error {
+	infos, exists := m.GetIndexes(tableId)
+	if !exists {
+		return fmt.Errorf("table %s does not exist", tableId)
+	}
+
+	wanted := make(map[string]bool, len(dataFiles))
+	for _, path := range dataFiles {
+		wanted[path] = true
+	}
+	for _, info := range infos {
+		if _, err := indexFiles(m, tableId, info.Column, wanted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexFiles writes a single index file covering current files of the table not covered by the index yet,
+// limited to the wanted files unless it is nil
+func indexFiles(m *metadata.Metastore, tableId string, column string, wanted map[string]bool) (int, error) {
+	tableDef, exists := m.GetTableById(tableId)
+	if !exists {
+		return 0, fmt.Errorf("table %s does not exist", tableId)
+	}
+	snapshot, err := m.GetTableSnapshotById(tableId)
+	if err != nil {
+		return 0, err
+	}
+	defer snapshot.Release()
+
+	snapshot.Retain(func(f *metadata.FileEntry) bool {
+		if wanted != nil && !wanted[f.Path] {
+			return false
+		}
+		for _, indexFile := range snapshot.Indexes[column] {
+			if indexFile.Covers(f.Path) {
+				return false
+			}
+		}
+		return true
+	})
+	if len(snapshot.Files) == 0 {
+		return 0, nil
+	}
+
+	dataFiles := metadata.FileNames(snapshot.Files)
+	table, err := buildIndexTable(dataFiles, column)
+	if err != nil {
+		return 0, err
+	}
+
+	fileName := fmt.Sprintf("%s_index_%s_%d.tomy", tableDef.Name, column, time.Now().UnixNano())
+	outPath := filepath.Join(m.DatabaseDir(tableDef.Database), fileName)
+	if err := table.Serialize(outPath); err != nil {
+		return 0, fmt.Errorf("failed to serialize index: %w", err)
+	}
+	stats, err := metadata.FileStatsFromFooter(outPath)
+	if err != nil {
+		os.Remove(outPath)
+		return 0, fmt.Errorf("failed to collect index statistics: %w", err)
+	}
+
+	if err := m.AddIndexFile(tableId, column, metadata.NewFile{Path: outPath, Stats: stats}, dataFiles); err != nil {
+		os.Remove(outPath)
+		return 0, err
+	}
+	return len(dataFiles), nil
+}
+
+type entry[K cmp.Ordered] struct {
+	key  K
+	file string
+	row  int64
+}
+
+// buildIndexTable reads the column of the data files and returns its (key, file, row) entries sorted
+func buildIndexTable(dataFiles []string, column string) (*tomy_file.ColumnarTable, error) {
+	var intEntries []entry[int64]
+	var varcharEntries []entry[string]
+	isVarchar := false
+
+	for _, path := range dataFiles {
+		data, err := tomy_file.DeserializeColumns(path, []string{column})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+		if len(data.Columns) != 1 {
+			return nil, fmt.Errorf("column %s not found in file %s", column, path)
+		}
+
+		switch c := data.Columns[0].(type) {
+		case *tomy_file.Int64Column:
+			for i, v := range c.Values {
+				intEntries = append(intEntries, entry[int64]{key: v, file: path, row: int64(i)})
+			}
+		case *tomy_file.VarcharColumn:
+			isVarchar = true
+			for i := range c.Offsets {
+				varcharEntries = append(varcharEntries, entry[string]{key: varcharValue(c, i), file: path, row: int64(i)})
+			}
+		default:
+			return nil, fmt.Errorf("unsupported column type: %T", c)
+		}
+	}
+
+	if isVarchar {
+		return sortedTable(varcharEntries, func(keys []string) tomy_file.AnyColumn {
+			col := &tomy_file.VarcharColumn{Name: keyColumn, Offsets: make([]uint64, 0, len(keys))}
+			for _, k := range keys {
+				col.Offsets = append(col.Offsets, uint64(len(col.Data)))
+				col.Data = append(col.Data, k...)
+			}
+			return col
+		}), nil
+	}
+	return sortedTable(intEntries, func(keys []int64) tomy_file.AnyColumn {
+		return &tomy_file.Int64Column{Name: keyColumn, Values: keys}
+	}), nil
+}
+
+func sortedTable[K cmp.Ordered](entries []entry[K], keyCol func([]K) tomy_file.AnyColumn) *tomy_file.ColumnarTable {
+	slices.SortFunc(entries, func(a, b entry[K]) int {
+		return cmp.Or(cmp.Compare(a.key, b.key), cmp.Compare(a.file, b.file), cmp.Compare(a.row, b.row))
+	})
+
+	keys := make([]K, len(entries))
+	files := &tomy_file.VarcharColumn{Name: fileColumn, Offsets: make([]uint64, 0, len(entries))}
+	rows := &tomy_file.Int64Column{Name: rowColumn, Values: make([]int64, len(entries))}
+	for i, e := range entries {
+		keys[i] = e.key
+		files.Offsets = append(files.Offsets, uint64(len(files.Data)))
+		files.Data = append(files.Data, e.file...)
+		rows.Values[i] = e.row
+	}
+
+	return &tomy_file.ColumnarTable{
+		NumRows:   uint64(len(entries)),
+		Columns:   []tomy_file.AnyColumn{keyCol(keys), files, rows},
+		SortOrder: []tomy_file.SortColumn{{ColumnIdx: 0, Ascending: true}, {ColumnIdx: 1, Ascending: true}, {ColumnIdx: 2, Ascending: true}},
+	}
+}
+
+// Lookup returns sorted positions of rows of the data files with keys in the range, by data file.
+// Data files without any matching row are left out.
+func Lookup(indexFiles []*metadata.IndexFile, keys KeyRange, dataFiles map[string]bool) (map[string][]uint64, error) {
+	rows := make(map[string][]uint64)
+	for _, f := range indexFiles {
+		if f.File.Stats != nil && len(f.File.Stats.Columns) > 0 {
+			keyStats := f.File.Stats.Columns[0]
+			if !keys.overlaps(keyStats.Min, keyStats.Max) {
+				continue
+			}
+		}
+
+		table, err := tomy_file.Deserialize(f.File.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read index file %s: %w", f.File.Path, err)
+		}
+		if len(table.Columns) != 3 {
+			return nil, fmt.Errorf("index file %s is malformed", f.File.Path)
+		}
+
+		var start, end int
+		switch c := table.Columns[0].(type) {
+		case *tomy_file.Int64Column:
+			start, end = searchRange(len(c.Values), keys, func(i int) *metadata.ColumnBound {
+				return &metadata.ColumnBound{Int64: &c.Values[i]}
+			})
+		case *tomy_file.VarcharColumn:
+			start, end = searchRange(len(c.Offsets), keys, func(i int) *metadata.ColumnBound {
+				v := varcharValue(c, i)
+				return &metadata.ColumnBound{Varchar: &v}
+			})
+		}
+
+		files := table.Columns[1].(*tomy_file.VarcharColumn)
+		positions := table.Columns[2].(*tomy_file.Int64Column)
+		for i := start; i < end; i++ {
+			if file := varcharValue(files, i); dataFiles[file] {
+				rows[file] = append(rows[file], uint64(positions.Values[i]))
+			}
+		}
+	}
+
+	for _, positions := range rows {
+		slices.Sort(positions)
+	}
+	return rows, nil
+}
+
+// searchRange returns the half-open range of sorted keys which are in the range
+func searchRange(n int, keys KeyRange, keyAt func(i int) *metadata.ColumnBound) (int, int) {
+	start := 0
+	if keys.Lower != nil {
+		start = sort.Search(n, func(i int) bool {
+			c := keyAt(i).Compare(keys.Lower)
+			return c > 0 || (c == 0 && keys.LowerInclusive)
+		})
+	}
+	end := n
+	if keys.Upper != nil {
+		end = sort.Search(n, func(i int) bool {
+			c := keyAt(i).Compare(keys.Upper)
+			return c > 0 || (c == 0 && !keys.UpperInclusive)
+		})
+	}
+	return start, max(start, end)
+}
+
+func varcharValue(c *tomy_file.VarcharColumn, i int) string {
+	end := uint64(len(c.Data))
+	if i+1 < len(c.Offsets) {
+		end = c.Offsets[i+1]
+	}
+	return string(c.Data[c.Offsets[i]:end])
+}
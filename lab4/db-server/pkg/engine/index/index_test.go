@@ -0,0 +1,112 @@
+package index
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+func addFile(t *testing.T, m *metadata.Metastore, tableName string, ids []int64) string {
+	t.Helper()
+	names := &tomy_file.VarcharColumn{Name: "name"}
+	for _, id := range ids {
+		names.Offsets = append(names.Offsets, uint64(len(names.Data)))
+		names.Data = append(names.Data, fmt.Sprintf("row%d", id)...)
+	}
+	table := tomy_file.ColumnarTable{
+		NumRows: uint64(len(ids)),
+		Columns: []tomy_file.AnyColumn{&tomy_file.Int64Column{Name: "id", Values: ids}, names},
+	}
+
+	path := filepath.Join(m.DatabaseDir(metadata.DefaultDatabase), fmt.Sprintf("f_%d.tomy", time.Now().UnixNano()))
+	if err := table.Serialize(path); err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	stats, err := metadata.FileStatsFromFooter(path)
+	if err != nil {
+		t.Fatalf("FileStatsFromFooter failed: %v", err)
+	}
+	if err := m.AddFile(tableName, path, stats); err != nil {
+		t.Fatalf("AddFile failed: %v", err)
+	}
+	return path
+}
+
+func int64Bound(v int64) *metadata.ColumnBound {
+	return &metadata.ColumnBound{Int64: &v}
+}
+
+func TestIndex_BuildAndLookup(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	tableId, err := m.CreateTable("t1", []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "name", Type: metadata.VarcharType},
+	}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	f1 := addFile(t, m, "t1", []int64{5, 1, 9, 5})
+	f2 := addFile(t, m, "t1", []int64{7, 3})
+
+	if indexed, err := Build(m, tableId, "id"); err != nil || indexed != 2 {
+		t.Fatalf("Expected 2 indexed files, got %d, %v", indexed, err)
+	}
+	if indexed, err := Build(m, tableId, "id"); err != nil || indexed != 0 {
+		t.Fatalf("Expected no files to index again, got %d, %v", indexed, err)
+	}
+	f3 := addFile(t, m, "t1", []int64{5})
+
+	snapshot, err := m.GetTableSnapshotById(tableId)
+	if err != nil {
+		t.Fatalf("GetTableSnapshotById failed: %v", err)
+	}
+	defer snapshot.Release()
+	indexFiles := snapshot.Indexes["id"]
+	if len(indexFiles) != 1 || !indexFiles[0].Covers(f1) || !indexFiles[0].Covers(f2) || indexFiles[0].Covers(f3) {
+		t.Fatalf("Expected one index file covering the first two files, got %+v", indexFiles)
+	}
+	covered := map[string]bool{f1: true, f2: true}
+
+	point := KeyRange{}
+	point.RestrictLower(int64Bound(5), true)
+	point.RestrictUpper(int64Bound(5), true)
+	rows, err := Lookup(indexFiles, point, covered)
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if expected := map[string][]uint64{f1: {0, 3}}; !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected rows %v, got %v", expected, rows)
+	}
+
+	between := KeyRange{}
+	between.RestrictLower(int64Bound(1), false)
+	between.RestrictUpper(int64Bound(7), true)
+	between.RestrictUpper(int64Bound(8), true) // looser bound is ignored
+	rows, err = Lookup(indexFiles, between, covered)
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if expected := map[string][]uint64{f1: {0, 3}, f2: {0, 1}}; !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected rows %v, got %v", expected, rows)
+	}
+
+	if err := Update(m, tableId, []string{f3}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	infos, _ := m.GetIndexes(tableId)
+	if len(infos) != 1 || infos[0].IndexFiles != 2 || infos[0].IndexedFiles != 3 {
+		t.Errorf("Expected all files to be indexed, got %+v", infos)
+	}
+
+	if err := m.DropIndex(tableId, "id"); err != nil {
+		t.Fatalf("DropIndex failed: %v", err)
+	}
+	if infos, _ := m.GetIndexes(tableId); len(infos) != 0 {
+		t.Errorf("Expected no indexes after drop, got %+v", infos)
+	}
+}
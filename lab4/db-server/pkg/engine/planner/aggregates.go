@@ -0,0 +1,200 @@
+package planner
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/types"
+)
+
+// hasAggregates reports whether the query groups rows, i.e. it has a group by or having clause or aggregates
+// in column clauses. Queries without group by clause aggregate all rows as a single group.
+func hasAggregates(apiQueryDef openapi.SelectQuery) bool {
+	if len(apiQueryDef.GroupByClause) > 0 || apiQueryDef.HavingClause.Expression != nil {
+		return true
+	}
+	for _, col := range apiQueryDef.ColumnClauses {
+		if containsAggregate(col) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAggregate(col openapi.ColumnExpression) bool {
+	switch e := col.Expression.(type) {
+	case openapi.AggregateFunction:
+		return true
+	case openapi.ColumnarBinaryOperation:
+		return containsAggregate(e.LeftOperand) || containsAggregate(e.RightOperand)
+	case openapi.ColumnarUnaryOperation:
+		return containsAggregate(e.Operand)
+	case openapi.Function:
+		for _, arg := range e.Arguments {
+			if containsAggregate(arg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// aggregationMapper maps expressions evaluated after grouping: group by expressions and aggregates
+// become references to output columns of the aggregation, other column references are rejected
+type aggregationMapper struct {
+	base        *Mapper
+	aggregation *Aggregation
+	usedNames   map[string]bool
+}
+
+func newAggregationMapper(base *Mapper, groupByClause []openapi.ColumnExpression) (*aggregationMapper, error) {
+	am := &aggregationMapper{
+		base:        base,
+		aggregation: &Aggregation{},
+		usedNames:   make(map[string]bool),
+	}
+	ve := &types.ValidationError{}
+
+	for i, clause := range groupByClause {
+		context := fmt.Sprintf("GroupByClause %d", i)
+		if containsAggregate(clause) {
+			ve.Add("aggregate functions are not allowed in group by clause", context)
+			continue
+		}
+		groupExpr, err := base.MapExpression(clause)
+		if err != nil {
+			extendWithContext(ve, err, context)
+			continue
+		}
+		if am.findGroup(groupExpr) >= 0 {
+			continue
+		}
+
+		var name string
+		if colRef, ok := groupExpr.(*expr.ColumnRefExpr); ok && !am.usedNames[colRef.ColName] {
+			name = am.uniqueName(colRef.ColName)
+		} else {
+			name = am.uniqueName(fmt.Sprintf("group_%d", i))
+		}
+		am.aggregation.GroupBy = append(am.aggregation.GroupBy, groupExpr)
+		am.aggregation.GroupNames = append(am.aggregation.GroupNames, name)
+	}
+
+	if ve.HasProblems() {
+		return nil, ve
+	}
+	return am, nil
+}
+
+func (am *aggregationMapper) findGroup(e expr.Expression) int {
+	for i, groupExpr := range am.aggregation.GroupBy {
+		if reflect.DeepEqual(groupExpr, e) {
+			return i
+		}
+	}
+	return -1
+}
+
+// mapper returns a mapper of expressions evaluated on the output of the aggregation
+func (am *aggregationMapper) mapper() *Mapper {
+	return &Mapper{
+		tableName:  am.base.tableName,
+		alias:      am.base.alias,
+		nameToType: am.base.nameToType,
+		tables:     am.base.tables,
+		replace:    am.replace,
+	}
+}
+
+func (am *aggregationMapper) replace(apiExpr openapi.ColumnExpression) (expr.Expression, error) {
+	if e, ok := apiExpr.Expression.(openapi.AggregateFunction); ok {
+		return am.mapAggregate(e)
+	}
+	if _, ok := apiExpr.Expression.(openapi.Literal); ok {
+		return nil, nil
+	}
+
+	// expressions listed in the group by clause are computed before grouping, tables names are ignored by the mapping
+	if !containsAggregate(apiExpr) {
+		if mapped, err := am.base.MapExpression(apiExpr); err == nil {
+			if idx := am.findGroup(mapped); idx >= 0 {
+				return &expr.ColumnRefExpr{ColName: am.aggregation.GroupNames[idx], ColType: mapped.ResultType()}, nil
+			}
+		}
+	}
+
+	if colRef, ok := apiExpr.Expression.(openapi.ColumnReferenceExpression); ok {
+		if len(am.aggregation.GroupBy) == 0 {
+			return nil, fmt.Errorf("column %s cannot be mixed with aggregate functions unless it is used in an aggregate function or group by clause", colRef.ColumnName)
+		}
+		return nil, fmt.Errorf("column %s must appear in group by clause or be used in an aggregate function", colRef.ColumnName)
+	}
+	return nil, nil
+}
+
+func (am *aggregationMapper) mapAggregate(apiAgg openapi.AggregateFunction) (expr.Expression, error) {
+	fn, err := expr.AggregateFunctionFromString(apiAgg.AggregateName)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiAgg.TableName != "" {
+		if am.base.tables != nil {
+			if _, err := am.base.findJoinedTable(apiAgg.TableName); err != nil {
+				return nil, err
+			}
+		} else if !am.base.refersToQueryTable(apiAgg.TableName) {
+			return nil, fmt.Errorf("aggregate function %s refers to table %s, but query is on table %s", fn, apiAgg.TableName, am.base.tableName)
+		}
+	}
+
+	var arg expr.Expression
+	if apiAgg.Argument != nil {
+		if containsAggregate(*apiAgg.Argument) {
+			return nil, fmt.Errorf("aggregate function %s cannot contain other aggregate functions", fn)
+		}
+		if arg, err = am.base.MapExpression(*apiAgg.Argument); err != nil {
+			return nil, err
+		}
+	}
+	resultType, err := expr.AggregateResultType(fn, arg)
+	if err != nil {
+		return nil, err
+	}
+	if apiAgg.Distinct && (fn != expr.Count || arg == nil) {
+		return nil, fmt.Errorf("distinct is allowed only in COUNT with an argument")
+	}
+
+	// aggregates with the same function and argument are computed once
+	for _, call := range am.aggregation.Aggregates {
+		if call.Function == fn && call.Distinct == apiAgg.Distinct && reflect.DeepEqual(call.Argument, arg) {
+			return &expr.ColumnRefExpr{ColName: call.Name, ColType: resultType}, nil
+		}
+	}
+
+	prefix := strings.ToLower(string(fn))
+	if apiAgg.Distinct {
+		prefix += "_distinct"
+	}
+	name := am.uniqueName(fmt.Sprintf("%s_%d", prefix, len(am.aggregation.Aggregates)))
+	am.aggregation.Aggregates = append(am.aggregation.Aggregates, AggregateCall{
+		Function:   fn,
+		Argument:   arg,
+		Distinct:   apiAgg.Distinct,
+		Name:       name,
+		ResultType: resultType,
+	})
+	return &expr.ColumnRefExpr{ColName: name, ColType: resultType}, nil
+}
+
+func (am *aggregationMapper) uniqueName(name string) string {
+	unique := name
+	for i := 1; am.usedNames[unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	am.usedNames[unique] = true
+	return unique
+}
@@ -0,0 +1,187 @@
+package planner
+
+import (
+	"fmt"
+	"testing"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+)
+
+func aggregateExpr(name string, arg *openapi.ColumnExpression) openapi.ColumnExpression {
+	return openapi.ColumnExpression{Expression: openapi.AggregateFunction{AggregateName: name, Argument: arg}}
+}
+
+func TestValidateAndMapQuery_GroupBy(t *testing.T) {
+	snapshot := &metadata.MetastoreSnapshot{Columns: []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "name", Type: metadata.VarcharType},
+	}}
+	colRef := func(table, name string) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{TableName: table, ColumnName: name}}
+	}
+	id := colRef("t1", "id")
+
+	queryDef, err := validateAndMapQuery(openapi.SelectQuery{
+		ColumnClauses: []openapi.ColumnExpression{
+			colRef("", "name"),
+			aggregateExpr("COUNT", nil),
+			{Expression: openapi.ColumnarBinaryOperation{
+				Operator:     "ADD",
+				LeftOperand:  aggregateExpr("SUM", &id),
+				RightOperand: aggregateExpr("MAX", &id),
+			}},
+			aggregateExpr("SUM", &id),
+		},
+		GroupByClause: []openapi.ColumnExpression{colRef("t1", "name")},
+	}, "t1", snapshot)
+	if err != nil {
+		t.Fatalf("validateAndMapQuery failed: %v", err)
+	}
+
+	agg := queryDef.Aggregation
+	if agg == nil || len(agg.GroupBy) != 1 || agg.GroupNames[0] != "name" {
+		t.Fatalf("Expected grouping by name, got %+v", agg)
+	}
+	if len(agg.Aggregates) != 3 {
+		t.Fatalf("Expected COUNT, SUM and MAX computed once each, got %+v", agg.Aggregates)
+	}
+	if colRef, ok := queryDef.SelectExpr[3].(*expr.ColumnRefExpr); !ok || colRef.ColName != agg.Aggregates[1].Name {
+		t.Errorf("Expected SUM to refer to the output of the aggregation, got %+v", queryDef.SelectExpr[3])
+	}
+	if got := len(agg.InputExprs()); got != 3 {
+		t.Errorf("Expected group key and 2 arguments as input, got %d", got)
+	}
+
+	_, err = validateAndMapQuery(openapi.SelectQuery{
+		ColumnClauses: []openapi.ColumnExpression{
+			colRef("", "id"),
+			aggregateExpr("SUM", &openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{ColumnName: "name"}}),
+			aggregateExpr("MEDIAN", &id),
+		},
+		GroupByClause: []openapi.ColumnExpression{colRef("", "name"), aggregateExpr("COUNT", nil)},
+	}, "t1", snapshot)
+	ve, ok := err.(*types.ValidationError)
+	if !ok {
+		t.Fatalf("Expected validation error, got %v", err)
+	}
+	if len(ve.Problems) != 1 || ve.Problems[0].Context != "GroupByClause 1" {
+		t.Fatalf("Expected aggregate in group by clause to be rejected, got %+v", ve.Problems)
+	}
+
+	_, err = validateAndMapQuery(openapi.SelectQuery{
+		ColumnClauses: []openapi.ColumnExpression{
+			colRef("", "id"),
+			aggregateExpr("SUM", &openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{ColumnName: "name"}}),
+			aggregateExpr("MEDIAN", &id),
+		},
+		GroupByClause: []openapi.ColumnExpression{colRef("", "name")},
+	}, "t1", snapshot)
+	ve, ok = err.(*types.ValidationError)
+	if !ok || len(ve.Problems) != 3 {
+		t.Fatalf("Expected 3 validation problems, got %v", err)
+	}
+	for i, p := range ve.Problems {
+		if want := fmt.Sprintf("ColumnClause %d", i); p.Context != want {
+			t.Errorf("Expected problem %q in context %s, got %s", p.Error, want, p.Context)
+		}
+	}
+
+	_, err = validateAndMapQuery(openapi.SelectQuery{
+		ColumnClauses: []openapi.ColumnExpression{colRef("", "id")},
+		WhereClause:   aggregateExpr("COUNT", nil),
+		GroupByClause: []openapi.ColumnExpression{colRef("", "id")},
+	}, "t1", snapshot)
+	if err == nil {
+		t.Errorf("Expected aggregate in where clause to be rejected")
+	}
+}
+
+func TestValidateAndMapQuery_GlobalAggregatesAndHaving(t *testing.T) {
+	snapshot := &metadata.MetastoreSnapshot{Columns: []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "ts", Type: metadata.Int64Type},
+	}}
+	ts := openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{ColumnName: "ts"}}
+	countAll := openapi.ColumnExpression{Expression: openapi.AggregateFunction{AggregateName: "COUNT", TableName: "t1"}}
+	having := openapi.ColumnExpression{Expression: openapi.ColumnarBinaryOperation{
+		Operator:     "GREATER_THAN",
+		LeftOperand:  aggregateExpr("MIN", &ts),
+		RightOperand: openapi.ColumnExpression{Expression: openapi.Literal{Value: openapi.LiteralValue{Data: int64(10)}}},
+	}}
+
+	query := openapi.SelectQuery{
+		ColumnClauses: []openapi.ColumnExpression{countAll, aggregateExpr("MAX", &ts)},
+		HavingClause:  having,
+	}
+	if name, _ := extractTableName(query); name != "t1" {
+		t.Errorf("Expected table name of COUNT to be used, got %q", name)
+	}
+	queryDef, err := validateAndMapQuery(query, "t1", snapshot)
+	if err != nil {
+		t.Fatalf("validateAndMapQuery failed: %v", err)
+	}
+	agg := queryDef.Aggregation
+	if agg == nil || len(agg.GroupBy) != 0 {
+		t.Fatalf("Expected aggregation of all rows, got %+v", agg)
+	}
+	// MIN used only by the having clause is computed as well
+	if len(agg.Aggregates) != 3 || agg.Aggregates[2].Function != expr.Min {
+		t.Errorf("Expected COUNT, MAX and MIN, got %+v", agg.Aggregates)
+	}
+	if queryDef.HavingExpr == nil || queryDef.HavingExpr.ResultType() != types.ChunkColumnTypeBoolean {
+		t.Errorf("Expected boolean having expression, got %+v", queryDef.HavingExpr)
+	}
+
+	_, err = validateAndMapQuery(openapi.SelectQuery{
+		ColumnClauses: []openapi.ColumnExpression{countAll, ts},
+		HavingClause:  aggregateExpr("MAX", &ts),
+	}, "t1", snapshot)
+	ve, ok := err.(*types.ValidationError)
+	if !ok || len(ve.Problems) != 2 {
+		t.Fatalf("Expected 2 validation problems, got %v", err)
+	}
+	if ve.Problems[0].Context != "ColumnClause 1" || ve.Problems[1].Context != "HavingClause" {
+		t.Errorf("Expected problems of the non-aggregated column and of the having clause, got %+v", ve.Problems)
+	}
+}
+
+func TestValidateAndMapQuery_Distinct(t *testing.T) {
+	snapshot := &metadata.MetastoreSnapshot{Columns: []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "name", Type: metadata.VarcharType},
+	}}
+	id := openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{ColumnName: "id"}}
+	countDistinct := func(arg *openapi.ColumnExpression) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.AggregateFunction{AggregateName: "COUNT", Argument: arg, Distinct: true}}
+	}
+
+	queryDef, err := validateAndMapQuery(openapi.SelectQuery{
+		ColumnClauses: []openapi.ColumnExpression{countDistinct(&id), aggregateExpr("COUNT", &id), countDistinct(&id)},
+		Distinct:      true,
+	}, "t1", snapshot)
+	if err != nil {
+		t.Fatalf("validateAndMapQuery failed: %v", err)
+	}
+	if !queryDef.Distinct {
+		t.Errorf("Expected a distinct query")
+	}
+	aggregates := queryDef.Aggregation.Aggregates
+	if len(aggregates) != 2 || !aggregates[0].Distinct || aggregates[1].Distinct {
+		t.Fatalf("Expected COUNT DISTINCT and COUNT computed once each, got %+v", aggregates)
+	}
+	if aggregates[0].Name != "count_distinct_0" {
+		t.Errorf("Expected aggregate named count_distinct_0, got %s", aggregates[0].Name)
+	}
+
+	for _, clause := range []openapi.ColumnExpression{
+		countDistinct(nil),
+		{Expression: openapi.AggregateFunction{AggregateName: "SUM", Argument: &id, Distinct: true}},
+	} {
+		if _, err := validateAndMapQuery(openapi.SelectQuery{ColumnClauses: []openapi.ColumnExpression{clause}}, "t1", snapshot); err == nil {
+			t.Errorf("Expected %+v to be rejected", clause.Expression)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package planner
+
+import (
+	"fmt"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/types"
+)
+
+// scope holds a common table expression visible to a query and, through its parent, those defined before it
+// or by outer queries. Definitions shadow earlier ones with the same name.
+type scope struct {
+	parent     *scope // scope the query of the definition is planned in
+	definition openapi.CommonTableExpression
+	context    string // clause of the definition, the context of problems of its query
+}
+
+// with returns the scope of a query defining the common table expressions, each of them sees the preceding ones
+func (s *scope) with(ctes []openapi.CommonTableExpression) (*scope, error) {
+	ve := &types.ValidationError{}
+	defined := make(map[string]bool, len(ctes))
+	for i, cte := range ctes {
+		context := fmt.Sprintf("WithClause %d", i)
+		if err := validateAlias(cte.Name); err != nil {
+			ve.Add(err.Error(), context)
+			continue
+		}
+		if defined[cte.Name] {
+			ve.Add(fmt.Sprintf("common table expression %s is defined more than once", cte.Name), context)
+			continue
+		}
+		defined[cte.Name] = true
+		s = &scope{parent: s, definition: cte, context: context}
+	}
+
+	if ve.HasProblems() {
+		return nil, ve
+	}
+	return s, nil
+}
+
+// lookup returns the scope of the common table expression with the given name, nil if there is none
+func (s *scope) lookup(name string) *scope {
+	for ; s != nil; s = s.parent {
+		if s.definition.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// validateJoinedTables rejects derived tables in the from clause of a query with joins, which joins only stored tables
+func (s *scope) validateJoinedTables(from openapi.FromClause) error {
+	const msg = "subqueries and common table expressions cannot be joined"
+	ve := &types.ValidationError{}
+	if from.Subquery != nil || s.lookup(from.TableName) != nil {
+		ve.Add(msg, "FromClause")
+	}
+	for i, join := range from.Joins {
+		if s.lookup(join.TableName) != nil {
+			ve.Add(msg, fmt.Sprintf("JoinClause %d", i))
+		}
+	}
+
+	if ve.HasProblems() {
+		return ve
+	}
+	return nil
+}
+
+// planDerivedQuery plans a select reading the result of the source select, a subquery of the from clause or a common
+// table expression planned in its scope. Column references of the select resolve against the selected columns
+// of the source named by deriveColumnNames.
+func (p *Planner) planDerivedQuery(apiQueryDef openapi.SelectQuery, tableName string, source openapi.SelectQuery,
+	columnNames []string, s *scope, context string) (QueryPlan, error) {
+	if apiQueryDef.AsOf != nil {
+		return nil, fmt.Errorf("AS OF requires a query on a table")
+	}
+
+	ve := &types.ValidationError{}
+	sourcePlan, err := p.planSelectIn(source, s)
+	if err != nil {
+		prefixContext(ve, err, context)
+		return nil, ve
+	}
+	derived := &DerivedTable{Select: sourcePlan.(*SelectPlan)}
+	if derived.Columns, err = deriveColumnNames(source, columnNames); err != nil {
+		derived.Select.Release()
+		prefixContext(ve, err, context)
+		return nil, ve
+	}
+
+	mapper := newDerivedMapper(derived, tableName)
+	if from := apiQueryDef.FromClause; from != nil {
+		mapper.alias = from.Alias
+	}
+	selectQueryDef, err := validateAndMapQueryWith(apiQueryDef, tableName, mapper)
+	if err != nil {
+		derived.Select.Release()
+		return nil, err
+	}
+
+	return &SelectPlan{
+		QueryDef: selectQueryDef,
+		Derived:  derived,
+	}, nil
+}
+
+// deriveColumnNames names the columns of a derived table by the given names, by the column of column references
+// of the source or col_<index> for other expressions
+func deriveColumnNames(source openapi.SelectQuery, columnNames []string) ([]string, error) {
+	clauses := source.ColumnClauses
+	if len(columnNames) > 0 && len(columnNames) != len(clauses) {
+		return nil, types.NewVErr(fmt.Sprintf("got %d column names, expected %d", len(columnNames), len(clauses)), "ColumnNames")
+	}
+
+	ve := &types.ValidationError{}
+	seen := make(map[string]bool, len(clauses))
+	names := make([]string, len(clauses))
+	for i, clause := range clauses {
+		names[i] = fmt.Sprintf("col_%d", i)
+		if len(columnNames) > 0 {
+			names[i] = columnNames[i]
+		} else if colRef, ok := clause.Expression.(openapi.ColumnReferenceExpression); ok {
+			names[i] = colRef.ColumnName
+		}
+		if seen[names[i]] {
+			ve.Add(fmt.Sprintf("duplicate column name %s", names[i]), fmt.Sprintf("ColumnClause %d", i))
+		}
+		seen[names[i]] = true
+	}
+
+	if ve.HasProblems() {
+		return nil, ve
+	}
+	return names, nil
+}
@@ -0,0 +1,143 @@
+package planner
+
+import (
+	"reflect"
+	"testing"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+)
+
+func TestPlanSelect_DerivedTables(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	if _, err := m.CreateTable("orders", []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "customer", Type: metadata.VarcharType},
+		{Name: "price", Type: metadata.Int64Type},
+	}, nil); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	p := NewPlanner(m)
+
+	colRef := func(table, name string) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{TableName: table, ColumnName: name}}
+	}
+	literal := func(value int64) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.Literal{Value: openapi.LiteralValue{Data: value}}}
+	}
+	greater := func(left, right openapi.ColumnExpression) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.ColumnarBinaryOperation{Operator: "GREATER_THAN", LeftOperand: left, RightOperand: right}}
+	}
+	price := colRef("", "price")
+	totals := openapi.SelectQuery{
+		ColumnClauses: []openapi.ColumnExpression{
+			colRef("", "customer"),
+			{Expression: openapi.AggregateFunction{AggregateName: "SUM", Argument: &price}},
+		},
+		FromClause:    &openapi.FromClause{TableName: "orders"},
+		GroupByClause: []openapi.ColumnExpression{colRef("", "customer")},
+	}
+
+	// WITH totals(customer, total) AS (...), big AS (SELECT * FROM totals WHERE total > 100)
+	// SELECT b.customer FROM (SELECT customer, total > 1000 FROM big) AS b WHERE b.col_1
+	plan, err := p.PlanSelect(openapi.SelectQuery{
+		WithClause: []openapi.CommonTableExpression{
+			{Name: "totals", ColumnNames: []string{"customer", "total"}, Query: totals},
+			{Name: "big", Query: openapi.SelectQuery{
+				ColumnClauses: []openapi.ColumnExpression{colRef("", "customer"), colRef("totals", "total")},
+				FromClause:    &openapi.FromClause{TableName: "totals"},
+				WhereClause:   greater(colRef("", "total"), literal(100)),
+			}},
+		},
+		ColumnClauses: []openapi.ColumnExpression{colRef("b", "customer")},
+		FromClause: &openapi.FromClause{Alias: "b", Subquery: &openapi.SelectQuery{
+			ColumnClauses: []openapi.ColumnExpression{colRef("", "customer"), greater(colRef("", "total"), literal(1000))},
+			FromClause:    &openapi.FromClause{TableName: "big"},
+		}},
+		WhereClause: colRef("b", "col_1"),
+	})
+	if err != nil {
+		t.Fatalf("PlanSelect failed: %v", err)
+	}
+	defer plan.(*SelectPlan).Release()
+
+	outer := plan.(*SelectPlan)
+	if outer.Snapshot != nil || outer.Derived == nil || !reflect.DeepEqual(outer.Derived.Columns, []string{"customer", "col_1"}) {
+		t.Fatalf("Expected a derived table with columns customer and col_1, got %+v", outer.Derived)
+	}
+	if where, ok := outer.QueryDef.WhereExpr.(*expr.ColumnRefExpr); !ok || where.ColType != types.ChunkColumnTypeBoolean {
+		t.Errorf("Expected the where clause to refer to the BOOLEAN column of the derived table, got %+v", outer.QueryDef.WhereExpr)
+	}
+	big := outer.Derived.Select
+	if big.Derived == nil || !reflect.DeepEqual(big.Derived.Columns, []string{"customer", "total"}) {
+		t.Fatalf("Expected the subquery to read the common table expression big, got %+v", big.Derived)
+	}
+	grouped := big.Derived.Select.Derived.Select
+	if grouped.Snapshot == nil || grouped.QueryDef.Aggregation == nil {
+		t.Errorf("Expected totals to group rows of the table, got %+v", grouped)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		query    openapi.SelectQuery
+		problems []types.ErrWithCtx
+	}{
+		{
+			name: "subquery without alias",
+			query: openapi.SelectQuery{
+				ColumnClauses: []openapi.ColumnExpression{price},
+				FromClause:    &openapi.FromClause{Subquery: &totals},
+			},
+			problems: []types.ErrWithCtx{{Error: "subquery of the from clause requires an alias", Context: "FromClause"}},
+		},
+		{
+			name: "duplicate definition",
+			query: openapi.SelectQuery{
+				WithClause:    []openapi.CommonTableExpression{{Name: "t", Query: totals}, {Name: "t", Query: totals}},
+				ColumnClauses: []openapi.ColumnExpression{colRef("t", "customer")},
+			},
+			problems: []types.ErrWithCtx{{Error: "common table expression t is defined more than once", Context: "WithClause 1"}},
+		},
+		{
+			name: "invalid query of a definition",
+			query: openapi.SelectQuery{
+				WithClause:    []openapi.CommonTableExpression{{Name: "t", Query: totals}, {Name: "u", ColumnNames: []string{"a"}, Query: totals}},
+				ColumnClauses: []openapi.ColumnExpression{colRef("", "a")},
+				FromClause:    &openapi.FromClause{TableName: "u"},
+			},
+			problems: []types.ErrWithCtx{{Error: "got 1 column names, expected 2", Context: "WithClause 1, ColumnNames"}},
+		},
+		{
+			name: "column of the table hidden by the subquery",
+			query: openapi.SelectQuery{
+				ColumnClauses: []openapi.ColumnExpression{colRef("", "price")},
+				FromClause:    &openapi.FromClause{Alias: "s", Subquery: &totals},
+			},
+			problems: []types.ErrWithCtx{{Error: "column price not found in table s", Context: ""}},
+		},
+		{
+			name: "joined common table expression",
+			query: openapi.SelectQuery{
+				WithClause:    []openapi.CommonTableExpression{{Name: "t", Query: totals}},
+				ColumnClauses: []openapi.ColumnExpression{colRef("o", "id")},
+				FromClause: &openapi.FromClause{TableName: "orders", Alias: "o", Joins: []openapi.JoinClause{
+					{JoinType: "INNER", TableName: "t"},
+				}},
+			},
+			problems: []types.ErrWithCtx{{Error: "subqueries and common table expressions cannot be joined", Context: "JoinClause 0"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := p.PlanSelect(tc.query)
+			ve, ok := err.(*types.ValidationError)
+			if !ok {
+				t.Fatalf("Expected a validation error, got %v", err)
+			}
+			if !reflect.DeepEqual(ve.Problems, tc.problems) {
+				t.Errorf("Expected problems %v, got %v", tc.problems, ve.Problems)
+			}
+		})
+	}
+}
@@ -10,7 +10,13 @@ import (
 
 type Mapper struct {
 	tableName  string
+	alias      string // alias of the table given in the from clause
 	nameToType map[string]types.ChunkColumnType
+	// tables of a query with joins, their columns are mapped to "<alias>.<column>"
+	tables []*JoinedTable
+	// replace maps expressions computed by an earlier operator (e.g. aggregates) to references of its output,
+	// it returns nil for expressions which are mapped as usual
+	replace func(apiExpr openapi.ColumnExpression) (expr.Expression, error)
 }
 
 func NewMapper(snapshot *metadata.MetastoreSnapshot, tableName string) (*Mapper, error) {
@@ -35,6 +41,11 @@ func (m *Mapper) MapExpression(apiExpr openapi.ColumnExpression) (expr.Expressio
 	if apiExpr.Expression == nil {
 		return nil, fmt.Errorf("expression cannot be nil")
 	}
+	if m.replace != nil {
+		if e, err := m.replace(apiExpr); e != nil || err != nil {
+			return e, err
+		}
+	}
 
 	switch e := apiExpr.Expression.(type) {
 	case openapi.ColumnReferenceExpression:
@@ -47,13 +58,45 @@ func (m *Mapper) MapExpression(apiExpr openapi.ColumnExpression) (expr.Expressio
 		return m.mapUnaryOp(e)
 	case openapi.Function:
 		return m.mapFunction(e)
+	case openapi.AggregateFunction:
+		return nil, fmt.Errorf("aggregate function %s can be used only in column and having clauses", e.AggregateName)
+	case openapi.WindowFunction:
+		return nil, fmt.Errorf("window function %s can be used only in column clauses of queries without aggregates", e.WindowName)
+	case openapi.InSubquery, openapi.ExistsSubquery:
+		return nil, fmt.Errorf("subqueries can be used only as conditions of the where clause joined by AND")
 	default:
 		return nil, fmt.Errorf("unsupported expression type: %T", e)
 	}
 }
 
+// NewJoinMapper returns a mapper of expressions over rows of the joined tables
+func NewJoinMapper(tables []*JoinedTable) *Mapper {
+	return &Mapper{tables: tables}
+}
+
+// newDerivedMapper returns a mapper of expressions over rows of the derived table, its columns are referenced
+// by their names in the derived table qualified by the given table name or the alias
+func newDerivedMapper(derived *DerivedTable, tableName string) *Mapper {
+	nameToType := make(map[string]types.ChunkColumnType, len(derived.Columns))
+	for i, name := range derived.Columns {
+		nameToType[name] = derived.Select.QueryDef.SelectExpr[i].ResultType()
+	}
+	return &Mapper{tableName: tableName, nameToType: nameToType}
+}
+
+// refersToQueryTable reports whether the name given in a column reference names the table of the query
+func (m *Mapper) refersToQueryTable(tableName string) bool {
+	if m.alias != "" && tableName == m.alias {
+		return true
+	}
+	return metadata.QualifiedName(tableName) == metadata.QualifiedName(m.tableName)
+}
+
 func (m *Mapper) mapColumnReference(apiColRef openapi.ColumnReferenceExpression) (expr.Expression, error) {
-	if apiColRef.TableName != "" && apiColRef.TableName != m.tableName {
+	if m.tables != nil {
+		return m.mapJoinedColumnReference(apiColRef)
+	}
+	if apiColRef.TableName != "" && !m.refersToQueryTable(apiColRef.TableName) {
 		return nil, fmt.Errorf("column %s refers to table %s, but query is on table %s", apiColRef.ColumnName, apiColRef.TableName, m.tableName)
 	}
 
@@ -68,6 +111,71 @@ func (m *Mapper) mapColumnReference(apiColRef openapi.ColumnReferenceExpression)
 	}, nil
 }
 
+// mapJoinedColumnReference resolves the column among the joined tables, a column of an unqualified reference
+// must be unique among them. Tables are referenced by their aliases or names.
+func (m *Mapper) mapJoinedColumnReference(apiColRef openapi.ColumnReferenceExpression) (expr.Expression, error) {
+	var candidates []*JoinedTable
+	if apiColRef.TableName != "" {
+		table, err := m.findJoinedTable(apiColRef.TableName)
+		if err != nil {
+			return nil, err
+		}
+		candidates = []*JoinedTable{table}
+	} else {
+		candidates = m.tables
+	}
+
+	var found *expr.ColumnRefExpr
+	for _, table := range candidates {
+		for _, col := range table.Snapshot.Columns {
+			if col.Name != apiColRef.ColumnName {
+				continue
+			}
+			if found != nil {
+				return nil, fmt.Errorf("column %s is ambiguous, qualify it with a table name", apiColRef.ColumnName)
+			}
+			colType, err := types.ChunkColumnTypeFromMetadataColumnType(col.Type)
+			if err != nil {
+				return nil, err
+			}
+			found = &expr.ColumnRefExpr{ColName: JoinedColumnName(table.Alias, col.Name), ColType: colType}
+		}
+	}
+	if found == nil {
+		if apiColRef.TableName != "" {
+			return nil, fmt.Errorf("column %s not found in table %s", apiColRef.ColumnName, apiColRef.TableName)
+		}
+		return nil, fmt.Errorf("column %s not found in joined tables", apiColRef.ColumnName)
+	}
+	return found, nil
+}
+
+func (m *Mapper) findJoinedTable(tableName string) (*JoinedTable, error) {
+	for _, table := range m.tables {
+		if table.Alias == tableName {
+			return table, nil
+		}
+	}
+	var found *JoinedTable
+	for _, table := range m.tables {
+		if metadata.QualifiedName(table.Name) == metadata.QualifiedName(tableName) {
+			if found != nil {
+				return nil, fmt.Errorf("table %s is joined more than once, refer to it by alias", tableName)
+			}
+			found = table
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("table %s is not joined by the query", tableName)
+	}
+	return found, nil
+}
+
+// JoinedColumnName is the name of a column of a joined table in joined batches
+func JoinedColumnName(alias string, column string) string {
+	return alias + "." + column
+}
+
 func (m *Mapper) mapLiteral(lit openapi.Literal) (expr.Expression, error) {
 	switch v := lit.Value.Data.(type) {
 	case int64:
@@ -0,0 +1,89 @@
+package planner
+
+import (
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/metadata"
+)
+
+// chooseIndexLookup returns a lookup of rows satisfying comparisons of an indexed column with literals
+// joined by AND at the top of the where expression, equality is preferred. Returns nil when no index applies.
+func chooseIndexLookup(snapshot *metadata.MetastoreSnapshot, whereExpr expr.Expression) *IndexLookup {
+	if whereExpr == nil || len(snapshot.Indexes) == 0 {
+		return nil
+	}
+
+	var lookups []*IndexLookup
+	byColumn := make(map[string]*IndexLookup)
+	for _, e := range conjuncts(whereExpr) {
+		binExpr, ok := e.(*expr.BinaryOpExpr)
+		if !ok {
+			continue
+		}
+		col, lit, op, ok := columnComparison(binExpr)
+		if !ok || len(snapshot.Indexes[col.ColName]) == 0 {
+			continue
+		}
+		bound, ok := literalBound(lit, col)
+		if !ok {
+			continue
+		}
+
+		lookup := byColumn[col.ColName]
+		if lookup == nil {
+			lookup = &IndexLookup{Column: col.ColName}
+		}
+		switch op {
+		case expr.Equal:
+			lookup.Keys.RestrictLower(bound, true)
+			lookup.Keys.RestrictUpper(bound, true)
+		case expr.GreaterThan:
+			lookup.Keys.RestrictLower(bound, false)
+		case expr.GreaterEqual:
+			lookup.Keys.RestrictLower(bound, true)
+		case expr.LessThan:
+			lookup.Keys.RestrictUpper(bound, false)
+		case expr.LessEqual:
+			lookup.Keys.RestrictUpper(bound, true)
+		default:
+			continue
+		}
+		if byColumn[col.ColName] == nil {
+			byColumn[col.ColName] = lookup
+			lookups = append(lookups, lookup)
+		}
+	}
+
+	var best *IndexLookup
+	for _, lookup := range lookups {
+		switch {
+		case best == nil:
+			best = lookup
+		case lookup.Keys.IsPoint() && !best.Keys.IsPoint():
+			best = lookup
+		case lookup.Keys.Lower != nil && lookup.Keys.Upper != nil && (best.Keys.Lower == nil || best.Keys.Upper == nil):
+			best = lookup
+		}
+	}
+	return best
+}
+
+func conjuncts(e expr.Expression) []expr.Expression {
+	if binExpr, ok := e.(*expr.BinaryOpExpr); ok && binExpr.Operator == expr.And {
+		return append(conjuncts(binExpr.Left), conjuncts(binExpr.Right)...)
+	}
+	return []expr.Expression{e}
+}
+
+// literalBound converts the literal to a bound of the column, returns false on a type mismatch
+func literalBound(lit *expr.LiteralExpr, col *expr.ColumnRefExpr) (*metadata.ColumnBound, bool) {
+	if lit.Type != col.ColType {
+		return nil, false
+	}
+	switch v := lit.Value.(type) {
+	case int64:
+		return &metadata.ColumnBound{Int64: &v}, true
+	case string:
+		return &metadata.ColumnBound{Varchar: &v}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,45 @@
+package planner
+
+import (
+	"testing"
+
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+)
+
+func TestChooseIndexLookup(t *testing.T) {
+	id := &expr.ColumnRefExpr{ColName: "id", ColType: types.ChunkColumnTypeInt64}
+	name := &expr.ColumnRefExpr{ColName: "name", ColType: types.ChunkColumnTypeVarchar}
+	lit := func(v int64) expr.Expression { return &expr.LiteralExpr{Value: v, Type: types.ChunkColumnTypeInt64} }
+	str := &expr.LiteralExpr{Value: "b", Type: types.ChunkColumnTypeVarchar}
+
+	snapshot := &metadata.MetastoreSnapshot{Indexes: map[string][]*metadata.IndexFile{
+		"id":   {{DataFiles: []string{"a"}}},
+		"name": {{DataFiles: []string{"a"}}},
+	}}
+
+	// range on id and equality on name, equality wins
+	where := compare(t,
+		compare(t, compare(t, lit(3), id, expr.LessThan), compare(t, id, lit(10), expr.LessEqual), expr.And),
+		compare(t, name, str, expr.Equal),
+		expr.And)
+	lookup := chooseIndexLookup(snapshot, where)
+	if lookup == nil || lookup.Column != "name" || !lookup.Keys.IsPoint() {
+		t.Fatalf("Expected point lookup on name, got %+v", lookup)
+	}
+
+	lookup = chooseIndexLookup(snapshot, compare(t, compare(t, lit(3), id, expr.LessThan), compare(t, id, lit(10), expr.LessEqual), expr.And))
+	if lookup == nil || lookup.Column != "id" || *lookup.Keys.Lower.Int64 != 3 || lookup.Keys.LowerInclusive ||
+		*lookup.Keys.Upper.Int64 != 10 || !lookup.Keys.UpperInclusive {
+		t.Fatalf("Expected lookup of id in (3, 10], got %+v", lookup)
+	}
+
+	// OR can't be answered by a single range
+	if lookup := chooseIndexLookup(snapshot, compare(t, compare(t, id, lit(1), expr.Equal), compare(t, id, lit(2), expr.Equal), expr.Or)); lookup != nil {
+		t.Errorf("Expected no lookup for OR, got %+v", lookup)
+	}
+	if lookup := chooseIndexLookup(&metadata.MetastoreSnapshot{}, compare(t, id, lit(1), expr.Equal)); lookup != nil {
+		t.Errorf("Expected no lookup without indexes, got %+v", lookup)
+	}
+}
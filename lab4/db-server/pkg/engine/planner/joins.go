@@ -0,0 +1,308 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+)
+
+// planJoinQuery plans a select reading tables of the from clause joined in order, every table is read
+// at the version given by AS OF
+func (p *Planner) planJoinQuery(apiQueryDef openapi.SelectQuery) (QueryPlan, error) {
+	from := apiQueryDef.FromClause
+	plan := &SelectPlan{}
+
+	ve := &types.ValidationError{}
+	aliases := make(map[string]bool)
+	addTable := func(tableName, alias, context string) {
+		if alias == "" {
+			_, alias = metadata.SplitQualifiedName(tableName)
+		} else if err := validateAlias(alias); err != nil {
+			ve.Add(err.Error(), context)
+			return
+		}
+		if aliases[alias] {
+			ve.Add(fmt.Sprintf("table alias %s is used more than once", alias), context)
+			return
+		}
+		aliases[alias] = true
+
+		table, err := p.joinedTable(tableName, apiQueryDef.AsOf)
+		if err != nil {
+			ve.Add(err.Error(), context)
+			return
+		}
+		table.Alias = alias
+		plan.Tables = append(plan.Tables, table)
+	}
+
+	addTable(from.TableName, from.Alias, "FromClause")
+	for i, join := range from.Joins {
+		addTable(join.TableName, join.Alias, fmt.Sprintf("JoinClause %d", i))
+	}
+	if ve.HasProblems() {
+		plan.Release()
+		return nil, ve
+	}
+
+	mapper := NewJoinMapper(plan.Tables)
+	for i, join := range from.Joins {
+		// the condition may refer only to the joined table and the tables preceding it
+		mappedJoin, err := mapJoin(join, NewJoinMapper(plan.Tables[:i+2]), plan.Tables[i+1].Alias)
+		if err != nil {
+			extendWithContext(ve, err, fmt.Sprintf("JoinClause %d", i))
+			continue
+		}
+		plan.Joins = append(plan.Joins, mappedJoin)
+	}
+
+	queryDef, err := validateAndMapQueryWith(apiQueryDef, from.TableName, mapper)
+	if err != nil {
+		ve.Extend(err)
+	}
+	if ve.HasProblems() {
+		plan.Release()
+		return nil, ve
+	}
+
+	plan.QueryDef = queryDef
+	setJoinedColumns(plan)
+	p.chooseJoinStrategies(plan)
+	return plan, nil
+}
+
+func validateAlias(alias string) error {
+	if strings.ContainsAny(alias, "./\\") {
+		return fmt.Errorf("table alias %s cannot contain '.', '/' or '\\'", alias)
+	}
+	return nil
+}
+
+func (p *Planner) joinedTable(tableName string, asOf *openapi.AsOfExpression) (*JoinedTable, error) {
+	if db, name := metadata.SplitQualifiedName(tableName); db == metadata.SystemDatabase {
+		table, exists := p.systemTables[name]
+		if !exists {
+			return nil, fmt.Errorf("system table %s does not exist", tableName)
+		}
+		return &JoinedTable{
+			Name:        tableName,
+			Snapshot:    &metadata.MetastoreSnapshot{Columns: table.Columns},
+			SystemTable: table.Rows(),
+		}, nil
+	}
+
+	snapshot, err := p.Metastore.GetTableSnapshot(tableName, asOfFromAPI(asOf))
+	if err != nil {
+		return nil, err
+	}
+	return &JoinedTable{Name: tableName, Snapshot: snapshot}, nil
+}
+
+func joinTypeFromString(joinType string) (JoinType, error) {
+	switch t := JoinType(joinType); t {
+	case InnerJoin, LeftJoin, RightJoin, FullJoin:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unknown join type: %s", joinType)
+	}
+}
+
+// mapJoin maps the join condition and splits it into equalities of keys of both sides and the rest
+func mapJoin(apiJoin openapi.JoinClause, mapper *Mapper, rightAlias string) (Join, error) {
+	joinType, err := joinTypeFromString(apiJoin.JoinType)
+	if err != nil {
+		return Join{}, err
+	}
+	join := Join{Type: joinType}
+	if apiJoin.Condition.Expression == nil {
+		return join, nil
+	}
+
+	condition, err := mapper.MapExpression(apiJoin.Condition)
+	if err != nil {
+		return Join{}, err
+	}
+	if condition.ResultType() != types.ChunkColumnTypeBoolean {
+		return Join{}, fmt.Errorf("join condition must return boolean")
+	}
+
+	isRight := func(e expr.Expression) (right bool, left bool) {
+		for _, col := range e.GetUsedColumns() {
+			if strings.HasPrefix(col, rightAlias+".") {
+				right = true
+			} else {
+				left = true
+			}
+		}
+		return right, left
+	}
+
+	var rest []expr.Expression
+	for _, conjunct := range splitConjunction(condition) {
+		if eq, ok := conjunct.(*expr.BinaryOpExpr); ok && eq.Operator == expr.Equal {
+			lRight, lLeft := isRight(eq.Left)
+			rRight, rLeft := isRight(eq.Right)
+			switch {
+			case lLeft && !lRight && rRight && !rLeft:
+				join.LeftKeys = append(join.LeftKeys, eq.Left)
+				join.RightKeys = append(join.RightKeys, eq.Right)
+				continue
+			case rLeft && !rRight && lRight && !lLeft:
+				join.LeftKeys = append(join.LeftKeys, eq.Right)
+				join.RightKeys = append(join.RightKeys, eq.Left)
+				continue
+			}
+		}
+		rest = append(rest, conjunct)
+	}
+
+	for _, e := range rest {
+		if join.Condition == nil {
+			join.Condition = e
+		} else if join.Condition, err = expr.NewBinaryOp(join.Condition, e, expr.And); err != nil {
+			return Join{}, err
+		}
+	}
+	return join, nil
+}
+
+func splitConjunction(e expr.Expression) []expr.Expression {
+	if and, ok := e.(*expr.BinaryOpExpr); ok && and.Operator == expr.And {
+		return append(splitConjunction(and.Left), splitConjunction(and.Right)...)
+	}
+	return []expr.Expression{e}
+}
+
+// setJoinedColumns sets columns of every table read by expressions of the query, in the order of the table
+func setJoinedColumns(plan *SelectPlan) {
+	var exprs []expr.Expression
+	if plan.QueryDef.Aggregation != nil {
+		exprs = append(exprs, plan.QueryDef.Aggregation.InputExprs()...)
+	} else if len(plan.QueryDef.Windows) > 0 {
+		exprs = append(exprs, plan.QueryDef.Windows[0].InputExprs()...)
+	} else {
+		exprs = append(exprs, plan.QueryDef.SelectExpr...)
+	}
+	if plan.QueryDef.WhereExpr != nil {
+		exprs = append(exprs, plan.QueryDef.WhereExpr)
+	}
+	for _, semiJoin := range plan.QueryDef.SemiJoins {
+		exprs = append(exprs, semiJoin.Keys...)
+	}
+	for _, join := range plan.Joins {
+		exprs = append(exprs, join.LeftKeys...)
+		exprs = append(exprs, join.RightKeys...)
+		if join.Condition != nil {
+			exprs = append(exprs, join.Condition)
+		}
+	}
+
+	used := make(map[string]bool)
+	for _, col := range expr.GetUsedColumnsFromExpressions(exprs) {
+		used[col] = true
+	}
+	for _, table := range plan.Tables {
+		table.Columns = nil
+		for _, col := range table.Snapshot.Columns {
+			name := JoinedColumnName(table.Alias, col.Name)
+			if !used[name] {
+				continue
+			}
+			colType, _ := types.ChunkColumnTypeFromMetadataColumnType(col.Type)
+			table.Columns = append(table.Columns, JoinedColumn{Name: name, TableColumn: col.Name, Type: colType})
+		}
+	}
+}
+
+// chooseJoinStrategies uses merge joins when both sides are read in order of keys, or when the joined table
+// doesn't fit in memory and the preceding tables either are read in order or don't fit in memory either,
+// so sorting both sides is cheaper than spilling the hash join. Other joins are hash joins.
+func (p *Planner) chooseJoinStrategies(plan *SelectPlan) {
+	exceedsMemory := func(bytes uint64) bool {
+		return p.MemoryLimitBytes > 0 && bytes > p.MemoryLimitBytes
+	}
+
+	leftBytes := estimateReadBytes(plan.Tables[0])
+	for i := range plan.Joins {
+		join := &plan.Joins[i]
+		right := plan.Tables[i+1]
+		rightBytes := estimateReadBytes(right)
+
+		join.Strategy = HashJoin
+		if mergeableKeys(join) {
+			// only the first table is read in order, joined rows are not ordered by keys of the next join
+			leftSorted := i == 0 && readInKeyOrder(plan.Tables[0], join.LeftKeys)
+			rightSorted := readInKeyOrder(right, join.RightKeys)
+			if (leftSorted && rightSorted) || (exceedsMemory(rightBytes) && (leftSorted || rightSorted || exceedsMemory(leftBytes))) {
+				join.Strategy = MergeJoin
+				join.LeftSorted, join.RightSorted = leftSorted, rightSorted
+			}
+		}
+		leftBytes += rightBytes
+	}
+}
+
+// mergeableKeys reports whether the join has keys which are columns of both sides, the sides are then
+// sorted by these columns
+func mergeableKeys(join *Join) bool {
+	if len(join.LeftKeys) == 0 {
+		return false
+	}
+	for i := range join.LeftKeys {
+		_, leftCol := join.LeftKeys[i].(*expr.ColumnRefExpr)
+		_, rightCol := join.RightKeys[i].(*expr.ColumnRefExpr)
+		if !leftCol || !rightCol {
+			return false
+		}
+	}
+	return true
+}
+
+// readInKeyOrder reports whether keys are a prefix of ascending sort keys of the table and all its files
+// are sorted by them, so merging the files returns rows in order of keys
+func readInKeyOrder(table *JoinedTable, keys []expr.Expression) bool {
+	sortKeys := table.Snapshot.SortKeys
+	if table.SystemTable != nil || len(keys) > len(sortKeys) {
+		return false
+	}
+	sortKeys = sortKeys[:len(keys)]
+	for i, key := range keys {
+		colRef, ok := key.(*expr.ColumnRefExpr)
+		if !ok || colRef.ColName != JoinedColumnName(table.Alias, sortKeys[i].Column) || !sortKeys[i].Ascending {
+			return false
+		}
+	}
+	for _, f := range table.Snapshot.Files {
+		if !f.Stats.IsSortedBy(sortKeys) {
+			return false
+		}
+	}
+	return true
+}
+
+// estimateReadBytes estimates the size of columns of the table read by the query from statistics of its files
+func estimateReadBytes(table *JoinedTable) uint64 {
+	if table.SystemTable != nil {
+		return 0
+	}
+	read := make(map[string]bool, len(table.Columns))
+	for _, col := range table.Columns {
+		read[col.TableColumn] = true
+	}
+	var bytes uint64
+	for _, f := range table.Snapshot.Files {
+		if f.Stats == nil {
+			continue
+		}
+		for _, col := range f.Stats.Columns {
+			if read[col.Name] {
+				bytes += uint64(col.UncompressedBytes)
+			}
+		}
+	}
+	return bytes
+}
@@ -0,0 +1,162 @@
+package planner
+
+import (
+	"testing"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+)
+
+func joinedTables() []*JoinedTable {
+	return []*JoinedTable{
+		{Name: "orders", Alias: "o", Snapshot: &metadata.MetastoreSnapshot{Columns: []metadata.ColumnDef{
+			{Name: "id", Type: metadata.Int64Type},
+			{Name: "customer", Type: metadata.Int64Type},
+		}}},
+		{Name: "shop.customers", Alias: "customers", Snapshot: &metadata.MetastoreSnapshot{Columns: []metadata.ColumnDef{
+			{Name: "id", Type: metadata.Int64Type},
+			{Name: "name", Type: metadata.VarcharType},
+		}}},
+	}
+}
+
+func TestJoinMapper(t *testing.T) {
+	mapper := NewJoinMapper(joinedTables())
+	colRef := func(table, name string) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{TableName: table, ColumnName: name}}
+	}
+
+	tests := []struct {
+		name    string
+		colRef  openapi.ColumnExpression
+		want    string
+		wantErr bool
+	}{
+		{"unqualified unique column", colRef("", "name"), "customers.name", false},
+		{"alias", colRef("o", "id"), "o.id", false},
+		{"qualified table name", colRef("shop.customers", "id"), "customers.id", false},
+		{"ambiguous column", colRef("", "id"), "", true},
+		{"table name of aliased table", colRef("orders", "id"), "o.id", false},
+		{"missing column", colRef("o", "name"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapped, err := mapper.MapExpression(tt.colRef)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected error, got %+v", mapped)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MapExpression failed: %v", err)
+			}
+			if got := mapped.(*expr.ColumnRefExpr).ColName; got != tt.want {
+				t.Errorf("Expected column %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMapJoin(t *testing.T) {
+	colRef := func(table, name string) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{TableName: table, ColumnName: name}}
+	}
+	binary := func(op string, l, r openapi.ColumnExpression) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.ColumnarBinaryOperation{Operator: op, LeftOperand: l, RightOperand: r}}
+	}
+	literal := openapi.ColumnExpression{Expression: openapi.Literal{Value: openapi.LiteralValue{Data: "bob"}}}
+
+	join, err := mapJoin(openapi.JoinClause{
+		JoinType:  "LEFT",
+		TableName: "shop.customers",
+		Condition: binary("AND",
+			binary("EQUAL", colRef("customers", "id"), colRef("o", "customer")),
+			binary("EQUAL", colRef("", "name"), literal)),
+	}, NewJoinMapper(joinedTables()), "customers")
+	if err != nil {
+		t.Fatalf("mapJoin failed: %v", err)
+	}
+
+	if join.Type != LeftJoin {
+		t.Errorf("Expected left join, got %s", join.Type)
+	}
+	if len(join.LeftKeys) != 1 || len(join.RightKeys) != 1 {
+		t.Fatalf("Expected a single pair of keys, got %d and %d", len(join.LeftKeys), len(join.RightKeys))
+	}
+	if got := join.LeftKeys[0].GetUsedColumns(); len(got) != 1 || got[0] != "o.customer" {
+		t.Errorf("Expected left key o.customer, got %v", got)
+	}
+	if got := join.RightKeys[0].GetUsedColumns(); len(got) != 1 || got[0] != "customers.id" {
+		t.Errorf("Expected right key customers.id, got %v", got)
+	}
+	if join.Condition == nil || join.Condition.ResultType() != types.ChunkColumnTypeBoolean {
+		t.Errorf("Expected the comparison with a literal to remain as the join condition, got %+v", join.Condition)
+	}
+
+	if _, err := mapJoin(openapi.JoinClause{JoinType: "CROSS", TableName: "shop.customers"}, NewJoinMapper(joinedTables()), "customers"); err == nil {
+		t.Errorf("Expected unknown join type to be rejected")
+	}
+	if _, err := mapJoin(openapi.JoinClause{
+		JoinType:  "INNER",
+		TableName: "shop.customers",
+		Condition: colRef("o", "id"),
+	}, NewJoinMapper(joinedTables()), "customers"); err == nil {
+		t.Errorf("Expected non boolean join condition to be rejected")
+	}
+}
+
+func TestChooseJoinStrategies(t *testing.T) {
+	table := func(alias string, sortKeys []metadata.SortKey, bytes int64) *JoinedTable {
+		stats := &metadata.FileStats{
+			Columns:   []metadata.FileColumnStats{{Name: "id", UncompressedBytes: bytes}, {Name: "other", UncompressedBytes: 1 << 30}},
+			SortOrder: sortKeys,
+		}
+		return &JoinedTable{
+			Alias: alias,
+			Snapshot: &metadata.MetastoreSnapshot{
+				SortKeys: sortKeys,
+				Files:    []*metadata.FileEntry{{Path: alias + "_1", Stats: stats}, {Path: alias + "_2", Stats: stats}},
+			},
+			Columns: []JoinedColumn{{Name: alias + ".id", TableColumn: "id", Type: types.ChunkColumnTypeInt64}},
+		}
+	}
+	byID := []metadata.SortKey{{Column: "id", Ascending: true}}
+	descending := []metadata.SortKey{{Column: "id", Ascending: false}}
+	keys := func(alias string) []expr.Expression {
+		return []expr.Expression{&expr.ColumnRefExpr{ColName: alias + ".id", ColType: types.ChunkColumnTypeInt64}}
+	}
+
+	tests := []struct {
+		name          string
+		left, right   *JoinedTable
+		leftKeys      []expr.Expression
+		want          JoinStrategy
+		wantLeftSort  bool
+		wantRightSort bool
+	}{
+		{"small unsorted tables", table("a", nil, 100), table("b", nil, 100), keys("a"), HashJoin, false, false},
+		{"both sides sorted", table("a", byID, 100), table("b", byID, 100), keys("a"), MergeJoin, true, true},
+		{"descending sort keys", table("a", descending, 100), table("b", byID, 100), keys("a"), HashJoin, false, false},
+		{"large joined table with sorted left side", table("a", byID, 100), table("b", nil, 1000), keys("a"), MergeJoin, true, false},
+		{"large joined table with small left side", table("a", nil, 100), table("b", nil, 1000), keys("a"), HashJoin, false, false},
+		{"both sides large", table("a", nil, 1000), table("b", nil, 1000), keys("a"), MergeJoin, false, false},
+		{"keys are not columns", table("a", byID, 100), table("b", byID, 100), []expr.Expression{&expr.LiteralExpr{Value: int64(1), Type: types.ChunkColumnTypeInt64}}, HashJoin, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &SelectPlan{
+				Tables: []*JoinedTable{tt.left, tt.right},
+				Joins:  []Join{{Type: InnerJoin, LeftKeys: tt.leftKeys, RightKeys: keys("b")}},
+			}
+			(&Planner{MemoryLimitBytes: 1000}).chooseJoinStrategies(plan)
+			join := plan.Joins[0]
+			if join.Strategy != tt.want || join.LeftSorted != tt.wantLeftSort || join.RightSorted != tt.wantRightSort {
+				t.Errorf("Expected %s join (sorted %v, %v), got %s join (sorted %v, %v)", tt.want, tt.wantLeftSort, tt.wantRightSort,
+					join.Strategy, join.LeftSorted, join.RightSorted)
+			}
+		})
+	}
+}
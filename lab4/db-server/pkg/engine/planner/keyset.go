@@ -0,0 +1,79 @@
+package planner
+
+import (
+	"fmt"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/types"
+)
+
+// addKeysetCondition keeps only rows ordered after the given values of the order by expressions (keyset pagination).
+// The condition is added to the where clause, so files and index entries before the keys are skipped, or for queries
+// with aggregates or windows to the having expression evaluated on their output like the select expressions.
+func addKeysetCondition(queryDef *SelectQueryDefinition, after []openapi.Literal, mapper *Mapper) error {
+	if len(after) != len(queryDef.OrderByClause) {
+		return fmt.Errorf("after must give a value of every order by expression, expected %d values, got %d",
+			len(queryDef.OrderByClause), len(after))
+	}
+
+	keys := make([]expr.Expression, len(after))
+	values := make([]expr.Expression, len(after))
+	for i, lit := range after {
+		keys[i] = queryDef.SelectExpr[queryDef.OrderByClause[i].Index]
+		value, err := mapper.mapLiteral(lit)
+		if err != nil {
+			return err
+		}
+		if keys[i].ResultType() == types.ChunkColumnTypeBoolean {
+			return fmt.Errorf("after is not supported for BOOLEAN order by expressions")
+		}
+		if value.ResultType() != keys[i].ResultType() {
+			return fmt.Errorf("value %d of after doesn't match the type of its order by expression", i)
+		}
+		values[i] = value
+	}
+
+	// (k1 > v1) OR (k1 = v1 AND ((k2 > v2) OR ...)), with descending keys compared by LESS_THAN
+	var condition expr.Expression
+	for i := len(keys) - 1; i >= 0; i-- {
+		op := expr.GreaterThan
+		if !queryDef.OrderByClause[i].Ascending {
+			op = expr.LessThan
+		}
+		ordered, err := expr.NewBinaryOp(keys[i], values[i], op)
+		if err != nil {
+			return err
+		}
+		if condition == nil {
+			condition = ordered
+			continue
+		}
+		equal, err := expr.NewBinaryOp(keys[i], values[i], expr.Equal)
+		if err != nil {
+			return err
+		}
+		rest, err := expr.NewBinaryOp(equal, condition, expr.And)
+		if err != nil {
+			return err
+		}
+		if condition, err = expr.NewBinaryOp(ordered, rest, expr.Or); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if queryDef.Aggregation == nil && len(queryDef.Windows) == 0 {
+		queryDef.WhereExpr, err = conjunction(queryDef.WhereExpr, condition)
+	} else {
+		queryDef.HavingExpr, err = conjunction(queryDef.HavingExpr, condition)
+	}
+	return err
+}
+
+func conjunction(left, right expr.Expression) (expr.Expression, error) {
+	if left == nil {
+		return right, nil
+	}
+	return expr.NewBinaryOp(left, right, expr.And)
+}
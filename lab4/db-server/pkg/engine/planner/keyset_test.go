@@ -0,0 +1,98 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+)
+
+func TestAddKeysetCondition(t *testing.T) {
+	snapshot := &metadata.MetastoreSnapshot{Columns: []metadata.ColumnDef{
+		{Name: "a", Type: metadata.Int64Type},
+		{Name: "b", Type: metadata.VarcharType},
+	}}
+	colRef := func(name string) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{ColumnName: name}}
+	}
+	lit := func(value any) openapi.Literal {
+		return openapi.Literal{Value: openapi.LiteralValue{Data: value}}
+	}
+	positive := openapi.ColumnExpression{Expression: openapi.ColumnarBinaryOperation{
+		Operator:     "GREATER_THAN",
+		LeftOperand:  colRef("a"),
+		RightOperand: openapi.ColumnExpression{Expression: lit(int64(0))},
+	}}
+	limit := int32(10)
+	query := func(after ...openapi.Literal) openapi.SelectQuery {
+		return openapi.SelectQuery{
+			ColumnClauses: []openapi.ColumnExpression{colRef("a"), colRef("b"), positive},
+			OrderByClause: []openapi.OrderByExpression{{ColumnIndex: 0, Ascending: true}, {ColumnIndex: 1, Ascending: false}},
+			LimitClause:   &openapi.LimitExpression{Limit: &limit, Offset: 5, After: after},
+		}
+	}
+
+	queryDef, err := validateAndMapQuery(query(lit(int64(1)), lit("m")), "t1", snapshot)
+	if err != nil {
+		t.Fatalf("validateAndMapQuery failed: %v", err)
+	}
+	if queryDef.Limit != 10 || queryDef.Offset != 5 {
+		t.Errorf("Expected limit 10 and offset 5, got %d and %d", queryDef.Limit, queryDef.Offset)
+	}
+
+	// rows after (1, "m") are ordered by a ascending, then by b descending
+	as := []int64{0, 1, 1, 1, 2, 2}
+	bs := []string{"z", "z", "m", "a", "z", "a"}
+	batch := &types.ChunkResult{
+		RowCount:  uint64(len(as)),
+		Columns:   []types.ChunkColumn{types.NewInt64Column("a", as), types.VarcharChunkColumnFromStrings("b", bs)},
+		SelectIdx: []int{0, 1},
+		FilterIdx: -1,
+	}
+	result, err := queryDef.WhereExpr.Evaluate(batch, map[string]int{"a": 0, "b": 1})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	want := []bool{false, false, false, true, true, true}
+	for i, w := range want {
+		if got := result.GetValueAny(i).(bool); got != w {
+			t.Errorf("Row (%d, %s): expected %v, got %v", as[i], bs[i], w, got)
+		}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		after []openapi.Literal
+		err   string
+	}{
+		{"missing value", []openapi.Literal{lit(int64(1))}, "expected 2 values, got 1"},
+		{"type mismatch", []openapi.Literal{lit("1"), lit("m")}, "doesn't match the type"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := validateAndMapQuery(query(tc.after...), "t1", snapshot)
+			ve, ok := err.(*types.ValidationError)
+			if !ok || len(ve.Problems) != 1 || !strings.Contains(ve.Problems[0].Error, tc.err) {
+				t.Errorf("Expected a problem containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+
+	boolQuery := query(lit(true))
+	boolQuery.OrderByClause = []openapi.OrderByExpression{{ColumnIndex: 2}}
+	if _, err := validateAndMapQuery(boolQuery, "t1", snapshot); err == nil {
+		t.Errorf("Expected after on a BOOLEAN expression to be rejected")
+	}
+
+	grouped := query(lit(int64(1)), lit("m"))
+	grouped.ColumnClauses[2] = aggregateExpr("COUNT", nil)
+	grouped.GroupByClause = []openapi.ColumnExpression{colRef("a"), colRef("b")}
+	queryDef, err = validateAndMapQuery(grouped, "t1", snapshot)
+	if err != nil {
+		t.Fatalf("validateAndMapQuery failed: %v", err)
+	}
+	if queryDef.WhereExpr != nil || queryDef.HavingExpr == nil {
+		t.Errorf("Expected the keyset condition of a grouped query to filter groups")
+	}
+}
@@ -3,15 +3,22 @@ package planner
 import (
 	"fmt"
 	"isbd4/openapi"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/types"
 	"isbd4/pkg/metadata"
 )
 
 type Planner struct {
 	Metastore *metadata.Metastore
+	// MemoryLimitBytes is the memory available to a query, used to choose join algorithms, 0 means no limit
+	MemoryLimitBytes uint64
+	systemTables     map[string]*SystemTable
 }
 
 func NewPlanner(m *metadata.Metastore) *Planner {
-	return &Planner{Metastore: m}
+	p := &Planner{Metastore: m, systemTables: make(map[string]*SystemTable)}
+	p.registerCatalogTables()
+	return p
 }
 
 func (p *Planner) PlanCopy(tableName string, csvFilePath string, columnsMapping []string, csvContainsHeader bool) (*CopyPlan, error) {
@@ -29,24 +36,225 @@ func (p *Planner) PlanCopy(tableName string, csvFilePath string, columnsMapping
 }
 
 func (p *Planner) PlanSelect(apiQueryDef openapi.SelectQuery) (QueryPlan, error) {
+	return p.planSelectIn(apiQueryDef, nil)
+}
+
+// planSelectIn plans a select which may read common table expressions of the scope and of its own WITH clause
+func (p *Planner) planSelectIn(apiQueryDef openapi.SelectQuery, s *scope) (QueryPlan, error) {
+	s, err := s.with(apiQueryDef.WithClause)
+	if err != nil {
+		return nil, err
+	}
+	apiQueryDef, subqueries := splitSubqueryConditions(apiQueryDef)
+	queryPlan, err := p.planSelect(apiQueryDef, s)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := queryPlan.(*SelectPlan)
+	if len(subqueries) > 0 {
+		if err := p.planSemiJoins(plan, subqueries, apiQueryDef.FromClause, s); err != nil {
+			plan.Release()
+			return nil, err
+		}
+	}
+	plan.TopN = p.useTopN(plan)
+	return plan, nil
+}
+
+func (p *Planner) planSelect(apiQueryDef openapi.SelectQuery, s *scope) (QueryPlan, error) {
 	candidateTableName, hasColRefs := extractTableName(apiQueryDef)
+	if from := apiQueryDef.FromClause; from != nil {
+		if len(from.Joins) > 0 {
+			if err := s.validateJoinedTables(*from); err != nil {
+				return nil, err
+			}
+			return p.planJoinQuery(apiQueryDef)
+		}
+		if from.Subquery != nil {
+			if from.Alias == "" {
+				return nil, types.NewVErr("subquery of the from clause requires an alias", "FromClause")
+			}
+			if err := validateAlias(from.Alias); err != nil {
+				return nil, types.NewVErr(err.Error(), "FromClause")
+			}
+			return p.planDerivedQuery(apiQueryDef, from.Alias, *from.Subquery, nil, s, "FromClause")
+		}
+		candidateTableName = from.TableName
+	}
+	if cte := s.lookup(candidateTableName); cte != nil {
+		return p.planDerivedQuery(apiQueryDef, candidateTableName, cte.definition.Query, cte.definition.ColumnNames, cte.parent, cte.context)
+	}
 	if candidateTableName == "" {
 		return p.planLiteralQuery(apiQueryDef, hasColRefs)
 	}
+	if db, _ := metadata.SplitQualifiedName(candidateTableName); db == metadata.SystemDatabase {
+		return p.planSystemTableQuery(apiQueryDef, candidateTableName)
+	}
 
-	msSnapshot, err := p.Metastore.GetTableSnapshot(candidateTableName)
+	msSnapshot, err := p.Metastore.GetTableSnapshot(candidateTableName, asOfFromAPI(apiQueryDef.AsOf))
 	if err != nil {
 		return nil, err
 	}
 
 	selectQueryDef, err := validateAndMapQuery(apiQueryDef, candidateTableName, msSnapshot)
 	if err != nil {
+		msSnapshot.Release()
 		return nil, err
 	}
+	pruneFiles(msSnapshot, selectQueryDef.WhereExpr)
 
-	return &SelectPlan{
-		Snapshot: msSnapshot,
-		QueryDef: selectQueryDef,
+	plan := &SelectPlan{
+		Snapshot:   msSnapshot,
+		QueryDef:   selectQueryDef,
+		SortedScan: sortedScanKeys(msSnapshot, selectQueryDef),
+	}
+	if plan.SortedScan == nil {
+		plan.IndexLookup = chooseIndexLookup(msSnapshot, selectQueryDef.WhereExpr)
+	}
+	return plan, nil
+}
+
+func (p *Planner) PlanInsert(apiQueryDef openapi.InsertQuery) (*InsertPlan, error) {
+	tableId, exists := p.Metastore.GetTableId(apiQueryDef.IntoTableName)
+	if !exists {
+		return nil, fmt.Errorf("table %s does not exist", apiQueryDef.IntoTableName)
+	}
+	tableDef, _ := p.Metastore.GetTableById(tableId)
+
+	table, err := validateAndBuildInsertedRows(apiQueryDef, tableDef.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InsertPlan{
+		TableId:   tableId,
+		Table:     table,
+		Buffered:  apiQueryDef.Buffered,
+		Metastore: p.Metastore,
+	}, nil
+}
+
+func (p *Planner) PlanCreateTableAsSelect(apiQueryDef openapi.CreateTableAsSelectQuery) (*SelectIntoPlan, error) {
+	if _, exists := p.Metastore.GetTableId(apiQueryDef.CreateTableName); exists {
+		return nil, fmt.Errorf("table %s already exists", apiQueryDef.CreateTableName)
+	}
+	if db, _ := metadata.SplitQualifiedName(apiQueryDef.CreateTableName); !p.Metastore.DatabaseExists(db) {
+		return nil, fmt.Errorf("database %s does not exist", db)
+	}
+
+	selectPlan, err := p.PlanSelect(apiQueryDef.SelectQuery)
+	if err != nil {
+		return nil, err
+	}
+	plan := selectPlan.(*SelectPlan)
+
+	columns, err := validateAndDeriveColumns(plan.QueryDef.SelectExpr, apiQueryDef.ColumnNames, len(plan.Tables) > 0)
+	if err != nil {
+		plan.Release()
+		return nil, err
+	}
+	sortKeys := make([]metadata.SortKey, 0, len(apiQueryDef.SortKeys))
+	for _, k := range apiQueryDef.SortKeys {
+		sortKeys = append(sortKeys, metadata.SortKey{Column: k.ColumnName, Ascending: k.Ascending})
+	}
+	if err := metadata.ValidateSortKeys(columns, sortKeys); err != nil {
+		plan.Release()
+		return nil, types.NewVErr(err.Error(), "SortKeys")
+	}
+
+	return &SelectIntoPlan{
+		Select:    plan,
+		TableName: apiQueryDef.CreateTableName,
+		Columns:   columns,
+		SortKeys:  sortKeys,
+		Metastore: p.Metastore,
+	}, nil
+}
+
+func (p *Planner) PlanInsertSelect(apiQueryDef openapi.InsertSelectQuery) (*SelectIntoPlan, error) {
+	tableId, exists := p.Metastore.GetTableId(apiQueryDef.IntoTableName)
+	if !exists {
+		return nil, fmt.Errorf("table %s does not exist", apiQueryDef.IntoTableName)
+	}
+	tableDef, _ := p.Metastore.GetTableById(tableId)
+
+	selectPlan, err := p.PlanSelect(apiQueryDef.SelectQuery)
+	if err != nil {
+		return nil, err
+	}
+	plan := selectPlan.(*SelectPlan)
+
+	if err := validateSelectedColumns(plan.QueryDef.SelectExpr, tableDef.Columns); err != nil {
+		plan.Release()
+		return nil, err
+	}
+
+	return &SelectIntoPlan{
+		Select:    plan,
+		TableId:   tableId,
+		Columns:   tableDef.Columns,
+		SortKeys:  tableDef.SortKeys,
+		Metastore: p.Metastore,
+	}, nil
+}
+
+func (p *Planner) PlanDelete(apiQueryDef openapi.DeleteQuery) (*DeletePlan, error) {
+	tableId, exists := p.Metastore.GetTableId(apiQueryDef.TableName)
+	if !exists {
+		return nil, fmt.Errorf("table %s does not exist", apiQueryDef.TableName)
+	}
+	msSnapshot, err := p.Metastore.GetTableSnapshotById(tableId)
+	if err != nil {
+		return nil, err
+	}
+
+	whereExpr, err := validateAndMapWhere(apiQueryDef.WhereClause, apiQueryDef.TableName, msSnapshot)
+	if err != nil {
+		msSnapshot.Release()
+		return nil, err
+	}
+	pruneFiles(msSnapshot, whereExpr)
+
+	return &DeletePlan{
+		TableId:   tableId,
+		WhereExpr: whereExpr,
+		Snapshot:  msSnapshot,
+		Metastore: p.Metastore,
+	}, nil
+}
+
+func (p *Planner) PlanUpdate(apiQueryDef openapi.UpdateQuery) (*UpdatePlan, error) {
+	tableId, exists := p.Metastore.GetTableId(apiQueryDef.TableName)
+	if !exists {
+		return nil, fmt.Errorf("table %s does not exist", apiQueryDef.TableName)
+	}
+	msSnapshot, err := p.Metastore.GetTableSnapshotById(tableId)
+	if err != nil {
+		return nil, err
+	}
+
+	ve := &types.ValidationError{}
+	assignments, err := validateAndMapAssignments(apiQueryDef.SetClauses, apiQueryDef.TableName, msSnapshot)
+	if err != nil {
+		ve.Extend(err)
+	}
+	whereExpr, err := validateAndMapWhere(apiQueryDef.WhereClause, apiQueryDef.TableName, msSnapshot)
+	if err != nil {
+		ve.Extend(err)
+	}
+	if ve.HasProblems() {
+		msSnapshot.Release()
+		return nil, ve
+	}
+	pruneFiles(msSnapshot, whereExpr)
+
+	return &UpdatePlan{
+		TableId:     tableId,
+		Assignments: assignments,
+		WhereExpr:   whereExpr,
+		Snapshot:    msSnapshot,
+		Metastore:   p.Metastore,
 	}, nil
 }
 
@@ -54,6 +262,9 @@ func (p *Planner) planLiteralQuery(apiQueryDef openapi.SelectQuery, hasColRefs b
 	if hasColRefs {
 		return nil, fmt.Errorf("no table name specified and query contains column references")
 	}
+	if apiQueryDef.AsOf != nil {
+		return nil, fmt.Errorf("AS OF requires a query on a table")
+	}
 	selectQueryDef, err := validateAndMapQuery(apiQueryDef, "", nil)
 	if err != nil {
 		return nil, err
@@ -64,3 +275,59 @@ func (p *Planner) planLiteralQuery(apiQueryDef openapi.SelectQuery, hasColRefs b
 		Snapshot: nil,
 	}, nil
 }
+
+// Release releases snapshots of all tables read by the plan
+func (p *SelectPlan) Release() {
+	if p.Snapshot != nil {
+		p.Snapshot.Release()
+	}
+	for _, t := range p.Tables {
+		if t.Snapshot != nil {
+			t.Snapshot.Release()
+		}
+	}
+	if p.Derived != nil {
+		p.Derived.Select.Release()
+	}
+	if p.QueryDef != nil {
+		for _, semiJoin := range p.QueryDef.SemiJoins {
+			semiJoin.Subquery.Release()
+		}
+	}
+}
+
+// sortedScanKeys returns the prefix of the table sort keys matching ORDER BY, or nil if the result has to be sorted
+func sortedScanKeys(snapshot *metadata.MetastoreSnapshot, queryDef *SelectQueryDefinition) []metadata.SortKey {
+	// ordered columns of grouped queries are computed by the aggregation, distinct rows lose the order when spilled
+	// and windows sort rows by their own keys
+	if queryDef.Aggregation != nil || queryDef.Distinct || len(queryDef.Windows) > 0 || len(queryDef.OrderByClause) == 0 || len(queryDef.OrderByClause) > len(snapshot.SortKeys) {
+		return nil
+	}
+
+	keys := snapshot.SortKeys[:len(queryDef.OrderByClause)]
+	for i, orderBy := range queryDef.OrderByClause {
+		colRef, ok := queryDef.SelectExpr[orderBy.Index].(*expr.ColumnRefExpr)
+		if !ok || colRef.ColName != keys[i].Column || orderBy.Ascending != keys[i].Ascending {
+			return nil
+		}
+	}
+	for _, f := range snapshot.Files {
+		if !f.Stats.IsSortedBy(keys) {
+			return nil
+		}
+	}
+	return keys
+}
+
+func asOfFromAPI(apiAsOf *openapi.AsOfExpression) *metadata.AsOf {
+	if apiAsOf == nil {
+		return nil
+	}
+
+	asOf := &metadata.AsOf{Timestamp: apiAsOf.Timestamp}
+	if apiAsOf.Version != nil {
+		version := uint64(*apiAsOf.Version)
+		asOf.Version = &version
+	}
+	return asOf
+}
@@ -2,7 +2,10 @@ package planner
 
 import (
 	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/index"
+	"isbd4/pkg/engine/types"
 	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
 )
 
 type PlanType int
@@ -10,6 +13,11 @@ type PlanType int
 const (
 	PlanTypeCopy PlanType = iota
 	PlanTypeSelect
+	PlanTypeDelete
+	PlanTypeUpdate
+	PlanTypeInsert
+	PlanTypeSelectInto
+	PlanTypeSetOperation
 )
 
 type QueryPlan interface {
@@ -31,18 +39,277 @@ func (p *CopyPlan) Type() PlanType {
 type SelectPlan struct {
 	QueryDef *SelectQueryDefinition
 	Snapshot *metadata.MetastoreSnapshot
+	// SortedScan holds the table sort keys satisfying ORDER BY when all files are sorted by them,
+	// the files are then merged in order instead of sorting the whole result
+	SortedScan []metadata.SortKey
+	// IndexLookup restricts reading of files covered by a secondary index to rows with keys in the range
+	IndexLookup *IndexLookup
+	// SystemTable holds rows of a queried system table, which are read instead of files of the snapshot
+	SystemTable *tomy_file.ColumnarTable
+	// Tables of a query with joins, they are read instead of the snapshot. Joins[i] joins Tables[i+1]
+	// with the result of the preceding joins.
+	Tables []*JoinedTable
+	Joins  []Join
+	// TopN keeps only the limited rows of ORDER BY with LIMIT in memory instead of sorting all rows
+	TopN bool
+	// Derived is read instead of the snapshot by queries of a subquery in the from clause or a common table expression
+	Derived *DerivedTable
+}
+
+// DerivedTable is the result of a select read like a table, its selected columns are renamed to Columns
+type DerivedTable struct {
+	Select  *SelectPlan
+	Columns []string
+}
+
+// JoinedTable is a table of a query with joins, its columns are named "<alias>.<column>" in joined batches
+type JoinedTable struct {
+	Name        string
+	Alias       string
+	Snapshot    *metadata.MetastoreSnapshot
+	SystemTable *tomy_file.ColumnarTable
+	Columns     []JoinedColumn // columns read by the query
+}
+
+type JoinedColumn struct {
+	Name        string // name in joined batches
+	TableColumn string
+	Type        types.ChunkColumnType
+}
+
+type JoinType string
+
+const (
+	InnerJoin JoinType = "INNER"
+	LeftJoin  JoinType = "LEFT"
+	RightJoin JoinType = "RIGHT"
+	FullJoin  JoinType = "FULL"
+)
+
+// JoinStrategy is the algorithm pairing rows with equal keys
+type JoinStrategy string
+
+const (
+	// HashJoin collects rows of the joined table in a hash table probed with rows of the preceding tables
+	HashJoin JoinStrategy = "HASH"
+	// MergeJoin reads both sides ordered by keys, sorting the sides which are not read in order
+	MergeJoin JoinStrategy = "MERGE"
+)
+
+// Join pairs rows of the preceding tables with rows of the joined table. Pairs with equal keys match if they
+// satisfy the condition, rows without a match are kept by outer joins with columns of the other side zeroed.
+type Join struct {
+	Type      JoinType
+	LeftKeys  []expr.Expression // evaluated on rows of the preceding tables
+	RightKeys []expr.Expression // evaluated on rows of the joined table
+	Condition expr.Expression   // the rest of the join condition, nil if pairs with equal keys always match
+
+	Strategy JoinStrategy
+	// LeftSorted and RightSorted tell that the side is read in ascending order of keys by merging sorted files
+	// of a clustered table, so a merge join doesn't sort it
+	LeftSorted  bool
+	RightSorted bool
+}
+
+type IndexLookup struct {
+	Column string
+	Keys   index.KeyRange
 }
 
 func (p *SelectPlan) Type() PlanType {
 	return PlanTypeSelect
 }
 
+type InsertPlan struct {
+	TableId   string
+	Table     *tomy_file.ColumnarTable // inserted rows, columns in the order of the table definition
+	Buffered  bool
+	Metastore *metadata.Metastore
+}
+
+func (p *InsertPlan) Type() PlanType {
+	return PlanTypeInsert
+}
+
+// SelectIntoPlan writes the result of the select into files of a table created together with them
+// (CREATE TABLE AS SELECT) or appended to an existing table (INSERT INTO ... SELECT)
+type SelectIntoPlan struct {
+	Select    *SelectPlan
+	TableName string // name of the created table, empty when inserting into an existing one
+	TableId   string // id of the existing table
+	Columns   []metadata.ColumnDef
+	SortKeys  []metadata.SortKey
+	Metastore *metadata.Metastore
+}
+
+func (p *SelectIntoPlan) Type() PlanType {
+	return PlanTypeSelectInto
+}
+
+// SetOperator combines rows of queries with the same number and types of columns
+type SetOperator string
+
+const (
+	UnionAll  SetOperator = "UNION_ALL"
+	Union     SetOperator = "UNION"
+	Intersect SetOperator = "INTERSECT"
+	Except    SetOperator = "EXCEPT"
+)
+
+// SetOperationPlan combines results of the selects from left to right, all operators but UNION ALL return
+// distinct rows. The combined rows are then ordered and limited like rows of a select.
+type SetOperationPlan struct {
+	Operator      SetOperator
+	Selects       []*SelectPlan
+	OrderByClause []OrderByColumnReference // indices of the selected columns
+	Limit         int                      // -1 without a limit
+	Offset        int
+}
+
+func (p *SetOperationPlan) Type() PlanType {
+	return PlanTypeSetOperation
+}
+
+// Release releases snapshots of all tables read by the selects
+func (p *SetOperationPlan) Release() {
+	for _, s := range p.Selects {
+		s.Release()
+	}
+}
+
+type DeletePlan struct {
+	TableId   string
+	WhereExpr expr.Expression // nil deletes all rows
+	Snapshot  *metadata.MetastoreSnapshot
+	Metastore *metadata.Metastore
+}
+
+func (p *DeletePlan) Type() PlanType {
+	return PlanTypeDelete
+}
+
+type UpdatePlan struct {
+	TableId     string
+	Assignments []ColumnAssignment
+	WhereExpr   expr.Expression // nil updates all rows
+	Snapshot    *metadata.MetastoreSnapshot
+	Metastore   *metadata.Metastore
+}
+
+// ColumnAssignment sets the column with the given index in the table definition to the value of the expression
+type ColumnAssignment struct {
+	ColumnIdx int
+	Expr      expr.Expression
+}
+
+func (p *UpdatePlan) Type() PlanType {
+	return PlanTypeUpdate
+}
+
 type SelectQueryDefinition struct {
 	TableName     string
 	SelectExpr    []expr.Expression
 	WhereExpr     expr.Expression
 	OrderByClause []OrderByColumnReference
-	Limit         int
+	Limit         int // -1 without a limit
+	Offset        int // number of rows skipped before the limited ones
+	// Aggregation groups rows passing the where clause, having and select expressions then refer to its output columns
+	Aggregation *Aggregation
+	// HavingExpr filters rows returned by the aggregation or the windows
+	HavingExpr expr.Expression
+	// SemiJoins filter rows passing the where clause by IN and EXISTS subqueries
+	SemiJoins []SemiJoin
+	// Distinct removes duplicate rows of the selected expressions
+	Distinct bool
+	// Windows compute window functions of rows passing the where clause one after another,
+	// select expressions then refer to output columns of the last one
+	Windows []*Window
+}
+
+// SemiJoin keeps rows of the query with a row of the subquery with equal keys, or for anti joins (NOT IN,
+// NOT EXISTS) rows without one. Without keys rows are kept if the subquery returns any row (or none).
+type SemiJoin struct {
+	Anti     bool
+	Keys     []expr.Expression // evaluated on rows of the query
+	Subquery *SelectPlan
+	// SubqueryKeys are indices of the selected columns of the subquery compared with the keys
+	SubqueryKeys []int
+}
+
+// Aggregation groups rows by the group by expressions and computes aggregates of every group.
+// It outputs columns named by GroupNames followed by columns named by names of the aggregates.
+// Without group by expressions all rows form a single group, which is returned also if there are no rows.
+type Aggregation struct {
+	GroupBy    []expr.Expression
+	GroupNames []string
+	Aggregates []AggregateCall
+}
+
+type AggregateCall struct {
+	Function   expr.AggregateFunction
+	Argument   expr.Expression // nil for COUNT of all rows
+	Distinct   bool            // COUNT of distinct values of the argument
+	Name       string
+	ResultType types.ChunkColumnType
+}
+
+// InputExprs returns expressions evaluated for every aggregated row: group keys followed by arguments of aggregates
+func (a *Aggregation) InputExprs() []expr.Expression {
+	exprs := append([]expr.Expression(nil), a.GroupBy...)
+	for _, call := range a.Aggregates {
+		if call.Argument != nil {
+			exprs = append(exprs, call.Argument)
+		}
+	}
+	return exprs
+}
+
+// Window computes window functions over partitions of rows with equal partition keys, ordered by the order keys.
+// It outputs the passed columns followed by columns named by names of the functions.
+type Window struct {
+	Passed      []*expr.ColumnRefExpr
+	PartitionBy []expr.Expression
+	OrderBy     []WindowOrder
+	Functions   []WindowCall
+}
+
+type WindowOrder struct {
+	Expr      expr.Expression
+	Ascending bool
+}
+
+type WindowCall struct {
+	Function   expr.WindowFunction
+	Argument   expr.Expression // nil for ranking functions and COUNT of all rows
+	Offset     int64           // rows before (LAG) or after (LEAD) the current row
+	Frame      *WindowFrame    // nil for the default frame
+	Name       string
+	ResultType types.ChunkColumnType
+}
+
+// WindowFrame holds rows from Start to End relative to the current row (negative before it), nil bounds are unbounded
+type WindowFrame struct {
+	Start *int64
+	End   *int64
+}
+
+// InputExprs returns expressions evaluated for every row: passed columns followed by partition keys,
+// order keys and arguments of functions
+func (w *Window) InputExprs() []expr.Expression {
+	var exprs []expr.Expression
+	for _, col := range w.Passed {
+		exprs = append(exprs, col)
+	}
+	exprs = append(exprs, w.PartitionBy...)
+	for _, order := range w.OrderBy {
+		exprs = append(exprs, order.Expr)
+	}
+	for _, call := range w.Functions {
+		if call.Argument != nil {
+			exprs = append(exprs, call.Argument)
+		}
+	}
+	return exprs
 }
 
 type OrderByColumnReference struct {
@@ -0,0 +1,101 @@
+package planner
+
+import (
+	"fmt"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/types"
+)
+
+// PlanSetOperation plans every query of the set operation like a select, the queries have to return the same
+// number of columns of the same types as the first one
+func (p *Planner) PlanSetOperation(apiQueryDef openapi.SetOperationQuery) (*SetOperationPlan, error) {
+	ve := &types.ValidationError{}
+	operator, err := setOperatorFromString(apiQueryDef.SetOperator)
+	if err != nil {
+		ve.Add(err.Error(), "SetOperator")
+	}
+	if len(apiQueryDef.SetQueries) < 2 {
+		ve.Add("set operation needs at least two queries", "SetQueries")
+		return nil, ve
+	}
+
+	plan := &SetOperationPlan{Operator: operator}
+	selects := make([]*SelectPlan, len(apiQueryDef.SetQueries))
+	for i, query := range apiQueryDef.SetQueries {
+		selectPlan, err := p.PlanSelect(query)
+		if err != nil {
+			prefixContext(ve, err, fmt.Sprintf("SetQuery %d", i))
+			continue
+		}
+		selects[i] = selectPlan.(*SelectPlan)
+		plan.Selects = append(plan.Selects, selects[i])
+	}
+
+	columnsCount := len(apiQueryDef.SetQueries[0].ColumnClauses)
+	if first := selects[0]; first != nil {
+		columnsCount = len(first.QueryDef.SelectExpr)
+		for i, s := range selects[1:] {
+			if s != nil {
+				validateSetColumns(ve, first, s, i+1)
+			}
+		}
+	}
+
+	plan.OrderByClause, err = validateAndExtractOrderBy(apiQueryDef.OrderByClause, columnsCount)
+	if err != nil {
+		ve.Extend(err)
+	}
+	plan.Limit, plan.Offset, err = validateAndExtractLimit(apiQueryDef.LimitClause)
+	if err != nil {
+		ve.Add(err.Error(), "LimitClause")
+	} else if clause := apiQueryDef.LimitClause; clause != nil && len(clause.After) > 0 {
+		ve.Add("after is not supported by set operations", "LimitClause")
+	}
+
+	if ve.HasProblems() {
+		plan.Release()
+		return nil, ve
+	}
+	return plan, nil
+}
+
+func setOperatorFromString(operator string) (SetOperator, error) {
+	switch o := SetOperator(operator); o {
+	case UnionAll, Union, Intersect, Except:
+		return o, nil
+	default:
+		return "", fmt.Errorf("unknown set operator: %s", operator)
+	}
+}
+
+// validateSetColumns checks that the i-th query returns columns of the same types as the first one
+func validateSetColumns(ve *types.ValidationError, first, query *SelectPlan, i int) {
+	expected, got := first.QueryDef.SelectExpr, query.QueryDef.SelectExpr
+	if len(got) != len(expected) {
+		ve.Add(fmt.Sprintf("query returns %d columns, the first query returns %d", len(got), len(expected)),
+			fmt.Sprintf("SetQuery %d", i))
+		return
+	}
+	for j, e := range got {
+		if e.ResultType() != expected[j].ResultType() {
+			ve.Add("column has a different type than in the first query", fmt.Sprintf("SetQuery %d, ColumnClause %d", i, j))
+		}
+	}
+}
+
+// prefixContext adds problems of the error of a nested query, their contexts are prefixed with the context of the query
+func prefixContext(ve *types.ValidationError, err error, context string) {
+	other, ok := err.(*types.ValidationError)
+	if !ok {
+		ve.Add(err.Error(), context)
+		return
+	}
+	for _, p := range other.Problems {
+		if p.Context == "" {
+			ve.Add(p.Error, context)
+		} else {
+			ve.Add(p.Error, context+", "+p.Context)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package planner
+
+import (
+	"reflect"
+	"testing"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+)
+
+func TestPlanSetOperation(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	columns := []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "name", Type: metadata.VarcharType},
+	}
+	for _, table := range []string{"jan", "feb"} {
+		if _, err := m.CreateTable(table, columns, nil); err != nil {
+			t.Fatalf("CreateTable failed: %v", err)
+		}
+	}
+	p := NewPlanner(m)
+
+	selectOf := func(table string, names ...string) openapi.SelectQuery {
+		query := openapi.SelectQuery{FromClause: &openapi.FromClause{TableName: table}}
+		for _, name := range names {
+			query.ColumnClauses = append(query.ColumnClauses, openapi.ColumnExpression{
+				Expression: openapi.ColumnReferenceExpression{ColumnName: name},
+			})
+		}
+		return query
+	}
+	limit := int32(5)
+
+	plan, err := p.PlanSetOperation(openapi.SetOperationQuery{
+		SetOperator:   "EXCEPT",
+		SetQueries:    []openapi.SelectQuery{selectOf("jan", "id", "name"), selectOf("feb", "id", "name")},
+		OrderByClause: []openapi.OrderByExpression{{ColumnIndex: 1, Ascending: true}},
+		LimitClause:   &openapi.LimitExpression{Limit: &limit, Offset: 10},
+	})
+	if err != nil {
+		t.Fatalf("PlanSetOperation failed: %v", err)
+	}
+	defer plan.Release()
+	if plan.Operator != Except || len(plan.Selects) != 2 || plan.Limit != 5 || plan.Offset != 10 {
+		t.Errorf("Unexpected plan %+v", plan)
+	}
+	if expected := []OrderByColumnReference{{Index: 1, Ascending: true}}; !reflect.DeepEqual(plan.OrderByClause, expected) {
+		t.Errorf("Expected order by %v, got %v", expected, plan.OrderByClause)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		query    openapi.SetOperationQuery
+		problems []types.ErrWithCtx
+	}{
+		{
+			name: "unknown operator and a single query",
+			query: openapi.SetOperationQuery{
+				SetOperator: "MINUS",
+				SetQueries:  []openapi.SelectQuery{selectOf("jan", "id")},
+			},
+			problems: []types.ErrWithCtx{
+				{Error: "unknown set operator: MINUS", Context: "SetOperator"},
+				{Error: "set operation needs at least two queries", Context: "SetQueries"},
+			},
+		},
+		{
+			name: "different column counts",
+			query: openapi.SetOperationQuery{
+				SetOperator: "UNION",
+				SetQueries:  []openapi.SelectQuery{selectOf("jan", "id", "name"), selectOf("feb", "id")},
+			},
+			problems: []types.ErrWithCtx{{Error: "query returns 1 columns, the first query returns 2", Context: "SetQuery 1"}},
+		},
+		{
+			name: "different column types",
+			query: openapi.SetOperationQuery{
+				SetOperator: "INTERSECT",
+				SetQueries:  []openapi.SelectQuery{selectOf("jan", "id", "name"), selectOf("feb", "id", "id")},
+			},
+			problems: []types.ErrWithCtx{{Error: "column has a different type than in the first query", Context: "SetQuery 1, ColumnClause 1"}},
+		},
+		{
+			name: "invalid nested query and order by",
+			query: openapi.SetOperationQuery{
+				SetOperator:   "UNION_ALL",
+				SetQueries:    []openapi.SelectQuery{selectOf("jan", "id"), selectOf("feb", "price")},
+				OrderByClause: []openapi.OrderByExpression{{ColumnIndex: 1}},
+			},
+			problems: []types.ErrWithCtx{
+				{Error: "column price not found in table feb", Context: "SetQuery 1"},
+				{Error: "invalid column index: 1", Context: "OrderByClause 0"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := p.PlanSetOperation(tc.query)
+			ve, ok := err.(*types.ValidationError)
+			if !ok {
+				t.Fatalf("Expected a validation error, got %v", err)
+			}
+			if !reflect.DeepEqual(ve.Problems, tc.problems) {
+				t.Errorf("Expected problems %v, got %v", tc.problems, ve.Problems)
+			}
+		})
+	}
+}
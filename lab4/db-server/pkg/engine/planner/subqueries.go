@@ -0,0 +1,221 @@
+package planner
+
+import (
+	"fmt"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+)
+
+// splitSubqueryConditions removes IN and EXISTS subqueries joined by AND from the where clause of the query
+func splitSubqueryConditions(apiQueryDef openapi.SelectQuery) (openapi.SelectQuery, []openapi.ColumnExpression) {
+	if apiQueryDef.WhereClause.Expression == nil {
+		return apiQueryDef, nil
+	}
+
+	var subqueries []openapi.ColumnExpression
+	var rest []openapi.ColumnExpression
+	for _, conjunct := range splitAPIConjunction(apiQueryDef.WhereClause) {
+		switch conjunct.Expression.(type) {
+		case openapi.InSubquery, openapi.ExistsSubquery:
+			subqueries = append(subqueries, conjunct)
+		default:
+			rest = append(rest, conjunct)
+		}
+	}
+	apiQueryDef.WhereClause = joinAPIConjunction(rest)
+	return apiQueryDef, subqueries
+}
+
+func splitAPIConjunction(e openapi.ColumnExpression) []openapi.ColumnExpression {
+	if and, ok := e.Expression.(openapi.ColumnarBinaryOperation); ok && and.Operator == "AND" {
+		return append(splitAPIConjunction(and.LeftOperand), splitAPIConjunction(and.RightOperand)...)
+	}
+	return []openapi.ColumnExpression{e}
+}
+
+func joinAPIConjunction(conjuncts []openapi.ColumnExpression) openapi.ColumnExpression {
+	var res openapi.ColumnExpression
+	for _, c := range conjuncts {
+		if res.Expression == nil {
+			res = c
+		} else {
+			res = openapi.ColumnExpression{Expression: openapi.ColumnarBinaryOperation{Operator: "AND", LeftOperand: res, RightOperand: c}}
+		}
+	}
+	return res
+}
+
+// planSemiJoins plans the subquery conditions of the where clause as semi joins of rows passing it
+func (p *Planner) planSemiJoins(plan *SelectPlan, subqueries []openapi.ColumnExpression, from *openapi.FromClause, s *scope) error {
+	var outer *Mapper
+	if len(plan.Tables) > 0 {
+		outer = NewJoinMapper(plan.Tables)
+	} else {
+		var err error
+		if plan.Derived != nil {
+			outer = newDerivedMapper(plan.Derived, plan.QueryDef.TableName)
+		} else if outer, err = NewMapper(plan.Snapshot, plan.QueryDef.TableName); err != nil {
+			return err
+		}
+		if from != nil {
+			outer.alias = from.Alias
+		}
+	}
+
+	for _, subquery := range subqueries {
+		semiJoin, err := p.planSemiJoin(subquery, outer, s)
+		if err != nil {
+			ve := &types.ValidationError{}
+			extendWithContext(ve, err, "WhereClause")
+			return ve
+		}
+		plan.QueryDef.SemiJoins = append(plan.QueryDef.SemiJoins, semiJoin)
+	}
+
+	if len(plan.Tables) > 0 {
+		setJoinedColumns(plan)
+		p.chooseJoinStrategies(plan)
+	} else if plan.SortedScan != nil {
+		// rows passing semi joins which spilled are not in order of the sort keys
+		plan.SortedScan = nil
+		plan.IndexLookup = chooseIndexLookup(plan.Snapshot, plan.QueryDef.WhereExpr)
+	}
+	return nil
+}
+
+func (p *Planner) planSemiJoin(condition openapi.ColumnExpression, outer *Mapper, s *scope) (SemiJoin, error) {
+	var semiJoin SemiJoin
+	var subquery openapi.SelectQuery
+	switch c := condition.Expression.(type) {
+	case openapi.InSubquery:
+		if len(c.Subquery.ColumnClauses) != 1 {
+			return SemiJoin{}, fmt.Errorf("subquery of IN must return a single column, got %d", len(c.Subquery.ColumnClauses))
+		}
+		operand, err := outer.MapExpression(c.Operand)
+		if err != nil {
+			return SemiJoin{}, err
+		}
+		semiJoin = SemiJoin{Anti: c.Negated, Keys: []expr.Expression{operand}, SubqueryKeys: []int{0}}
+		subquery = c.Subquery
+	case openapi.ExistsSubquery:
+		semiJoin = SemiJoin{Anti: c.Negated}
+		subquery = c.Subquery
+	}
+
+	subquery, innerKeys, outerKeys, err := splitCorrelation(subquery, outer)
+	if err != nil {
+		return SemiJoin{}, err
+	}
+	for i := range innerKeys {
+		semiJoin.SubqueryKeys = append(semiJoin.SubqueryKeys, len(subquery.ColumnClauses)+i)
+	}
+	subquery.ColumnClauses = append(append([]openapi.ColumnExpression(nil), subquery.ColumnClauses...), innerKeys...)
+	semiJoin.Keys = append(semiJoin.Keys, outerKeys...)
+
+	subPlan, err := p.planSelectIn(subquery, s)
+	if err != nil {
+		return SemiJoin{}, err
+	}
+	semiJoin.Subquery = subPlan.(*SelectPlan)
+	for i, key := range semiJoin.Keys {
+		subqueryKey := semiJoin.Subquery.QueryDef.SelectExpr[semiJoin.SubqueryKeys[i]]
+		if key.ResultType() != subqueryKey.ResultType() {
+			semiJoin.Subquery.Release()
+			return SemiJoin{}, fmt.Errorf("expression compared with column %d of the subquery must be of the same type", semiJoin.SubqueryKeys[i])
+		}
+	}
+	return semiJoin, nil
+}
+
+// splitCorrelation removes conditions comparing columns of the subquery with columns of the outer query from its
+// where clause, returning the compared expressions of both sides. Columns of the outer query are referenced with
+// a table name or alias which is not one of the tables of the from clause of the subquery.
+func splitCorrelation(subquery openapi.SelectQuery, outer *Mapper) (openapi.SelectQuery, []openapi.ColumnExpression, []expr.Expression, error) {
+	if subquery.FromClause == nil || subquery.WhereClause.Expression == nil {
+		return subquery, nil, nil, nil
+	}
+	isOuter := outerReferenceCheck(*subquery.FromClause)
+
+	var innerKeys []openapi.ColumnExpression
+	var outerKeys []expr.Expression
+	var rest []openapi.ColumnExpression
+	for _, conjunct := range splitAPIConjunction(subquery.WhereClause) {
+		if !referencesColumns(conjunct, isOuter) {
+			rest = append(rest, conjunct)
+			continue
+		}
+
+		eq, ok := conjunct.Expression.(openapi.ColumnarBinaryOperation)
+		if !ok || eq.Operator != "EQUAL" {
+			return subquery, nil, nil, fmt.Errorf("conditions of a subquery referring to the outer query must be equalities")
+		}
+		innerSide, outerSide := eq.LeftOperand, eq.RightOperand
+		if referencesColumns(innerSide, isOuter) {
+			innerSide, outerSide = outerSide, innerSide
+		}
+		isInner := func(tableName string) bool { return !isOuter(tableName) }
+		if referencesColumns(innerSide, isOuter) || referencesColumns(outerSide, isInner) {
+			return subquery, nil, nil, fmt.Errorf("equalities of a subquery referring to the outer query must compare an expression of the subquery with an expression of the outer query")
+		}
+
+		outerKey, err := outer.MapExpression(outerSide)
+		if err != nil {
+			return subquery, nil, nil, err
+		}
+		innerKeys = append(innerKeys, innerSide)
+		outerKeys = append(outerKeys, outerKey)
+	}
+
+	if len(innerKeys) > 0 && (hasAggregates(subquery) || hasWindows(subquery) || subquery.LimitClause != nil) {
+		return subquery, nil, nil, fmt.Errorf("subqueries referring to the outer query cannot use aggregates, window functions or limit")
+	}
+	subquery.WhereClause = joinAPIConjunction(rest)
+	return subquery, innerKeys, outerKeys, nil
+}
+
+// outerReferenceCheck returns a function telling whether a table name given in a column reference of the subquery
+// doesn't name any of its tables
+func outerReferenceCheck(from openapi.FromClause) func(tableName string) bool {
+	inner := make(map[string]bool)
+	addTable := func(tableName, alias string) {
+		if alias == "" {
+			_, alias = metadata.SplitQualifiedName(tableName)
+		}
+		inner[alias] = true
+		inner[metadata.QualifiedName(tableName)] = true
+	}
+	addTable(from.TableName, from.Alias)
+	for _, join := range from.Joins {
+		addTable(join.TableName, join.Alias)
+	}
+	return func(tableName string) bool {
+		return tableName != "" && !inner[tableName] && !inner[metadata.QualifiedName(tableName)]
+	}
+}
+
+// referencesColumns reports whether the expression references a column of a table matching the predicate,
+// expressions of nested subqueries are not inspected
+func referencesColumns(e openapi.ColumnExpression, matches func(tableName string) bool) bool {
+	switch e := e.Expression.(type) {
+	case openapi.ColumnReferenceExpression:
+		return matches(e.TableName)
+	case openapi.ColumnarBinaryOperation:
+		return referencesColumns(e.LeftOperand, matches) || referencesColumns(e.RightOperand, matches)
+	case openapi.ColumnarUnaryOperation:
+		return referencesColumns(e.Operand, matches)
+	case openapi.Function:
+		for _, arg := range e.Arguments {
+			if referencesColumns(arg, matches) {
+				return true
+			}
+		}
+	case openapi.AggregateFunction:
+		return e.Argument != nil && referencesColumns(*e.Argument, matches)
+	case openapi.InSubquery:
+		return referencesColumns(e.Operand, matches)
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package planner
+
+import (
+	"testing"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/expr"
+)
+
+func TestSplitSubqueryConditions(t *testing.T) {
+	colRef := func(table, name string) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{TableName: table, ColumnName: name}}
+	}
+	binary := func(op string, l, r openapi.ColumnExpression) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.ColumnarBinaryOperation{Operator: op, LeftOperand: l, RightOperand: r}}
+	}
+	in := openapi.ColumnExpression{Expression: openapi.InSubquery{Operand: colRef("o", "id")}}
+	exists := openapi.ColumnExpression{Expression: openapi.ExistsSubquery{Negated: true}}
+	cond := binary("GREATER", colRef("o", "id"), colRef("o", "customer"))
+
+	query, subqueries := splitSubqueryConditions(openapi.SelectQuery{WhereClause: binary("AND", binary("AND", in, cond), exists)})
+	if len(subqueries) != 2 {
+		t.Fatalf("Expected 2 subqueries, got %d", len(subqueries))
+	}
+	if _, ok := subqueries[1].Expression.(openapi.ExistsSubquery); !ok {
+		t.Errorf("Expected EXISTS subquery second, got %T", subqueries[1].Expression)
+	}
+	if op, ok := query.WhereClause.Expression.(openapi.ColumnarBinaryOperation); !ok || op.Operator != "GREATER" {
+		t.Errorf("Expected the remaining where clause, got %+v", query.WhereClause.Expression)
+	}
+
+	query, subqueries = splitSubqueryConditions(openapi.SelectQuery{WhereClause: in})
+	if len(subqueries) != 1 || query.WhereClause.Expression != nil {
+		t.Errorf("Expected an empty where clause and 1 subquery, got %+v and %d", query.WhereClause.Expression, len(subqueries))
+	}
+
+	// subqueries under OR stay in the where clause and are rejected by the mapper
+	query, subqueries = splitSubqueryConditions(openapi.SelectQuery{WhereClause: binary("OR", in, cond)})
+	if len(subqueries) != 0 {
+		t.Errorf("Expected no subqueries, got %d", len(subqueries))
+	}
+	if _, err := NewJoinMapper(joinedTables()).MapExpression(query.WhereClause); err == nil {
+		t.Errorf("Expected mapping a subquery under OR to fail")
+	}
+}
+
+func TestSplitCorrelation(t *testing.T) {
+	colRef := func(table, name string) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{TableName: table, ColumnName: name}}
+	}
+	binary := func(op string, l, r openapi.ColumnExpression) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.ColumnarBinaryOperation{Operator: op, LeftOperand: l, RightOperand: r}}
+	}
+	literal := openapi.ColumnExpression{Expression: openapi.Literal{Value: openapi.LiteralValue{Data: "bob"}}}
+	outer := NewJoinMapper(joinedTables())
+	subquery := func(where openapi.ColumnExpression) openapi.SelectQuery {
+		return openapi.SelectQuery{
+			ColumnClauses: []openapi.ColumnExpression{colRef("i", "id")},
+			FromClause:    &openapi.FromClause{TableName: "items", Alias: "i"},
+			WhereClause:   where,
+		}
+	}
+
+	t.Run("correlated equality", func(t *testing.T) {
+		query, innerKeys, outerKeys, err := splitCorrelation(subquery(binary("AND",
+			binary("EQUAL", colRef("o", "id"), colRef("i", "order")),
+			binary("EQUAL", colRef("i", "name"), literal))), outer)
+		if err != nil {
+			t.Fatalf("splitCorrelation failed: %v", err)
+		}
+		if len(innerKeys) != 1 || innerKeys[0].Expression.(openapi.ColumnReferenceExpression).ColumnName != "order" {
+			t.Fatalf("Expected inner key i.order, got %+v", innerKeys)
+		}
+		if len(outerKeys) != 1 || outerKeys[0].(*expr.ColumnRefExpr).ColName != "o.id" {
+			t.Fatalf("Expected outer key o.id, got %+v", outerKeys)
+		}
+		if op := query.WhereClause.Expression.(openapi.ColumnarBinaryOperation); op.LeftOperand.Expression.(openapi.ColumnReferenceExpression).ColumnName != "name" {
+			t.Errorf("Expected the uncorrelated condition to stay, got %+v", op)
+		}
+	})
+
+	t.Run("uncorrelated", func(t *testing.T) {
+		_, innerKeys, _, err := splitCorrelation(subquery(binary("EQUAL", colRef("items", "name"), literal)), outer)
+		if err != nil || len(innerKeys) != 0 {
+			t.Errorf("Expected no correlation, got %+v, %v", innerKeys, err)
+		}
+	})
+
+	errorCases := map[string]openapi.SelectQuery{
+		"not an equality":  subquery(binary("GREATER", colRef("o", "id"), colRef("i", "order"))),
+		"both sides outer": subquery(binary("EQUAL", colRef("o", "id"), colRef("o", "customer"))),
+		"with aggregates": func() openapi.SelectQuery {
+			q := subquery(binary("EQUAL", colRef("o", "id"), colRef("i", "order")))
+			q.ColumnClauses = []openapi.ColumnExpression{{Expression: openapi.AggregateFunction{AggregateName: "COUNT"}}}
+			return q
+		}(),
+	}
+	for name, query := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			if _, _, _, err := splitCorrelation(query, outer); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"isbd4/openapi"
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+// SystemTable is a read only virtual table of the system database, its rows are generated when a query is planned
+type SystemTable struct {
+	Columns []metadata.ColumnDef
+	Rows    func() *tomy_file.ColumnarTable
+}
+
+// RegisterSystemTable makes the table queryable as system.<name>
+func (p *Planner) RegisterSystemTable(name string, table *SystemTable) {
+	p.systemTables[name] = table
+}
+
+func (p *Planner) planSystemTableQuery(apiQueryDef openapi.SelectQuery, tableName string) (QueryPlan, error) {
+	_, name := metadata.SplitQualifiedName(tableName)
+	table, exists := p.systemTables[name]
+	if !exists {
+		return nil, fmt.Errorf("system table %s does not exist", tableName)
+	}
+	if apiQueryDef.AsOf != nil {
+		return nil, fmt.Errorf("system table %s has no versions", tableName)
+	}
+
+	snapshot := &metadata.MetastoreSnapshot{Columns: table.Columns}
+	selectQueryDef, err := validateAndMapQuery(apiQueryDef, tableName, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SelectPlan{
+		Snapshot:    snapshot,
+		QueryDef:    selectQueryDef,
+		SystemTable: table.Rows(),
+	}, nil
+}
+
+// SystemRows builds rows of a system table, values are int64 or string in the order of columns
+type SystemRows struct {
+	table *tomy_file.ColumnarTable
+}
+
+func NewSystemRows(columns []metadata.ColumnDef) *SystemRows {
+	table := &tomy_file.ColumnarTable{Columns: make([]tomy_file.AnyColumn, len(columns))}
+	for i, col := range columns {
+		if col.Type == metadata.VarcharType {
+			table.Columns[i] = &tomy_file.VarcharColumn{Name: col.Name}
+		} else {
+			table.Columns[i] = &tomy_file.Int64Column{Name: col.Name}
+		}
+	}
+	return &SystemRows{table: table}
+}
+
+func (r *SystemRows) Append(values ...any) {
+	for i, v := range values {
+		switch c := r.table.Columns[i].(type) {
+		case *tomy_file.Int64Column:
+			c.Values = append(c.Values, v.(int64))
+		case *tomy_file.VarcharColumn:
+			c.Offsets = append(c.Offsets, uint64(len(c.Data)))
+			c.Data = append(c.Data, v.(string)...)
+		}
+	}
+	r.table.NumRows++
+}
+
+func (r *SystemRows) Table() *tomy_file.ColumnarTable {
+	return r.table
+}
+
+var (
+	systemTablesColumns = []metadata.ColumnDef{
+		{Name: "table_id", Type: metadata.VarcharType},
+		{Name: "database", Type: metadata.VarcharType},
+		{Name: "name", Type: metadata.VarcharType},
+		{Name: "column_count", Type: metadata.Int64Type},
+		{Name: "sort_keys", Type: metadata.VarcharType},
+		{Name: "version", Type: metadata.Int64Type},
+		{Name: "file_count", Type: metadata.Int64Type},
+		{Name: "row_count", Type: metadata.Int64Type},
+		{Name: "size_bytes", Type: metadata.Int64Type},
+	}
+	systemColumnsColumns = []metadata.ColumnDef{
+		{Name: "database", Type: metadata.VarcharType},
+		{Name: "table_name", Type: metadata.VarcharType},
+		{Name: "name", Type: metadata.VarcharType},
+		{Name: "type", Type: metadata.VarcharType},
+		{Name: "position", Type: metadata.Int64Type},
+	}
+	systemFilesColumns = []metadata.ColumnDef{
+		{Name: "database", Type: metadata.VarcharType},
+		{Name: "table_name", Type: metadata.VarcharType},
+		{Name: "path", Type: metadata.VarcharType},
+		{Name: "rows", Type: metadata.Int64Type},
+		{Name: "deleted_rows", Type: metadata.Int64Type},
+		{Name: "bytes", Type: metadata.Int64Type},
+	}
+)
+
+// registerCatalogTables adds system tables describing tables of the metastore
+func (p *Planner) registerCatalogTables() {
+	p.RegisterSystemTable("tables", &SystemTable{Columns: systemTablesColumns, Rows: func() *tomy_file.ColumnarTable {
+		rows := NewSystemRows(systemTablesColumns)
+		for _, t := range p.Metastore.Catalog() {
+			sortKeys := make([]string, len(t.SortKeys))
+			for i, k := range t.SortKeys {
+				sortKeys[i] = k.Column
+				if !k.Ascending {
+					sortKeys[i] += " DESC"
+				}
+			}
+			var rowCount, sizeBytes int64
+			for _, f := range t.Files {
+				rowCount += int64(f.NumRows - f.DeletedRows)
+				sizeBytes += f.SizeBytes
+			}
+			rows.Append(t.Id, t.Database, t.Name, int64(len(t.Columns)), strings.Join(sortKeys, ", "),
+				int64(t.Version), int64(len(t.Files)), rowCount, sizeBytes)
+		}
+		return rows.Table()
+	}})
+
+	p.RegisterSystemTable("columns", &SystemTable{Columns: systemColumnsColumns, Rows: func() *tomy_file.ColumnarTable {
+		rows := NewSystemRows(systemColumnsColumns)
+		for _, t := range p.Metastore.Catalog() {
+			for i, col := range t.Columns {
+				rows.Append(t.Database, t.Name, col.Name, string(col.Type), int64(i))
+			}
+		}
+		return rows.Table()
+	}})
+
+	p.RegisterSystemTable("files", &SystemTable{Columns: systemFilesColumns, Rows: func() *tomy_file.ColumnarTable {
+		rows := NewSystemRows(systemFilesColumns)
+		for _, t := range p.Metastore.Catalog() {
+			for _, f := range t.Files {
+				rows.Append(t.Database, t.Name, f.Path, int64(f.NumRows), int64(f.DeletedRows), f.SizeBytes)
+			}
+		}
+		return rows.Table()
+	}})
+}
@@ -0,0 +1,59 @@
+package planner
+
+import (
+	"reflect"
+	"testing"
+
+	"isbd4/openapi"
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+func TestPlanSelect_SystemTables(t *testing.T) {
+	m := metadata.NewMetastore(t.TempDir())
+	if _, err := m.CreateTable("t1", []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "name", Type: metadata.VarcharType},
+	}, nil); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	p := NewPlanner(m)
+
+	systemRef := func(table, column string) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{TableName: table, ColumnName: column}}
+	}
+	plan, err := p.PlanSelect(openapi.SelectQuery{
+		ColumnClauses: []openapi.ColumnExpression{systemRef("system.columns", "name"), systemRef("system.columns", "type")},
+		WhereClause: openapi.ColumnExpression{Expression: openapi.ColumnarBinaryOperation{
+			Operator:     "EQUAL",
+			LeftOperand:  systemRef("system.columns", "table_name"),
+			RightOperand: openapi.ColumnExpression{Expression: openapi.Literal{Value: openapi.LiteralValue{Data: "t1"}}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("PlanSelect failed: %v", err)
+	}
+	// rows are filtered by the executor, the plan holds the whole table
+	rows := plan.(*SelectPlan).SystemTable
+	if rows == nil || rows.NumRows != 2 {
+		t.Fatalf("Expected 2 rows of system.columns, got %+v", rows)
+	}
+	names := rows.Columns[2].(*tomy_file.VarcharColumn)
+	if expected := "idname"; string(names.Data) != expected || !reflect.DeepEqual(names.Offsets, []uint64{0, 2}) {
+		t.Errorf("Expected column names id and name, got %q", names.Data)
+	}
+
+	if _, err := p.PlanSelect(openapi.SelectQuery{
+		ColumnClauses: []openapi.ColumnExpression{systemRef("system.missing", "name")},
+	}); err == nil {
+		t.Errorf("Expected error for not existing system table")
+	}
+	if _, err := p.PlanSelect(openapi.SelectQuery{
+		ColumnClauses: []openapi.ColumnExpression{systemRef("system.tables", "size")},
+	}); err == nil {
+		t.Errorf("Expected error for not existing column of system table")
+	}
+	if err := m.CreateDatabase(metadata.SystemDatabase); err == nil {
+		t.Errorf("Expected error when creating the system database")
+	}
+}
@@ -0,0 +1,85 @@
+package planner
+
+import (
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+)
+
+const (
+	// memory of a row kept by the top-N operator besides its values, and of every value besides its data
+	topNRowOverheadBytes   = 48
+	topNValueOverheadBytes = 16
+	// size of a VARCHAR value without statistics of its column
+	defaultVarcharBytes = 32
+)
+
+// useTopN reports whether ORDER BY with LIMIT keeps only the limited rows in a heap instead of sorting all rows,
+// which is when the limited rows are estimated to fit in the memory limit. Otherwise the external sort is used.
+func (p *Planner) useTopN(plan *SelectPlan) bool {
+	queryDef := plan.QueryDef
+	if len(queryDef.OrderByClause) == 0 || queryDef.Limit < 0 || plan.SortedScan != nil {
+		return false
+	}
+	if p.MemoryLimitBytes == 0 {
+		return true
+	}
+	// rows skipped by the offset are kept too
+	return uint64(queryDef.Offset+queryDef.Limit)*estimateRowBytes(plan) <= p.MemoryLimitBytes
+}
+
+// estimateRowBytes estimates the memory of a selected row, sizes of VARCHAR columns of tables are averaged
+// from statistics of their files
+func estimateRowBytes(plan *SelectPlan) uint64 {
+	bytes := uint64(topNRowOverheadBytes)
+	for _, e := range plan.QueryDef.SelectExpr {
+		bytes += topNValueOverheadBytes
+		switch e.ResultType() {
+		case types.ChunkColumnTypeInt64:
+			bytes += 8
+		case types.ChunkColumnTypeBoolean:
+			bytes += 1
+		default:
+			bytes += averageVarcharBytes(plan, e)
+		}
+	}
+	return bytes
+}
+
+func averageVarcharBytes(plan *SelectPlan, e expr.Expression) uint64 {
+	colRef, ok := e.(*expr.ColumnRefExpr)
+	if !ok {
+		return defaultVarcharBytes
+	}
+	snapshot, column := plan.Snapshot, colRef.ColName
+	for _, table := range plan.Tables {
+		for _, col := range table.Columns {
+			if col.Name == colRef.ColName {
+				snapshot, column = table.Snapshot, col.TableColumn
+			}
+		}
+	}
+	if snapshot == nil {
+		return defaultVarcharBytes
+	}
+	return averageColumnBytes(snapshot.Files, column)
+}
+
+func averageColumnBytes(files []*metadata.FileEntry, column string) uint64 {
+	var bytes, rows uint64
+	for _, f := range files {
+		if f.Stats == nil {
+			continue
+		}
+		for _, col := range f.Stats.Columns {
+			if col.Name == column {
+				bytes += uint64(col.UncompressedBytes)
+				rows += f.Stats.NumRows
+			}
+		}
+	}
+	if rows == 0 {
+		return defaultVarcharBytes
+	}
+	return bytes/rows + 1
+}
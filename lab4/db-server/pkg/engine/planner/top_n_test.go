@@ -0,0 +1,51 @@
+package planner
+
+import (
+	"testing"
+
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+)
+
+func TestUseTopN(t *testing.T) {
+	snapshot := &metadata.MetastoreSnapshot{Files: []*metadata.FileEntry{
+		{Stats: &metadata.FileStats{NumRows: 100, Columns: []metadata.FileColumnStats{{Name: "name", UncompressedBytes: 10000}}}},
+	}}
+	plan := func(limit int, orderBy ...OrderByColumnReference) *SelectPlan {
+		return &SelectPlan{Snapshot: snapshot, QueryDef: &SelectQueryDefinition{
+			SelectExpr: []expr.Expression{
+				&expr.ColumnRefExpr{ColName: "id", ColType: types.ChunkColumnTypeInt64},
+				&expr.ColumnRefExpr{ColName: "name", ColType: types.ChunkColumnTypeVarchar},
+			},
+			OrderByClause: orderBy,
+			Limit:         limit,
+		}}
+	}
+	byId := OrderByColumnReference{Index: 0, Ascending: false}
+
+	// a row holds 48 bytes of overhead, 2 values of 16 bytes, the id and 101 bytes of the name
+	if got := estimateRowBytes(plan(10, byId)); got != 189 {
+		t.Errorf("Expected rows of 189 bytes, got %d", got)
+	}
+
+	p := &Planner{MemoryLimitBytes: 1890}
+	tests := []struct {
+		name string
+		plan *SelectPlan
+		want bool
+	}{
+		{"rows fit in memory", plan(10, byId), true},
+		{"rows exceed memory", plan(11, byId), false},
+		{"no limit", plan(-1, byId), false},
+		{"no order", plan(10), false},
+		{"sorted scan", &SelectPlan{QueryDef: plan(10, byId).QueryDef, SortedScan: []metadata.SortKey{{Column: "id"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.useTopN(tt.plan); got != tt.want {
+				t.Errorf("Expected useTopN %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"isbd4/pkg/engine/expr"
 	"isbd4/pkg/engine/types"
 	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+	"strings"
 )
 
 func extractTableName(queryDef openapi.SelectQuery) (string, bool) {
@@ -26,6 +28,22 @@ func extractTableName(queryDef openapi.SelectQuery) (string, bool) {
 		}
 		hasColumnRefs = hasColumnRefs || foundColRefs
 	}
+
+	for _, col := range queryDef.GroupByClause {
+		name, foundColRefs := traverseExpression(col)
+		if name != "" {
+			return name, true
+		}
+		hasColumnRefs = hasColumnRefs || foundColRefs
+	}
+
+	if queryDef.HavingClause.Expression != nil {
+		name, foundColRefs := traverseExpression(queryDef.HavingClause)
+		if name != "" {
+			return name, true
+		}
+		hasColumnRefs = hasColumnRefs || foundColRefs
+	}
 	return "", hasColumnRefs
 }
 
@@ -62,14 +80,51 @@ func traverseExpression(col openapi.ColumnExpression) (tableName string, hasColu
 			anyFound = anyFound || f
 		}
 		return "", anyFound
+	case openapi.AggregateFunction:
+		if e.TableName != "" {
+			return e.TableName, false
+		}
+		if e.Argument == nil {
+			return "", false
+		}
+		return traverseExpression(*e.Argument)
+	case openapi.WindowFunction:
+		exprs := append([]openapi.ColumnExpression(nil), e.PartitionBy...)
+		for _, order := range e.OrderBy {
+			exprs = append(exprs, order.Expression)
+		}
+		if e.Argument != nil {
+			exprs = append(exprs, *e.Argument)
+		}
+		anyFound := false
+		for _, windowExpr := range exprs {
+			n, f := traverseExpression(windowExpr)
+			if n != "" {
+				return n, true
+			}
+			anyFound = anyFound || f
+		}
+		return "", anyFound
 	}
 	return "", false
 }
 
 func validateAndMapQuery(apiQueryDef openapi.SelectQuery, tableName string, msSnapshot *metadata.MetastoreSnapshot) (*SelectQueryDefinition, error) {
+	mapper, err := NewMapper(msSnapshot, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if apiQueryDef.FromClause != nil {
+		mapper.alias = apiQueryDef.FromClause.Alias
+	}
+	return validateAndMapQueryWith(apiQueryDef, tableName, mapper)
+}
+
+// validateAndMapQueryWith maps expressions of the query by the given mapper, e.g. of joined tables
+func validateAndMapQueryWith(apiQueryDef openapi.SelectQuery, tableName string, mapper *Mapper) (*SelectQueryDefinition, error) {
 	ve := &types.ValidationError{}
 
-	selectExprs, whereExpr, exprErrs := validateAndPrepareExpressions(apiQueryDef, msSnapshot, tableName)
+	selectQueryDef, exprErrs := validateAndPrepareExpressions(apiQueryDef, mapper)
 	if exprErrs != nil {
 		ve.Extend(exprErrs)
 	}
@@ -79,7 +134,7 @@ func validateAndMapQuery(apiQueryDef openapi.SelectQuery, tableName string, msSn
 		ve.Extend(orderByErrs)
 	}
 
-	limit, err := validateAndExtractLimit(apiQueryDef.LimitClause)
+	limit, offset, err := validateAndExtractLimit(apiQueryDef.LimitClause)
 	if err != nil {
 		ve.Extend(err)
 	}
@@ -88,29 +143,49 @@ func validateAndMapQuery(apiQueryDef openapi.SelectQuery, tableName string, msSn
 		return nil, ve
 	}
 
-	selectQueryDef := &SelectQueryDefinition{
-		TableName:     tableName,
-		SelectExpr:    selectExprs,
-		WhereExpr:     whereExpr,
-		OrderByClause: orderByClause,
-		Limit:         limit,
+	selectQueryDef.TableName = tableName
+	selectQueryDef.OrderByClause = orderByClause
+	selectQueryDef.Limit = limit
+	selectQueryDef.Offset = offset
+	selectQueryDef.Distinct = apiQueryDef.Distinct
+	if clause := apiQueryDef.LimitClause; clause != nil && len(clause.After) > 0 {
+		if err := addKeysetCondition(selectQueryDef, clause.After, mapper); err != nil {
+			return nil, types.NewVErr(err.Error(), "LimitClause")
+		}
 	}
-
 	return selectQueryDef, nil
 }
 
-func validateAndPrepareExpressions(apiQueryDef openapi.SelectQuery, msSnapshot *metadata.MetastoreSnapshot, tableName string) ([]expr.Expression, expr.Expression, error) {
-	mapper, err := NewMapper(msSnapshot, tableName)
-	if err != nil {
-		return nil, nil, err
-	}
+// validateAndPrepareExpressions maps select and where expressions, for queries grouping rows it also returns
+// the aggregation and the having expression, which refer to its output like the select expressions.
+// Select expressions of queries with window functions refer to output columns of the windows.
+func validateAndPrepareExpressions(apiQueryDef openapi.SelectQuery, mapper *Mapper) (*SelectQueryDefinition, error) {
 	ve := &types.ValidationError{}
 
+	selectMapper := mapper
+	var aggregation *Aggregation
+	var wm *windowMapper
+	if hasAggregates(apiQueryDef) {
+		am, err := newAggregationMapper(mapper, apiQueryDef.GroupByClause)
+		if err != nil {
+			return nil, err
+		}
+		selectMapper = am.mapper()
+		aggregation = am.aggregation
+	} else if hasWindows(apiQueryDef) {
+		wm = newWindowMapper(mapper)
+		selectMapper = wm.mapper()
+	}
+
 	selectExprs := make([]expr.Expression, len(apiQueryDef.ColumnClauses))
 	for i, selectExpr := range apiQueryDef.ColumnClauses {
-		mappedExpr, err := mapper.MapExpression(selectExpr)
+		mappedExpr, err := selectMapper.MapExpression(selectExpr)
 		if err != nil {
-			ve.Extend(err)
+			if aggregation != nil || wm != nil {
+				extendWithContext(ve, err, fmt.Sprintf("ColumnClause %d", i))
+			} else {
+				ve.Extend(err)
+			}
 		}
 		selectExprs[i] = mappedExpr
 	}
@@ -124,11 +199,285 @@ func validateAndPrepareExpressions(apiQueryDef openapi.SelectQuery, msSnapshot *
 		ve.Add("where expression must return boolean", "WhereClause")
 	}
 
+	var havingExpr expr.Expression
+	if apiQueryDef.HavingClause.Expression != nil {
+		havingExpr, err = selectMapper.MapExpression(apiQueryDef.HavingClause)
+		if err != nil {
+			extendWithContext(ve, err, "HavingClause")
+		} else if havingExpr.ResultType() != types.ChunkColumnTypeBoolean {
+			ve.Add("having expression must return boolean", "HavingClause")
+		}
+	}
+
 	if ve.HasProblems() {
-		return nil, nil, ve
+		return nil, ve
+	}
+
+	queryDef := &SelectQueryDefinition{
+		SelectExpr:  selectExprs,
+		WhereExpr:   whereExpr,
+		Aggregation: aggregation,
+		HavingExpr:  havingExpr,
+	}
+	if wm != nil {
+		queryDef.Windows = wm.finish()
 	}
+	return queryDef, nil
+}
 
-	return selectExprs, whereExpr, nil
+// extendWithContext adds problems of the error, problems without a context are given the context of the clause
+func extendWithContext(ve *types.ValidationError, err error, context string) {
+	other, ok := err.(*types.ValidationError)
+	if !ok {
+		ve.Add(err.Error(), context)
+		return
+	}
+	for _, p := range other.Problems {
+		if p.Context == "" {
+			p.Context = context
+		}
+		ve.Add(p.Error, p.Context)
+	}
+}
+
+// validateAndMapWhere maps the where clause of a query modifying the table, returns nil if it is not given
+func validateAndMapWhere(whereClause openapi.ColumnExpression, tableName string, msSnapshot *metadata.MetastoreSnapshot) (expr.Expression, error) {
+	if whereClause.Expression == nil {
+		return nil, nil
+	}
+
+	mapper, err := NewMapper(msSnapshot, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	whereExpr, err := mapper.MapExpression(whereClause)
+	if err != nil {
+		return nil, err
+	}
+	if whereExpr.ResultType() != types.ChunkColumnTypeBoolean {
+		return nil, types.NewVErr("where expression must return boolean", "WhereClause")
+	}
+	return whereExpr, nil
+}
+
+func validateAndMapAssignments(setClauses []openapi.ColumnAssignment, tableName string, msSnapshot *metadata.MetastoreSnapshot) ([]ColumnAssignment, error) {
+	mapper, err := NewMapper(msSnapshot, tableName)
+	if err != nil {
+		return nil, err
+	}
+	ve := &types.ValidationError{}
+
+	colIdx := make(map[string]int, len(msSnapshot.Columns))
+	for i, col := range msSnapshot.Columns {
+		colIdx[col.Name] = i
+	}
+
+	assigned := make(map[string]bool, len(setClauses))
+	assignments := make([]ColumnAssignment, 0, len(setClauses))
+	for i, clause := range setClauses {
+		context := fmt.Sprintf("SetClause %d", i)
+		idx, ok := colIdx[clause.ColumnName]
+		if !ok {
+			ve.Add(fmt.Sprintf("column %s not found in table %s", clause.ColumnName, tableName), context)
+			continue
+		}
+		if assigned[clause.ColumnName] {
+			ve.Add(fmt.Sprintf("column %s is assigned more than once", clause.ColumnName), context)
+			continue
+		}
+		assigned[clause.ColumnName] = true
+
+		mappedExpr, err := mapper.MapExpression(clause.Expression)
+		if err != nil {
+			ve.Extend(err)
+			continue
+		}
+		colType, err := types.ChunkColumnTypeFromMetadataColumnType(msSnapshot.Columns[idx].Type)
+		if err != nil {
+			return nil, err
+		}
+		if mappedExpr.ResultType() != colType {
+			ve.Add(fmt.Sprintf("expression assigned to column %s must return %s", clause.ColumnName, msSnapshot.Columns[idx].Type), context)
+			continue
+		}
+		assignments = append(assignments, ColumnAssignment{ColumnIdx: idx, Expr: mappedExpr})
+	}
+
+	if ve.HasProblems() {
+		return nil, ve
+	}
+	return assignments, nil
+}
+
+// validateAndBuildInsertedRows checks that values of the INSERT query match columns of the table
+// and converts them to a columnar table with columns in the order of the table definition
+func validateAndBuildInsertedRows(apiQueryDef openapi.InsertQuery, columns []metadata.ColumnDef) (*tomy_file.ColumnarTable, error) {
+	ve := &types.ValidationError{}
+
+	// position of values of every column of the table in a row of the query
+	valueIdx := make([]int, len(columns))
+	if len(apiQueryDef.Columns) == 0 {
+		for i := range columns {
+			valueIdx[i] = i
+		}
+	} else {
+		given := make(map[string]int, len(apiQueryDef.Columns))
+		for i, name := range apiQueryDef.Columns {
+			if _, ok := given[name]; ok {
+				ve.Add(fmt.Sprintf("column %s is given more than once", name), "Columns")
+			}
+			given[name] = i
+		}
+		for i, col := range columns {
+			idx, ok := given[col.Name]
+			if !ok {
+				ve.Add(fmt.Sprintf("no value given for column %s", col.Name), "Columns")
+			}
+			valueIdx[i] = idx
+			delete(given, col.Name)
+		}
+		for name := range given {
+			ve.Add(fmt.Sprintf("column %s not found in table", name), "Columns")
+		}
+	}
+	if ve.HasProblems() {
+		return nil, ve
+	}
+	width := len(columns)
+
+	var values [][]openapi.LiteralValue // values of every column of the query
+	switch {
+	case len(apiQueryDef.Rows) > 0 && len(apiQueryDef.ColumnarValues) > 0:
+		return nil, types.NewVErr("only one of rows and columnar values can be given", "")
+	case len(apiQueryDef.Rows) > 0:
+		values = make([][]openapi.LiteralValue, width)
+		for r, row := range apiQueryDef.Rows {
+			if len(row) != width {
+				ve.Add(fmt.Sprintf("row has %d values, expected %d", len(row), width), fmt.Sprintf("Row %d", r))
+				continue
+			}
+			for i, v := range row {
+				values[i] = append(values[i], v)
+			}
+		}
+	case len(apiQueryDef.ColumnarValues) > 0:
+		values = apiQueryDef.ColumnarValues
+		if len(values) != width {
+			return nil, types.NewVErr(fmt.Sprintf("got values of %d columns, expected %d", len(values), width), "ColumnarValues")
+		}
+		for i := range values {
+			if len(values[i]) != len(values[0]) {
+				ve.Add(fmt.Sprintf("column has %d values, expected %d", len(values[i]), len(values[0])), fmt.Sprintf("ColumnarValues %d", i))
+			}
+		}
+	default:
+		return nil, types.NewVErr("no values to insert", "")
+	}
+	if ve.HasProblems() {
+		return nil, ve
+	}
+
+	table := &tomy_file.ColumnarTable{
+		NumRows: uint64(len(values[0])),
+		Columns: make([]tomy_file.AnyColumn, len(columns)),
+	}
+	for i, col := range columns {
+		colValues := values[valueIdx[i]]
+		switch col.Type {
+		case metadata.Int64Type:
+			tomyCol := &tomy_file.Int64Column{Name: col.Name, Values: make([]int64, 0, len(colValues))}
+			for r, v := range colValues {
+				val, ok := v.Data.(int64)
+				if !ok {
+					ve.Add(fmt.Sprintf("value %v of column %s is not INT64", v.Data, col.Name), fmt.Sprintf("Row %d", r))
+				}
+				tomyCol.Values = append(tomyCol.Values, val)
+			}
+			table.Columns[i] = tomyCol
+		case metadata.VarcharType:
+			tomyCol := &tomy_file.VarcharColumn{Name: col.Name, Offsets: make([]uint64, 0, len(colValues))}
+			for r, v := range colValues {
+				val, ok := v.Data.(string)
+				if !ok {
+					ve.Add(fmt.Sprintf("value %v of column %s is not VARCHAR", v.Data, col.Name), fmt.Sprintf("Row %d", r))
+				}
+				tomyCol.Offsets = append(tomyCol.Offsets, uint64(len(tomyCol.Data)))
+				tomyCol.Data = append(tomyCol.Data, val...)
+			}
+			table.Columns[i] = tomyCol
+		default:
+			return nil, fmt.Errorf("unknown column type: %s", col.Type)
+		}
+	}
+
+	if ve.HasProblems() {
+		return nil, ve
+	}
+	return table, nil
+}
+
+// validateAndDeriveColumns returns columns of a table created from the select expressions.
+// Columns are named by the given names, by the referenced column or col_<index> for other expressions.
+// Columns referenced by queries with joins are named without the alias of their table.
+func validateAndDeriveColumns(selectExprs []expr.Expression, columnNames []string, joined bool) ([]metadata.ColumnDef, error) {
+	ve := &types.ValidationError{}
+	if len(columnNames) > 0 && len(columnNames) != len(selectExprs) {
+		return nil, types.NewVErr(fmt.Sprintf("got %d column names, expected %d", len(columnNames), len(selectExprs)), "ColumnNames")
+	}
+
+	seen := make(map[string]bool, len(selectExprs))
+	columns := make([]metadata.ColumnDef, len(selectExprs))
+	for i, e := range selectExprs {
+		context := fmt.Sprintf("ColumnClause %d", i)
+		name := fmt.Sprintf("col_%d", i)
+		if len(columnNames) > 0 {
+			name = columnNames[i]
+		} else if colRef, ok := e.(*expr.ColumnRefExpr); ok {
+			name = colRef.ColName
+			if _, column, found := strings.Cut(name, "."); found && joined {
+				name = column
+			}
+		}
+		if seen[name] {
+			ve.Add(fmt.Sprintf("duplicate column name %s", name), context)
+		}
+		seen[name] = true
+
+		colType, err := types.MetadataColumnTypeFromChunkColumnType(e.ResultType())
+		if err != nil {
+			ve.Add(fmt.Sprintf("column %s: %v", name, err), context)
+		}
+		columns[i] = metadata.ColumnDef{Name: name, Type: colType}
+	}
+
+	if ve.HasProblems() {
+		return nil, ve
+	}
+	return columns, nil
+}
+
+// validateSelectedColumns checks that the select expressions match columns of the table by position and type
+func validateSelectedColumns(selectExprs []expr.Expression, columns []metadata.ColumnDef) error {
+	if len(selectExprs) != len(columns) {
+		return types.NewVErr(fmt.Sprintf("query selects %d columns, table has %d", len(selectExprs), len(columns)), "ColumnClauses")
+	}
+
+	ve := &types.ValidationError{}
+	for i, e := range selectExprs {
+		colType, err := types.ChunkColumnTypeFromMetadataColumnType(columns[i].Type)
+		if err != nil {
+			return err
+		}
+		if e.ResultType() != colType {
+			ve.Add(fmt.Sprintf("expression inserted into column %s must return %s", columns[i].Name, columns[i].Type), fmt.Sprintf("ColumnClause %d", i))
+		}
+	}
+
+	if ve.HasProblems() {
+		return ve
+	}
+	return nil
 }
 
 func validateAndExtractOrderBy(orderByClauses []openapi.OrderByExpression, columnsCount int) ([]OrderByColumnReference, error) {
@@ -154,14 +503,21 @@ func validateAndExtractOrderBy(orderByClauses []openapi.OrderByExpression, colum
 	return orderByColumns, nil
 }
 
-func validateAndExtractLimit(limitClause *openapi.LimitExpression) (int, error) {
+// validateAndExtractLimit returns the limit, -1 without one, and the offset
+func validateAndExtractLimit(limitClause *openapi.LimitExpression) (int, int, error) {
 	if limitClause == nil {
-		return -1, nil
+		return -1, 0, nil
 	}
 
-	if limitClause.Limit < 0 {
-		return -1, fmt.Errorf("limit must be non-negative")
+	if limitClause.Offset < 0 {
+		return -1, 0, fmt.Errorf("offset must be non-negative")
+	}
+	if limitClause.Limit == nil {
+		return -1, int(limitClause.Offset), nil
+	}
+	if *limitClause.Limit < 0 {
+		return -1, 0, fmt.Errorf("limit must be non-negative")
 	}
 
-	return int(limitClause.Limit), nil
+	return int(*limitClause.Limit), int(limitClause.Offset), nil
 }
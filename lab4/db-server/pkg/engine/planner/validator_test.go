@@ -0,0 +1,148 @@
+package planner
+
+import (
+	"reflect"
+	"testing"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+func TestValidateAndMapAssignments(t *testing.T) {
+	snapshot := &metadata.MetastoreSnapshot{Columns: []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "name", Type: metadata.VarcharType},
+	}}
+	colRef := func(name string) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{ColumnName: name}}
+	}
+
+	assignments, err := validateAndMapAssignments([]openapi.ColumnAssignment{
+		{ColumnName: "name", Expression: colRef("name")},
+		{ColumnName: "id", Expression: openapi.ColumnExpression{Expression: openapi.ColumnarBinaryOperation{
+			Operator:     "ADD",
+			LeftOperand:  colRef("id"),
+			RightOperand: openapi.ColumnExpression{Expression: openapi.Literal{Value: openapi.LiteralValue{Data: int64(1)}}},
+		}}},
+	}, "t1", snapshot)
+	if err != nil {
+		t.Fatalf("validateAndMapAssignments failed: %v", err)
+	}
+	if len(assignments) != 2 || assignments[0].ColumnIdx != 1 || assignments[1].ColumnIdx != 0 {
+		t.Errorf("Unexpected assignments %+v", assignments)
+	}
+
+	_, err = validateAndMapAssignments([]openapi.ColumnAssignment{
+		{ColumnName: "missing", Expression: colRef("id")},
+		{ColumnName: "id", Expression: colRef("name")},
+		{ColumnName: "name", Expression: colRef("name")},
+		{ColumnName: "name", Expression: colRef("name")},
+	}, "t1", snapshot)
+	ve, ok := err.(*types.ValidationError)
+	if !ok || len(ve.Problems) != 3 {
+		t.Fatalf("Expected 3 validation problems, got %v", err)
+	}
+}
+
+func literals(values ...any) []openapi.LiteralValue {
+	res := make([]openapi.LiteralValue, len(values))
+	for i, v := range values {
+		res[i] = openapi.LiteralValue{Data: v}
+	}
+	return res
+}
+
+func TestValidateAndBuildInsertedRows(t *testing.T) {
+	columns := []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "name", Type: metadata.VarcharType},
+	}
+
+	byRows, err := validateAndBuildInsertedRows(openapi.InsertQuery{
+		IntoTableName: "t1",
+		Columns:       []string{"name", "id"},
+		Rows:          [][]openapi.LiteralValue{literals("a", int64(1)), literals("bc", int64(2))},
+	}, columns)
+	if err != nil {
+		t.Fatalf("validateAndBuildInsertedRows failed: %v", err)
+	}
+	byColumns, err := validateAndBuildInsertedRows(openapi.InsertQuery{
+		IntoTableName:  "t1",
+		ColumnarValues: [][]openapi.LiteralValue{literals(int64(1), int64(2)), literals("a", "bc")},
+	}, columns)
+	if err != nil {
+		t.Fatalf("validateAndBuildInsertedRows failed: %v", err)
+	}
+
+	for _, table := range []*tomy_file.ColumnarTable{byRows, byColumns} {
+		ids := table.Columns[0].(*tomy_file.Int64Column).Values
+		names := table.Columns[1].(*tomy_file.VarcharColumn)
+		if table.NumRows != 2 || !reflect.DeepEqual(ids, []int64{1, 2}) || string(names.Data) != "abc" || !reflect.DeepEqual(names.Offsets, []uint64{0, 1}) {
+			t.Errorf("Unexpected table %+v", table)
+		}
+	}
+
+	invalid := []openapi.InsertQuery{
+		{IntoTableName: "t1"},
+		{IntoTableName: "t1", Rows: [][]openapi.LiteralValue{literals(int64(1))}},
+		{IntoTableName: "t1", Rows: [][]openapi.LiteralValue{literals("a", "b")}},
+		{IntoTableName: "t1", Columns: []string{"id"}, Rows: [][]openapi.LiteralValue{literals(int64(1))}},
+		{IntoTableName: "t1", ColumnarValues: [][]openapi.LiteralValue{literals(int64(1)), literals("a", "b")}},
+	}
+	for i, q := range invalid {
+		if _, err := validateAndBuildInsertedRows(q, columns); err == nil {
+			t.Errorf("Expected error for invalid insert %d", i)
+		}
+	}
+}
+
+func TestValidateAndDeriveColumns(t *testing.T) {
+	selectExprs := []expr.Expression{
+		&expr.ColumnRefExpr{ColName: "id", ColType: types.ChunkColumnTypeInt64},
+		&expr.LiteralExpr{Value: "x", Type: types.ChunkColumnTypeVarchar},
+	}
+
+	columns, err := validateAndDeriveColumns(selectExprs, nil, false)
+	if err != nil {
+		t.Fatalf("validateAndDeriveColumns failed: %v", err)
+	}
+	expected := []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "col_1", Type: metadata.VarcharType},
+	}
+	if !reflect.DeepEqual(columns, expected) {
+		t.Errorf("Expected columns %+v, got %+v", expected, columns)
+	}
+
+	columns, err = validateAndDeriveColumns(selectExprs, []string{"a", "b"}, false)
+	if err != nil || columns[0].Name != "a" || columns[1].Name != "b" {
+		t.Errorf("Expected given column names, got %+v, %v", columns, err)
+	}
+
+	columns, err = validateAndDeriveColumns([]expr.Expression{
+		&expr.ColumnRefExpr{ColName: "u.id", ColType: types.ChunkColumnTypeInt64},
+	}, nil, true)
+	if err != nil || columns[0].Name != "id" {
+		t.Errorf("Expected joined column named without alias, got %+v, %v", columns, err)
+	}
+
+	_, err = validateAndDeriveColumns(append(selectExprs,
+		&expr.ColumnRefExpr{ColName: "id", ColType: types.ChunkColumnTypeInt64},
+		&expr.LiteralExpr{Value: true, Type: types.ChunkColumnTypeBoolean},
+	), nil, false)
+	ve, ok := err.(*types.ValidationError)
+	if !ok || len(ve.Problems) != 2 {
+		t.Fatalf("Expected 2 validation problems, got %v", err)
+	}
+
+	err = validateSelectedColumns(selectExprs, []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "name", Type: metadata.Int64Type},
+	})
+	if ve, ok := err.(*types.ValidationError); !ok || len(ve.Problems) != 1 {
+		t.Errorf("Expected type mismatch to be reported, got %v", err)
+	}
+}
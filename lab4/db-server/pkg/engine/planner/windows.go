@@ -0,0 +1,288 @@
+package planner
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/expr"
+)
+
+// hasWindows reports whether column clauses of the query use window functions
+func hasWindows(apiQueryDef openapi.SelectQuery) bool {
+	for _, col := range apiQueryDef.ColumnClauses {
+		if containsWindow(col) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsWindow(col openapi.ColumnExpression) bool {
+	switch e := col.Expression.(type) {
+	case openapi.WindowFunction:
+		return true
+	case openapi.ColumnarBinaryOperation:
+		return containsWindow(e.LeftOperand) || containsWindow(e.RightOperand)
+	case openapi.ColumnarUnaryOperation:
+		return containsWindow(e.Operand)
+	case openapi.Function:
+		for _, arg := range e.Arguments {
+			if containsWindow(arg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// windowMapper maps expressions evaluated after the windows: window functions become references to output
+// columns of their windows, columns of the tables are passed through the windows
+type windowMapper struct {
+	base    *Mapper
+	windows []*Window
+	// columns of the tables used by the select expressions and by inputs of every window
+	selected  []*expr.ColumnRefExpr
+	inputs    map[*Window][]*expr.ColumnRefExpr
+	recorded  *[]*expr.ColumnRefExpr // columns used by the expression being mapped
+	usedNames map[string]bool
+}
+
+func newWindowMapper(base *Mapper) *windowMapper {
+	wm := &windowMapper{
+		base:      base,
+		inputs:    make(map[*Window][]*expr.ColumnRefExpr),
+		usedNames: make(map[string]bool),
+	}
+	wm.recorded = &wm.selected
+	for name := range base.nameToType {
+		wm.usedNames[name] = true
+	}
+	for _, table := range base.tables {
+		for _, col := range table.Snapshot.Columns {
+			wm.usedNames[JoinedColumnName(table.Alias, col.Name)] = true
+		}
+	}
+	return wm
+}
+
+// mapper returns a mapper of expressions evaluated on the output of the last window
+func (wm *windowMapper) mapper() *Mapper {
+	m := wm.recording()
+	m.replace = func(apiExpr openapi.ColumnExpression) (expr.Expression, error) {
+		if e, ok := apiExpr.Expression.(openapi.WindowFunction); ok {
+			return wm.mapWindow(e)
+		}
+		return wm.record(apiExpr)
+	}
+	return m
+}
+
+// recording returns a mapper of expressions evaluated before the windows which records the used columns
+func (wm *windowMapper) recording() *Mapper {
+	return &Mapper{
+		tableName:  wm.base.tableName,
+		alias:      wm.base.alias,
+		nameToType: wm.base.nameToType,
+		tables:     wm.base.tables,
+		replace: func(apiExpr openapi.ColumnExpression) (expr.Expression, error) {
+			if e, ok := apiExpr.Expression.(openapi.WindowFunction); ok {
+				return nil, fmt.Errorf("window function %s cannot contain other window functions", e.WindowName)
+			}
+			return wm.record(apiExpr)
+		},
+	}
+}
+
+func (wm *windowMapper) record(apiExpr openapi.ColumnExpression) (expr.Expression, error) {
+	if _, ok := apiExpr.Expression.(openapi.ColumnReferenceExpression); !ok {
+		return nil, nil
+	}
+	mapped, err := wm.base.MapExpression(apiExpr)
+	if err != nil {
+		return nil, err
+	}
+	colRef := mapped.(*expr.ColumnRefExpr)
+	*wm.recorded = appendColumn(*wm.recorded, colRef)
+	return colRef, nil
+}
+
+func appendColumn(columns []*expr.ColumnRefExpr, colRef *expr.ColumnRefExpr) []*expr.ColumnRefExpr {
+	for _, col := range columns {
+		if col.ColName == colRef.ColName {
+			return columns
+		}
+	}
+	return append(columns, colRef)
+}
+
+func (wm *windowMapper) mapWindow(apiWindow openapi.WindowFunction) (expr.Expression, error) {
+	fn, err := expr.WindowFunctionFromString(apiWindow.WindowName)
+	if err != nil {
+		return nil, err
+	}
+	m := wm.recording()
+	var used []*expr.ColumnRefExpr
+	wm.recorded = &used
+	defer func() { wm.recorded = &wm.selected }()
+
+	var arg expr.Expression
+	if apiWindow.Argument != nil {
+		if arg, err = m.MapExpression(*apiWindow.Argument); err != nil {
+			return nil, err
+		}
+	}
+	resultType, err := expr.WindowResultType(fn, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := apiWindow.Offset
+	if offset < 0 {
+		return nil, fmt.Errorf("offset of window function %s cannot be negative", fn)
+	}
+	if fn == expr.Lag || fn == expr.Lead {
+		if offset == 0 {
+			offset = 1
+		}
+	} else if offset != 0 {
+		return nil, fmt.Errorf("offset is allowed only in LAG and LEAD")
+	}
+
+	var frame *WindowFrame
+	if apiWindow.Frame != nil {
+		if !fn.IsAggregate() {
+			return nil, fmt.Errorf("frame is allowed only in aggregate window functions")
+		}
+		if frame, err = mapFrame(*apiWindow.Frame); err != nil {
+			return nil, err
+		}
+	}
+
+	window := &Window{}
+	for _, key := range apiWindow.PartitionBy {
+		partitionExpr, err := m.MapExpression(key)
+		if err != nil {
+			return nil, err
+		}
+		window.PartitionBy = append(window.PartitionBy, partitionExpr)
+	}
+	for _, key := range apiWindow.OrderBy {
+		orderExpr, err := m.MapExpression(key.Expression)
+		if err != nil {
+			return nil, err
+		}
+		window.OrderBy = append(window.OrderBy, WindowOrder{Expr: orderExpr, Ascending: key.Ascending})
+	}
+
+	// functions with the same partitioning and order are computed by a single window
+	for _, w := range wm.windows {
+		if reflect.DeepEqual(w.PartitionBy, window.PartitionBy) && reflect.DeepEqual(w.OrderBy, window.OrderBy) {
+			window = w
+			break
+		}
+	}
+	if len(window.Functions) == 0 {
+		wm.windows = append(wm.windows, window)
+	}
+	for _, col := range used {
+		wm.inputs[window] = appendColumn(wm.inputs[window], col)
+	}
+
+	call := WindowCall{
+		Function:   fn,
+		Argument:   arg,
+		Offset:     offset,
+		Frame:      frame,
+		ResultType: resultType,
+	}
+	for _, existing := range window.Functions {
+		name := existing.Name
+		existing.Name = ""
+		if reflect.DeepEqual(existing, call) {
+			return &expr.ColumnRefExpr{ColName: name, ColType: resultType}, nil
+		}
+	}
+
+	count := 0
+	for _, w := range wm.windows {
+		count += len(w.Functions)
+	}
+	call.Name = wm.uniqueName(fmt.Sprintf("%s_%d", strings.ToLower(string(fn)), count))
+	window.Functions = append(window.Functions, call)
+	return &expr.ColumnRefExpr{ColName: call.Name, ColType: resultType}, nil
+}
+
+// mapFrame maps bounds of the frame to offsets relative to the current row
+func mapFrame(apiFrame openapi.WindowFrame) (*WindowFrame, error) {
+	start, err := mapFrameBound(apiFrame.Start, "UNBOUNDED_PRECEDING")
+	if err != nil {
+		return nil, err
+	}
+	end, err := mapFrameBound(apiFrame.End, "UNBOUNDED_FOLLOWING")
+	if err != nil {
+		return nil, err
+	}
+	if start != nil && end != nil && *start > *end {
+		return nil, fmt.Errorf("frame cannot start after its end")
+	}
+	return &WindowFrame{Start: start, End: end}, nil
+}
+
+// mapFrameBound returns the offset of the bound, nil if it is the given unbounded type
+func mapFrameBound(bound openapi.WindowFrameBound, unbounded string) (*int64, error) {
+	if bound.Offset < 0 {
+		return nil, fmt.Errorf("offset of frame bound %s cannot be negative", bound.BoundType)
+	}
+	var offset int64
+	switch bound.BoundType {
+	case unbounded:
+		return nil, nil
+	case "PRECEDING":
+		offset = -bound.Offset
+	case "CURRENT_ROW":
+		offset = 0
+	case "FOLLOWING":
+		offset = bound.Offset
+	case "UNBOUNDED_PRECEDING", "UNBOUNDED_FOLLOWING":
+		return nil, fmt.Errorf("frame bound %s is not allowed here", bound.BoundType)
+	default:
+		return nil, fmt.Errorf("unknown frame bound: %s", bound.BoundType)
+	}
+	return &offset, nil
+}
+
+// finish sets columns passed by the windows: columns of the tables used by the select expressions or by later
+// windows, and the results of earlier windows
+func (wm *windowMapper) finish() []*Window {
+	var results []*expr.ColumnRefExpr
+	for i, w := range wm.windows {
+		passed := append([]*expr.ColumnRefExpr(nil), wm.selected...)
+		for _, later := range wm.windows[i+1:] {
+			for _, col := range wm.inputs[later] {
+				passed = appendColumn(passed, col)
+			}
+		}
+		w.Passed = append(passed, results...)
+		results = append(results, windowResults(w)...)
+	}
+	return wm.windows
+}
+
+func windowResults(w *Window) []*expr.ColumnRefExpr {
+	results := make([]*expr.ColumnRefExpr, len(w.Functions))
+	for i, call := range w.Functions {
+		results[i] = &expr.ColumnRefExpr{ColName: call.Name, ColType: call.ResultType}
+	}
+	return results
+}
+
+func (wm *windowMapper) uniqueName(name string) string {
+	unique := name
+	for i := 1; wm.usedNames[unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	wm.usedNames[unique] = true
+	return unique
+}
@@ -0,0 +1,144 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+)
+
+func TestValidateAndMapQuery_Windows(t *testing.T) {
+	snapshot := &metadata.MetastoreSnapshot{Columns: []metadata.ColumnDef{
+		{Name: "id", Type: metadata.Int64Type},
+		{Name: "name", Type: metadata.VarcharType},
+		{Name: "price", Type: metadata.Int64Type},
+	}}
+	colRef := func(name string) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{ColumnName: name}}
+	}
+	window := func(fn openapi.WindowFunction) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: fn}
+	}
+	price := colRef("price")
+	byName := []openapi.ColumnExpression{colRef("name")}
+	byId := []openapi.WindowOrderExpression{{Expression: colRef("id"), Ascending: true}}
+
+	queryDef, err := validateAndMapQuery(openapi.SelectQuery{
+		ColumnClauses: []openapi.ColumnExpression{
+			colRef("id"),
+			window(openapi.WindowFunction{WindowName: "ROW_NUMBER", PartitionBy: byName, OrderBy: byId}),
+			{Expression: openapi.ColumnarBinaryOperation{
+				Operator:     "SUBTRACT",
+				LeftOperand:  price,
+				RightOperand: window(openapi.WindowFunction{WindowName: "LAG", Argument: &price, PartitionBy: byName, OrderBy: byId}),
+			}},
+			window(openapi.WindowFunction{WindowName: "SUM", Argument: &price, Frame: &openapi.WindowFrame{
+				Start: openapi.WindowFrameBound{BoundType: "PRECEDING", Offset: 2},
+				End:   openapi.WindowFrameBound{BoundType: "CURRENT_ROW"},
+			}}),
+			window(openapi.WindowFunction{WindowName: "ROW_NUMBER", PartitionBy: byName, OrderBy: byId}),
+		},
+	}, "t1", snapshot)
+	if err != nil {
+		t.Fatalf("validateAndMapQuery failed: %v", err)
+	}
+
+	if len(queryDef.Windows) != 2 {
+		t.Fatalf("Expected functions grouped into 2 windows, got %d", len(queryDef.Windows))
+	}
+	first, second := queryDef.Windows[0], queryDef.Windows[1]
+	if len(first.Functions) != 2 || first.Functions[1].Offset != 1 {
+		t.Fatalf("Expected ROW_NUMBER computed once and LAG with offset 1, got %+v", first.Functions)
+	}
+	frame := second.Functions[0].Frame
+	if frame == nil || *frame.Start != -2 || *frame.End != 0 {
+		t.Errorf("Expected frame from 2 rows before to the current row, got %+v", frame)
+	}
+
+	var passed []string
+	for _, col := range second.Passed {
+		passed = append(passed, col.ColName)
+	}
+	if want := "id,price,row_number_0,lag_1"; strings.Join(passed, ",") != want {
+		t.Errorf("Expected the second window to pass %s, got %v", want, passed)
+	}
+	if colRef, ok := queryDef.SelectExpr[4].(*expr.ColumnRefExpr); !ok || colRef.ColName != "row_number_0" {
+		t.Errorf("Expected the repeated ROW_NUMBER to refer to the first one, got %+v", queryDef.SelectExpr[4])
+	}
+	if got := expr.GetUsedColumnsFromExpressions(first.InputExprs()); len(got) != 3 {
+		t.Errorf("Expected the first window to read id, name and price, got %v", got)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		query openapi.SelectQuery
+		err   string
+	}{
+		{
+			name: "nested window",
+			query: openapi.SelectQuery{ColumnClauses: []openapi.ColumnExpression{window(openapi.WindowFunction{
+				WindowName: "SUM",
+				Argument:   &openapi.ColumnExpression{Expression: openapi.WindowFunction{WindowName: "RANK"}},
+			})}},
+			err: "cannot contain other window functions",
+		},
+		{
+			name: "window with aggregates",
+			query: openapi.SelectQuery{ColumnClauses: []openapi.ColumnExpression{
+				aggregateExpr("COUNT", nil),
+				window(openapi.WindowFunction{WindowName: "RANK"}),
+			}},
+			err: "window function RANK can be used only",
+		},
+		{
+			name: "window in where clause",
+			query: openapi.SelectQuery{
+				ColumnClauses: []openapi.ColumnExpression{colRef("id")},
+				WhereClause:   window(openapi.WindowFunction{WindowName: "RANK"}),
+			},
+			err: "window function RANK can be used only",
+		},
+		{
+			name:  "argument of ranking function",
+			query: openapi.SelectQuery{ColumnClauses: []openapi.ColumnExpression{window(openapi.WindowFunction{WindowName: "RANK", Argument: &price})}},
+			err:   "RANK expects no arguments",
+		},
+		{
+			name:  "frame of ranking function",
+			query: openapi.SelectQuery{ColumnClauses: []openapi.ColumnExpression{window(openapi.WindowFunction{WindowName: "ROW_NUMBER", Frame: &openapi.WindowFrame{}})}},
+			err:   "frame is allowed only in aggregate window functions",
+		},
+		{
+			name: "frame starting after its end",
+			query: openapi.SelectQuery{ColumnClauses: []openapi.ColumnExpression{window(openapi.WindowFunction{WindowName: "MAX", Argument: &price, Frame: &openapi.WindowFrame{
+				Start: openapi.WindowFrameBound{BoundType: "FOLLOWING", Offset: 1},
+				End:   openapi.WindowFrameBound{BoundType: "CURRENT_ROW"},
+			}})}},
+			err: "frame cannot start after its end",
+		},
+		{
+			name: "unbounded following start",
+			query: openapi.SelectQuery{ColumnClauses: []openapi.ColumnExpression{window(openapi.WindowFunction{WindowName: "MAX", Argument: &price, Frame: &openapi.WindowFrame{
+				Start: openapi.WindowFrameBound{BoundType: "UNBOUNDED_FOLLOWING"},
+				End:   openapi.WindowFrameBound{BoundType: "UNBOUNDED_FOLLOWING"},
+			}})}},
+			err: "frame bound UNBOUNDED_FOLLOWING is not allowed here",
+		},
+		{
+			name:  "negative offset",
+			query: openapi.SelectQuery{ColumnClauses: []openapi.ColumnExpression{window(openapi.WindowFunction{WindowName: "LEAD", Argument: &price, Offset: -1})}},
+			err:   "cannot be negative",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := validateAndMapQuery(tc.query, "t1", snapshot)
+			ve, ok := err.(*types.ValidationError)
+			if !ok || len(ve.Problems) != 1 || !strings.Contains(ve.Problems[0].Error, tc.err) {
+				t.Errorf("Expected a problem containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
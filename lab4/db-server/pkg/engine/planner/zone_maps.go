@@ -0,0 +1,112 @@
+package planner
+
+import (
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/metadata"
+)
+
+// pruneFiles drops files of the snapshot whose min/max statistics prove that no row satisfies the where expression
+func pruneFiles(snapshot *metadata.MetastoreSnapshot, whereExpr expr.Expression) {
+	if whereExpr == nil {
+		return
+	}
+	snapshot.Retain(func(f *metadata.FileEntry) bool {
+		return f.Stats == nil || mayMatch(f.Stats, whereExpr)
+	})
+}
+
+// mayMatch returns false only if no row of the file can satisfy the expression
+func mayMatch(stats *metadata.FileStats, e expr.Expression) bool {
+	binExpr, ok := e.(*expr.BinaryOpExpr)
+	if !ok {
+		return true
+	}
+
+	switch binExpr.Operator {
+	case expr.And:
+		return mayMatch(stats, binExpr.Left) && mayMatch(stats, binExpr.Right)
+	case expr.Or:
+		return mayMatch(stats, binExpr.Left) || mayMatch(stats, binExpr.Right)
+	}
+
+	col, lit, op, ok := columnComparison(binExpr)
+	if !ok {
+		return true
+	}
+	colStats := findColumnStats(stats, col.ColName)
+	if colStats == nil || colStats.Min == nil || colStats.Max == nil {
+		return true
+	}
+	bound, ok := boundFromLiteral(lit, colStats.Min)
+	if !ok {
+		return true
+	}
+
+	minCmp := colStats.Min.Compare(bound) // min ? literal
+	maxCmp := colStats.Max.Compare(bound) // max ? literal
+	switch op {
+	case expr.Equal:
+		return minCmp <= 0 && maxCmp >= 0
+	case expr.NotEqual:
+		return minCmp != 0 || maxCmp != 0
+	case expr.LessThan:
+		return minCmp < 0
+	case expr.LessEqual:
+		return minCmp <= 0
+	case expr.GreaterThan:
+		return maxCmp > 0
+	case expr.GreaterEqual:
+		return maxCmp >= 0
+	}
+	return true
+}
+
+// columnComparison matches `column op literal` and `literal op column`, the latter is returned with the operator flipped
+func columnComparison(e *expr.BinaryOpExpr) (*expr.ColumnRefExpr, *expr.LiteralExpr, expr.BinaryOperator, bool) {
+	if col, ok := e.Left.(*expr.ColumnRefExpr); ok {
+		if lit, ok := e.Right.(*expr.LiteralExpr); ok {
+			return col, lit, e.Operator, true
+		}
+	}
+	if col, ok := e.Right.(*expr.ColumnRefExpr); ok {
+		if lit, ok := e.Left.(*expr.LiteralExpr); ok {
+			var flipped = map[expr.BinaryOperator]expr.BinaryOperator{
+				expr.Equal:        expr.Equal,
+				expr.NotEqual:     expr.NotEqual,
+				expr.LessThan:     expr.GreaterThan,
+				expr.LessEqual:    expr.GreaterEqual,
+				expr.GreaterThan:  expr.LessThan,
+				expr.GreaterEqual: expr.LessEqual,
+			}
+			op, ok := flipped[e.Operator]
+			return col, lit, op, ok
+		}
+	}
+	return nil, nil, 0, false
+}
+
+func findColumnStats(stats *metadata.FileStats, name string) *metadata.FileColumnStats {
+	for i := range stats.Columns {
+		if stats.Columns[i].Name == name {
+			return &stats.Columns[i]
+		}
+	}
+	return nil
+}
+
+// boundFromLiteral converts the literal to a bound comparable with like, returns false on a type mismatch
+func boundFromLiteral(lit *expr.LiteralExpr, like *metadata.ColumnBound) (*metadata.ColumnBound, bool) {
+	switch v := lit.Value.(type) {
+	case int64:
+		if like.Int64 == nil {
+			return nil, false
+		}
+		return &metadata.ColumnBound{Int64: &v}, true
+	case string:
+		if like.Varchar == nil {
+			return nil, false
+		}
+		return &metadata.ColumnBound{Varchar: &v}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,80 @@
+package planner
+
+import (
+	"testing"
+
+	"isbd4/pkg/engine/expr"
+	"isbd4/pkg/engine/types"
+	"isbd4/pkg/metadata"
+)
+
+func fileWithIdRange(path string, min, max int64) *metadata.FileEntry {
+	return &metadata.FileEntry{
+		Path: path,
+		Stats: &metadata.FileStats{
+			NumRows: 10,
+			Columns: []metadata.FileColumnStats{{
+				Name: "id",
+				Min:  &metadata.ColumnBound{Int64: &min},
+				Max:  &metadata.ColumnBound{Int64: &max},
+			}},
+		},
+	}
+}
+
+func compare(t *testing.T, left, right expr.Expression, op expr.BinaryOperator) expr.Expression {
+	t.Helper()
+	e, err := expr.NewBinaryOp(left, right, op)
+	if err != nil {
+		t.Fatalf("NewBinaryOp failed: %v", err)
+	}
+	return e
+}
+
+func TestPruneFiles(t *testing.T) {
+	id := &expr.ColumnRefExpr{ColName: "id", ColType: types.ChunkColumnTypeInt64}
+	lit := func(v int64) expr.Expression { return &expr.LiteralExpr{Value: v, Type: types.ChunkColumnTypeInt64} }
+
+	tests := []struct {
+		name     string
+		where    expr.Expression
+		expected []string
+	}{
+		{"equal", compare(t, id, lit(15), expr.Equal), []string{"b"}},
+		{"flipped less than", compare(t, lit(15), id, expr.LessThan), []string{"b", "c"}},
+		{"greater equal at max", compare(t, id, lit(9), expr.GreaterEqual), []string{"a", "b", "c"}},
+		{"and", compare(t, compare(t, id, lit(5), expr.GreaterThan), compare(t, id, lit(12), expr.LessEqual), expr.And), []string{"a", "b"}},
+		{"or", compare(t, compare(t, id, lit(1), expr.Equal), compare(t, id, lit(25), expr.Equal), expr.Or), []string{"a", "c"}},
+		{"not a comparison", compare(t, id, lit(1), expr.Add), []string{"a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := &metadata.MetastoreSnapshot{Files: []*metadata.FileEntry{
+				fileWithIdRange("a", 0, 9),
+				fileWithIdRange("b", 10, 19),
+				fileWithIdRange("c", 20, 29),
+			}}
+			for _, f := range snapshot.Files {
+				f.IncRef()
+			}
+
+			pruneFiles(snapshot, tt.where)
+			if got := metadata.FileNames(snapshot.Files); !equalStrings(got, tt.expected) {
+				t.Errorf("Expected files %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
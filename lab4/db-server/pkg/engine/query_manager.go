@@ -22,11 +22,14 @@ const (
 )
 
 type QueryInfo struct {
-	Id         string
-	State      QueryState
-	Result     *types.ColumnarResult
-	Error      error
-	Definition any
+	Id          string
+	State       QueryState
+	Result      *types.ColumnarResult
+	Error       error
+	Definition  any
+	SubmittedAt time.Time
+	StartedAt   time.Time // zero until the query runs
+	FinishedAt  time.Time // zero until the query finishes or fails
 }
 
 type QueryManager struct {
@@ -37,11 +40,14 @@ type QueryManager struct {
 }
 
 func NewQueryManager(m *metadata.Metastore, baseDir string, chunkSize uint64, maxRowsInFile uint64, memoryLimitBytes uint64) *QueryManager {
-	return &QueryManager{
+	qm := &QueryManager{
 		Planner:  planner.NewPlanner(m),
 		Executor: executor.NewExecutor(baseDir, chunkSize, maxRowsInFile, memoryLimitBytes),
 		Queries:  make(map[string]*QueryInfo),
 	}
+	qm.Planner.MemoryLimitBytes = memoryLimitBytes
+	qm.Planner.RegisterSystemTable("queries", &planner.SystemTable{Columns: systemQueriesColumns, Rows: qm.queriesRows})
+	return qm
 }
 
 func (qm *QueryManager) SubmitCopy(tableName, csvPath string, columnsMapping []string, csvContainsHeader bool, queryDefinition any) (string, error) {
@@ -49,38 +55,69 @@ func (qm *QueryManager) SubmitCopy(tableName, csvPath string, columnsMapping []s
 	if err != nil {
 		return "", err
 	}
+	return qm.run("COPY", plan, queryDefinition), nil
+}
 
-	queryId := fmt.Sprintf("COPY_%d", time.Now().UnixNano())
-	qm.createQuery(queryId, queryDefinition)
+func (qm *QueryManager) SubmitSelect(queryDefinition openapi.SelectQuery, qd any) (string, error) {
+	plan, err := qm.Planner.PlanSelect(queryDefinition)
+	if err != nil {
+		return "", err
+	}
+	return qm.run("SELECT", plan, qd), nil
+}
 
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				qm.failQuery(queryId, fmt.Errorf("panic: %v", r))
-			}
-		}()
+func (qm *QueryManager) SubmitSetOperation(queryDefinition openapi.SetOperationQuery, qd any) (string, error) {
+	plan, err := qm.Planner.PlanSetOperation(queryDefinition)
+	if err != nil {
+		return "", err
+	}
+	return qm.run("SELECT", plan, qd), nil
+}
 
-		qm.updateState(queryId, QueryStateRunning)
+func (qm *QueryManager) SubmitInsert(queryDefinition openapi.InsertQuery, qd any) (string, error) {
+	plan, err := qm.Planner.PlanInsert(queryDefinition)
+	if err != nil {
+		return "", err
+	}
+	return qm.run("INSERT", plan, qd), nil
+}
 
-		_, err = qm.Executor.Execute(plan)
-		if err != nil {
-			qm.failQuery(queryId, err)
-			return
-		}
+func (qm *QueryManager) SubmitInsertSelect(queryDefinition openapi.InsertSelectQuery, qd any) (string, error) {
+	plan, err := qm.Planner.PlanInsertSelect(queryDefinition)
+	if err != nil {
+		return "", err
+	}
+	return qm.run("INSERT", plan, qd), nil
+}
 
-		qm.finishQuery(queryId, nil)
-	}()
+func (qm *QueryManager) SubmitCreateTableAsSelect(queryDefinition openapi.CreateTableAsSelectQuery, qd any) (string, error) {
+	plan, err := qm.Planner.PlanCreateTableAsSelect(queryDefinition)
+	if err != nil {
+		return "", err
+	}
+	return qm.run("CTAS", plan, qd), nil
+}
 
-	return queryId, nil
+func (qm *QueryManager) SubmitDelete(queryDefinition openapi.DeleteQuery, qd any) (string, error) {
+	plan, err := qm.Planner.PlanDelete(queryDefinition)
+	if err != nil {
+		return "", err
+	}
+	return qm.run("DELETE", plan, qd), nil
 }
 
-func (qm *QueryManager) SubmitSelect(queryDefinition openapi.SelectQuery, qd any) (string, error) {
-	plan, err := qm.Planner.PlanSelect(queryDefinition)
+func (qm *QueryManager) SubmitUpdate(queryDefinition openapi.UpdateQuery, qd any) (string, error) {
+	plan, err := qm.Planner.PlanUpdate(queryDefinition)
 	if err != nil {
 		return "", err
 	}
+	return qm.run("UPDATE", plan, qd), nil
+}
 
-	queryId := fmt.Sprintf("SELECT_%d", time.Now().UnixNano())
+// run registers a query with an id of the given prefix and executes its plan in the background,
+// the query fails if the execution returns an error or panics
+func (qm *QueryManager) run(prefix string, plan planner.QueryPlan, qd any) string {
+	queryId := fmt.Sprintf("%s_%d", prefix, time.Now().UnixNano())
 	qm.createQuery(queryId, qd)
 
 	go func() {
@@ -101,7 +138,7 @@ func (qm *QueryManager) SubmitSelect(queryDefinition openapi.SelectQuery, qd any
 		qm.finishQuery(queryId, result)
 	}()
 
-	return queryId, nil
+	return queryId
 }
 
 func (qm *QueryManager) GetQueryInfo(queryId string) (*QueryInfo, bool) {
@@ -156,9 +193,10 @@ func (qm *QueryManager) createQuery(id string, definition any) {
 	qm.Mu.Lock()
 	defer qm.Mu.Unlock()
 	qm.Queries[id] = &QueryInfo{
-		Id:         id,
-		State:      QueryStatePending,
-		Definition: definition,
+		Id:          id,
+		State:       QueryStatePending,
+		Definition:  definition,
+		SubmittedAt: time.Now(),
 	}
 }
 
@@ -167,6 +205,9 @@ func (qm *QueryManager) updateState(id string, state QueryState) {
 	defer qm.Mu.Unlock()
 	if q, ok := qm.Queries[id]; ok {
 		q.State = state
+		if state == QueryStateRunning {
+			q.StartedAt = time.Now()
+		}
 	}
 }
 
@@ -176,6 +217,7 @@ func (qm *QueryManager) failQuery(id string, err error) {
 	if q, ok := qm.Queries[id]; ok {
 		q.State = QueryStateFailed
 		q.Error = err
+		q.FinishedAt = time.Now()
 	}
 }
 
@@ -185,6 +227,7 @@ func (qm *QueryManager) finishQuery(id string, result *types.ColumnarResult) {
 	if q, ok := qm.Queries[id]; ok {
 		q.State = QueryStateFinished
 		q.Result = result
+		q.FinishedAt = time.Now()
 	}
 }
 
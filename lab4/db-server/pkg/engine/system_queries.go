@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"isbd4/pkg/engine/planner"
+	"isbd4/pkg/metadata"
+	"isbd4/pkg/tomy_file"
+)
+
+// Times in system.queries are unix milliseconds, 0 when the query didn't reach the state yet
+var systemQueriesColumns = []metadata.ColumnDef{
+	{Name: "query_id", Type: metadata.VarcharType},
+	{Name: "type", Type: metadata.VarcharType},
+	{Name: "state", Type: metadata.VarcharType},
+	{Name: "submitted_at", Type: metadata.Int64Type},
+	{Name: "started_at", Type: metadata.Int64Type},
+	{Name: "finished_at", Type: metadata.Int64Type},
+	{Name: "duration_ms", Type: metadata.Int64Type}, // time spent running, up to now for queries still running
+	{Name: "error", Type: metadata.VarcharType},
+}
+
+// queriesRows returns rows of system.queries ordered by submission time
+func (qm *QueryManager) queriesRows() *tomy_file.ColumnarTable {
+	queries := qm.GetAllQueriesInfo()
+	slices.SortFunc(queries, func(a, b QueryInfo) int {
+		return a.SubmittedAt.Compare(b.SubmittedAt)
+	})
+
+	now := time.Now()
+	rows := planner.NewSystemRows(systemQueriesColumns)
+	for _, q := range queries {
+		queryType, _, _ := strings.Cut(q.Id, "_")
+		var duration int64
+		if !q.StartedAt.IsZero() {
+			end := q.FinishedAt
+			if end.IsZero() {
+				end = now
+			}
+			duration = end.Sub(q.StartedAt).Milliseconds()
+		}
+		errMsg := ""
+		if q.Error != nil {
+			errMsg = q.Error.Error()
+		}
+		rows.Append(q.Id, queryType, string(q.State), unixMilli(q.SubmittedAt), unixMilli(q.StartedAt),
+			unixMilli(q.FinishedAt), duration, errMsg)
+	}
+	return rows.Table()
+}
+
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
@@ -33,6 +33,24 @@ func ChunkColumnFromTomy(tomyCol tomy_file.AnyColumn) (ChunkColumn, error) {
 	}
 }
 
+func TomyColumnFromChunk(col ChunkColumn) (tomy_file.AnyColumn, error) {
+	switch c := col.(type) {
+	case *Int64ChunkColumn:
+		return &tomy_file.Int64Column{
+			Name:   c.Name,
+			Values: c.Values,
+		}, nil
+	case *VarcharChunkColumn:
+		return &tomy_file.VarcharColumn{
+			Name:    c.Name,
+			Offsets: c.Offsets,
+			Data:    c.Data,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported chunk column type for tomy file: %T", col)
+	}
+}
+
 type ChunkColumnType int
 
 const (
@@ -52,6 +70,18 @@ func ChunkColumnTypeFromMetadataColumnType(colType metadata.ColumnType) (ChunkCo
 	}
 }
 
+// MetadataColumnTypeFromChunkColumnType returns the type of a table column storing values of the chunk column type
+func MetadataColumnTypeFromChunkColumnType(colType ChunkColumnType) (metadata.ColumnType, error) {
+	switch colType {
+	case ChunkColumnTypeInt64:
+		return metadata.Int64Type, nil
+	case ChunkColumnTypeVarchar:
+		return metadata.VarcharType, nil
+	default:
+		return "", fmt.Errorf("values of chunk column type %v can't be stored in a table", colType)
+	}
+}
+
 type Int64ChunkColumn struct {
 	Name   string
 	Values []int64
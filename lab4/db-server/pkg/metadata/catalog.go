@@ -0,0 +1,57 @@
+package metadata
+
+import (
+	"cmp"
+	"slices"
+)
+
+// TableCatalog is a read only summary of the current version of a table
+type TableCatalog struct {
+	Id       string
+	Database string
+	Name     string
+	Columns  []ColumnDef
+	SortKeys []SortKey
+	Version  uint64
+	Files    []FileCatalog
+}
+
+type FileCatalog struct {
+	Path        string
+	NumRows     uint64
+	SizeBytes   int64
+	DeletedRows uint64
+}
+
+// Catalog returns summaries of all tables sorted by database and name
+func (m *Metastore) Catalog() []TableCatalog {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+
+	res := make([]TableCatalog, 0, len(m.Schema.Tables))
+	for id, table := range m.Schema.Tables {
+		version := table.CurrentVersion()
+		tc := TableCatalog{
+			Id:       id,
+			Database: table.Database,
+			Name:     table.Name,
+			Columns:  slices.Clone(table.Columns),
+			SortKeys: slices.Clone(table.SortKeys),
+			Version:  version.Version,
+			Files:    make([]FileCatalog, len(table.Files)),
+		}
+		for i, f := range table.Files {
+			tc.Files[i] = FileCatalog{Path: f.Path, DeletedRows: version.Deletions[f.Path].Count()}
+			if f.Stats != nil {
+				tc.Files[i].NumRows = f.Stats.NumRows
+				tc.Files[i].SizeBytes = f.Stats.SizeBytes
+			}
+		}
+		res = append(res, tc)
+	}
+
+	slices.SortFunc(res, func(a, b TableCatalog) int {
+		return cmp.Or(cmp.Compare(a.Database, b.Database), cmp.Compare(a.Name, b.Name))
+	})
+	return res
+}
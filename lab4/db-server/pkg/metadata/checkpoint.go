@@ -0,0 +1,52 @@
+package metadata
+
+import "encoding/json"
+
+// Checkpoint is a consistent state of the whole metastore. Files it references are kept until Release,
+// even if tables change or are dropped in the meantime.
+type Checkpoint struct {
+	Metadata []byte       // serialized metastore
+	Files    []*FileEntry // data and index files of all retained versions of all tables
+}
+
+// Checkpoint serializes the metastore and retains every file it references, e.g. to copy them for a backup
+func (m *Metastore) Checkpoint() (*Checkpoint, error) {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Checkpoint{Metadata: data}
+	seen := make(map[*FileEntry]bool)
+	retain := func(f *FileEntry) {
+		if !seen[f] {
+			seen[f] = true
+			f.IncRef()
+			c.Files = append(c.Files, f)
+		}
+	}
+	for _, table := range m.Schema.Tables {
+		for _, f := range table.Files {
+			retain(f)
+		}
+		for _, f := range table.HistoricalFiles {
+			retain(f)
+		}
+		for _, idx := range table.Indexes {
+			for _, f := range idx.Files {
+				retain(f.File)
+			}
+		}
+	}
+	return c, nil
+}
+
+// Release allows files of the checkpoint to be removed
+func (c *Checkpoint) Release() {
+	for _, f := range c.Files {
+		f.DecRef()
+	}
+}
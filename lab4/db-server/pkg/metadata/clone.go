@@ -0,0 +1,82 @@
+package metadata
+
+import (
+	"fmt"
+	"time"
+)
+
+// CloneTable creates a table (given by the qualified name) with the current version of the source table without
+// copying its files, the tables share the file entries. Later changes of either table don't affect the other one
+// and shared files are removed only when no table references them. Secondary indexes are not cloned.
+func (m *Metastore) CloneTable(sourceId string, name string) (string, error) {
+	db, tableName := SplitQualifiedName(name)
+	if err := validateIdentifier("table", tableName); err != nil {
+		return "", err
+	}
+
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	source, exists := m.getTableByIdUnlocked(sourceId)
+	if !exists {
+		return "", fmt.Errorf("table %s does not exist", sourceId)
+	}
+	if _, exists := m.Schema.Databases[db]; !exists {
+		return "", fmt.Errorf("database %s does not exist", db)
+	}
+
+	tableId, err := m.newTableId(db, tableName)
+	if err != nil {
+		return "", err
+	}
+
+	files := append([]*FileEntry(nil), source.Files...)
+	for _, f := range files {
+		f.Share()
+	}
+
+	clone := &TableDef{
+		Name:     tableName,
+		Database: db,
+		Columns:  append([]ColumnDef(nil), source.Columns...),
+		SortKeys: append([]SortKey(nil), source.SortKeys...),
+		Files:    files,
+		Versions: []*TableVersion{{
+			Version:     0,
+			CommittedAt: time.Now(),
+			Files:       FileNames(files),
+			Deletions:   source.CurrentVersion().Deletions, // vectors are never modified, so they can be shared
+		}},
+	}
+	if source.Stats != nil {
+		clone.Stats = &TableStats{
+			DistinctSketches: make(map[string][]uint8, len(source.Stats.DistinctSketches)),
+			AnalyzedAt:       source.Stats.AnalyzedAt,
+		}
+		for col, sketch := range source.Stats.DistinctSketches {
+			clone.Stats.DistinctSketches[col] = append([]uint8(nil), sketch...)
+		}
+	}
+
+	m.Schema.Tables[tableId] = clone
+	return tableId, m.save()
+}
+
+// shareFileEntries makes tables referencing the same file (clones) use a single entry again after loading,
+// so a query of any of them keeps the file from being removed. Assumes write lock is held
+func (m *Metastore) shareFileEntries() {
+	byPath := make(map[string]*FileEntry)
+	share := func(files []*FileEntry) {
+		for i, f := range files {
+			if entry, ok := byPath[f.Path]; ok {
+				files[i] = entry
+			} else {
+				byPath[f.Path] = f
+			}
+		}
+	}
+	for _, table := range m.Schema.Tables {
+		share(table.Files)
+		share(table.HistoricalFiles)
+	}
+}
@@ -0,0 +1,69 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMetastore_CloneTable(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewMetastore(tmpDir)
+	sourceId, err := m.CreateTable("t1", []ColumnDef{{Name: "a", Type: Int64Type}}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	shared := addDummyFile(t, m, "t1", "f1.tomy")
+
+	cloneId, err := m.CloneTable(sourceId, "t2")
+	if err != nil {
+		t.Fatalf("CloneTable failed: %v", err)
+	}
+	if _, err := m.CloneTable(sourceId, "t2"); err == nil {
+		t.Errorf("Expected error when cloning into existing table")
+	}
+	if _, err := m.CloneTable(sourceId, "missing.t3"); err == nil {
+		t.Errorf("Expected error when cloning into not existing database")
+	}
+
+	addDummyFile(t, m, "t1", "f2.tomy")
+	addDummyFile(t, m, "t2", "f3.tomy")
+	source, _ := m.GetTableById(sourceId)
+	clone, _ := m.GetTableById(cloneId)
+	if len(source.Files) != 2 || len(clone.Files) != 2 || source.Files[0] != clone.Files[0] {
+		t.Fatalf("Expected tables to share only the cloned file, got %v and %v", FileNames(source.Files), FileNames(clone.Files))
+	}
+
+	// shared entries survive restart
+	m = NewMetastore(tmpDir)
+	source, _ = m.GetTableById(sourceId)
+	clone, _ = m.GetTableById(cloneId)
+	if source.Files[0] != clone.Files[0] || source.Files[0].SharedWith != 1 {
+		t.Fatalf("Expected single shared entry after reload, got %+v and %+v", source.Files[0], clone.Files[0])
+	}
+
+	snapshot, err := m.GetTableSnapshotById(cloneId)
+	if err != nil {
+		t.Fatalf("GetTableSnapshotById failed: %v", err)
+	}
+	if err := m.DeleteTable(sourceId); err != nil {
+		t.Fatalf("DeleteTable failed: %v", err)
+	}
+	if _, err := os.Stat(shared); err != nil {
+		t.Errorf("Shared file should be kept while the clone references it: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(m.DatabaseDir(DefaultDatabase), "f2.tomy")); !os.IsNotExist(err) {
+		t.Errorf("File of the dropped table only should have been deleted, got err: %v", err)
+	}
+
+	if err := m.DeleteTable(cloneId); err != nil {
+		t.Fatalf("DeleteTable failed: %v", err)
+	}
+	if _, err := os.Stat(shared); err != nil {
+		t.Errorf("Shared file should be kept while a query uses it: %v", err)
+	}
+	snapshot.Release()
+	if _, err := os.Stat(shared); !os.IsNotExist(err) {
+		t.Errorf("Shared file should have been deleted, got err: %v", err)
+	}
+}
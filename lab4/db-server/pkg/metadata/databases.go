@@ -0,0 +1,162 @@
+package metadata
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Tables referenced without a database name live in the default database
+const DefaultDatabase = "default"
+
+// SystemDatabase holds read only virtual tables describing the system, it cannot be created by users
+const SystemDatabase = "system"
+
+type DatabaseDef struct {
+	Name string `json:"name"`
+}
+
+type DatabaseInfo struct {
+	Name       string
+	TableCount int
+}
+
+// QualifiedName turns "table" into "default.table", already qualified names are returned unchanged
+func QualifiedName(name string) string {
+	db, table := SplitQualifiedName(name)
+	return db + "." + table
+}
+
+func SplitQualifiedName(name string) (db string, table string) {
+	if idx := strings.Index(name, "."); idx >= 0 {
+		return name[:idx], name[idx+1:]
+	}
+	return DefaultDatabase, name
+}
+
+func validateIdentifier(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s name cannot be empty", kind)
+	}
+	if strings.ContainsAny(name, "./\\") {
+		return fmt.Errorf("%s name %s cannot contain '.', '/' or '\\'", kind, name)
+	}
+	return nil
+}
+
+// DatabaseDir is the directory where data files of tables from the database are stored
+func (m *Metastore) DatabaseDir(db string) string {
+	return filepath.Join(m.TablesDir, db)
+}
+
+func (m *Metastore) CreateDatabase(name string) error {
+	if err := validateIdentifier("database", name); err != nil {
+		return err
+	}
+	if name == SystemDatabase {
+		return fmt.Errorf("database name %s is reserved", name)
+	}
+
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	if _, exists := m.Schema.Databases[name]; exists {
+		return fmt.Errorf("database %s already exists", name)
+	}
+
+	if err := os.MkdirAll(m.DatabaseDir(name), 0755); err != nil {
+		return fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	m.Schema.Databases[name] = &DatabaseDef{Name: name}
+	return m.save()
+}
+
+// DropDatabase removes the database, tables inside are dropped only when cascade is set
+func (m *Metastore) DropDatabase(name string, cascade bool) error {
+	if name == DefaultDatabase {
+		return fmt.Errorf("default database cannot be dropped")
+	}
+
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	if _, exists := m.Schema.Databases[name]; !exists {
+		return fmt.Errorf("database %s does not exist", name)
+	}
+
+	tableIds := m.tableIdsInDatabase(name)
+	if len(tableIds) > 0 && !cascade {
+		return fmt.Errorf("database %s contains %d tables, use cascade to drop them", name, len(tableIds))
+	}
+
+	for _, id := range tableIds {
+		m.dropTable(id)
+	}
+	delete(m.Schema.Databases, name)
+
+	if err := m.save(); err != nil {
+		return err
+	}
+
+	// files still used by running queries are removed later, so the directory may stay until restart
+	os.Remove(m.DatabaseDir(name))
+	return nil
+}
+
+func (m *Metastore) DatabaseExists(name string) bool {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+	_, exists := m.Schema.Databases[name]
+	return exists
+}
+
+func (m *Metastore) GetDatabases() []DatabaseInfo {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+
+	res := make([]DatabaseInfo, 0, len(m.Schema.Databases))
+	for name := range m.Schema.Databases {
+		res = append(res, DatabaseInfo{
+			Name:       name,
+			TableCount: len(m.tableIdsInDatabase(name)),
+		})
+	}
+	return res
+}
+
+// Assumes lock is held
+func (m *Metastore) tableIdsInDatabase(db string) []string {
+	var ids []string
+	for id, table := range m.Schema.Tables {
+		if table.Database == db {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Brings metastores saved before databases were introduced to the current layout.
+// Assumes write lock is held
+func (m *Metastore) migrateToDatabases() {
+	if m.Schema.Databases == nil {
+		m.Schema.Databases = make(map[string]*DatabaseDef)
+	}
+	if _, exists := m.Schema.Databases[DefaultDatabase]; !exists {
+		m.Schema.Databases[DefaultDatabase] = &DatabaseDef{Name: DefaultDatabase}
+	}
+
+	for _, table := range m.Schema.Tables {
+		if table.Database == "" {
+			table.Database = DefaultDatabase
+		}
+	}
+
+	for name, id := range m.NameToId {
+		if !strings.Contains(name, ".") {
+			delete(m.NameToId, name)
+			m.NameToId[QualifiedName(name)] = id
+		}
+	}
+}
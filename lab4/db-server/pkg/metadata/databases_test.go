@@ -0,0 +1,106 @@
+package metadata
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMetastore_Databases(t *testing.T) {
+	tmpDir := t.TempDir()
+	cols := []ColumnDef{{Name: "a", Type: Int64Type}}
+
+	m := NewMetastore(tmpDir)
+	if _, err := m.CreateTable("sales.orders", cols, nil); err == nil {
+		t.Fatalf("Expected error when creating table in missing database")
+	}
+	if err := m.CreateDatabase("sales"); err != nil {
+		t.Fatalf("CreateDatabase failed: %v", err)
+	}
+	if err := m.CreateDatabase("sales"); err == nil {
+		t.Errorf("Expected error when creating duplicate database")
+	}
+
+	// the same table name can be used in different databases
+	defaultId, err := m.CreateTable("orders", cols, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	salesId, err := m.CreateTable("sales.orders", cols, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	if table, ok := m.GetTableByName("default.orders"); !ok || table != m.Schema.Tables[defaultId] {
+		t.Errorf("Expected default.orders to resolve to the unqualified table")
+	}
+	if table, ok := m.GetTableByName("sales.orders"); !ok || table.Database != "sales" || table.Name != "orders" {
+		t.Errorf("Expected sales.orders to resolve to table in database sales")
+	}
+
+	dataFile := filepath.Join(m.DatabaseDir("sales"), "orders.tomy")
+	if err := os.WriteFile(dataFile, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to create data file: %v", err)
+	}
+	if err := m.AddFile("sales.orders", dataFile, nil); err != nil {
+		t.Fatalf("AddFile failed: %v", err)
+	}
+
+	if err := m.DropDatabase("sales", false); err == nil {
+		t.Errorf("Expected error when dropping non-empty database without cascade")
+	}
+	if err := m.DropDatabase(DefaultDatabase, true); err == nil {
+		t.Errorf("Expected error when dropping default database")
+	}
+	if err := m.DropDatabase("sales", true); err != nil {
+		t.Fatalf("DropDatabase failed: %v", err)
+	}
+
+	if _, ok := m.GetTableById(salesId); ok {
+		t.Errorf("Expected tables of dropped database to be removed")
+	}
+	if _, err := os.Stat(m.DatabaseDir("sales")); !os.IsNotExist(err) {
+		t.Errorf("Expected database directory to be removed")
+	}
+
+	m2 := NewMetastore(tmpDir)
+	dbs := m2.GetDatabases()
+	if len(dbs) != 1 || dbs[0].Name != DefaultDatabase || dbs[0].TableCount != 1 {
+		t.Errorf("Expected only default database with one table, got %+v", dbs)
+	}
+}
+
+func TestMetastore_LegacyLayoutMigration(t *testing.T) {
+	tmpDir := t.TempDir()
+	metastoreDir := filepath.Join(tmpDir, "ms_data")
+	if err := os.MkdirAll(metastoreDir, 0755); err != nil {
+		t.Fatalf("Failed to create metastore directory: %v", err)
+	}
+
+	// metastore saved before databases were introduced
+	legacy := map[string]any{
+		"schema": map[string]any{
+			"tables": map[string]any{
+				"users_1": map[string]any{"name": "users", "columns": []any{}, "files": []any{}},
+			},
+		},
+		"name_to_id": map[string]string{"users": "users_1"},
+	}
+	data, _ := json.Marshal(legacy)
+	if err := os.WriteFile(filepath.Join(metastoreDir, "metastore.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write legacy metastore: %v", err)
+	}
+
+	m := NewMetastore(tmpDir)
+	table, ok := m.GetTableByName("users")
+	if !ok {
+		t.Fatalf("Expected legacy table to be found by unqualified name")
+	}
+	if table.Database != DefaultDatabase {
+		t.Errorf("Expected legacy table in default database, got %s", table.Database)
+	}
+	if _, ok := m.GetTableByName("default.users"); !ok {
+		t.Errorf("Expected legacy table to be found by qualified name")
+	}
+}
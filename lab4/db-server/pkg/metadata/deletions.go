@@ -0,0 +1,113 @@
+package metadata
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// DeletionVector is a bitmap of deleted row positions of a single file.
+// Vectors referenced by a table version are never modified, deletes commit modified copies.
+type DeletionVector struct {
+	Words []uint64 `json:"words"`
+}
+
+func (d *DeletionVector) Contains(row uint64) bool {
+	if d == nil || row/64 >= uint64(len(d.Words)) {
+		return false
+	}
+	return d.Words[row/64]&(1<<(row%64)) != 0
+}
+
+func (d *DeletionVector) Add(row uint64) {
+	for row/64 >= uint64(len(d.Words)) {
+		d.Words = append(d.Words, 0)
+	}
+	d.Words[row/64] |= 1 << (row % 64)
+}
+
+// Count returns the number of deleted rows
+func (d *DeletionVector) Count() uint64 {
+	if d == nil {
+		return 0
+	}
+	count := 0
+	for _, w := range d.Words {
+		count += bits.OnesCount64(w)
+	}
+	return uint64(count)
+}
+
+func (d *DeletionVector) Clone() *DeletionVector {
+	if d == nil {
+		return &DeletionVector{}
+	}
+	return &DeletionVector{Words: append([]uint64(nil), d.Words...)}
+}
+
+// DeleteRows commits a new version with the given rows marked as deleted, the vectors are merged with rows
+// deleted before. It fails if any of the files is no longer part of the current version, e.g. it was compacted.
+// Returns the number of rows which were not deleted before.
+func (m *Metastore) DeleteRows(tableId string, deleted map[string]*DeletionVector) (uint64, error) {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	table, exists := m.getTableByIdUnlocked(tableId)
+	if !exists {
+		return 0, fmt.Errorf("table %s does not exist", tableId)
+	}
+
+	current := table.CurrentVersion()
+	present := make(map[string]bool, len(current.Files))
+	for _, path := range current.Files {
+		present[path] = true
+	}
+
+	deletions := make(map[string]*DeletionVector, len(current.Deletions)+len(deleted))
+	for path, dv := range current.Deletions {
+		deletions[path] = dv
+	}
+
+	var newlyDeleted uint64
+	for path, dv := range deleted {
+		if !present[path] {
+			return 0, fmt.Errorf("table %s was modified concurrently, file %s is no longer present", tableId, path)
+		}
+		before := deletions[path].Count()
+		merged := deletions[path].Clone()
+		for i, w := range dv.Words {
+			if i >= len(merged.Words) {
+				merged.Words = append(merged.Words, 0)
+			}
+			merged.Words[i] |= w
+		}
+		if after := merged.Count(); after > before {
+			newlyDeleted += after - before
+			deletions[path] = merged
+		}
+	}
+
+	if newlyDeleted == 0 {
+		return 0, nil
+	}
+	m.commitVersion(table, table.Files, deletions)
+	return newlyDeleted, m.save()
+}
+
+// DeletedRows returns the number of deleted rows of every file of the current version which has any
+func (m *Metastore) DeletedRows(tableId string) (map[string]uint64, bool) {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+
+	table, exists := m.getTableByIdUnlocked(tableId)
+	if !exists {
+		return nil, false
+	}
+
+	counts := make(map[string]uint64)
+	for path, dv := range table.CurrentVersion().Deletions {
+		if count := dv.Count(); count > 0 {
+			counts[path] = count
+		}
+	}
+	return counts, true
+}
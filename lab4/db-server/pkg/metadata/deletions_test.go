@@ -0,0 +1,116 @@
+package metadata
+
+import (
+	"testing"
+)
+
+func deletionOf(rows ...uint64) *DeletionVector {
+	dv := &DeletionVector{}
+	for _, row := range rows {
+		dv.Add(row)
+	}
+	return dv
+}
+
+func TestDeletionVector(t *testing.T) {
+	dv := deletionOf(0, 63, 64, 200, 64)
+	if dv.Count() != 4 {
+		t.Errorf("Expected 4 deleted rows, got %d", dv.Count())
+	}
+	for _, row := range []uint64{0, 63, 64, 200} {
+		if !dv.Contains(row) {
+			t.Errorf("Expected row %d to be deleted", row)
+		}
+	}
+	if dv.Contains(1) || dv.Contains(1000) {
+		t.Errorf("Unexpected deleted row")
+	}
+
+	var none *DeletionVector
+	if none.Contains(0) || none.Count() != 0 {
+		t.Errorf("Expected nil vector to contain no rows")
+	}
+}
+
+func TestMetastore_DeleteRows(t *testing.T) {
+	m := NewMetastore(t.TempDir())
+	tableId, err := m.CreateTable("t1", []ColumnDef{{Name: "a", Type: Int64Type}}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	f1 := addDummyFile(t, m, "t1", "f1.tomy")
+
+	deleted, err := m.DeleteRows(tableId, map[string]*DeletionVector{f1: deletionOf(1, 2)})
+	if err != nil || deleted != 2 {
+		t.Fatalf("Expected 2 deleted rows, got %d (err: %v)", deleted, err)
+	}
+	deleted, err = m.DeleteRows(tableId, map[string]*DeletionVector{f1: deletionOf(2, 5)})
+	if err != nil || deleted != 1 {
+		t.Fatalf("Expected 1 newly deleted row, got %d (err: %v)", deleted, err)
+	}
+
+	// a later COPY keeps the deletions
+	addDummyFile(t, m, "t1", "f2.tomy")
+
+	snapshot, err := m.GetTableSnapshot("t1", nil)
+	if err != nil {
+		t.Fatalf("GetTableSnapshot failed: %v", err)
+	}
+	if dv := snapshot.Deletions[f1]; dv.Count() != 3 || !dv.Contains(5) {
+		t.Errorf("Expected rows 1, 2 and 5 deleted in the current version, got %v", dv)
+	}
+	snapshot.Release()
+
+	// time travel reads rows deleted later
+	v := uint64(2)
+	snapshot, err = m.GetTableSnapshot("t1", &AsOf{Version: &v})
+	if err != nil {
+		t.Fatalf("GetTableSnapshot failed: %v", err)
+	}
+	if dv := snapshot.Deletions[f1]; dv.Count() != 2 || dv.Contains(5) {
+		t.Errorf("Expected rows 1 and 2 deleted in version 2, got %v", dv)
+	}
+	snapshot.Release()
+
+	// deletions are persisted
+	reloaded := &Metastore{FilePath: m.FilePath}
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	snapshot, err = reloaded.GetTableSnapshot("t1", nil)
+	if err != nil {
+		t.Fatalf("GetTableSnapshot failed: %v", err)
+	}
+	if snapshot.Deletions[f1].Count() != 3 {
+		t.Errorf("Expected 3 deleted rows after reload, got %d", snapshot.Deletions[f1].Count())
+	}
+}
+
+func TestMetastore_DeleteRowsConflicts(t *testing.T) {
+	m := NewMetastore(t.TempDir())
+	tableId, err := m.CreateTable("t1", []ColumnDef{{Name: "a", Type: Int64Type}}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	f1 := addDummyFile(t, m, "t1", "f1.tomy")
+	f2 := addDummyFile(t, m, "t1", "f2.tomy")
+	versions, _ := m.GetTableVersions(tableId)
+	base := versions[len(versions)-1].Version
+
+	// a compaction started before the delete must not drop the deletion
+	if _, err := m.DeleteRows(tableId, map[string]*DeletionVector{f1: deletionOf(0)}); err != nil {
+		t.Fatalf("DeleteRows failed: %v", err)
+	}
+	if err := m.ReplaceFiles(tableId, base, []string{f1}, nil); err == nil {
+		t.Errorf("Expected ReplaceFiles to fail after rows of a replaced file were deleted")
+	}
+
+	// a delete of rows of a compacted file must fail
+	versions, _ = m.GetTableVersions(tableId)
+	if err := m.ReplaceFiles(tableId, versions[len(versions)-1].Version, []string{f2}, nil); err != nil {
+		t.Fatalf("ReplaceFiles failed: %v", err)
+	}
+	if _, err := m.DeleteRows(tableId, map[string]*DeletionVector{f2: deletionOf(0)}); err == nil {
+		t.Errorf("Expected DeleteRows to fail for a file which is no longer present")
+	}
+}
@@ -0,0 +1,189 @@
+package metadata
+
+import "fmt"
+
+// SecondaryIndex keeps (key, file, row) entries of the column sorted in index files. Every index file covers
+// a set of data files, rows of data files not covered by any index file are found by scanning them.
+type SecondaryIndex struct {
+	Column string       `json:"column"`
+	Files  []*IndexFile `json:"files"`
+}
+
+type IndexFile struct {
+	File      *FileEntry `json:"file"`
+	DataFiles []string   `json:"data_files"` // data files whose rows are indexed
+}
+
+// Covers reports whether rows of the data file are indexed by the file
+func (f *IndexFile) Covers(dataFile string) bool {
+	for _, path := range f.DataFiles {
+		if path == dataFile {
+			return true
+		}
+	}
+	return false
+}
+
+// IndexInfo is a read only summary of a secondary index
+type IndexInfo struct {
+	Column       string
+	IndexFiles   int
+	IndexedFiles int // data files of the current version covered by the index
+	TotalFiles   int
+}
+
+// CreateIndex adds an empty secondary index on the column, does nothing if the index already exists
+func (m *Metastore) CreateIndex(tableId string, column string) error {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	table, exists := m.getTableByIdUnlocked(tableId)
+	if !exists {
+		return fmt.Errorf("table %s does not exist", tableId)
+	}
+	if table.index(column) != nil {
+		return nil
+	}
+
+	found := false
+	for _, col := range table.Columns {
+		found = found || col.Name == column
+	}
+	if !found {
+		return fmt.Errorf("column %s does not exist in table %s", column, table.QualifiedName())
+	}
+
+	table.Indexes = append(table.Indexes, &SecondaryIndex{Column: column, Files: []*IndexFile{}})
+	return m.save()
+}
+
+// DropIndex removes the index, its files are removed as soon as no running query uses them
+func (m *Metastore) DropIndex(tableId string, column string) error {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	table, exists := m.getTableByIdUnlocked(tableId)
+	if !exists {
+		return fmt.Errorf("table %s does not exist", tableId)
+	}
+
+	for i, idx := range table.Indexes {
+		if idx.Column == column {
+			for _, f := range idx.Files {
+				f.File.MarkDeleted()
+			}
+			table.Indexes = append(table.Indexes[:i], table.Indexes[i+1:]...)
+			return m.save()
+		}
+	}
+	return fmt.Errorf("index on column %s does not exist", column)
+}
+
+// AddIndexFile adds the index file covering the data files to the index on the column.
+// It fails if the index was dropped, the caller should then remove the file.
+func (m *Metastore) AddIndexFile(tableId string, column string, file NewFile, dataFiles []string) error {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	table, exists := m.getTableByIdUnlocked(tableId)
+	if !exists {
+		return fmt.Errorf("table %s does not exist", tableId)
+	}
+	idx := table.index(column)
+	if idx == nil {
+		return fmt.Errorf("index on column %s does not exist", column)
+	}
+
+	idx.Files = append(idx.Files, &IndexFile{
+		File:      &FileEntry{Path: file.Path, Stats: file.Stats},
+		DataFiles: dataFiles,
+	})
+	return m.save()
+}
+
+// GetIndexes returns summaries of secondary indexes of the table
+func (m *Metastore) GetIndexes(tableId string) ([]IndexInfo, bool) {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+
+	table, exists := m.getTableByIdUnlocked(tableId)
+	if !exists {
+		return nil, false
+	}
+
+	infos := make([]IndexInfo, len(table.Indexes))
+	for i, idx := range table.Indexes {
+		infos[i] = IndexInfo{Column: idx.Column, IndexFiles: len(idx.Files), TotalFiles: len(table.Files)}
+		for _, f := range table.Files {
+			if idx.covers(f.Path) {
+				infos[i].IndexedFiles++
+			}
+		}
+	}
+	return infos, true
+}
+
+// Assumes lock is held
+func (t *TableDef) index(column string) *SecondaryIndex {
+	for _, idx := range t.Indexes {
+		if idx.Column == column {
+			return idx
+		}
+	}
+	return nil
+}
+
+func (idx *SecondaryIndex) covers(dataFile string) bool {
+	for _, f := range idx.Files {
+		if f.Covers(dataFile) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexFilesOf returns files of every index covering any of the data files, by indexed column.
+// Assumes lock is held
+func (t *TableDef) indexFilesOf(dataFiles []*FileEntry) map[string][]*IndexFile {
+	if len(t.Indexes) == 0 {
+		return nil
+	}
+
+	present := make(map[string]bool, len(dataFiles))
+	for _, f := range dataFiles {
+		present[f.Path] = true
+	}
+
+	res := make(map[string][]*IndexFile, len(t.Indexes))
+	for _, idx := range t.Indexes {
+		for _, f := range idx.Files {
+			for _, path := range f.DataFiles {
+				if present[path] {
+					res[idx.Column] = append(res[idx.Column], f)
+					break
+				}
+			}
+		}
+	}
+	return res
+}
+
+// expireIndexFiles removes index files covering only data files which are no longer part of any retained version.
+// Assumes write lock is held
+func (t *TableDef) expireIndexFiles(referenced func(path string) bool) {
+	for _, idx := range t.Indexes {
+		retained := idx.Files[:0]
+		for _, f := range idx.Files {
+			used := false
+			for _, path := range f.DataFiles {
+				used = used || referenced(path)
+			}
+			if used {
+				retained = append(retained, f)
+			} else {
+				f.File.MarkDeleted()
+			}
+		}
+		idx.Files = retained
+	}
+}
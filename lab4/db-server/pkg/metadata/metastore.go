@@ -2,6 +2,7 @@ package metadata
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,15 +11,47 @@ import (
 )
 
 type Metastore struct {
-	Schema   Schema            `json:"schema"`
-	NameToId map[string]string `json:"name_to_id"`
-	FilePath string            `json:"-"`
-	Mu       sync.RWMutex      `json:"-"`
+	Schema    Schema            `json:"schema"`
+	NameToId  map[string]string `json:"name_to_id"` // qualified name (database.table) -> table id
+	FilePath  string            `json:"-"`
+	TablesDir string            `json:"-"` // every database keeps its files in a subdirectory
+	Retention RetentionPolicy   `json:"-"`
+	Mu        sync.RWMutex      `json:"-"`
 }
 
 type MetastoreSnapshot struct {
-	Files   []*FileEntry `json:"files"`
-	Columns []ColumnDef  `json:"columns"`
+	Version  uint64       `json:"version"`
+	Files    []*FileEntry `json:"files"`
+	Columns  []ColumnDef  `json:"columns"`
+	SortKeys []SortKey    `json:"sort_keys"`
+
+	Deletions map[string]*DeletionVector `json:"deletions"` // rows deleted from files of the version, read only
+	Indexes   map[string][]*IndexFile    `json:"indexes"`   // index files covering files of the version, by column
+}
+
+// Release allows files of the snapshot to be removed, must be called once the snapshot is no longer read
+func (s *MetastoreSnapshot) Release() {
+	for _, f := range s.Files {
+		f.DecRef()
+	}
+	for _, files := range s.Indexes {
+		for _, f := range files {
+			f.File.DecRef()
+		}
+	}
+}
+
+// Retain keeps only files of the snapshot for which keep returns true, the dropped files are released
+func (s *MetastoreSnapshot) Retain(keep func(f *FileEntry) bool) {
+	retained := s.Files[:0]
+	for _, f := range s.Files {
+		if keep(f) {
+			retained = append(retained, f)
+		} else {
+			f.DecRef()
+		}
+	}
+	s.Files = retained
 }
 
 func NewMetastore(dbmsBaseDir string) *Metastore {
@@ -31,10 +64,13 @@ func NewMetastore(dbmsBaseDir string) *Metastore {
 	metaFilePath := filepath.Join(metastoreDir, "metastore.json")
 	ms := &Metastore{
 		Schema: Schema{
-			Tables: make(map[string]*TableDef),
+			Tables:    make(map[string]*TableDef),
+			Databases: make(map[string]*DatabaseDef),
 		},
-		NameToId: make(map[string]string),
-		FilePath: metaFilePath,
+		NameToId:  make(map[string]string),
+		FilePath:  metaFilePath,
+		TablesDir: filepath.Join(dbmsBaseDir, "tables"),
+		Retention: DefaultRetentionPolicy,
 	}
 
 	if err := ms.Load(); err != nil {
@@ -42,6 +78,13 @@ func NewMetastore(dbmsBaseDir string) *Metastore {
 		os.Exit(1)
 	}
 
+	for db := range ms.Schema.Databases {
+		if err := os.MkdirAll(ms.DatabaseDir(db), 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating database directory: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	return ms
 }
 
@@ -52,12 +95,19 @@ func (m *Metastore) Load() error {
 	data, err := os.ReadFile(m.FilePath)
 	if err != nil {
 		if os.IsNotExist(err) {
+			m.migrateToDatabases()
 			return nil
 		}
 		return err
 	}
 
-	return json.Unmarshal(data, m)
+	if err := json.Unmarshal(data, m); err != nil {
+		return err
+	}
+	m.migrateToDatabases()
+	m.migrateToVersions()
+	m.shareFileEntries()
+	return nil
 }
 
 // Save persists metadata to disk. It acquires a read lock
@@ -77,19 +127,59 @@ func (m *Metastore) save() error {
 	return os.WriteFile(m.FilePath, data, 0644)
 }
 
-func (m *Metastore) CreateTable(name string, columns []ColumnDef) (string, error) {
+// ErrNotPersisted is returned when files were committed in memory, but saving the metastore failed.
+// The committed files are part of the table, so they must not be removed; the next successful save persists them.
+var ErrNotPersisted = errors.New("committed, but the metastore could not be saved")
+
+// persist saves the metastore after files were committed, failures wrap ErrNotPersisted. Assumes lock is held
+func (m *Metastore) persist() error {
+	if err := m.save(); err != nil {
+		return fmt.Errorf("%w: %w", ErrNotPersisted, err)
+	}
+	return nil
+}
+
+// CreateTable creates the table in the database given by the qualified name ("db.table"),
+// unqualified names are created in the default database. Rows of tables with sort keys are kept sorted.
+func (m *Metastore) CreateTable(name string, columns []ColumnDef, sortKeys []SortKey) (string, error) {
+	return m.CreateTableWithFiles(name, columns, sortKeys, nil)
+}
+
+// CreateTableWithFiles creates the table like CreateTable with the files already committed,
+// either the table with all of the files is created or nothing is
+func (m *Metastore) CreateTableWithFiles(name string, columns []ColumnDef, sortKeys []SortKey, files []NewFile) (string, error) {
+	db, tableName := SplitQualifiedName(name)
+	if err := validateIdentifier("table", tableName); err != nil {
+		return "", err
+	}
+	if err := ValidateSortKeys(columns, sortKeys); err != nil {
+		return "", err
+	}
+
 	m.Mu.Lock()
 	defer m.Mu.Unlock()
 
-	tableId, err := m.newTableId(name)
+	if _, exists := m.Schema.Databases[db]; !exists {
+		return "", fmt.Errorf("database %s does not exist", db)
+	}
+
+	tableId, err := m.newTableId(db, tableName)
 	if err != nil {
 		return "", err
 	}
 
-	m.Schema.Tables[tableId] = &TableDef{
-		Name:    name,
-		Columns: columns,
-		Files:   make([]*FileEntry, 0),
+	table := &TableDef{
+		Name:     tableName,
+		Database: db,
+		Columns:  columns,
+		SortKeys: sortKeys,
+		Files:    make([]*FileEntry, 0),
+		Versions: []*TableVersion{{Version: 0, CommittedAt: time.Now(), Files: []string{}}},
+	}
+	m.Schema.Tables[tableId] = table
+	if len(files) > 0 {
+		m.addFiles(table, files)
+		return tableId, m.persist()
 	}
 	return tableId, m.save()
 }
@@ -108,6 +198,19 @@ func (m *Metastore) getTableByNameUnlocked(name string) (*TableDef, bool) {
 	return m.getTableByIdUnlocked(id)
 }
 
+// GetTableId resolves the (optionally qualified) table name to the id of the table
+func (m *Metastore) GetTableId(name string) (string, bool) {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+
+	id, err := m.tableIdFromName(name)
+	if err != nil {
+		return "", false
+	}
+	_, exists := m.getTableByIdUnlocked(id)
+	return id, exists
+}
+
 func (m *Metastore) GetTableById(id string) (*TableDef, bool) {
 	m.Mu.RLock()
 	defer m.Mu.RUnlock()
@@ -123,21 +226,55 @@ func (m *Metastore) DeleteTable(tableId string) error {
 	m.Mu.Lock()
 	defer m.Mu.Unlock()
 
-	table, exists := m.getTableByIdUnlocked(tableId)
-	if !exists {
+	if _, exists := m.getTableByIdUnlocked(tableId); !exists {
 		return fmt.Errorf("table %s does not exist", tableId)
 	}
 
+	m.dropTable(tableId)
+	return m.save()
+}
+
+// ValidateSortKeys checks that sort keys refer to distinct columns of the table
+func ValidateSortKeys(columns []ColumnDef, sortKeys []SortKey) error {
+	seen := make(map[string]bool, len(sortKeys))
+	for _, key := range sortKeys {
+		found := false
+		for _, col := range columns {
+			found = found || col.Name == key.Column
+		}
+		if !found {
+			return fmt.Errorf("sort key column %s does not exist", key.Column)
+		}
+		if seen[key.Column] {
+			return fmt.Errorf("duplicate sort key column %s", key.Column)
+		}
+		seen[key.Column] = true
+	}
+	return nil
+}
+
+// Assumes write lock is held and the table exists
+func (m *Metastore) dropTable(tableId string) {
+	table := m.Schema.Tables[tableId]
 	for _, f := range table.Files {
 		f.MarkDeleted()
 	}
+	for _, f := range table.HistoricalFiles {
+		f.MarkDeleted()
+	}
+	for _, idx := range table.Indexes {
+		for _, f := range idx.Files {
+			f.File.MarkDeleted()
+		}
+	}
 
 	delete(m.Schema.Tables, tableId)
-	delete(m.NameToId, table.Name)
-	return m.save()
+	delete(m.NameToId, table.QualifiedName())
 }
 
-func (m *Metastore) AddFile(tableName string, filePath string) error {
+// AddFile commits a new version of the table with the file appended,
+// stats can be nil when they are not known (e.g. in tests)
+func (m *Metastore) AddFile(tableName string, filePath string, stats *FileStats) error {
 	m.Mu.Lock()
 	defer m.Mu.Unlock()
 
@@ -146,16 +283,169 @@ func (m *Metastore) AddFile(tableName string, filePath string) error {
 		return fmt.Errorf("table %s does not exist", tableName)
 	}
 
-	entry := &FileEntry{
-		Path:     filePath,
-		refCount: 0,
-		deleted:  false,
+	m.addFiles(table, []NewFile{{Path: filePath, Stats: stats}})
+	return m.persist()
+}
+
+// AddFiles commits a single new version of the table with all of the files appended
+func (m *Metastore) AddFiles(tableId string, added []NewFile) error {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	table, exists := m.getTableByIdUnlocked(tableId)
+	if !exists {
+		return fmt.Errorf("table %s does not exist", tableId)
 	}
-	table.Files = append(table.Files, entry)
-	return m.save()
+
+	m.addFiles(table, added)
+	return m.persist()
+}
+
+// Assumes write lock is held
+func (m *Metastore) addFiles(table *TableDef, added []NewFile) {
+	files := make([]*FileEntry, 0, len(table.Files)+len(added))
+	files = append(files, table.Files...)
+	for _, nf := range added {
+		files = append(files, &FileEntry{Path: nf.Path, Stats: nf.Stats})
+	}
+	m.commitFiles(table, files)
+	for _, nf := range added {
+		if nf.Stats != nil {
+			table.mergeSketches(nf.Stats)
+		}
+	}
+}
+
+// NewFile is a file written by a rewrite of the table (e.g. compaction) together with its statistics
+type NewFile struct {
+	Path  string
+	Stats *FileStats
+}
+
+// ReplaceFiles atomically commits a new version in which the removed files are replaced by the added ones.
+// The added files must contain rows of the removed files as of baseVersion. It fails if any of the removed files
+// is no longer part of the current version or rows were deleted from it after baseVersion, e.g. the table was
+// rewritten concurrently. Running snapshots keep reading the removed files until they are released.
+func (m *Metastore) ReplaceFiles(tableId string, baseVersion uint64, removed []string, added []NewFile) error {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	table, exists := m.getTableByIdUnlocked(tableId)
+	if !exists {
+		return fmt.Errorf("table %s does not exist", tableId)
+	}
+
+	toRemove := make(map[string]bool, len(removed))
+	for _, path := range removed {
+		toRemove[path] = true
+	}
+
+	files := make([]*FileEntry, 0, len(table.Files)-len(removed)+len(added))
+	for _, f := range table.Files {
+		if toRemove[f.Path] {
+			delete(toRemove, f.Path)
+			continue
+		}
+		files = append(files, f)
+	}
+	if len(toRemove) > 0 {
+		return fmt.Errorf("table %s was modified concurrently, %d of replaced files are no longer present", tableId, len(toRemove))
+	}
+
+	base, err := table.resolveVersion(&AsOf{Version: &baseVersion})
+	if err != nil {
+		return fmt.Errorf("table %s was modified concurrently: %w", tableId, err)
+	}
+	for _, path := range removed {
+		if table.CurrentVersion().Deletions[path].Count() != base.Deletions[path].Count() {
+			return fmt.Errorf("table %s was modified concurrently, rows of %s were deleted", tableId, path)
+		}
+	}
+
+	for _, nf := range added {
+		files = append(files, &FileEntry{Path: nf.Path, Stats: nf.Stats})
+	}
+	m.commitFiles(table, files)
+	return m.persist()
 }
 
-func (m *Metastore) GetTableSnapshot(tableName string) (*MetastoreSnapshot, error) {
+func (m *Metastore) GetTableStatistics(tableId string) (*TableStatistics, bool) {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+
+	table, exists := m.getTableByIdUnlocked(tableId)
+	if !exists {
+		return nil, false
+	}
+	return table.statistics(), true
+}
+
+// AnalyzeTable recomputes statistics of all table files from their footers.
+// Footers are read without holding the lock, files are protected by the reference count.
+func (m *Metastore) AnalyzeTable(tableId string) (*TableStatistics, error) {
+	m.Mu.RLock()
+	table, exists := m.getTableByIdUnlocked(tableId)
+	if !exists {
+		m.Mu.RUnlock()
+		return nil, fmt.Errorf("table %s does not exist", tableId)
+	}
+	files := make([]*FileEntry, len(table.Files))
+	for i, f := range table.Files {
+		f.IncRef()
+		files[i] = f
+	}
+	m.Mu.RUnlock()
+
+	defer func() {
+		for _, f := range files {
+			f.DecRef()
+		}
+	}()
+
+	collected := make(map[*FileEntry]*FileStats, len(files))
+	for _, f := range files {
+		stats, err := FileStatsFromFooter(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		collected[f] = stats
+	}
+
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	table, exists = m.getTableByIdUnlocked(tableId)
+	if !exists {
+		return nil, fmt.Errorf("table %s was removed during analyze", tableId)
+	}
+
+	oldStats := table.Stats
+	now := time.Now()
+	table.Stats = &TableStats{AnalyzedAt: &now}
+	addedDuringAnalyze := false
+	for _, f := range table.Files {
+		stats, ok := collected[f]
+		if !ok {
+			addedDuringAnalyze = true
+			continue
+		}
+		f.Stats = stats
+		table.mergeSketches(stats)
+	}
+
+	// sketches of files added in the meantime were merged only into the old statistics
+	if addedDuringAnalyze && oldStats != nil {
+		for colName, registers := range oldStats.DistinctSketches {
+			table.mergeSketch(colName, registers)
+		}
+	}
+
+	return table.statistics(), m.save()
+}
+
+// GetTableSnapshot pins files of the selected table version (the current one when asOf is nil),
+// they are not removed until the snapshot is released
+func (m *Metastore) GetTableSnapshot(tableName string, asOf *AsOf) (*MetastoreSnapshot, error) {
 	m.Mu.RLock()
 	defer m.Mu.RUnlock()
 
@@ -163,43 +453,64 @@ func (m *Metastore) GetTableSnapshot(tableName string) (*MetastoreSnapshot, erro
 	if !exists {
 		return nil, fmt.Errorf("table %s does not exist", tableName)
 	}
+	return table.snapshot(asOf)
+}
 
-	filesSnapshot := make([]*FileEntry, 0, len(table.Files))
-	for _, f := range table.Files {
-		f.IncRef()
-		filesSnapshot = append(filesSnapshot, f)
+func (m *Metastore) GetTableSnapshotById(tableId string) (*MetastoreSnapshot, error) {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+
+	table, exists := m.getTableByIdUnlocked(tableId)
+	if !exists {
+		return nil, fmt.Errorf("table %s does not exist", tableId)
 	}
+	return table.snapshot(nil)
+}
+
+// GetTableFiles returns files of the current table version, they are not pinned like in a snapshot
+func (m *Metastore) GetTableFiles(tableId string) ([]*FileEntry, bool) {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
 
-	return &MetastoreSnapshot{
-		Files:   filesSnapshot,
-		Columns: table.Columns,
-	}, nil
+	table, exists := m.getTableByIdUnlocked(tableId)
+	if !exists {
+		return nil, false
+	}
+	return append([]*FileEntry(nil), table.Files...), true
 }
 
-func (m *Metastore) GetTables() (names []string, ids []string) {
+// GetTables lists all tables, databases[i] is the database of the table names[i]
+func (m *Metastore) GetTables() (names []string, ids []string, databases []string) {
 	m.Mu.RLock()
 	defer m.Mu.RUnlock()
 	for id, table := range m.Schema.Tables {
 		names = append(names, table.Name)
 		ids = append(ids, id)
+		databases = append(databases, table.Database)
 	}
-	return names, ids
+	return names, ids, databases
 }
 
 // Assumes lock is held
 func (m *Metastore) tableIdFromName(name string) (string, error) {
-	if _, exists := m.NameToId[name]; !exists {
+	id, exists := m.NameToId[QualifiedName(name)]
+	if !exists {
 		return "", fmt.Errorf("table %s does not exist", name)
 	}
-	return m.NameToId[name], nil
+	return id, nil
 }
 
 // Assumes write lock is held
-func (m *Metastore) newTableId(tableName string) (string, error) {
+func (m *Metastore) newTableId(db string, tableName string) (string, error) {
+	qualified := db + "." + tableName
+	if _, exists := m.NameToId[qualified]; exists {
+		return "", fmt.Errorf("table %s already exists", qualified)
+	}
+
 	id := fmt.Sprintf("%s_%d", tableName, time.Now().UnixNano())
-	if _, exists := m.NameToId[tableName]; exists {
-		return "", fmt.Errorf("table %s already exists", tableName)
+	if db != DefaultDatabase {
+		id = fmt.Sprintf("%s.%s", db, id)
 	}
-	m.NameToId[tableName] = id
+	m.NameToId[qualified] = id
 	return id, nil
 }
@@ -1,9 +1,13 @@
 package metadata
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"isbd4/pkg/tomy_file"
 )
 
 func TestMetastore_Persistence(t *testing.T) {
@@ -14,7 +18,7 @@ func TestMetastore_Persistence(t *testing.T) {
 		{Name: "id", Type: Int64Type},
 		{Name: "name", Type: VarcharType},
 	}
-	tableId, err := m1.CreateTable("users", cols)
+	tableId, err := m1.CreateTable("users", cols, nil)
 	if err != nil {
 		t.Fatalf("CreateTable failed: %v", err)
 	}
@@ -37,7 +41,7 @@ func TestMetastore_DeleteTable(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	m := NewMetastore(tmpDir)
-	tableId, err := m.CreateTable("t1", []ColumnDef{{Name: "a", Type: Int64Type}})
+	tableId, err := m.CreateTable("t1", []ColumnDef{{Name: "a", Type: Int64Type}}, nil)
 	if err != nil {
 		t.Fatalf("CreateTable failed: %v", err)
 	}
@@ -48,7 +52,7 @@ func TestMetastore_DeleteTable(t *testing.T) {
 		t.Fatalf("Failed to create dummy file: %v", err)
 	}
 
-	err = m.AddFile("t1", dummyFile)
+	err = m.AddFile("t1", dummyFile, nil)
 	if err != nil {
 		t.Fatalf("AddFile failed: %v", err)
 	}
@@ -71,3 +75,132 @@ func TestMetastore_DeleteTable(t *testing.T) {
 		t.Errorf("Dummy file should have been deleted, got err: %v", err)
 	}
 }
+
+func TestMetastore_TableStatistics(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	m := NewMetastore(tmpDir)
+	tableId, err := m.CreateTable("t1", []ColumnDef{{Name: "a", Type: Int64Type}}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	for i, values := range [][]int64{{1, 2, 3}, {3, 4, 10, -5}} {
+		path := filepath.Join(tmpDir, fmt.Sprintf("f%d.tomy", i))
+		table := tomy_file.ColumnarTable{
+			NumRows: uint64(len(values)),
+			Columns: []tomy_file.AnyColumn{tomy_file.Int64Column{Name: "a", Values: values}},
+		}
+		if err := table.Serialize(path); err != nil {
+			t.Fatalf("Serialize failed: %v", err)
+		}
+		stats, err := FileStatsFromFooter(path)
+		if err != nil {
+			t.Fatalf("FileStatsFromFooter failed: %v", err)
+		}
+		if err := m.AddFile("t1", path, stats); err != nil {
+			t.Fatalf("AddFile failed: %v", err)
+		}
+	}
+
+	check := func(stats *TableStatistics) {
+		t.Helper()
+		if stats.RowCount != 7 || stats.FileCount != 2 {
+			t.Errorf("Expected 7 rows in 2 files, got %d rows in %d files", stats.RowCount, stats.FileCount)
+		}
+		col := stats.Columns[0]
+		if *col.Min.Int64 != -5 || *col.Max.Int64 != 10 {
+			t.Errorf("Expected bounds [-5, 10], got [%d, %d]", *col.Min.Int64, *col.Max.Int64)
+		}
+		if col.DistinctEstimate != 6 {
+			t.Errorf("Expected 6 distinct values, got %d", col.DistinctEstimate)
+		}
+		if col.UncompressedBytes != 56 {
+			t.Errorf("Expected 56 uncompressed bytes, got %d", col.UncompressedBytes)
+		}
+	}
+
+	stats, _ := m.GetTableStatistics(tableId)
+	check(stats)
+
+	// sketches are persisted at the table level, bounds at the file level
+	m2 := NewMetastore(tmpDir)
+	stats, _ = m2.GetTableStatistics(tableId)
+	check(stats)
+
+	stats, err = m2.AnalyzeTable(tableId)
+	if err != nil {
+		t.Fatalf("AnalyzeTable failed: %v", err)
+	}
+	check(stats)
+	if stats.AnalyzedAt == nil {
+		t.Errorf("Expected analyze time to be set")
+	}
+}
+
+func TestMetastore_CreateTableWithFiles(t *testing.T) {
+	m := NewMetastore(t.TempDir())
+	cols := []ColumnDef{{Name: "a", Type: Int64Type}}
+	files := []NewFile{{Path: "f1.tomy"}, {Path: "f2.tomy"}}
+
+	tableId, err := m.CreateTableWithFiles("t1", cols, nil, files)
+	if err != nil {
+		t.Fatalf("CreateTableWithFiles failed: %v", err)
+	}
+	versions, _ := m.GetTableVersions(tableId)
+	if len(versions) != 2 || len(versions[1].Files) != 2 {
+		t.Fatalf("Expected files committed in a single version, got %+v", versions)
+	}
+
+	if _, err := m.CreateTableWithFiles("t1", cols, nil, files); err == nil {
+		t.Errorf("Expected error when creating existing table")
+	}
+	if _, err := m.CreateTableWithFiles("t2", cols, []SortKey{{Column: "b"}}, files); err == nil {
+		t.Errorf("Expected error for unknown sort key column")
+	}
+	if _, exists := m.GetTableId("t2"); exists {
+		t.Errorf("Expected table not to be created when it is invalid")
+	}
+
+	if err := m.AddFiles(tableId, []NewFile{{Path: "f3.tomy"}, {Path: "f4.tomy"}}); err != nil {
+		t.Fatalf("AddFiles failed: %v", err)
+	}
+	versions, _ = m.GetTableVersions(tableId)
+	if len(versions) != 3 || len(versions[2].Files) != 4 {
+		t.Errorf("Expected files added in a single version, got %+v", versions)
+	}
+}
+
+func TestMetastore_ReplaceFilesNotPersisted(t *testing.T) {
+	m := NewMetastore(t.TempDir())
+	tableId, err := m.CreateTable("t1", []ColumnDef{{Name: "a", Type: Int64Type}}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	if err := m.AddFiles(tableId, []NewFile{{Path: "f1.tomy"}}); err != nil {
+		t.Fatalf("AddFiles failed: %v", err)
+	}
+	versions, _ := m.GetTableVersions(tableId)
+	base := versions[len(versions)-1].Version
+
+	// saving fails, as the directory of the metastore file does not exist
+	m.FilePath = filepath.Join(t.TempDir(), "missing", "metastore.json")
+	err = m.ReplaceFiles(tableId, base, []string{"f1.tomy"}, []NewFile{{Path: "f2.tomy"}})
+	if !errors.Is(err, ErrNotPersisted) {
+		t.Fatalf("Expected ErrNotPersisted, got %v", err)
+	}
+	files, _ := m.GetTableFiles(tableId)
+	if len(files) != 1 || files[0].Path != "f2.tomy" {
+		t.Errorf("Expected the replacement to stay committed in memory, got %v", files)
+	}
+
+	if err := m.AddFiles(tableId, []NewFile{{Path: "f3.tomy"}}); !errors.Is(err, ErrNotPersisted) {
+		t.Errorf("Expected ErrNotPersisted, got %v", err)
+	}
+	if _, err := m.CreateTableWithFiles("t2", []ColumnDef{{Name: "a", Type: Int64Type}}, nil, []NewFile{{Path: "f4.tomy"}}); !errors.Is(err, ErrNotPersisted) {
+		t.Errorf("Expected ErrNotPersisted, got %v", err)
+	}
+	if err := m.ReplaceFiles(tableId, base, []string{"f1.tomy"}, nil); err == nil || errors.Is(err, ErrNotPersisted) {
+		t.Errorf("Expected a concurrent modification error, got %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package metadata
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"isbd4/pkg/tomy_file"
+)
+
+// Per file statistics, collected from the tomy footer when the file is added to the table
+type FileStats struct {
+	NumRows   uint64            `json:"num_rows"`
+	SizeBytes int64             `json:"size_bytes"`
+	Columns   []FileColumnStats `json:"columns"`
+	SortOrder []SortKey         `json:"sort_order,omitempty"` // order of rows recorded in the footer
+}
+
+// IsSortedBy reports whether rows of the file are sorted by the given keys (or a longer order starting with them)
+func (s *FileStats) IsSortedBy(keys []SortKey) bool {
+	if s == nil || len(keys) > len(s.SortOrder) {
+		return false
+	}
+	for i, key := range keys {
+		if s.SortOrder[i] != key {
+			return false
+		}
+	}
+	return true
+}
+
+type FileColumnStats struct {
+	Name              string       `json:"name"`
+	CompressedBytes   int64        `json:"compressed_bytes"`
+	UncompressedBytes int64        `json:"uncompressed_bytes"`
+	Min               *ColumnBound `json:"min,omitempty"`
+	Max               *ColumnBound `json:"max,omitempty"`
+	Sketch            []uint8      `json:"-"` // merged into TableStats, the file keeps its own copy in the footer
+}
+
+// ColumnBound holds a min or max value of a column, exactly one of the fields is set
+type ColumnBound struct {
+	Int64   *int64  `json:"int64,omitempty"`
+	Varchar *string `json:"varchar,omitempty"`
+}
+
+func (b *ColumnBound) Value() any {
+	if b.Int64 != nil {
+		return *b.Int64
+	}
+	return *b.Varchar
+}
+
+// Compare returns -1, 0 or 1, assumes both bounds are of the same type
+func (b *ColumnBound) Compare(other *ColumnBound) int {
+	if b.Int64 != nil {
+		switch {
+		case *b.Int64 < *other.Int64:
+			return -1
+		case *b.Int64 > *other.Int64:
+			return 1
+		}
+		return 0
+	}
+	switch {
+	case *b.Varchar < *other.Varchar:
+		return -1
+	case *b.Varchar > *other.Varchar:
+		return 1
+	}
+	return 0
+}
+
+// Table level statistics which can't be derived from per file statistics
+type TableStats struct {
+	DistinctSketches map[string][]uint8 `json:"distinct_sketches"` // column name -> HyperLogLog registers
+	AnalyzedAt       *time.Time         `json:"analyzed_at,omitempty"`
+}
+
+// TableStatistics is a read only summary of the table, aggregated from its files
+type TableStatistics struct {
+	RowCount   uint64
+	FileCount  int
+	SizeBytes  int64
+	Columns    []ColumnStatistics
+	AnalyzedAt *time.Time
+}
+
+type ColumnStatistics struct {
+	Name              string
+	CompressedBytes   int64
+	UncompressedBytes int64
+	Min               *ColumnBound
+	Max               *ColumnBound
+	DistinctEstimate  uint64
+}
+
+// FileStatsFromFooter reads statistics of a tomy file.
+// Files written without the statistics section are fully read to compute them.
+func FileStatsFromFooter(filePath string) (*FileStats, error) {
+	fi, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file %s: %w", filePath, err)
+	}
+
+	meta, err := tomy_file.ReadFileMetadata(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read footer of %s: %w", filePath, err)
+	}
+
+	if err := fillMissingStatistics(filePath, meta); err != nil {
+		return nil, err
+	}
+
+	stats := &FileStats{
+		NumRows:   meta.NumRows,
+		SizeBytes: fi.Size(),
+		Columns:   make([]FileColumnStats, len(meta.Columns)),
+	}
+	for i, col := range meta.Columns {
+		colStats := FileColumnStats{
+			Name:              col.Name,
+			CompressedBytes:   col.CompressedSize,
+			UncompressedBytes: col.Stats.UncompressedSize,
+			Sketch:            col.Stats.Sketch.Registers,
+		}
+		if col.Stats.HasMinMax {
+			colStats.Min, colStats.Max = boundsFromTomy(col.Type, col.Stats)
+		}
+		stats.Columns[i] = colStats
+	}
+	for _, key := range meta.SortOrder {
+		stats.SortOrder = append(stats.SortOrder, SortKey{
+			Column:    meta.Columns[key.ColumnIdx].Name,
+			Ascending: key.Ascending,
+		})
+	}
+	return stats, nil
+}
+
+func fillMissingStatistics(filePath string, meta *tomy_file.FileMetaData) error {
+	missing := false
+	for _, col := range meta.Columns {
+		if col.Stats == nil {
+			missing = true
+		}
+	}
+	if !missing {
+		return nil
+	}
+
+	table, err := tomy_file.Deserialize(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s for statistics: %w", filePath, err)
+	}
+	for i := range meta.Columns {
+		meta.Columns[i].Stats = table.Columns[i].ComputeStatistics()
+	}
+	return nil
+}
+
+func boundsFromTomy(colType tomy_file.ColumnType, stats *tomy_file.ColumnStatistics) (*ColumnBound, *ColumnBound) {
+	if colType == tomy_file.TypeInt64 {
+		minVal, maxVal := stats.MinInt64, stats.MaxInt64
+		return &ColumnBound{Int64: &minVal}, &ColumnBound{Int64: &maxVal}
+	}
+	minVal, maxVal := string(stats.MinVarchar), string(stats.MaxVarchar)
+	return &ColumnBound{Varchar: &minVal}, &ColumnBound{Varchar: &maxVal}
+}
+
+// Assumes lock is held
+func (t *TableDef) mergeSketches(stats *FileStats) {
+	for _, col := range stats.Columns {
+		t.mergeSketch(col.Name, col.Sketch)
+	}
+}
+
+// Assumes lock is held
+func (t *TableDef) mergeSketch(colName string, registers []uint8) {
+	sketch, err := tomy_file.HyperLogLogFromRegisters(registers)
+	if err != nil {
+		return
+	}
+
+	if t.Stats == nil {
+		t.Stats = &TableStats{}
+	}
+	if t.Stats.DistinctSketches == nil {
+		t.Stats.DistinctSketches = make(map[string][]uint8)
+	}
+
+	tableSketch, err := tomy_file.HyperLogLogFromRegisters(t.Stats.DistinctSketches[colName])
+	if err != nil {
+		tableSketch = tomy_file.NewHyperLogLog()
+	}
+	tableSketch.Merge(sketch)
+	t.Stats.DistinctSketches[colName] = tableSketch.Registers
+}
+
+// Assumes lock is held
+func (t *TableDef) statistics() *TableStatistics {
+	res := &TableStatistics{
+		FileCount: len(t.Files),
+		Columns:   make([]ColumnStatistics, len(t.Columns)),
+	}
+
+	colIdx := make(map[string]int)
+	for i, col := range t.Columns {
+		colIdx[col.Name] = i
+		res.Columns[i].Name = col.Name
+	}
+
+	deletions := t.CurrentVersion().Deletions
+	for _, f := range t.Files {
+		if f.Stats == nil {
+			continue
+		}
+		res.RowCount += f.Stats.NumRows - deletions[f.Path].Count()
+		res.SizeBytes += f.Stats.SizeBytes
+
+		for _, fc := range f.Stats.Columns {
+			i, ok := colIdx[fc.Name]
+			if !ok {
+				continue
+			}
+			col := &res.Columns[i]
+			col.CompressedBytes += fc.CompressedBytes
+			col.UncompressedBytes += fc.UncompressedBytes
+			if fc.Min != nil && (col.Min == nil || fc.Min.Compare(col.Min) < 0) {
+				col.Min = fc.Min
+			}
+			if fc.Max != nil && (col.Max == nil || fc.Max.Compare(col.Max) > 0) {
+				col.Max = fc.Max
+			}
+		}
+	}
+
+	if t.Stats != nil {
+		res.AnalyzedAt = t.Stats.AnalyzedAt
+		for i := range res.Columns {
+			sketch, err := tomy_file.HyperLogLogFromRegisters(t.Stats.DistinctSketches[res.Columns[i].Name])
+			if err != nil {
+				continue
+			}
+			// sketches are never reduced, so clamp the estimate after rows were removed
+			res.Columns[i].DistinctEstimate = min(sketch.Estimate(), res.RowCount)
+		}
+	}
+	return res
+}
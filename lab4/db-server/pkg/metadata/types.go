@@ -7,10 +7,12 @@ import (
 )
 
 type FileEntry struct {
-	Path     string     `json:"path"`
-	refCount int        `json:"-"`
-	deleted  bool       `json:"-"` // used to mark that no longer in Metastore
-	mu       sync.Mutex `json:"-"`
+	Path       string     `json:"path"`
+	Stats      *FileStats `json:"stats,omitempty"`
+	SharedWith int        `json:"shared_with,omitempty"` // number of other tables referencing the file, e.g. clones
+	refCount   int        `json:"-"`
+	deleted    bool       `json:"-"` // used to mark that no longer in Metastore
+	mu         sync.Mutex `json:"-"`
 }
 
 func (f *FileEntry) IncRef() {
@@ -26,9 +28,22 @@ func (f *FileEntry) DecRef() {
 	f.tryCleanup()
 }
 
+// Share records that one more table references the file
+func (f *FileEntry) Share() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.SharedWith++
+}
+
+// MarkDeleted is called when a table no longer references the file, it is removed once no other table
+// references it and no running query uses it
 func (f *FileEntry) MarkDeleted() {
 	f.mu.Lock()
 	defer f.mu.Unlock()
+	if f.SharedWith > 0 {
+		f.SharedWith--
+		return
+	}
 	f.deleted = true
 	f.tryCleanup()
 }
@@ -63,12 +78,29 @@ type ColumnDef struct {
 	Type ColumnType `json:"type"` // INT64, VARCHAR
 }
 
+// SortKey is a column by which rows of a clustered table are sorted
+type SortKey struct {
+	Column    string `json:"column"`
+	Ascending bool   `json:"ascending"`
+}
+
 type TableDef struct {
-	Name    string       `json:"name"`
-	Columns []ColumnDef  `json:"columns"`
-	Files   []*FileEntry `json:"files"`
+	Name            string            `json:"name"`
+	Database        string            `json:"database"`
+	Columns         []ColumnDef       `json:"columns"`
+	SortKeys        []SortKey         `json:"sort_keys,omitempty"`        // cluster keys, files are sorted by them
+	Files           []*FileEntry      `json:"files"`                      // files of the current version
+	HistoricalFiles []*FileEntry      `json:"historical_files,omitempty"` // files only in retained older versions
+	Versions        []*TableVersion   `json:"versions"`
+	Indexes         []*SecondaryIndex `json:"indexes,omitempty"`
+	Stats           *TableStats       `json:"stats,omitempty"`
+}
+
+func (t *TableDef) QualifiedName() string {
+	return t.Database + "." + t.Name
 }
 
 type Schema struct {
-	Tables map[string]*TableDef `json:"tables"`
+	Tables    map[string]*TableDef    `json:"tables"`
+	Databases map[string]*DatabaseDef `json:"databases"`
 }
@@ -0,0 +1,238 @@
+package metadata
+
+import (
+	"fmt"
+	"time"
+)
+
+// TableVersion describes the set of files forming the table after a committed change
+type TableVersion struct {
+	Version     uint64    `json:"version"`
+	CommittedAt time.Time `json:"committed_at"`
+	Files       []string  `json:"files"`
+
+	Deletions map[string]*DeletionVector `json:"deletions,omitempty"` // file path -> rows deleted from it
+}
+
+// RetentionPolicy decides which old versions can still be read with time travel.
+// A version is retained if it is one of the KeepVersions newest versions or it was
+// committed within KeepFor. The current version is always retained.
+type RetentionPolicy struct {
+	KeepVersions int
+	KeepFor      time.Duration
+}
+
+var DefaultRetentionPolicy = RetentionPolicy{
+	KeepVersions: 10,
+	KeepFor:      24 * time.Hour,
+}
+
+// AsOf selects a historical version of a table, at most one of the fields is set.
+// Nil AsOf (or with no fields set) selects the current version.
+type AsOf struct {
+	Version   *uint64
+	Timestamp *time.Time
+}
+
+func (t *TableDef) CurrentVersion() *TableVersion {
+	return t.Versions[len(t.Versions)-1]
+}
+
+// SetRetentionPolicy replaces the policy and immediately expires versions which are no longer retained
+func (m *Metastore) SetRetentionPolicy(policy RetentionPolicy) error {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	m.Retention = policy
+	now := time.Now()
+	for _, table := range m.Schema.Tables {
+		m.expireVersions(table, now)
+	}
+	return m.save()
+}
+
+func (m *Metastore) GetTableVersions(tableId string) ([]TableVersion, bool) {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+
+	table, exists := m.getTableByIdUnlocked(tableId)
+	if !exists {
+		return nil, false
+	}
+
+	versions := make([]TableVersion, len(table.Versions))
+	for i, v := range table.Versions {
+		versions[i] = *v
+	}
+	return versions, true
+}
+
+// commitFiles makes files the current file set of the table, recording it as a new version.
+// Rows deleted from files which stay in the table remain deleted. Assumes write lock is held
+func (m *Metastore) commitFiles(table *TableDef, files []*FileEntry) {
+	deletions := make(map[string]*DeletionVector)
+	for _, f := range files {
+		if dv, ok := table.CurrentVersion().Deletions[f.Path]; ok {
+			deletions[f.Path] = dv
+		}
+	}
+	m.commitVersion(table, files, deletions)
+}
+
+// Assumes write lock is held
+func (m *Metastore) commitVersion(table *TableDef, files []*FileEntry, deletions map[string]*DeletionVector) {
+	if len(deletions) == 0 {
+		deletions = nil
+	}
+
+	now := time.Now()
+	table.HistoricalFiles = append(table.HistoricalFiles, table.Files...)
+	table.Files = files
+	table.Versions = append(table.Versions, &TableVersion{
+		Version:     table.CurrentVersion().Version + 1,
+		CommittedAt: now,
+		Files:       FileNames(files),
+		Deletions:   deletions,
+	})
+	m.expireVersions(table, now)
+}
+
+// expireVersions drops versions not retained by the policy, files referenced only by them are
+// removed as soon as no running query uses them. Assumes write lock is held
+func (m *Metastore) expireVersions(table *TableDef, now time.Time) {
+	// versions are ordered by commit time, so the retained ones form a suffix
+	firstRetained := len(table.Versions) - 1
+	for firstRetained > 0 {
+		prev := table.Versions[firstRetained-1]
+		byCount := len(table.Versions)-(firstRetained-1) <= m.Retention.KeepVersions
+		byAge := now.Sub(prev.CommittedAt) <= m.Retention.KeepFor
+		if !byCount && !byAge {
+			break
+		}
+		firstRetained--
+	}
+	table.Versions = table.Versions[firstRetained:]
+
+	current := make(map[string]bool, len(table.Files))
+	for _, f := range table.Files {
+		current[f.Path] = true
+	}
+	referenced := make(map[string]bool)
+	for _, v := range table.Versions {
+		for _, path := range v.Files {
+			referenced[path] = true
+		}
+	}
+
+	table.expireIndexFiles(func(path string) bool { return current[path] || referenced[path] })
+
+	// keep only files of historical versions which are not part of the current one
+	historical := make([]*FileEntry, 0, len(table.HistoricalFiles))
+	for _, f := range table.HistoricalFiles {
+		switch {
+		case current[f.Path]:
+			// added back to the current version, tracked in table.Files
+		case referenced[f.Path]:
+			historical = append(historical, f)
+			delete(referenced, f.Path)
+		default:
+			f.MarkDeleted()
+		}
+	}
+	table.HistoricalFiles = historical
+}
+
+// Assumes lock is held
+func (t *TableDef) resolveVersion(asOf *AsOf) (*TableVersion, error) {
+	if asOf == nil || (asOf.Version == nil && asOf.Timestamp == nil) {
+		return t.CurrentVersion(), nil
+	}
+	if asOf.Version != nil && asOf.Timestamp != nil {
+		return nil, fmt.Errorf("only one of version and timestamp can be given")
+	}
+
+	if asOf.Version != nil {
+		for _, v := range t.Versions {
+			if v.Version == *asOf.Version {
+				return v, nil
+			}
+		}
+		if *asOf.Version > t.CurrentVersion().Version {
+			return nil, fmt.Errorf("version %d of table %s does not exist, current version is %d", *asOf.Version, t.QualifiedName(), t.CurrentVersion().Version)
+		}
+		return nil, fmt.Errorf("version %d of table %s is no longer retained", *asOf.Version, t.QualifiedName())
+	}
+
+	for i := len(t.Versions) - 1; i >= 0; i-- {
+		if !t.Versions[i].CommittedAt.After(*asOf.Timestamp) {
+			return t.Versions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no retained version of table %s at %s", t.QualifiedName(), asOf.Timestamp.Format(time.RFC3339Nano))
+}
+
+// Assumes lock is held
+func (t *TableDef) snapshot(asOf *AsOf) (*MetastoreSnapshot, error) {
+	version, err := t.resolveVersion(asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	filesSnapshot, err := t.filesOfVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range filesSnapshot {
+		f.IncRef()
+	}
+	indexes := t.indexFilesOf(filesSnapshot)
+	for _, files := range indexes {
+		for _, f := range files {
+			f.File.IncRef()
+		}
+	}
+
+	return &MetastoreSnapshot{
+		Version:   version.Version,
+		Files:     filesSnapshot,
+		Columns:   t.Columns,
+		SortKeys:  t.SortKeys,
+		Deletions: version.Deletions,
+		Indexes:   indexes,
+	}, nil
+}
+
+// Assumes lock is held
+func (t *TableDef) filesOfVersion(version *TableVersion) ([]*FileEntry, error) {
+	byPath := make(map[string]*FileEntry, len(t.Files)+len(t.HistoricalFiles))
+	for _, f := range t.HistoricalFiles {
+		byPath[f.Path] = f
+	}
+	for _, f := range t.Files {
+		byPath[f.Path] = f
+	}
+
+	files := make([]*FileEntry, len(version.Files))
+	for i, path := range version.Files {
+		f, ok := byPath[path]
+		if !ok {
+			return nil, fmt.Errorf("file %s of version %d is missing", path, version.Version)
+		}
+		files[i] = f
+	}
+	return files, nil
+}
+
+// Brings tables saved before versions were introduced to the current layout.
+// Assumes write lock is held
+func (m *Metastore) migrateToVersions() {
+	for _, table := range m.Schema.Tables {
+		if len(table.Versions) == 0 {
+			table.Versions = []*TableVersion{{
+				Version:     0,
+				CommittedAt: time.Now(),
+				Files:       FileNames(table.Files),
+			}}
+		}
+	}
+}
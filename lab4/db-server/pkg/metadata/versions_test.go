@@ -0,0 +1,122 @@
+package metadata
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func addDummyFile(t *testing.T, m *Metastore, table string, name string) string {
+	t.Helper()
+	path := filepath.Join(m.DatabaseDir(DefaultDatabase), name)
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := m.AddFile(table, path, nil); err != nil {
+		t.Fatalf("AddFile failed: %v", err)
+	}
+	return path
+}
+
+func TestMetastore_TimeTravel(t *testing.T) {
+	m := NewMetastore(t.TempDir())
+	tableId, err := m.CreateTable("t1", []ColumnDef{{Name: "a", Type: Int64Type}}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	addDummyFile(t, m, "t1", "f1.tomy")
+	between := time.Now()
+	time.Sleep(time.Millisecond)
+	addDummyFile(t, m, "t1", "f2.tomy")
+
+	versions, _ := m.GetTableVersions(tableId)
+	if len(versions) != 3 {
+		t.Fatalf("Expected 3 versions, got %d", len(versions))
+	}
+
+	for version, expectedFiles := range []int{0, 1, 2} {
+		v := uint64(version)
+		snapshot, err := m.GetTableSnapshot("t1", &AsOf{Version: &v})
+		if err != nil {
+			t.Fatalf("GetTableSnapshot of version %d failed: %v", v, err)
+		}
+		if len(snapshot.Files) != expectedFiles {
+			t.Errorf("Expected %d files in version %d, got %d", expectedFiles, v, len(snapshot.Files))
+		}
+		snapshot.Release()
+	}
+
+	snapshot, err := m.GetTableSnapshot("t1", &AsOf{Timestamp: &between})
+	if err != nil {
+		t.Fatalf("GetTableSnapshot by timestamp failed: %v", err)
+	}
+	if snapshot.Version != 1 {
+		t.Errorf("Expected version 1 at timestamp, got %d", snapshot.Version)
+	}
+	snapshot.Release()
+
+	missing := uint64(5)
+	if _, err := m.GetTableSnapshot("t1", &AsOf{Version: &missing}); err == nil {
+		t.Errorf("Expected error for not existing version")
+	}
+
+	// versions survive restart
+	m2 := NewMetastore(filepath.Dir(filepath.Dir(m.FilePath)))
+	if versions, _ := m2.GetTableVersions(tableId); len(versions) != 3 {
+		t.Errorf("Expected 3 versions after reload, got %d", len(versions))
+	}
+}
+
+func TestMetastore_RetentionPolicy(t *testing.T) {
+	m := NewMetastore(t.TempDir())
+	if err := m.SetRetentionPolicy(RetentionPolicy{KeepVersions: 2}); err != nil {
+		t.Fatalf("SetRetentionPolicy failed: %v", err)
+	}
+	tableId, err := m.CreateTable("t1", []ColumnDef{{Name: "a", Type: Int64Type}}, nil)
+	if err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	var paths []string
+	for i := 0; i < 3; i++ {
+		paths = append(paths, addDummyFile(t, m, "t1", fmt.Sprintf("f%d.tomy", i)))
+	}
+
+	versions, _ := m.GetTableVersions(tableId)
+	if len(versions) != 2 || versions[0].Version != 2 {
+		t.Fatalf("Expected versions 2 and 3 to be retained, got %+v", versions)
+	}
+
+	old := uint64(1)
+	if _, err := m.GetTableSnapshot("t1", &AsOf{Version: &old}); err == nil {
+		t.Errorf("Expected error for expired version")
+	}
+
+	// replace the whole file set, old files stay readable through a pinned snapshot
+	snapshot, err := m.GetTableSnapshot("t1", nil)
+	if err != nil {
+		t.Fatalf("GetTableSnapshot failed: %v", err)
+	}
+
+	m.Mu.Lock()
+	table := m.Schema.Tables[tableId]
+	m.commitFiles(table, []*FileEntry{})
+	m.commitFiles(table, []*FileEntry{})
+	m.Mu.Unlock()
+
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected file %s to exist while snapshot is used", path)
+		}
+	}
+
+	snapshot.Release()
+	for _, path := range paths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected file %s to be removed after release", path)
+		}
+	}
+}
@@ -9,16 +9,18 @@ import (
 	"isbd4/openapi"
 	"isbd4/pkg/engine"
 	"isbd4/pkg/engine/types"
+	"isbd4/pkg/sqlparser"
 )
 
 // ExecutionAPIService is a service that implements the logic for the ExecutionAPIServicer
 type ExecutionAPIService struct {
 	QueryManager *engine.QueryManager
+	Schema       openapi.SchemaAPIServicer // executes CREATE TABLE and DROP TABLE statements of SubmitSql
 }
 
 // NewExecutionAPIService creates a default api service
-func NewExecutionAPIService(qm *engine.QueryManager) *ExecutionAPIService {
-	return &ExecutionAPIService{QueryManager: qm}
+func NewExecutionAPIService(qm *engine.QueryManager, schema openapi.SchemaAPIServicer) *ExecutionAPIService {
+	return &ExecutionAPIService{QueryManager: qm, Schema: schema}
 }
 
 // GetQueries - Get list of queries (optional in project 3, but useful). Use those IDs to get details by calling /query endpoint.
@@ -84,6 +86,13 @@ func (s *ExecutionAPIService) SubmitQuery(ctx context.Context, req openapi.Execu
 		}
 		return openapi.Response(http.StatusOK, queryId), nil
 
+	case openapi.SetOperationQuery:
+		queryId, err := s.QueryManager.SubmitSetOperation(q, req.QueryDefinition)
+		if err != nil {
+			return openapi.Response(http.StatusBadRequest, types.ToOpenApiError(err)), nil
+		}
+		return openapi.Response(http.StatusOK, queryId), nil
+
 	case openapi.CopyQuery:
 		if q.DestinationTableName == "" || q.SourceFilepath == "" {
 			return openapi.Response(http.StatusBadRequest, openapi.MultipleProblemsError{Problems: []openapi.MultipleProblemsErrorProblemsInner{{Error: "Missing destination table or source filepath for COPY"}}}), nil
@@ -95,6 +104,53 @@ func (s *ExecutionAPIService) SubmitQuery(ctx context.Context, req openapi.Execu
 		}
 		return openapi.Response(http.StatusOK, queryId), nil
 
+	case openapi.InsertQuery:
+		queryId, err := s.QueryManager.SubmitInsert(q, req.QueryDefinition)
+		if err != nil {
+			return openapi.Response(http.StatusBadRequest, types.ToOpenApiError(err)), nil
+		}
+		return openapi.Response(http.StatusOK, queryId), nil
+
+	case openapi.InsertSelectQuery:
+		queryId, err := s.QueryManager.SubmitInsertSelect(q, req.QueryDefinition)
+		if err != nil {
+			return openapi.Response(http.StatusBadRequest, types.ToOpenApiError(err)), nil
+		}
+		return openapi.Response(http.StatusOK, queryId), nil
+
+	case openapi.CreateTableAsSelectQuery:
+		if len(q.SelectQuery.ColumnClauses) == 0 {
+			return openapi.Response(http.StatusBadRequest, openapi.MultipleProblemsError{Problems: []openapi.MultipleProblemsErrorProblemsInner{{Error: "No columns specified for CREATE TABLE AS SELECT"}}}), nil
+		}
+
+		queryId, err := s.QueryManager.SubmitCreateTableAsSelect(q, req.QueryDefinition)
+		if err != nil {
+			return openapi.Response(http.StatusBadRequest, types.ToOpenApiError(err)), nil
+		}
+		return openapi.Response(http.StatusOK, queryId), nil
+
+	case openapi.DeleteQuery:
+		if q.TableName == "" {
+			return openapi.Response(http.StatusBadRequest, openapi.MultipleProblemsError{Problems: []openapi.MultipleProblemsErrorProblemsInner{{Error: "Missing table name for DELETE"}}}), nil
+		}
+
+		queryId, err := s.QueryManager.SubmitDelete(q, req.QueryDefinition)
+		if err != nil {
+			return openapi.Response(http.StatusBadRequest, types.ToOpenApiError(err)), nil
+		}
+		return openapi.Response(http.StatusOK, queryId), nil
+
+	case openapi.UpdateQuery:
+		if q.TableName == "" || len(q.SetClauses) == 0 {
+			return openapi.Response(http.StatusBadRequest, openapi.MultipleProblemsError{Problems: []openapi.MultipleProblemsErrorProblemsInner{{Error: "Missing table name or set clauses for UPDATE"}}}), nil
+		}
+
+		queryId, err := s.QueryManager.SubmitUpdate(q, req.QueryDefinition)
+		if err != nil {
+			return openapi.Response(http.StatusBadRequest, types.ToOpenApiError(err)), nil
+		}
+		return openapi.Response(http.StatusOK, queryId), nil
+
 	default:
 		return openapi.Response(http.StatusBadRequest, openapi.MultipleProblemsError{Problems: []openapi.MultipleProblemsErrorProblemsInner{{Error: "Unknown query type"}}}), nil
 	}
@@ -135,3 +191,24 @@ func (s *ExecutionAPIService) GetQueryError(ctx context.Context, queryId string)
 
 	return openapi.Response(http.StatusOK, types.ToOpenApiError(info.Error)), nil
 }
+
+// SubmitSql - Execute a SQL statement, compiled to the query definition or table schema of the other endpoints
+func (s *ExecutionAPIService) SubmitSql(ctx context.Context, req openapi.ExecuteSqlRequest) (openapi.ImplResponse, error) {
+	stmt, err := sqlparser.Parse(req.Sql)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, types.ToOpenApiError(err)), nil
+	}
+
+	switch {
+	case stmt.Query != nil:
+		return s.SubmitQuery(ctx, openapi.ExecuteQueryRequest{QueryDefinition: *stmt.Query})
+	case stmt.CreateTable != nil:
+		return s.Schema.CreateTable(ctx, *stmt.CreateTable)
+	default:
+		tableId, exists := s.QueryManager.Planner.Metastore.GetTableId(stmt.DropTable)
+		if !exists {
+			return openapi.Response(http.StatusNotFound, openapi.Error{Message: fmt.Sprintf("table %s does not exist", stmt.DropTable)}), nil
+		}
+		return s.Schema.DeleteTable(ctx, tableId)
+	}
+}
@@ -3,8 +3,12 @@ package service
 import (
 	"context"
 	"net/http"
+	"strings"
 
 	"isbd4/openapi"
+	"isbd4/pkg/backup"
+	"isbd4/pkg/engine/compaction"
+	"isbd4/pkg/engine/index"
 	"isbd4/pkg/metadata"
 )
 
@@ -12,25 +16,30 @@ import (
 // This service should implement the business logic for every endpoint for the SchemaAPI API.
 // Include any external packages or services that will be required by this service.
 type SchemaAPIService struct {
-	metastore *metadata.Metastore
+	metastore   *metadata.Metastore
+	compactor   *compaction.Compactor
+	dbmsBaseDir string
 }
 
 // NewSchemaAPIService creates a default api service
-func NewSchemaAPIService(m *metadata.Metastore) *SchemaAPIService {
+func NewSchemaAPIService(m *metadata.Metastore, c *compaction.Compactor, dbmsBaseDir string) *SchemaAPIService {
 	return &SchemaAPIService{
-		metastore: m,
+		metastore:   m,
+		compactor:   c,
+		dbmsBaseDir: dbmsBaseDir,
 	}
 }
 
 // GetTables - Get list of tables with their accompanying IDs. Use those IDs to get details by calling /table endpoint.
 func (s *SchemaAPIService) GetTables(ctx context.Context) (openapi.ImplResponse, error) {
-	names, ids := s.metastore.GetTables()
+	names, ids, databases := s.metastore.GetTables()
 
 	tables := []openapi.ShallowTable{}
 	for i := range names {
 		tables = append(tables, openapi.ShallowTable{
-			TableId: ids[i],
-			Name:    names[i],
+			TableId:  ids[i],
+			Name:     names[i],
+			Database: databases[i],
 		})
 	}
 
@@ -49,12 +58,200 @@ func (s *SchemaAPIService) GetTableById(ctx context.Context, tableId string) (op
 		cols = append(cols, openapi.Column{Name: c.Name, Type: openapi.LogicalColumnType(c.Type)})
 	}
 
+	sortKeys := []openapi.SortKey{}
+	for _, k := range tableDef.SortKeys {
+		sortKeys = append(sortKeys, openapi.SortKey{ColumnName: k.Column, Ascending: k.Ascending})
+	}
+
+	stats, _ := s.metastore.GetTableStatistics(tableId)
+
 	return openapi.Response(http.StatusOK, openapi.TableSchema{
-		Name:    tableDef.Name,
-		Columns: cols,
+		Name:       tableDef.Name,
+		Database:   tableDef.Database,
+		Columns:    cols,
+		SortKeys:   sortKeys,
+		Statistics: tableStatisticsToOpenAPI(stats),
 	}), nil
 }
 
+// AnalyzeTable - Recompute statistics of selected table
+func (s *SchemaAPIService) AnalyzeTable(ctx context.Context, tableId string) (openapi.ImplResponse, error) {
+	if _, exists := s.metastore.GetTableById(tableId); !exists {
+		return openapi.Response(http.StatusNotFound, openapi.Error{Message: "Table not found"}), nil
+	}
+
+	stats, err := s.metastore.AnalyzeTable(tableId)
+	if err != nil {
+		return openapi.Response(http.StatusInternalServerError, openapi.Error{Message: err.Error()}), nil
+	}
+
+	return openapi.Response(http.StatusOK, tableStatisticsToOpenAPI(stats)), nil
+}
+
+// GetTableVersions - Get retained versions of selected table
+func (s *SchemaAPIService) GetTableVersions(ctx context.Context, tableId string) (openapi.ImplResponse, error) {
+	versions, exists := s.metastore.GetTableVersions(tableId)
+	if !exists {
+		return openapi.Response(http.StatusNotFound, openapi.Error{Message: "Table not found"}), nil
+	}
+
+	res := make([]openapi.TableVersion, len(versions))
+	for i, v := range versions {
+		res[i] = openapi.TableVersion{
+			Version:     int64(v.Version),
+			CommittedAt: v.CommittedAt,
+			FileCount:   int32(len(v.Files)),
+		}
+	}
+
+	return openapi.Response(http.StatusOK, res), nil
+}
+
+// GetCompactionStatus - Get status of compaction of selected table
+func (s *SchemaAPIService) GetCompactionStatus(ctx context.Context, tableId string) (openapi.ImplResponse, error) {
+	status, exists := s.compactionStatus(tableId)
+	if !exists {
+		return openapi.Response(http.StatusNotFound, openapi.Error{Message: "Table not found"}), nil
+	}
+
+	return openapi.Response(http.StatusOK, status), nil
+}
+
+// CompactTable - Start compaction of small files of selected table
+func (s *SchemaAPIService) CompactTable(ctx context.Context, tableId string) (openapi.ImplResponse, error) {
+	if _, exists := s.metastore.GetTableById(tableId); !exists {
+		return openapi.Response(http.StatusNotFound, openapi.Error{Message: "Table not found"}), nil
+	}
+
+	if err := s.compactor.Trigger(tableId); err != nil {
+		return openapi.Response(http.StatusBadRequest, openapi.Error{Message: err.Error()}), nil
+	}
+
+	status, _ := s.compactionStatus(tableId)
+	return openapi.Response(http.StatusOK, status), nil
+}
+
+// GetIndexes - Get secondary indexes of selected table
+func (s *SchemaAPIService) GetIndexes(ctx context.Context, tableId string) (openapi.ImplResponse, error) {
+	infos, exists := s.metastore.GetIndexes(tableId)
+	if !exists {
+		return openapi.Response(http.StatusNotFound, openapi.Error{Message: "Table not found"}), nil
+	}
+
+	indexes := make([]openapi.SecondaryIndex, len(infos))
+	for i, info := range infos {
+		indexes[i] = secondaryIndexToOpenAPI(info)
+	}
+	return openapi.Response(http.StatusOK, indexes), nil
+}
+
+// CreateIndex - Create secondary index of a column of selected table or index files not covered by it yet
+func (s *SchemaAPIService) CreateIndex(ctx context.Context, tableId string, columnName string) (openapi.ImplResponse, error) {
+	if _, exists := s.metastore.GetTableById(tableId); !exists {
+		return openapi.Response(http.StatusNotFound, openapi.Error{Message: "Table not found"}), nil
+	}
+
+	if _, err := index.Build(s.metastore, tableId, columnName); err != nil {
+		return openapi.Response(http.StatusBadRequest, openapi.Error{Message: err.Error()}), nil
+	}
+
+	infos, _ := s.metastore.GetIndexes(tableId)
+	for _, info := range infos {
+		if info.Column == columnName {
+			return openapi.Response(http.StatusOK, secondaryIndexToOpenAPI(info)), nil
+		}
+	}
+	return openapi.Response(http.StatusBadRequest, openapi.Error{Message: "Index was dropped concurrently"}), nil
+}
+
+// DropIndex - Drop secondary index of a column of selected table
+func (s *SchemaAPIService) DropIndex(ctx context.Context, tableId string, columnName string) (openapi.ImplResponse, error) {
+	if _, exists := s.metastore.GetTableById(tableId); !exists {
+		return openapi.Response(http.StatusNotFound, openapi.Error{Message: "Table not found"}), nil
+	}
+
+	if err := s.metastore.DropIndex(tableId, columnName); err != nil {
+		return openapi.Response(http.StatusNotFound, openapi.Error{Message: err.Error()}), nil
+	}
+	return openapi.Response(http.StatusOK, nil), nil
+}
+
+// CloneTable - Create a table sharing files of the current version of selected table
+func (s *SchemaAPIService) CloneTable(ctx context.Context, tableId string, request openapi.CloneTableRequest) (openapi.ImplResponse, error) {
+	if _, exists := s.metastore.GetTableById(tableId); !exists {
+		return openapi.Response(http.StatusNotFound, openapi.Error{Message: "Table not found"}), nil
+	}
+
+	cloneId, err := s.metastore.CloneTable(tableId, request.Name)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, openapi.MultipleProblemsError{
+			Problems: []openapi.MultipleProblemsErrorProblemsInner{
+				{Error: err.Error()},
+			},
+		}), nil
+	}
+
+	return openapi.Response(http.StatusOK, cloneId), nil
+}
+
+func secondaryIndexToOpenAPI(info metadata.IndexInfo) openapi.SecondaryIndex {
+	return openapi.SecondaryIndex{
+		ColumnName:       info.Column,
+		IndexFileCount:   int32(info.IndexFiles),
+		IndexedFileCount: int32(info.IndexedFiles),
+		FileCount:        int32(info.TotalFiles),
+	}
+}
+
+func (s *SchemaAPIService) compactionStatus(tableId string) (openapi.CompactionStatus, bool) {
+	small, total, exists := s.compactor.SmallFiles(tableId)
+	if !exists {
+		return openapi.CompactionStatus{}, false
+	}
+
+	status := s.compactor.Status(tableId)
+	return openapi.CompactionStatus{
+		State:          string(status.State),
+		FileCount:      int32(total),
+		SmallFileCount: int32(small),
+		LastStartedAt:  status.LastStartedAt,
+		LastFinishedAt: status.LastFinishedAt,
+		LastError:      status.LastError,
+		FilesCompacted: int32(status.FilesCompacted),
+		FilesWritten:   int32(status.FilesWritten),
+	}, true
+}
+
+func tableStatisticsToOpenAPI(stats *metadata.TableStatistics) *openapi.TableStatistics {
+	if stats == nil {
+		return nil
+	}
+
+	cols := make([]openapi.ColumnStatistics, len(stats.Columns))
+	for i, c := range stats.Columns {
+		cols[i] = openapi.ColumnStatistics{
+			Name:              c.Name,
+			CompressedBytes:   c.CompressedBytes,
+			UncompressedBytes: c.UncompressedBytes,
+			DistinctEstimate:  int64(c.DistinctEstimate),
+		}
+		if c.Min != nil {
+			cols[i].Min = &openapi.LiteralValue{Data: c.Min.Value()}
+		}
+		if c.Max != nil {
+			cols[i].Max = &openapi.LiteralValue{Data: c.Max.Value()}
+		}
+	}
+
+	return &openapi.TableStatistics{
+		RowCount:   int64(stats.RowCount),
+		FileCount:  int32(stats.FileCount),
+		SizeBytes:  stats.SizeBytes,
+		Columns:    cols,
+		AnalyzedAt: stats.AnalyzedAt,
+	}
+}
+
 // DeleteTable - Delete selected table from database
 func (s *SchemaAPIService) DeleteTable(ctx context.Context, tableId string) (openapi.ImplResponse, error) {
 	err := s.metastore.DeleteTable(tableId)
@@ -100,7 +297,24 @@ func (s *SchemaAPIService) CreateTable(ctx context.Context, tableSchema openapi.
 		}), nil
 	}
 
-	tableId, err := s.metastore.CreateTable(tableSchema.Name, cols)
+	name := tableSchema.Name
+	if tableSchema.Database != "" {
+		if strings.Contains(name, ".") {
+			return openapi.Response(http.StatusBadRequest, openapi.MultipleProblemsError{
+				Problems: []openapi.MultipleProblemsErrorProblemsInner{
+					{Error: "Database given both in the table name and in the database field"},
+				},
+			}), nil
+		}
+		name = tableSchema.Database + "." + name
+	}
+
+	sortKeys := []metadata.SortKey{}
+	for _, k := range tableSchema.SortKeys {
+		sortKeys = append(sortKeys, metadata.SortKey{Column: k.ColumnName, Ascending: k.Ascending})
+	}
+
+	tableId, err := s.metastore.CreateTable(name, cols, sortKeys)
 	if err != nil {
 		return openapi.Response(http.StatusBadRequest, openapi.MultipleProblemsError{
 			Problems: []openapi.MultipleProblemsErrorProblemsInner{
@@ -111,3 +325,56 @@ func (s *SchemaAPIService) CreateTable(ctx context.Context, tableSchema openapi.
 
 	return openapi.Response(http.StatusOK, tableId), nil
 }
+
+// GetDatabases - Get list of databases
+func (s *SchemaAPIService) GetDatabases(ctx context.Context) (openapi.ImplResponse, error) {
+	databases := []openapi.Database{}
+	for _, db := range s.metastore.GetDatabases() {
+		databases = append(databases, openapi.Database{
+			Name:       db.Name,
+			TableCount: int32(db.TableCount),
+		})
+	}
+
+	return openapi.Response(http.StatusOK, databases), nil
+}
+
+// CreateDatabase - Create new database
+func (s *SchemaAPIService) CreateDatabase(ctx context.Context, database openapi.Database) (openapi.ImplResponse, error) {
+	if err := s.metastore.CreateDatabase(database.Name); err != nil {
+		return openapi.Response(http.StatusBadRequest, openapi.MultipleProblemsError{
+			Problems: []openapi.MultipleProblemsErrorProblemsInner{
+				{Error: err.Error()},
+			},
+		}), nil
+	}
+
+	return openapi.Response(http.StatusOK, nil), nil
+}
+
+// DropDatabase - Drop selected database
+func (s *SchemaAPIService) DropDatabase(ctx context.Context, databaseName string, cascade bool) (openapi.ImplResponse, error) {
+	if !s.metastore.DatabaseExists(databaseName) {
+		return openapi.Response(http.StatusNotFound, openapi.Error{Message: "Database not found"}), nil
+	}
+
+	if err := s.metastore.DropDatabase(databaseName, cascade); err != nil {
+		return openapi.Response(http.StatusBadRequest, openapi.Error{Message: err.Error()}), nil
+	}
+
+	return openapi.Response(http.StatusOK, nil), nil
+}
+
+// Backup - Write a consistent backup of all databases to a directory
+func (s *SchemaAPIService) Backup(ctx context.Context, request openapi.BackupRequest) (openapi.ImplResponse, error) {
+	summary, err := backup.Backup(s.metastore, s.dbmsBaseDir, request.TargetDirectory, request.PreviousBackup)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, openapi.Error{Message: err.Error()}), nil
+	}
+
+	return openapi.Response(http.StatusOK, openapi.BackupSummary{
+		FileCount:       int32(summary.Files),
+		CopiedFileCount: int32(summary.CopiedFiles),
+		CopiedBytes:     summary.CopiedBytes,
+	}), nil
+}
@@ -0,0 +1,147 @@
+package sqlparser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"isbd4/pkg/engine/types"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenQuotedIdent // "name", never a keyword
+	tokenInt
+	tokenString
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string // identifier, digits, unquoted string or symbol
+	line int
+	col  int
+}
+
+func (t token) position() string {
+	return fmt.Sprintf("line %d, column %d", t.line, t.col)
+}
+
+// describe returns the token as quoted in syntax errors
+func (t token) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of input"
+	case tokenString:
+		return fmt.Sprintf("string '%s'", t.text)
+	case tokenQuotedIdent:
+		return fmt.Sprintf("identifier \"%s\"", t.text)
+	default:
+		return fmt.Sprintf("'%s'", t.text)
+	}
+}
+
+// symbols are matched longest first
+var symbols = []string{"<=", ">=", "<>", "!=", "(", ")", ",", ".", ";", "*", "+", "-", "/", "=", "<", ">"}
+
+// lex splits the SQL text into tokens ending with an EOF token. Keywords are case-insensitive identifiers,
+// -- starts a comment till the end of the line.
+func lex(sql string) ([]token, error) {
+	runes := []rune(sql)
+	tokens := []token{}
+	line, col := 1, 1
+	advance := func(n int) {
+		for _, r := range runes[:n] {
+			if r == '\n' {
+				line++
+				col = 1
+			} else {
+				col++
+			}
+		}
+		runes = runes[n:]
+	}
+
+	for len(runes) > 0 {
+		r := runes[0]
+		switch {
+		case unicode.IsSpace(r):
+			advance(1)
+			continue
+		case r == '-' && len(runes) > 1 && runes[1] == '-':
+			n := 0
+			for n < len(runes) && runes[n] != '\n' {
+				n++
+			}
+			advance(n)
+			continue
+		}
+
+		tok := token{line: line, col: col}
+		n := 0
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+			for n < len(runes) && (runes[n] == '_' || unicode.IsLetter(runes[n]) || unicode.IsDigit(runes[n])) {
+				n++
+			}
+			tok.kind, tok.text = tokenIdent, string(runes[:n])
+		case unicode.IsDigit(r):
+			for n < len(runes) && unicode.IsDigit(runes[n]) {
+				n++
+			}
+			if n+1 < len(runes) && runes[n] == '.' && unicode.IsDigit(runes[n+1]) {
+				return nil, types.NewVErr("only integer numbers are supported", tok.position())
+			}
+			tok.kind, tok.text = tokenInt, string(runes[:n])
+		case r == '\'' || r == '"':
+			text, length, ok := quoted(runes)
+			if !ok {
+				if r == '\'' {
+					return nil, types.NewVErr("unterminated string", tok.position())
+				}
+				return nil, types.NewVErr("unterminated quoted identifier", tok.position())
+			}
+			tok.kind, tok.text, n = tokenString, text, length
+			if r == '"' {
+				tok.kind = tokenQuotedIdent
+			}
+		default:
+			for _, s := range symbols {
+				if strings.HasPrefix(string(runes[:min(len(runes), len(s))]), s) {
+					tok.kind, tok.text, n = tokenSymbol, s, len(s)
+					break
+				}
+			}
+			if n == 0 {
+				return nil, types.NewVErr(fmt.Sprintf("unexpected character '%c'", r), tok.position())
+			}
+		}
+		tokens = append(tokens, tok)
+		advance(n)
+	}
+
+	return append(tokens, token{kind: tokenEOF, line: line, col: col}), nil
+}
+
+// quoted returns the text between the quote starting the runes and the matching one, in which a doubled quote
+// stands for the quote, and the number of runes including the quotes
+func quoted(runes []rune) (string, int, bool) {
+	quote := runes[0]
+	var sb strings.Builder
+	for i := 1; i < len(runes); i++ {
+		if runes[i] != quote {
+			sb.WriteRune(runes[i])
+			continue
+		}
+		if i+1 < len(runes) && runes[i+1] == quote {
+			sb.WriteRune(quote)
+			i++
+			continue
+		}
+		return sb.String(), i + 1, true
+	}
+	return "", 0, false
+}
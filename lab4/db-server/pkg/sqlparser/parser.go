@@ -0,0 +1,777 @@
+package sqlparser
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/types"
+)
+
+// Statement is a parsed SQL statement, exactly one of its fields is set
+type Statement struct {
+	Query       *openapi.QueryQueryDefinition // SELECT or COPY, executed like a query definition of POST /query
+	CreateTable *openapi.TableSchema
+	DropTable   string // name of the dropped table
+}
+
+// reserved keywords can be used as column names, table names or aliases only when quoted
+var reserved = map[string]bool{
+	"SELECT": true, "DISTINCT": true, "FROM": true, "WHERE": true, "GROUP": true, "BY": true, "HAVING": true,
+	"ORDER": true, "ASC": true, "DESC": true, "LIMIT": true, "OFFSET": true, "AS": true, "JOIN": true, "INNER": true,
+	"LEFT": true, "RIGHT": true, "FULL": true, "OUTER": true, "ON": true, "AND": true, "OR": true, "NOT": true,
+	"IN": true, "EXISTS": true, "TRUE": true, "FALSE": true,
+}
+
+var aggregateFunctions = map[string]bool{"COUNT": true, "SUM": true, "MIN": true, "MAX": true, "AVG": true}
+
+var scalarFunctions = map[string]bool{"STRLEN": true, "CONCAT": true, "REPLACE": true, "UPPER": true, "LOWER": true}
+
+var comparisons = map[string]string{
+	"=":  "EQUAL",
+	"<>": "NOT_EQUAL",
+	"!=": "NOT_EQUAL",
+	"<":  "LESS_THAN",
+	"<=": "LESS_EQUAL",
+	">":  "GREATER_THAN",
+	">=": "GREATER_EQUAL",
+}
+
+// Parse parses a single SQL statement optionally ending with a semicolon: SELECT, COPY, CREATE TABLE or DROP TABLE.
+// Syntax errors are validation errors in the context of their position, e.g. "line 2, column 7".
+func Parse(sql string) (*Statement, error) {
+	tokens, err := lex(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	stmt, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	p.acceptSymbol(";")
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, errorAt(tok, fmt.Sprintf("unexpected %s after the end of the statement", tok.describe()))
+	}
+	return stmt, nil
+}
+
+// parser is a recursive descent parser, each parse method consumes the tokens of one grammar rule
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func errorAt(tok token, msg string) error {
+	return types.NewVErr(msg, tok.position())
+}
+
+func (p *parser) peek() token {
+	return p.peekAt(0)
+}
+
+func (p *parser) peekAt(offset int) token {
+	return p.tokens[min(p.pos+offset, len(p.tokens)-1)]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) isKeywordAt(offset int, keyword string) bool {
+	tok := p.peekAt(offset)
+	return tok.kind == tokenIdent && strings.EqualFold(tok.text, keyword)
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	return p.isKeywordAt(0, keyword)
+}
+
+func (p *parser) acceptKeyword(keyword string) bool {
+	if p.isKeyword(keyword) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	if !p.acceptKeyword(keyword) {
+		return p.unexpected(keyword)
+	}
+	return nil
+}
+
+func (p *parser) isSymbolAt(offset int, symbol string) bool {
+	tok := p.peekAt(offset)
+	return tok.kind == tokenSymbol && tok.text == symbol
+}
+
+func (p *parser) acceptSymbol(symbol string) bool {
+	if p.isSymbolAt(0, symbol) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectSymbol(symbol string) error {
+	if !p.acceptSymbol(symbol) {
+		return p.unexpected(fmt.Sprintf("'%s'", symbol))
+	}
+	return nil
+}
+
+// unexpected reports the next token in place of the expected one
+func (p *parser) unexpected(expected string) error {
+	tok := p.peek()
+	return errorAt(tok, fmt.Sprintf("expected %s, got %s", expected, tok.describe()))
+}
+
+// isName tells if the next token is an identifier which is not a reserved keyword
+func (p *parser) isName() bool {
+	tok := p.peek()
+	return tok.kind == tokenQuotedIdent || tok.kind == tokenIdent && !reserved[strings.ToUpper(tok.text)]
+}
+
+func (p *parser) parseName(what string) (string, error) {
+	if !p.isName() {
+		return "", p.unexpected(what)
+	}
+	return p.next().text, nil
+}
+
+// parseTableName parses a table name, optionally qualified by the database
+func (p *parser) parseTableName() (string, error) {
+	name, err := p.parseName("a table name")
+	if err != nil {
+		return "", err
+	}
+	if p.acceptSymbol(".") {
+		table, err := p.parseName("a table name")
+		if err != nil {
+			return "", err
+		}
+		name += "." + table
+	}
+	return name, nil
+}
+
+func (p *parser) parseInteger() (int64, error) {
+	tok := p.peek()
+	if tok.kind != tokenInt {
+		return 0, p.unexpected("an integer")
+	}
+	p.next()
+	return parseInt(tok, tok.text)
+}
+
+func parseInt(tok token, text string) (int64, error) {
+	value, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return 0, errorAt(tok, fmt.Sprintf("integer %s is out of range", text))
+	}
+	return value, nil
+}
+
+func (p *parser) parseStatement() (*Statement, error) {
+	switch {
+	case p.isKeyword("SELECT"):
+		query, err := p.parseSelect()
+		if err != nil {
+			return nil, err
+		}
+		return &Statement{Query: &openapi.QueryQueryDefinition{Definition: query}}, nil
+	case p.isKeyword("COPY"):
+		query, err := p.parseCopy()
+		if err != nil {
+			return nil, err
+		}
+		return &Statement{Query: &openapi.QueryQueryDefinition{Definition: query}}, nil
+	case p.isKeyword("CREATE"):
+		schema, err := p.parseCreateTable()
+		if err != nil {
+			return nil, err
+		}
+		return &Statement{CreateTable: &schema}, nil
+	case p.isKeyword("DROP"):
+		p.next()
+		if err := p.expectKeyword("TABLE"); err != nil {
+			return nil, err
+		}
+		name, err := p.parseTableName()
+		if err != nil {
+			return nil, err
+		}
+		return &Statement{DropTable: name}, nil
+	default:
+		return nil, p.unexpected("SELECT, COPY, CREATE TABLE or DROP TABLE")
+	}
+}
+
+// parseSelect parses SELECT [DISTINCT] columns [FROM ...] [WHERE ...] [GROUP BY ...] [HAVING ...] [ORDER BY ...]
+// [LIMIT n] [OFFSET n]
+func (p *parser) parseSelect() (openapi.SelectQuery, error) {
+	query := openapi.SelectQuery{}
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return query, err
+	}
+	query.Distinct = p.acceptKeyword("DISTINCT")
+	if p.isSymbolAt(0, "*") {
+		return query, errorAt(p.peek(), "SELECT * is not supported, list the selected columns")
+	}
+	columns, err := p.parseExprList()
+	if err != nil {
+		return query, err
+	}
+	if p.isKeyword("AS") {
+		return query, errorAt(p.peek(), "aliases of selected columns are not supported")
+	}
+	query.ColumnClauses = columns
+
+	if p.acceptKeyword("FROM") {
+		from, err := p.parseFrom()
+		if err != nil {
+			return query, err
+		}
+		query.FromClause = &from
+	}
+	if p.acceptKeyword("WHERE") {
+		if query.WhereClause, err = p.parseExpr(); err != nil {
+			return query, err
+		}
+	}
+	if p.acceptKeyword("GROUP") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return query, err
+		}
+		if query.GroupByClause, err = p.parseExprList(); err != nil {
+			return query, err
+		}
+	}
+	if p.acceptKeyword("HAVING") {
+		if query.HavingClause, err = p.parseExpr(); err != nil {
+			return query, err
+		}
+	}
+	if p.acceptKeyword("ORDER") {
+		if query.OrderByClause, err = p.parseOrderBy(query.ColumnClauses); err != nil {
+			return query, err
+		}
+	}
+	if p.acceptKeyword("LIMIT") {
+		tok := p.peek()
+		limit, err := p.parseInteger()
+		if err != nil {
+			return query, err
+		}
+		if limit > math.MaxInt32 {
+			return query, errorAt(tok, fmt.Sprintf("integer %d is out of range", limit))
+		}
+		limit32 := int32(limit)
+		query.LimitClause = &openapi.LimitExpression{Limit: &limit32}
+	}
+	if p.acceptKeyword("OFFSET") {
+		offset, err := p.parseInteger()
+		if err != nil {
+			return query, err
+		}
+		if query.LimitClause == nil {
+			query.LimitClause = &openapi.LimitExpression{}
+		}
+		query.LimitClause.Offset = offset
+	}
+	return query, nil
+}
+
+// parseFrom parses a table or a parenthesized select with an alias, followed by joined tables
+func (p *parser) parseFrom() (openapi.FromClause, error) {
+	from := openapi.FromClause{}
+	var err error
+	if p.acceptSymbol("(") {
+		subquery, err := p.parseSelect()
+		if err != nil {
+			return from, err
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return from, err
+		}
+		from.Subquery = &subquery
+	} else if from.TableName, err = p.parseTableName(); err != nil {
+		return from, err
+	}
+	if from.Alias, err = p.parseAlias(); err != nil {
+		return from, err
+	}
+	if from.Subquery != nil && from.Alias == "" {
+		return from, p.unexpected("an alias of the subquery")
+	}
+
+	for {
+		joinType, ok, err := p.parseJoinType()
+		if err != nil {
+			return from, err
+		}
+		if !ok {
+			return from, nil
+		}
+		join := openapi.JoinClause{JoinType: joinType}
+		if join.TableName, err = p.parseTableName(); err != nil {
+			return from, err
+		}
+		if join.Alias, err = p.parseAlias(); err != nil {
+			return from, err
+		}
+		if p.acceptKeyword("ON") {
+			if join.Condition, err = p.parseExpr(); err != nil {
+				return from, err
+			}
+		}
+		from.Joins = append(from.Joins, join)
+	}
+}
+
+// parseAlias parses an optional [AS] alias, returning an empty alias if there is none
+func (p *parser) parseAlias() (string, error) {
+	if p.acceptKeyword("AS") {
+		return p.parseName("an alias")
+	}
+	if p.isName() {
+		return p.next().text, nil
+	}
+	return "", nil
+}
+
+// parseJoinType parses [INNER | LEFT [OUTER] | RIGHT [OUTER] | FULL [OUTER]] JOIN, ok is false if there is no join
+func (p *parser) parseJoinType() (string, bool, error) {
+	if p.acceptKeyword("JOIN") {
+		return "INNER", true, nil
+	}
+	for _, joinType := range []string{"INNER", "LEFT", "RIGHT", "FULL"} {
+		if p.acceptKeyword(joinType) {
+			if joinType != "INNER" {
+				p.acceptKeyword("OUTER")
+			}
+			return joinType, true, p.expectKeyword("JOIN")
+		}
+	}
+	return "", false, nil
+}
+
+// parseOrderBy parses BY followed by expressions with optional ASC or DESC, each of them has to be a selected column
+// or its position starting at 1
+func (p *parser) parseOrderBy(columns []openapi.ColumnExpression) ([]openapi.OrderByExpression, error) {
+	if err := p.expectKeyword("BY"); err != nil {
+		return nil, err
+	}
+	orderBy := []openapi.OrderByExpression{}
+	for {
+		tok := p.peek()
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		index, err := orderByIndex(columns, e, tok)
+		if err != nil {
+			return nil, err
+		}
+		ascending := !p.acceptKeyword("DESC")
+		if ascending {
+			p.acceptKeyword("ASC")
+		}
+		orderBy = append(orderBy, openapi.OrderByExpression{ColumnIndex: index, Ascending: ascending})
+		if !p.acceptSymbol(",") {
+			return orderBy, nil
+		}
+	}
+}
+
+// orderByIndex returns the index of the selected column given by its position or equal to the expression,
+// a column reference matches a selected one of the same column if at most one of them is qualified by the table
+func orderByIndex(columns []openapi.ColumnExpression, e openapi.ColumnExpression, tok token) (int32, error) {
+	if literal, ok := e.Expression.(openapi.Literal); ok {
+		if position, ok := literal.Value.Data.(int64); ok {
+			if position < 1 || position > int64(len(columns)) {
+				return 0, errorAt(tok, fmt.Sprintf("ORDER BY position %d is not in the select list", position))
+			}
+			return int32(position - 1), nil
+		}
+	}
+
+	for i, column := range columns {
+		if reflect.DeepEqual(column, e) {
+			return int32(i), nil
+		}
+		ref, ok := e.Expression.(openapi.ColumnReferenceExpression)
+		selected, selectedOk := column.Expression.(openapi.ColumnReferenceExpression)
+		if ok && selectedOk && ref.ColumnName == selected.ColumnName && (ref.TableName == "" || selected.TableName == "") {
+			return int32(i), nil
+		}
+	}
+	return 0, errorAt(tok, "ORDER BY expression has to be a selected column or its position")
+}
+
+// parseCopy parses COPY table [(columns)] FROM 'path' [WITH HEADER]
+func (p *parser) parseCopy() (openapi.CopyQuery, error) {
+	query := openapi.CopyQuery{}
+	if err := p.expectKeyword("COPY"); err != nil {
+		return query, err
+	}
+	var err error
+	if query.DestinationTableName, err = p.parseTableName(); err != nil {
+		return query, err
+	}
+	if p.acceptSymbol("(") {
+		for {
+			column, err := p.parseName("a column name")
+			if err != nil {
+				return query, err
+			}
+			query.DestinationColumns = append(query.DestinationColumns, column)
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return query, err
+		}
+	}
+	if err := p.expectKeyword("FROM"); err != nil {
+		return query, err
+	}
+	if p.peek().kind != tokenString {
+		return query, p.unexpected("a file path string")
+	}
+	query.SourceFilepath = p.next().text
+	if p.acceptKeyword("WITH") {
+		if err := p.expectKeyword("HEADER"); err != nil {
+			return query, err
+		}
+		query.DoesCsvContainHeader = true
+	}
+	return query, nil
+}
+
+// parseCreateTable parses CREATE TABLE table (column type, ...) [ORDER BY column [ASC | DESC], ...],
+// the order defines sort keys of a clustered table
+func (p *parser) parseCreateTable() (openapi.TableSchema, error) {
+	schema := openapi.TableSchema{}
+	if err := p.expectKeyword("CREATE"); err != nil {
+		return schema, err
+	}
+	if err := p.expectKeyword("TABLE"); err != nil {
+		return schema, err
+	}
+	var err error
+	if schema.Name, err = p.parseTableName(); err != nil {
+		return schema, err
+	}
+	if err := p.expectSymbol("("); err != nil {
+		return schema, err
+	}
+	for {
+		name, err := p.parseName("a column name")
+		if err != nil {
+			return schema, err
+		}
+		tok := p.peek()
+		if tok.kind != tokenIdent {
+			return schema, p.unexpected("a column type")
+		}
+		columnType := openapi.LogicalColumnType(strings.ToUpper(tok.text))
+		if !columnType.IsValid() {
+			return schema, errorAt(tok, fmt.Sprintf("unknown column type %s, expected INT64 or VARCHAR", tok.text))
+		}
+		p.next()
+		schema.Columns = append(schema.Columns, openapi.Column{Name: name, Type: columnType})
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return schema, err
+	}
+
+	if p.acceptKeyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return schema, err
+		}
+		for {
+			column, err := p.parseName("a column name")
+			if err != nil {
+				return schema, err
+			}
+			ascending := !p.acceptKeyword("DESC")
+			if ascending {
+				p.acceptKeyword("ASC")
+			}
+			schema.SortKeys = append(schema.SortKeys, openapi.SortKey{ColumnName: column, Ascending: ascending})
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+	return schema, nil
+}
+
+func (p *parser) parseExprList() ([]openapi.ColumnExpression, error) {
+	exprs := []openapi.ColumnExpression{}
+	for {
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+		if !p.acceptSymbol(",") {
+			return exprs, nil
+		}
+	}
+}
+
+// parseExpr parses an expression, operators bind from the weakest: OR, AND, NOT, comparisons and IN,
+// addition and subtraction, multiplication and division, unary minus
+func (p *parser) parseExpr() (openapi.ColumnExpression, error) {
+	return p.parseOr()
+}
+
+func (p *parser) parseOr() (openapi.ColumnExpression, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.acceptKeyword("OR") {
+		var right openapi.ColumnExpression
+		right, err = p.parseAnd()
+		left = binary("OR", left, right)
+	}
+	return left, err
+}
+
+func (p *parser) parseAnd() (openapi.ColumnExpression, error) {
+	left, err := p.parseNot()
+	for err == nil && p.acceptKeyword("AND") {
+		var right openapi.ColumnExpression
+		right, err = p.parseNot()
+		left = binary("AND", left, right)
+	}
+	return left, err
+}
+
+func (p *parser) parseNot() (openapi.ColumnExpression, error) {
+	if !p.acceptKeyword("NOT") {
+		return p.parseComparison()
+	}
+	if p.isKeyword("EXISTS") {
+		return p.parseExists(true)
+	}
+	operand, err := p.parseNot()
+	return openapi.ColumnExpression{Expression: openapi.ColumnarUnaryOperation{Operator: "NOT", Operand: operand}}, err
+}
+
+func (p *parser) parseComparison() (openapi.ColumnExpression, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return left, err
+	}
+
+	if tok := p.peek(); tok.kind == tokenSymbol {
+		if operator, ok := comparisons[tok.text]; ok {
+			p.next()
+			right, err := p.parseAdditive()
+			return binary(operator, left, right), err
+		}
+	}
+	negated := p.isKeyword("NOT") && p.isKeywordAt(1, "IN")
+	if negated {
+		p.next()
+	}
+	if p.acceptKeyword("IN") {
+		return p.parseIn(left, negated)
+	}
+	return left, nil
+}
+
+// parseIn parses the parenthesized select or list of values following IN. Values are compared one by one:
+// a IN (1, 2) is a = 1 OR a = 2, a NOT IN (1, 2) is a <> 1 AND a <> 2.
+func (p *parser) parseIn(operand openapi.ColumnExpression, negated bool) (openapi.ColumnExpression, error) {
+	if err := p.expectSymbol("("); err != nil {
+		return operand, err
+	}
+	if p.isKeyword("SELECT") {
+		subquery, err := p.parseSelect()
+		if err != nil {
+			return operand, err
+		}
+		in := openapi.InSubquery{Operand: operand, Subquery: subquery, Negated: negated}
+		return openapi.ColumnExpression{Expression: in}, p.expectSymbol(")")
+	}
+
+	comparison, connective := "EQUAL", "OR"
+	if negated {
+		comparison, connective = "NOT_EQUAL", "AND"
+	}
+	values, err := p.parseExprList()
+	if err != nil {
+		return operand, err
+	}
+	result := binary(comparison, operand, values[0])
+	for _, value := range values[1:] {
+		result = binary(connective, result, binary(comparison, operand, value))
+	}
+	return result, p.expectSymbol(")")
+}
+
+func (p *parser) parseExists(negated bool) (openapi.ColumnExpression, error) {
+	exists := openapi.ExistsSubquery{Negated: negated}
+	if err := p.expectKeyword("EXISTS"); err != nil {
+		return openapi.ColumnExpression{}, err
+	}
+	if err := p.expectSymbol("("); err != nil {
+		return openapi.ColumnExpression{}, err
+	}
+	var err error
+	if exists.Subquery, err = p.parseSelect(); err != nil {
+		return openapi.ColumnExpression{}, err
+	}
+	return openapi.ColumnExpression{Expression: exists}, p.expectSymbol(")")
+}
+
+func (p *parser) parseAdditive() (openapi.ColumnExpression, error) {
+	left, err := p.parseMultiplicative()
+	for err == nil && (p.isSymbolAt(0, "+") || p.isSymbolAt(0, "-")) {
+		operator := "ADD"
+		if p.next().text == "-" {
+			operator = "SUBTRACT"
+		}
+		var right openapi.ColumnExpression
+		right, err = p.parseMultiplicative()
+		left = binary(operator, left, right)
+	}
+	return left, err
+}
+
+func (p *parser) parseMultiplicative() (openapi.ColumnExpression, error) {
+	left, err := p.parseUnary()
+	for err == nil && (p.isSymbolAt(0, "*") || p.isSymbolAt(0, "/")) {
+		operator := "MULTIPLY"
+		if p.next().text == "/" {
+			operator = "DIVIDE"
+		}
+		var right openapi.ColumnExpression
+		right, err = p.parseUnary()
+		left = binary(operator, left, right)
+	}
+	return left, err
+}
+
+// parseUnary parses a unary minus, negative integers are parsed as literals
+func (p *parser) parseUnary() (openapi.ColumnExpression, error) {
+	minus := p.peek()
+	if !p.acceptSymbol("-") {
+		return p.parsePrimary()
+	}
+	if tok := p.peek(); tok.kind == tokenInt {
+		p.next()
+		value, err := parseInt(minus, "-"+tok.text)
+		return literal(value), err
+	}
+	operand, err := p.parseUnary()
+	return openapi.ColumnExpression{Expression: openapi.ColumnarUnaryOperation{Operator: "MINUS", Operand: operand}}, err
+}
+
+func (p *parser) parsePrimary() (openapi.ColumnExpression, error) {
+	tok := p.peek()
+	switch {
+	case tok.kind == tokenInt:
+		value, err := p.parseInteger()
+		return literal(value), err
+	case tok.kind == tokenString:
+		p.next()
+		return literal(tok.text), nil
+	case p.isKeyword("TRUE") || p.isKeyword("FALSE"):
+		p.next()
+		return literal(strings.EqualFold(tok.text, "TRUE")), nil
+	case p.isKeyword("EXISTS"):
+		return p.parseExists(false)
+	case p.acceptSymbol("("):
+		if p.isKeyword("SELECT") {
+			return openapi.ColumnExpression{}, errorAt(p.peek(), "scalar subqueries are not supported, use IN or EXISTS")
+		}
+		e, err := p.parseExpr()
+		if err != nil {
+			return e, err
+		}
+		return e, p.expectSymbol(")")
+	case tok.kind == tokenIdent && !reserved[strings.ToUpper(tok.text)] && p.isSymbolAt(1, "("):
+		return p.parseFunction()
+	case p.isName():
+		return p.parseColumnReference()
+	default:
+		return openapi.ColumnExpression{}, p.unexpected("an expression")
+	}
+}
+
+// parseColumnReference parses a column optionally qualified by a table, which may be qualified by the database
+func (p *parser) parseColumnReference() (openapi.ColumnExpression, error) {
+	parts := []string{p.next().text}
+	for len(parts) < 3 && p.acceptSymbol(".") {
+		part, err := p.parseName("a column name")
+		if err != nil {
+			return openapi.ColumnExpression{}, err
+		}
+		parts = append(parts, part)
+	}
+	return openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{
+		TableName:  strings.Join(parts[:len(parts)-1], "."),
+		ColumnName: parts[len(parts)-1],
+	}}, nil
+}
+
+// parseFunction parses a call of a scalar function or of an aggregate, COUNT(*) counts all rows
+func (p *parser) parseFunction() (openapi.ColumnExpression, error) {
+	tok := p.next()
+	p.next()
+	name := strings.ToUpper(tok.text)
+
+	if aggregateFunctions[name] {
+		aggregate := openapi.AggregateFunction{AggregateName: name}
+		if name != "COUNT" || !p.acceptSymbol("*") {
+			aggregate.Distinct = p.acceptKeyword("DISTINCT")
+			argument, err := p.parseExpr()
+			if err != nil {
+				return openapi.ColumnExpression{}, err
+			}
+			aggregate.Argument = &argument
+		}
+		return openapi.ColumnExpression{Expression: aggregate}, p.expectSymbol(")")
+	}
+
+	if !scalarFunctions[name] {
+		return openapi.ColumnExpression{}, errorAt(tok, fmt.Sprintf("unknown function %s", tok.text))
+	}
+	function := openapi.Function{FunctionName: name}
+	if !p.acceptSymbol(")") {
+		arguments, err := p.parseExprList()
+		if err != nil {
+			return openapi.ColumnExpression{}, err
+		}
+		function.Arguments = arguments
+		if err := p.expectSymbol(")"); err != nil {
+			return openapi.ColumnExpression{}, err
+		}
+	}
+	return openapi.ColumnExpression{Expression: function}, nil
+}
+
+func binary(operator string, left, right openapi.ColumnExpression) openapi.ColumnExpression {
+	return openapi.ColumnExpression{Expression: openapi.ColumnarBinaryOperation{Operator: operator, LeftOperand: left, RightOperand: right}}
+}
+
+func literal(value interface{}) openapi.ColumnExpression {
+	return openapi.ColumnExpression{Expression: openapi.Literal{Value: openapi.LiteralValue{Data: value}}}
+}
@@ -0,0 +1,144 @@
+package sqlparser
+
+import (
+	"reflect"
+	"testing"
+
+	"isbd4/openapi"
+	"isbd4/pkg/engine/types"
+)
+
+func TestParse(t *testing.T) {
+	colRef := func(table, name string) openapi.ColumnExpression {
+		return openapi.ColumnExpression{Expression: openapi.ColumnReferenceExpression{TableName: table, ColumnName: name}}
+	}
+	limit := int32(10)
+
+	for _, tc := range []struct {
+		name     string
+		sql      string
+		expected Statement
+	}{
+		{
+			name: "select",
+			sql: `select distinct o.customer, SUM(price * 2) -- total
+				FROM shop.orders AS o JOIN customers c ON o.customer = c.name
+				WHERE NOT o.price <= -5 AND (c.city = 'Warsaw' OR c.city IN ('Krak''ow', 'Gdansk'))
+				GROUP BY o.customer HAVING COUNT(*) > 1
+				ORDER BY 2 DESC, customer LIMIT 10 OFFSET 20;`,
+			expected: Statement{Query: &openapi.QueryQueryDefinition{Definition: openapi.SelectQuery{
+				Distinct: true,
+				ColumnClauses: []openapi.ColumnExpression{
+					colRef("o", "customer"),
+					{Expression: openapi.AggregateFunction{AggregateName: "SUM", Argument: &openapi.ColumnExpression{
+						Expression: openapi.ColumnarBinaryOperation{Operator: "MULTIPLY", LeftOperand: colRef("", "price"), RightOperand: literal(int64(2))},
+					}}},
+				},
+				FromClause: &openapi.FromClause{TableName: "shop.orders", Alias: "o", Joins: []openapi.JoinClause{{
+					JoinType:  "INNER",
+					TableName: "customers",
+					Alias:     "c",
+					Condition: binary("EQUAL", colRef("o", "customer"), colRef("c", "name")),
+				}}},
+				WhereClause: binary("AND",
+					openapi.ColumnExpression{Expression: openapi.ColumnarUnaryOperation{Operator: "NOT", Operand: binary("LESS_EQUAL", colRef("o", "price"), literal(int64(-5)))}},
+					binary("OR",
+						binary("EQUAL", colRef("c", "city"), literal("Warsaw")),
+						binary("OR", binary("EQUAL", colRef("c", "city"), literal("Krak'ow")), binary("EQUAL", colRef("c", "city"), literal("Gdansk"))),
+					),
+				),
+				GroupByClause: []openapi.ColumnExpression{colRef("o", "customer")},
+				HavingClause:  binary("GREATER_THAN", openapi.ColumnExpression{Expression: openapi.AggregateFunction{AggregateName: "COUNT"}}, literal(int64(1))),
+				OrderByClause: []openapi.OrderByExpression{{ColumnIndex: 1, Ascending: false}, {ColumnIndex: 0, Ascending: true}},
+				LimitClause:   &openapi.LimitExpression{Limit: &limit, Offset: 20},
+			}}},
+		},
+		{
+			name: "copy",
+			sql:  "COPY orders (id, price) FROM '/data/orders.csv' WITH HEADER",
+			expected: Statement{Query: &openapi.QueryQueryDefinition{Definition: openapi.CopyQuery{
+				SourceFilepath:       "/data/orders.csv",
+				DestinationTableName: "orders",
+				DestinationColumns:   []string{"id", "price"},
+				DoesCsvContainHeader: true,
+			}}},
+		},
+		{
+			name: "create table",
+			sql:  "CREATE TABLE orders (id int64, customer VARCHAR) ORDER BY customer, id DESC",
+			expected: Statement{CreateTable: &openapi.TableSchema{
+				Name:     "orders",
+				Columns:  []openapi.Column{{Name: "id", Type: openapi.INT64}, {Name: "customer", Type: openapi.VARCHAR}},
+				SortKeys: []openapi.SortKey{{ColumnName: "customer", Ascending: true}, {ColumnName: "id", Ascending: false}},
+			}},
+		},
+		{
+			name:     "drop table",
+			sql:      "drop table shop.orders",
+			expected: Statement{DropTable: "shop.orders"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stmt, err := Parse(tc.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if !reflect.DeepEqual(*stmt, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, *stmt)
+			}
+		})
+	}
+}
+
+func TestParse_Subqueries(t *testing.T) {
+	stmt, err := Parse(`SELECT "select" FROM (SELECT id FROM t) s WHERE id NOT IN (SELECT id FROM u) AND NOT EXISTS (SELECT 1)`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	query := stmt.Query.Definition.(openapi.SelectQuery)
+	if ref := query.ColumnClauses[0].Expression.(openapi.ColumnReferenceExpression); ref.ColumnName != "select" {
+		t.Errorf("Expected the quoted identifier to be a column name, got %+v", ref)
+	}
+	if query.FromClause.Subquery == nil || query.FromClause.Alias != "s" {
+		t.Errorf("Expected a derived table s, got %+v", query.FromClause)
+	}
+	where := query.WhereClause.Expression.(openapi.ColumnarBinaryOperation)
+	if in, ok := where.LeftOperand.Expression.(openapi.InSubquery); !ok || !in.Negated {
+		t.Errorf("Expected NOT IN subquery, got %+v", where.LeftOperand)
+	}
+	if exists, ok := where.RightOperand.Expression.(openapi.ExistsSubquery); !ok || !exists.Negated {
+		t.Errorf("Expected NOT EXISTS subquery, got %+v", where.RightOperand)
+	}
+}
+
+func TestParse_SyntaxErrors(t *testing.T) {
+	for _, tc := range []struct {
+		sql     string
+		problem types.ErrWithCtx
+	}{
+		{"SELEC a FROM t", types.ErrWithCtx{Error: "expected SELECT, COPY, CREATE TABLE or DROP TABLE, got 'SELEC'", Context: "line 1, column 1"}},
+		{"SELECT a\nFROM t\nWHERE a = ", types.ErrWithCtx{Error: "expected an expression, got end of input", Context: "line 3, column 11"}},
+		{"SELECT a FROM t WHERE b = 'x", types.ErrWithCtx{Error: "unterminated string", Context: "line 1, column 27"}},
+		{"SELECT * FROM t", types.ErrWithCtx{Error: "SELECT * is not supported, list the selected columns", Context: "line 1, column 8"}},
+		{"SELECT a FROM t ORDER BY b", types.ErrWithCtx{Error: "ORDER BY expression has to be a selected column or its position", Context: "line 1, column 26"}},
+		{"SELECT a FROM t ORDER BY 2", types.ErrWithCtx{Error: "ORDER BY position 2 is not in the select list", Context: "line 1, column 26"}},
+		{"SELECT a FROM t LIMIT 1.5", types.ErrWithCtx{Error: "only integer numbers are supported", Context: "line 1, column 23"}},
+		{"SELECT a AS b FROM t", types.ErrWithCtx{Error: "aliases of selected columns are not supported", Context: "line 1, column 10"}},
+		{"SELECT MEDIAN(a) FROM t", types.ErrWithCtx{Error: "unknown function MEDIAN", Context: "line 1, column 8"}},
+		{"SELECT a FROM (SELECT a FROM t)", types.ErrWithCtx{Error: "expected an alias of the subquery, got end of input", Context: "line 1, column 32"}},
+		{"SELECT a FROM t WHERE a > 1 GARBAGE", types.ErrWithCtx{Error: "unexpected 'GARBAGE' after the end of the statement", Context: "line 1, column 29"}},
+		{"CREATE TABLE t (a INT32)", types.ErrWithCtx{Error: "unknown column type INT32, expected INT64 or VARCHAR", Context: "line 1, column 19"}},
+		{"COPY t FROM data.csv", types.ErrWithCtx{Error: "expected a file path string, got 'data'", Context: "line 1, column 13"}},
+	} {
+		t.Run(tc.sql, func(t *testing.T) {
+			_, err := Parse(tc.sql)
+			ve, ok := err.(*types.ValidationError)
+			if !ok {
+				t.Fatalf("Expected a validation error, got %v", err)
+			}
+			if !reflect.DeepEqual(ve.Problems, []types.ErrWithCtx{tc.problem}) {
+				t.Errorf("Expected problem %v, got %v", tc.problem, ve.Problems)
+			}
+		})
+	}
+}
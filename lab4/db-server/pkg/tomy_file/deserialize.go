@@ -88,6 +88,26 @@ func DeserializeColumns(filePath string, columns []string) (table *ColumnarTable
 	return table, nil
 }
 
+// ReadFileMetadata reads only the footer of the file, without touching column data
+func ReadFileMetadata(filePath string) (*FileMetaData, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("can't open the file: %w", err)
+	}
+	defer f.Close()
+
+	if err := verifyMagicValue(f, BeginMagic, 0); err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("can't get the size of the file: %w", err)
+	}
+
+	return readMetadata(f, fi.Size())
+}
+
 func verifyMagicValue(f *os.File, expectedMagic string, offset int64) error {
 	if _, err := f.Seek(offset, io.SeekStart); err != nil {
 		return fmt.Errorf("failed to seek to %s (offset %d): %w", expectedMagic, offset, err)
@@ -195,6 +215,13 @@ func deserializeMetadata(buf []byte) (*FileMetaData, error) {
 		meta.Columns[i].CompressedSize = int64(compressedSize)
 	}
 
+	if err := readStatistics(reader, meta.Columns); err != nil {
+		return nil, err
+	}
+	if err := readSortOrder(reader, meta); err != nil {
+		return nil, err
+	}
+
 	return meta, nil
 }
 
@@ -0,0 +1,91 @@
+package tomy_file
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// HyperLogLog sketch used to estimate the number of distinct values in a column.
+// Registers are stored in the file footer, so sketches of different files can be merged.
+const (
+	HllPrecision    = 10
+	HllNumRegisters = 1 << HllPrecision
+)
+
+type HyperLogLog struct {
+	Registers []uint8
+}
+
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{Registers: make([]uint8, HllNumRegisters)}
+}
+
+func HyperLogLogFromRegisters(registers []uint8) (*HyperLogLog, error) {
+	if len(registers) != HllNumRegisters {
+		return nil, fmt.Errorf("invalid HyperLogLog sketch size: %d, expected %d", len(registers), HllNumRegisters)
+	}
+	regs := make([]uint8, HllNumRegisters)
+	copy(regs, registers)
+	return &HyperLogLog{Registers: regs}, nil
+}
+
+func (h *HyperLogLog) AddInt64(v int64) {
+	h.addHash(mix64(uint64(v)))
+}
+
+func (h *HyperLogLog) AddBytes(b []byte) {
+	hasher := fnv.New64a()
+	hasher.Write(b)
+	h.addHash(mix64(hasher.Sum64()))
+}
+
+func (h *HyperLogLog) addHash(hash uint64) {
+	idx := hash >> (64 - HllPrecision)
+	rest := hash<<HllPrecision | 1<<(HllPrecision-1) // guard bit bounds the rank
+	rank := uint8(bits.LeadingZeros64(rest)) + 1
+	if rank > h.Registers[idx] {
+		h.Registers[idx] = rank
+	}
+}
+
+// Merge folds other into h, the result estimates the size of the union of both sets.
+func (h *HyperLogLog) Merge(other *HyperLogLog) {
+	for i, r := range other.Registers {
+		if r > h.Registers[i] {
+			h.Registers[i] = r
+		}
+	}
+}
+
+func (h *HyperLogLog) Estimate() uint64 {
+	m := float64(HllNumRegisters)
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.Registers {
+		sum += 1.0 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+
+	// small range correction (linear counting)
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// splitmix64 finalizer, spreads poorly distributed inputs (e.g. sequential ids) over all bits
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -69,6 +69,7 @@ func (table ColumnarTable) Serialize(filePath string) error {
 			Type:           col.GetType(),
 			DataOffset:     offset,
 			CompressedSize: compressedSize,
+			Stats:          col.ComputeStatistics(),
 		})
 	}
 
@@ -77,6 +78,7 @@ func (table ColumnarTable) Serialize(filePath string) error {
 		NumRows:    table.NumRows,
 		NumColumns: numColumns,
 		Columns:    colMeta,
+		SortOrder:  table.SortOrder,
 	}); err != nil {
 		return fmt.Errorf("failed to write metadata: %w", err)
 	}
@@ -99,6 +101,11 @@ func writeMetadataBlockAndOffset(f *os.File, meta FileMetaData) error {
 	if err := writeMetadataVLE(f, meta.NumRows, meta.NumColumns, meta.Columns); err != nil {
 		return fmt.Errorf("failed to write metadata: %w", err)
 	}
+	if len(meta.SortOrder) > 0 {
+		if err := writeSortOrderVLE(f, meta.SortOrder); err != nil {
+			return fmt.Errorf("failed to write sort order: %w", err)
+		}
+	}
 
 	// Write Metadata Offset
 	if err := binary.Write(f, binary.LittleEndian, metadataOffset); err != nil {
@@ -143,5 +150,7 @@ func writeMetadataVLE(w io.Writer, numRows uint64, numCols uint64, cols []Column
 			return err
 		}
 	}
-	return nil
+
+	// Column statistics
+	return writeStatisticsVLE(w, cols)
 }
@@ -0,0 +1,77 @@
+package tomy_file
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Marks the beginning of the optional sort order section, written after statistics
+const sortOrderSectionMarker byte = 0x02
+
+// SortColumn is one key of the order in which rows of the file are sorted
+type SortColumn struct {
+	ColumnIdx int
+	Ascending bool
+}
+
+// [Marker(1B)][NumKeys (varint)] and for every key: [ColumnIdx (varint)][Ascending (1B)]
+func writeSortOrderVLE(w io.Writer, sortOrder []SortColumn) error {
+	if _, err := w.Write([]byte{sortOrderSectionMarker}); err != nil {
+		return err
+	}
+	if err := WriteVarint(w, uint64(len(sortOrder))); err != nil {
+		return err
+	}
+
+	for _, key := range sortOrder {
+		if err := WriteVarint(w, uint64(key.ColumnIdx)); err != nil {
+			return err
+		}
+		var ascending byte
+		if key.Ascending {
+			ascending = 1
+		}
+		if _, err := w.Write([]byte{ascending}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reads the sort order section if present, files of unsorted tables end right after statistics
+func readSortOrder(reader *bytes.Reader, meta *FileMetaData) error {
+	if reader.Len() == 0 {
+		return nil
+	}
+
+	marker, err := reader.ReadByte()
+	if err != nil {
+		return fmt.Errorf("failed to read sort order marker: %w", err)
+	}
+	if marker != sortOrderSectionMarker {
+		return fmt.Errorf("invalid sort order marker: %d", marker)
+	}
+
+	numKeys, err := ReadVarint(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read number of sort keys: %w", err)
+	}
+
+	meta.SortOrder = make([]SortColumn, numKeys)
+	for i := range meta.SortOrder {
+		colIdx, err := ReadVarint(reader)
+		if err != nil {
+			return fmt.Errorf("failed to read column of sort key %d: %w", i, err)
+		}
+		if colIdx >= meta.NumColumns {
+			return fmt.Errorf("sort key %d refers to column %d, file has %d columns", i, colIdx, meta.NumColumns)
+		}
+		ascending, err := reader.ReadByte()
+		if err != nil {
+			return fmt.Errorf("failed to read direction of sort key %d: %w", i, err)
+		}
+		meta.SortOrder[i] = SortColumn{ColumnIdx: int(colIdx), Ascending: ascending == 1}
+	}
+	return nil
+}
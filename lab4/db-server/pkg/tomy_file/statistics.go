@@ -0,0 +1,206 @@
+package tomy_file
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Marks the beginning of the optional statistics section in the metadata block
+const statisticsSectionMarker byte = 0x01
+
+// AnyColumn interface method
+func (c Int64Column) ComputeStatistics() *ColumnStatistics {
+	stats := &ColumnStatistics{
+		UncompressedSize: int64(len(c.Values) * 8),
+		Sketch:           NewHyperLogLog(),
+	}
+	for i, v := range c.Values {
+		if i == 0 || v < stats.MinInt64 {
+			stats.MinInt64 = v
+		}
+		if i == 0 || v > stats.MaxInt64 {
+			stats.MaxInt64 = v
+		}
+		stats.Sketch.AddInt64(v)
+	}
+	stats.HasMinMax = len(c.Values) > 0
+	return stats
+}
+
+// AnyColumn interface method
+func (c VarcharColumn) ComputeStatistics() *ColumnStatistics {
+	stats := &ColumnStatistics{
+		UncompressedSize: int64(len(c.Offsets)*8 + len(c.Data)),
+		Sketch:           NewHyperLogLog(),
+	}
+	for i := range c.Offsets {
+		end := uint64(len(c.Data))
+		if i+1 < len(c.Offsets) {
+			end = c.Offsets[i+1]
+		}
+		v := c.Data[c.Offsets[i]:end]
+		if i == 0 || bytes.Compare(v, stats.MinVarchar) < 0 {
+			stats.MinVarchar = v
+		}
+		if i == 0 || bytes.Compare(v, stats.MaxVarchar) > 0 {
+			stats.MaxVarchar = v
+		}
+		stats.Sketch.AddBytes(v)
+	}
+	stats.HasMinMax = len(c.Offsets) > 0
+	// detach from the column data
+	stats.MinVarchar = bytes.Clone(stats.MinVarchar)
+	stats.MaxVarchar = bytes.Clone(stats.MaxVarchar)
+	return stats
+}
+
+// [Marker(1B)] and for every column:
+// [UncompressedSize (varint)][HasMinMax (1B)][Min][Max][SketchLength (varint)][Sketch]
+// INT64 bounds are ZigZag varints, VARCHAR bounds are length (varint) + bytes
+func writeStatisticsVLE(w io.Writer, cols []ColumnMetaData) error {
+	if _, err := w.Write([]byte{statisticsSectionMarker}); err != nil {
+		return err
+	}
+
+	for _, col := range cols {
+		stats := col.Stats
+		if stats == nil {
+			return fmt.Errorf("missing statistics for column %s", col.Name)
+		}
+
+		if err := WriteVarint(w, uint64(stats.UncompressedSize)); err != nil {
+			return err
+		}
+
+		var hasMinMax byte
+		if stats.HasMinMax {
+			hasMinMax = 1
+		}
+		if err := binary.Write(w, binary.LittleEndian, hasMinMax); err != nil {
+			return err
+		}
+
+		if stats.HasMinMax {
+			if err := writeBounds(w, col.Type, stats); err != nil {
+				return err
+			}
+		}
+
+		if err := WriteVarint(w, uint64(len(stats.Sketch.Registers))); err != nil {
+			return err
+		}
+		if _, err := w.Write(stats.Sketch.Registers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeBounds(w io.Writer, colType ColumnType, stats *ColumnStatistics) error {
+	switch colType {
+	case TypeInt64:
+		if err := WriteVarint(w, ZigZagEncode(stats.MinInt64)); err != nil {
+			return err
+		}
+		return WriteVarint(w, ZigZagEncode(stats.MaxInt64))
+	case TypeVarchar:
+		for _, bound := range [][]byte{stats.MinVarchar, stats.MaxVarchar} {
+			if err := WriteVarint(w, uint64(len(bound))); err != nil {
+				return err
+			}
+			if _, err := w.Write(bound); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown column type: %v", colType)
+	}
+}
+
+// Reads the statistics section if present, files written before it was introduced end right after column definitions
+func readStatistics(reader *bytes.Reader, cols []ColumnMetaData) error {
+	if reader.Len() == 0 {
+		return nil
+	}
+
+	marker, err := reader.ReadByte()
+	if err != nil {
+		return fmt.Errorf("failed to read statistics marker: %w", err)
+	}
+	if marker != statisticsSectionMarker {
+		return fmt.Errorf("invalid statistics marker: %d", marker)
+	}
+
+	for i := range cols {
+		stats := &ColumnStatistics{}
+
+		uncompressedSize, err := ReadVarint(reader)
+		if err != nil {
+			return fmt.Errorf("failed to read uncompressed size of column %d: %w", i, err)
+		}
+		stats.UncompressedSize = int64(uncompressedSize)
+
+		hasMinMax, err := reader.ReadByte()
+		if err != nil {
+			return fmt.Errorf("failed to read statistics of column %d: %w", i, err)
+		}
+		stats.HasMinMax = hasMinMax == 1
+
+		if stats.HasMinMax {
+			if err := readBounds(reader, cols[i].Type, stats); err != nil {
+				return fmt.Errorf("failed to read bounds of column %d: %w", i, err)
+			}
+		}
+
+		sketchLength, err := ReadVarint(reader)
+		if err != nil {
+			return fmt.Errorf("failed to read sketch length of column %d: %w", i, err)
+		}
+		registers := make([]uint8, sketchLength)
+		if _, err := io.ReadFull(reader, registers); err != nil {
+			return fmt.Errorf("failed to read sketch of column %d: %w", i, err)
+		}
+		if stats.Sketch, err = HyperLogLogFromRegisters(registers); err != nil {
+			return fmt.Errorf("column %d: %w", i, err)
+		}
+
+		cols[i].Stats = stats
+	}
+	return nil
+}
+
+func readBounds(reader *bytes.Reader, colType ColumnType, stats *ColumnStatistics) error {
+	switch colType {
+	case TypeInt64:
+		minZZ, err := ReadVarint(reader)
+		if err != nil {
+			return err
+		}
+		maxZZ, err := ReadVarint(reader)
+		if err != nil {
+			return err
+		}
+		stats.MinInt64 = ZigZagDecode(minZZ)
+		stats.MaxInt64 = ZigZagDecode(maxZZ)
+		return nil
+	case TypeVarchar:
+		bounds := make([][]byte, 2)
+		for i := range bounds {
+			length, err := ReadVarint(reader)
+			if err != nil {
+				return err
+			}
+			bounds[i] = make([]byte, length)
+			if _, err := io.ReadFull(reader, bounds[i]); err != nil {
+				return err
+			}
+		}
+		stats.MinVarchar, stats.MaxVarchar = bounds[0], bounds[1]
+		return nil
+	default:
+		return fmt.Errorf("unknown column type: %v", colType)
+	}
+}
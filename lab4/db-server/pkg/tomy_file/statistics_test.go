@@ -0,0 +1,117 @@
+package tomy_file
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestStatistics_RoundTrip(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "stats.tomy")
+
+	table := ColumnarTable{
+		NumRows: 4,
+		Columns: []AnyColumn{
+			Int64Column{Name: "id", Values: []int64{7, -3, 12, 7}},
+			*makeVarcharColumn("name", "row", 8, 4), // row8, row9, row10, row11
+		},
+	}
+	if err := table.Serialize(filePath); err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+
+	meta, err := ReadFileMetadata(filePath)
+	if err != nil {
+		t.Fatalf("ReadFileMetadata failed: %v", err)
+	}
+	if meta.NumRows != 4 {
+		t.Errorf("Expected 4 rows, got %d", meta.NumRows)
+	}
+
+	idStats := meta.Columns[0].Stats
+	if idStats == nil || !idStats.HasMinMax {
+		t.Fatalf("Expected statistics for column id")
+	}
+	if idStats.MinInt64 != -3 || idStats.MaxInt64 != 12 {
+		t.Errorf("Expected id bounds [-3, 12], got [%d, %d]", idStats.MinInt64, idStats.MaxInt64)
+	}
+	if idStats.UncompressedSize != 32 {
+		t.Errorf("Expected uncompressed size 32, got %d", idStats.UncompressedSize)
+	}
+	if est := idStats.Sketch.Estimate(); est != 3 {
+		t.Errorf("Expected 3 distinct ids, got %d", est)
+	}
+
+	nameStats := meta.Columns[1].Stats
+	if string(nameStats.MinVarchar) != "row10" || string(nameStats.MaxVarchar) != "row9" {
+		t.Errorf("Expected name bounds [row10, row9], got [%s, %s]", nameStats.MinVarchar, nameStats.MaxVarchar)
+	}
+
+	// statistics section must not break reading the data
+	readTable, err := Deserialize(filePath)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	if readTable.NumRows != 4 {
+		t.Errorf("Expected 4 rows after deserialization, got %d", readTable.NumRows)
+	}
+}
+
+func TestHyperLogLog_MergeEstimate(t *testing.T) {
+	h1 := NewHyperLogLog()
+	h2 := NewHyperLogLog()
+	for i := 0; i < 20000; i++ {
+		h1.AddInt64(int64(i))
+		h2.AddBytes([]byte(fmt.Sprintf("key-%d", i)))
+	}
+	for i := 10000; i < 30000; i++ {
+		h2.AddInt64(int64(i))
+	}
+
+	h1.Merge(h2)
+	// 30000 ints + 20000 strings, allow 10% error
+	est := float64(h1.Estimate())
+	if est < 45000 || est > 55000 {
+		t.Errorf("Expected estimate close to 50000, got %.0f", est)
+	}
+}
+
+func TestSortOrder_RoundTrip(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "sorted.tomy")
+
+	table := ColumnarTable{
+		NumRows: 3,
+		Columns: []AnyColumn{
+			Int64Column{Name: "id", Values: []int64{1, 2, 2}},
+			*makeVarcharColumn("name", "row", 0, 3),
+		},
+		SortOrder: []SortColumn{{ColumnIdx: 0, Ascending: true}, {ColumnIdx: 1, Ascending: false}},
+	}
+	if err := table.Serialize(filePath); err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+
+	meta, err := ReadFileMetadata(filePath)
+	if err != nil {
+		t.Fatalf("ReadFileMetadata failed: %v", err)
+	}
+	if len(meta.SortOrder) != 2 || meta.SortOrder[0] != table.SortOrder[0] || meta.SortOrder[1] != table.SortOrder[1] {
+		t.Errorf("Expected sort order %v, got %v", table.SortOrder, meta.SortOrder)
+	}
+	if meta.Columns[0].Stats == nil {
+		t.Errorf("Expected statistics to be read before the sort order")
+	}
+
+	// files without the section are unsorted
+	table.SortOrder = nil
+	if err := table.Serialize(filePath); err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	meta, err = ReadFileMetadata(filePath)
+	if err != nil {
+		t.Fatalf("ReadFileMetadata failed: %v", err)
+	}
+	if len(meta.SortOrder) != 0 {
+		t.Errorf("Expected no sort order, got %v", meta.SortOrder)
+	}
+}
@@ -12,6 +12,9 @@ type BatchReader struct {
 	currentFileIdx int
 	currentTable   *ColumnarTable
 	currentRow     uint64
+
+	batchFile  string // file of the last returned batch
+	batchStart uint64 // position of the first row of the last returned batch in its file
 }
 
 func NewBatchReader(filePaths []string, columnsToRead []string) *BatchReader {
@@ -67,10 +70,17 @@ func (r *BatchReader) GetNextBatch(batchSize int) (*ColumnarTable, error) {
 		batch.Columns[i] = sliced
 	}
 
+	r.batchFile = r.filePaths[r.currentFileIdx]
+	r.batchStart = r.currentRow
 	r.currentRow += toRead
 	return batch, nil
 }
 
+// BatchPosition returns the file of the last returned batch and the position of its first row in that file
+func (r *BatchReader) BatchPosition() (string, uint64) {
+	return r.batchFile, r.batchStart
+}
+
 func (r *BatchReader) loadNextFile() error {
 	if r.currentFileIdx >= len(r.filePaths) {
 		return io.EOF
@@ -9,11 +9,13 @@ type AnyColumn interface {
 	GetType() ColumnType
 	GetNumRows() int
 	SerializeData(w io.Writer) (compressedSize int64, err error) // implemented in serailize.go
+	ComputeStatistics() *ColumnStatistics                        // implemented in statistics.go
 }
 
 type ColumnarTable struct {
-	NumRows uint64
-	Columns []AnyColumn
+	NumRows   uint64
+	Columns   []AnyColumn
+	SortOrder []SortColumn // order of rows recorded in the footer, empty for unsorted data
 }
 
 type Int64Column struct {
@@ -70,10 +72,22 @@ type ColumnMetaData struct {
 	Type           ColumnType
 	DataOffset     int64
 	CompressedSize int64
+	Stats          *ColumnStatistics // nil for files written without the statistics section
+}
+
+type ColumnStatistics struct {
+	UncompressedSize int64
+	HasMinMax        bool // false for empty columns
+	MinInt64         int64
+	MaxInt64         int64
+	MinVarchar       []byte
+	MaxVarchar       []byte
+	Sketch           *HyperLogLog
 }
 
 type FileMetaData struct {
 	NumRows    uint64 // assuming there won't be more than 2^64 rows, with a single column and (2^64)-1 rows this would result in a huuuuge file.
 	NumColumns uint64
 	Columns    []ColumnMetaData
+	SortOrder  []SortColumn // nil when rows are not sorted
 }